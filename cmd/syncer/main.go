@@ -30,6 +30,7 @@ import (
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/node"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/tracing"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
@@ -51,7 +52,9 @@ var (
 	enableLeaderElection    = flag.Bool("leader-election", false, "Enable leader election.")
 	leaderElectionNamespace = flag.String("leader-election-namespace", "",
 		"Namespace where the leader election resource lives. Defaults to the pod namespace if not set.")
-	printVersion  = flag.Bool("version", false, "Print syncer version and exit")
+	printVersion   = flag.Bool("version", false, "Print syncer version and exit")
+	validateConfig = flag.Bool("validate-config", false,
+		"Validate the config file, print all the errors found in it and exit")
 	operationMode = flag.String("operation-mode", operationModeMetaDataSync,
 		"specify operation mode METADATA_SYNC or WEBHOOK_SERVER")
 
@@ -70,10 +73,23 @@ func main() {
 		fmt.Printf("%s\n", syncer.Version)
 		return
 	}
+	if *validateConfig {
+		runConfigValidation()
+	}
 	logType := logger.LogLevel(os.Getenv(logger.EnvLoggerLevel))
 	logger.SetLoggerLevel(logType)
 	ctx, log := logger.GetNewContextWithLogger()
 	log.Infof("Version : %s", syncer.Version)
+	shutdownTracing, err := tracing.InitTracing(ctx, "vsphere-syncer")
+	if err != nil {
+		log.Errorf("Failed to initialize tracing. Error: %v", err)
+	} else {
+		defer func() {
+			if err := shutdownTracing(ctx); err != nil {
+				log.Errorf("Failed to shut down tracing. Error: %v", err)
+			}
+		}()
+	}
 
 	// Set CO agnostic init params.
 	clusterFlavor, err := config.GetClusterFlavor(ctx)
@@ -84,6 +100,14 @@ func main() {
 		*internalFSSName, *internalFSSNamespace, "")
 	admissionhandler.COInitParams = &syncer.COInitParams
 
+	logComponent := k8s.LogComponentSyncer
+	if *operationMode == operationModeWebHookServer {
+		logComponent = k8s.LogComponentWebhook
+	}
+	if err := k8s.WatchLogLevelConfigMap(ctx, common.GetCSINamespace(), logComponent); err != nil {
+		log.Warnf("Log level cannot be changed at runtime. Error: %v", err)
+	}
+
 	if *operationMode == operationModeWebHookServer {
 		log.Infof("Starting container with operation mode: %v", operationModeWebHookServer)
 		if webHookStartError := admissionhandler.StartWebhookServer(ctx); webHookStartError != nil {
@@ -179,6 +203,14 @@ func initSyncerComponents(ctx context.Context, clusterFlavor cnstypes.CnsCluster
 				os.Exit(1)
 			}
 		}
+		if clusterFlavor == cnstypes.CnsClusterFlavorVanilla {
+			// Read the vCenter credentials from the referenced Secrets before
+			// connecting to vCenter.
+			if err := k8s.WatchCredentialsSecrets(ctx, configInfo.Cfg, common.GetCSINamespace()); err != nil {
+				log.Errorf("failed to watch the vCenter credentials Secrets. Err: %+v", err)
+				os.Exit(1)
+			}
+		}
 
 		// Initialize CNS Operator for Supervisor clusters.
 		if clusterFlavor == cnstypes.CnsClusterFlavorWorkload {
@@ -215,3 +247,23 @@ func initSyncerComponents(ctx context.Context, clusterFlavor cnstypes.CnsCluster
 		}
 	}
 }
+
+// runConfigValidation validates the config file, prints all the errors found
+// in it and exits, with status 1 if errors were found.
+func runConfigValidation() {
+	// Only the validation report is printed.
+	if err := logger.SetLevel("fatal"); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to set log level: %v\n", err)
+	}
+	ctx, _ := logger.GetNewContextWithLogger()
+	cfgPath, errs := common.ValidateConfigFile(ctx)
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cfgPath, err)
+	}
+	if len(errs) > 0 {
+		fmt.Fprintf(os.Stderr, "%s: %d error(s) found\n", cfgPath, len(errs))
+		os.Exit(1)
+	}
+	fmt.Printf("%s: valid\n", cfgPath)
+	os.Exit(0)
+}
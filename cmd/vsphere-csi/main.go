@@ -20,16 +20,22 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	csiconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/tracing"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
 	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
 )
 
 var (
-	printVersion = flag.Bool("version", false, "Print driver version and exit")
+	printVersion   = flag.Bool("version", false, "Print driver version and exit")
+	validateConfig = flag.Bool("validate-config", false,
+		"Validate the config file, print all the errors found in it and exit")
 
 	supervisorFSSName = flag.String("supervisor-fss-name", "",
 		"Name of the feature state switch configmap in supervisor cluster")
@@ -46,10 +52,23 @@ func main() {
 		fmt.Printf("%s\n", service.Version)
 		return
 	}
+	if *validateConfig {
+		runConfigValidation()
+	}
 	logType := logger.LogLevel(os.Getenv(logger.EnvLoggerLevel))
 	logger.SetLoggerLevel(logType)
 	ctx, log := logger.GetNewContextWithLogger()
 	log.Infof("Version : %s", service.Version)
+	shutdownTracing, err := tracing.InitTracing(ctx, "vsphere-csi")
+	if err != nil {
+		log.Errorf("Failed to initialize tracing. Error: %v", err)
+	} else {
+		defer func() {
+			if err := shutdownTracing(ctx); err != nil {
+				log.Errorf("Failed to shut down tracing. Error: %v", err)
+			}
+		}()
+	}
 
 	// Set CO Init params.
 	clusterFlavor, err := csiconfig.GetClusterFlavor(ctx)
@@ -60,6 +79,14 @@ func main() {
 	commonco.SetInitParams(ctx, clusterFlavor, &service.COInitParams, *supervisorFSSName, *supervisorFSSNamespace,
 		*internalFSSName, *internalFSSNamespace, serviceMode)
 
+	logComponent := k8s.LogComponentController
+	if strings.EqualFold(serviceMode, "node") {
+		logComponent = k8s.LogComponentNode
+	}
+	if err := k8s.WatchLogLevelConfigMap(ctx, common.GetCSINamespace(), logComponent); err != nil {
+		log.Warnf("Log level cannot be changed at runtime. Error: %v", err)
+	}
+
 	// If no endpoint is set then exit the program.
 	CSIEndpoint := os.Getenv(csitypes.EnvVarEndpoint)
 	if CSIEndpoint == "" {
@@ -70,3 +97,23 @@ func main() {
 	vSphereCSIDriver := service.NewDriver()
 	vSphereCSIDriver.Run(ctx, CSIEndpoint)
 }
+
+// runConfigValidation validates the config file, prints all the errors found
+// in it and exits, with status 1 if errors were found.
+func runConfigValidation() {
+	// Only the validation report is printed.
+	if err := logger.SetLevel("fatal"); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to set log level: %v\n", err)
+	}
+	ctx, _ := logger.GetNewContextWithLogger()
+	cfgPath, errs := common.ValidateConfigFile(ctx)
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cfgPath, err)
+	}
+	if len(errs) > 0 {
+		fmt.Fprintf(os.Stderr, "%s: %d error(s) found\n", cfgPath, len(errs))
+		os.Exit(1)
+	}
+	fmt.Printf("%s: valid\n", cfgPath)
+	os.Exit(0)
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CnsCrossVCenterVolumeRelocationPhase is the phase of a
+// CnsCrossVCenterVolumeRelocation instance.
+type CnsCrossVCenterVolumeRelocationPhase string
+
+const (
+	// RelocationPhasePending indicates the relocation is yet to be validated.
+	RelocationPhasePending CnsCrossVCenterVolumeRelocationPhase = ""
+	// RelocationPhaseRelocating indicates the volume is being relocated to
+	// the target datastore.
+	RelocationPhaseRelocating CnsCrossVCenterVolumeRelocationPhase = "Relocating"
+	// RelocationPhaseUnregistering indicates the volume is being unregistered
+	// from CNS on the source vCenter.
+	RelocationPhaseUnregistering CnsCrossVCenterVolumeRelocationPhase = "Unregistering"
+	// RelocationPhaseRegistering indicates the volume is being registered
+	// with CNS on the target vCenter.
+	RelocationPhaseRegistering CnsCrossVCenterVolumeRelocationPhase = "Registering"
+	// RelocationPhaseUpdatingPersistentVolume indicates the node affinity of
+	// the PersistentVolume is being updated.
+	RelocationPhaseUpdatingPersistentVolume CnsCrossVCenterVolumeRelocationPhase = "UpdatingPersistentVolume"
+	// RelocationPhaseCompleted indicates the relocation is complete.
+	RelocationPhaseCompleted CnsCrossVCenterVolumeRelocationPhase = "Completed"
+)
+
+// CnsCrossVCenterVolumeRelocationSpec defines the desired state of CnsCrossVCenterVolumeRelocation
+// +k8s:openapi-gen=true
+type CnsCrossVCenterVolumeRelocationSpec struct {
+	// PersistentVolumeName is the name of the PersistentVolume to be relocated.
+	// The PersistentVolume must be a block volume which is not attached to
+	// any node.
+	PersistentVolumeName string `json:"persistentVolumeName"`
+
+	// TargetVCenter is the vCenter host, as specified in the vSphere config
+	// secret, the volume is relocated to.
+	TargetVCenter string `json:"targetVCenter"`
+
+	// TargetDatastoreURL is the URL of the datastore the volume is relocated
+	// to. The datastore must be shared between the source and target vCenters,
+	// i.e. accessible from both, otherwise the relocation is rejected. Moving
+	// volumes to a datastore which is not accessible from the source vCenter
+	// is not supported.
+	TargetDatastoreURL string `json:"targetDatastoreURL"`
+
+	// TargetStoragePolicyName is the name of the storage policy on the target
+	// vCenter to be associated with the volume. If not specified, no storage
+	// policy is associated with the volume on the target vCenter.
+	TargetStoragePolicyName string `json:"targetStoragePolicyName,omitempty"`
+}
+
+// CnsCrossVCenterVolumeRelocationStatus defines the observed state of CnsCrossVCenterVolumeRelocation
+// +k8s:openapi-gen=true
+type CnsCrossVCenterVolumeRelocationStatus struct {
+	// Phase is the current phase of the relocation.
+	Phase CnsCrossVCenterVolumeRelocationPhase `json:"phase,omitempty"`
+
+	// VolumeID is the ID of the volume being relocated.
+	VolumeID string `json:"volumeID,omitempty"`
+
+	// SourceVCenter is the vCenter host the volume was registered on before
+	// the relocation.
+	SourceVCenter string `json:"sourceVCenter,omitempty"`
+
+	// SavedPersistentVolume is the JSON encoded PersistentVolume saved before
+	// it is recreated with the node affinity of the target vCenter.
+	SavedPersistentVolume string `json:"savedPersistentVolume,omitempty"`
+
+	// The last error encountered during the relocation, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CnsCrossVCenterVolumeRelocation is the Schema for the cnscrossvcentervolumerelocations API.
+// It re-registers a detached block volume with the CNS of another vCenter,
+// after relocating it to a datastore shared between the vCenters.
+// +k8s:openapi-gen=true
+// +kubebuilder:resource:scope=Cluster
+type CnsCrossVCenterVolumeRelocation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CnsCrossVCenterVolumeRelocationSpec   `json:"spec,omitempty"`
+	Status CnsCrossVCenterVolumeRelocationStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CnsCrossVCenterVolumeRelocationList contains a list of CnsCrossVCenterVolumeRelocation
+type CnsCrossVCenterVolumeRelocationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CnsCrossVCenterVolumeRelocation `json:"items"`
+}
@@ -31,6 +31,66 @@ type CnsFileAccessConfigSpec struct {
 	// Support for PodVm will be added in the near future and
 	// either VMName or PodVMName needs to be set.
 	VMName string `json:"vmName,omitempty"`
+
+	// VMSelector selects the VirtualMachine instances in the namespace of
+	// the CnsFileAccessConfig instance to be granted access to the volume.
+	// The set of selected VMs is re-evaluated periodically.
+	VMSelector *metav1.LabelSelector `json:"vmSelector,omitempty"`
+
+	// AccessType is the access granted to the VMs specified using VMName
+	// and VMSelector. Defaults to ReadWrite.
+	AccessType CnsFileAccessType `json:"accessType,omitempty"`
+
+	// RootSquash indicates that root access from the VMs specified using
+	// VMName and VMSelector is squashed.
+	RootSquash bool `json:"rootSquash,omitempty"`
+
+	// IPRanges is the list of client IP ranges to be granted access to the
+	// volume.
+	IPRanges []CnsFileAccessConfigIPRange `json:"ipRanges,omitempty"`
+}
+
+// CnsFileAccessType is the type of access granted to the clients of a file
+// volume.
+type CnsFileAccessType string
+
+const (
+	// CnsFileAccessTypeReadWrite grants read-write access to the clients.
+	CnsFileAccessTypeReadWrite CnsFileAccessType = "ReadWrite"
+	// CnsFileAccessTypeReadOnly grants read-only access to the clients.
+	CnsFileAccessTypeReadOnly CnsFileAccessType = "ReadOnly"
+)
+
+// CnsFileAccessConfigIPRange defines the access granted to a range of
+// client IP addresses.
+// +k8s:openapi-gen=true
+type CnsFileAccessConfigIPRange struct {
+	// CIDR is the range of client IP addresses in CIDR notation,
+	// e.g. 10.20.30.0/24. A single IP address is also accepted.
+	CIDR string `json:"cidr"`
+
+	// AccessType is the access granted to the clients. Defaults to ReadWrite.
+	AccessType CnsFileAccessType `json:"accessType,omitempty"`
+
+	// RootSquash indicates that root access from the clients is squashed.
+	RootSquash bool `json:"rootSquash,omitempty"`
+}
+
+// CnsFileAccessConfigClientStatus defines the net permissions configured on
+// the volume for a single client.
+// +k8s:openapi-gen=true
+type CnsFileAccessConfigClientStatus struct {
+	// IPs is the IP address or the range of IP addresses of the client.
+	IPs string `json:"ips"`
+
+	// VMName is the name of the VirtualMachine instance, if the client is a VM.
+	VMName string `json:"vmName,omitempty"`
+
+	// AccessType is the access granted to the client.
+	AccessType CnsFileAccessType `json:"accessType"`
+
+	// RootSquash indicates that root access from the client is squashed.
+	RootSquash bool `json:"rootSquash,omitempty"`
 }
 
 // CnsFileAccessConfigStatus defines the observed state of CnsFileAccessConfig
@@ -48,6 +108,12 @@ type CnsFileAccessConfigStatus struct {
 	// field is set to true.
 	AccessPoints map[string]string `json:"accessPoints,omitempty"`
 
+	// Clients is the list of clients for which net permissions are
+	// configured on the volume.
+	// This field must only be set by the entity completing the config
+	// operation, i.e. the CNS Operator.
+	Clients []CnsFileAccessConfigClientStatus `json:"clients,omitempty"`
+
 	// The last error encountered during file volume config operation, if any
 	// This field must only be set by the entity completing the config
 	// operation, i.e. the CNS Operator.
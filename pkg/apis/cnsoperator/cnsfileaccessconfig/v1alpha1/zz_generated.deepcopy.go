@@ -5,6 +5,7 @@
 package v1alpha1
 
 import (
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -13,8 +14,8 @@ func (in *CnsFileAccessConfig) DeepCopyInto(out *CnsFileAccessConfig) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -72,6 +73,16 @@ func (in *CnsFileAccessConfigList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CnsFileAccessConfigSpec) DeepCopyInto(out *CnsFileAccessConfigSpec) {
 	*out = *in
+	if in.VMSelector != nil {
+		in, out := &in.VMSelector, &out.VMSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IPRanges != nil {
+		in, out := &in.IPRanges, &out.IPRanges
+		*out = make([]CnsFileAccessConfigIPRange, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -88,6 +99,18 @@ func (in *CnsFileAccessConfigSpec) DeepCopy() *CnsFileAccessConfigSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CnsFileAccessConfigStatus) DeepCopyInto(out *CnsFileAccessConfigStatus) {
 	*out = *in
+	if in.AccessPoints != nil {
+		in, out := &in.AccessPoints, &out.AccessPoints
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Clients != nil {
+		in, out := &in.Clients, &out.Clients
+		*out = make([]CnsFileAccessConfigClientStatus, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -100,3 +123,35 @@ func (in *CnsFileAccessConfigStatus) DeepCopy() *CnsFileAccessConfigStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CnsFileAccessConfigIPRange) DeepCopyInto(out *CnsFileAccessConfigIPRange) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CnsFileAccessConfigIPRange.
+func (in *CnsFileAccessConfigIPRange) DeepCopy() *CnsFileAccessConfigIPRange {
+	if in == nil {
+		return nil
+	}
+	out := new(CnsFileAccessConfigIPRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CnsFileAccessConfigClientStatus) DeepCopyInto(out *CnsFileAccessConfigClientStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CnsFileAccessConfigClientStatus.
+func (in *CnsFileAccessConfigClientStatus) DeepCopy() *CnsFileAccessConfigClientStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CnsFileAccessConfigClientStatus)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -54,6 +54,13 @@ type CnsRegisterVolumeSpec struct {
 	// This is for a 34a9c05d-5f03-e254-e692-02004479cb91/vm2_1.vmdk
 	// file under datacenter "Datacenter-1" and datastore "vsanDatastore".
 	DiskURLPath string `json:"diskURLPath,omitempty"`
+
+	// StorageClassName is the name of the StorageClass to be used for the
+	// PV and PVC created for the registered volume.
+	// StorageClassName is only used in vanilla clusters, where it must be
+	// specified. In Project Pacific clusters, the StorageClass is derived
+	// from the storage policy associated with the volume.
+	StorageClassName string `json:"storageClassName,omitempty"`
 }
 
 // CnsRegisterVolumeStatus defines the observed state of CnsRegisterVolume
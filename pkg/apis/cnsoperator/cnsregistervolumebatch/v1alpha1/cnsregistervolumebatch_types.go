@@ -0,0 +1,146 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CnsRegisterVolumeBatchSpec defines the desired state of CnsRegisterVolumeBatch
+// +k8s:openapi-gen=true
+type CnsRegisterVolumeBatchSpec struct {
+	// DatastoreFolderPath is URL path to a datastore folder. All the virtual
+	// disks present in this folder are registered.
+	// This field must be in the following format:
+	// Format:
+	// https://<vc_ip>/folder/<folder_path>?dcPath=<datacenterName>&dsName=<datastoreName>
+	// DatastoreFolderPath and Disks cannot be specified together.
+	DatastoreFolderPath string `json:"datastoreFolderPath,omitempty"`
+
+	// Disks is the list of disks to be registered.
+	// DatastoreFolderPath and Disks cannot be specified together.
+	Disks []CnsRegisterVolumeBatchDisk `json:"disks,omitempty"`
+
+	// PvcNameTemplate is the Go template used to generate the name of the PVC
+	// for each disk which does not specify a PvcName. The template can refer
+	// to {{.BatchName}}, {{.Index}} and {{.DiskName}}, where DiskName is the
+	// name of the disk file without the ".vmdk" extension.
+	// Defaults to "{{.BatchName}}-{{.Index}}".
+	PvcNameTemplate string `json:"pvcNameTemplate,omitempty"`
+
+	// AccessMode contains the access mode of the volumes to be registered.
+	// It is validated by the CnsRegisterVolume instances created for the
+	// disks. Defaults to "ReadWriteOnce".
+	AccessMode v1.PersistentVolumeAccessMode `json:"accessMode,omitempty"`
+
+	// StorageClassName is the name of the StorageClass to be used for the
+	// PVs and PVCs created for the registered volumes.
+	// StorageClassName must be specified in vanilla clusters.
+	StorageClassName string `json:"storageClassName,omitempty"`
+
+	// MaxConcurrentRegistrations is the maximum number of disks which are
+	// registered concurrently. A disk whose registration fails is retried and
+	// counts towards the maximum until it is registered. Defaults to 10.
+	MaxConcurrentRegistrations int `json:"maxConcurrentRegistrations,omitempty"`
+}
+
+// CnsRegisterVolumeBatchDisk identifies a single disk to be registered as
+// part of a CnsRegisterVolumeBatch.
+// +k8s:openapi-gen=true
+type CnsRegisterVolumeBatchDisk struct {
+	// VolumeID indicates an existing FCD to be registered.
+	// VolumeID and DiskURLPath cannot be specified together.
+	VolumeID string `json:"volumeID,omitempty"`
+
+	// DiskURLPath is URL path to an existing virtual disk to be registered.
+	// It has the same format as CnsRegisterVolumeSpec.DiskURLPath.
+	// VolumeID and DiskURLPath cannot be specified together.
+	DiskURLPath string `json:"diskURLPath,omitempty"`
+
+	// PvcName is the name of the PVC to be created for the disk.
+	// If not specified, the name is generated using PvcNameTemplate.
+	PvcName string `json:"pvcName,omitempty"`
+}
+
+// CnsRegisterVolumeBatchVolumeStatus defines the observed state of the
+// registration of a single disk in a CnsRegisterVolumeBatch.
+// +k8s:openapi-gen=true
+type CnsRegisterVolumeBatchVolumeStatus struct {
+	// VolumeID of the disk, if specified.
+	VolumeID string `json:"volumeID,omitempty"`
+
+	// DiskURLPath of the disk, if specified or discovered in the
+	// DatastoreFolderPath.
+	DiskURLPath string `json:"diskURLPath,omitempty"`
+
+	// PvcName is the name of the PVC created for the disk.
+	PvcName string `json:"pvcName"`
+
+	// CnsRegisterVolumeName is the name of the CnsRegisterVolume instance
+	// created to register the disk.
+	CnsRegisterVolumeName string `json:"cnsRegisterVolumeName"`
+
+	// Indicates the disk is successfully registered.
+	Registered bool `json:"registered"`
+
+	// The last error encountered while registering the disk, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// CnsRegisterVolumeBatchStatus defines the observed state of CnsRegisterVolumeBatch
+// +k8s:openapi-gen=true
+type CnsRegisterVolumeBatchStatus struct {
+	// Volumes contains the registration status of each disk in the batch.
+	Volumes []CnsRegisterVolumeBatchVolumeStatus `json:"volumes,omitempty"`
+
+	// RegisteredCount is the number of disks successfully registered.
+	RegisteredCount int `json:"registeredCount"`
+
+	// FailedCount is the number of disks whose latest registration attempt
+	// failed. Failed registrations are retried.
+	FailedCount int `json:"failedCount"`
+
+	// Indicates all the disks in the batch are successfully registered.
+	Completed bool `json:"completed"`
+
+	// The last error encountered while processing the batch, if any.
+	// Errors specific to a disk are reported in Volumes.
+	Error string `json:"error,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CnsRegisterVolumeBatch is the Schema for the cnsregistervolumebatches API
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+type CnsRegisterVolumeBatch struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CnsRegisterVolumeBatchSpec   `json:"spec,omitempty"`
+	Status CnsRegisterVolumeBatchStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CnsRegisterVolumeBatchList contains a list of CnsRegisterVolumeBatch
+type CnsRegisterVolumeBatchList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CnsRegisterVolumeBatch `json:"items"`
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CnsVolumeRelocationPhase is the phase of a CnsVolumeRelocation instance.
+type CnsVolumeRelocationPhase string
+
+const (
+	// RelocationPhasePending indicates the relocation is yet to be validated.
+	RelocationPhasePending CnsVolumeRelocationPhase = ""
+	// RelocationPhaseRelocating indicates the volume is being relocated to
+	// the target datastore.
+	RelocationPhaseRelocating CnsVolumeRelocationPhase = "Relocating"
+	// RelocationPhaseUpdatingPersistentVolume indicates the node affinity of
+	// the PersistentVolume is being updated.
+	RelocationPhaseUpdatingPersistentVolume CnsVolumeRelocationPhase = "UpdatingPersistentVolume"
+	// RelocationPhaseCompleted indicates the relocation is complete.
+	RelocationPhaseCompleted CnsVolumeRelocationPhase = "Completed"
+)
+
+// CnsVolumeRelocationSpec defines the desired state of CnsVolumeRelocation
+// +k8s:openapi-gen=true
+type CnsVolumeRelocationSpec struct {
+	// PersistentVolumeName is the name of the PersistentVolume whose volume
+	// is relocated. The PersistentVolume must be a block volume.
+	PersistentVolumeName string `json:"persistentVolumeName"`
+
+	// TargetDatastoreURL is the URL of the datastore the volume is relocated
+	// to. The datastore must be accessible from the nodes the volume is
+	// attached to.
+	TargetDatastoreURL string `json:"targetDatastoreURL"`
+
+	// TargetStoragePolicyName is the name of the storage policy to be
+	// associated with the volume on the target datastore. If not specified,
+	// the storage policy of the volume is retained.
+	TargetStoragePolicyName string `json:"targetStoragePolicyName,omitempty"`
+}
+
+// CnsVolumeRelocationStatus defines the observed state of CnsVolumeRelocation
+// +k8s:openapi-gen=true
+type CnsVolumeRelocationStatus struct {
+	// Phase is the current phase of the relocation.
+	Phase CnsVolumeRelocationPhase `json:"phase,omitempty"`
+
+	// VolumeID is the ID of the volume being relocated.
+	VolumeID string `json:"volumeID,omitempty"`
+
+	// VCenter is the vCenter host the volume is registered on.
+	VCenter string `json:"vCenter,omitempty"`
+
+	// SourceDatastoreURL is the URL of the datastore the volume was present
+	// on before the relocation.
+	SourceDatastoreURL string `json:"sourceDatastoreURL,omitempty"`
+
+	// TaskID is the ID of the CNS task relocating the volume.
+	TaskID string `json:"taskID,omitempty"`
+
+	// Progress is the percentage of completion of the CNS task relocating
+	// the volume.
+	Progress int32 `json:"progress,omitempty"`
+
+	// SavedPersistentVolume is the JSON encoded PersistentVolume saved before
+	// it is recreated with the node affinity of the target datastore.
+	SavedPersistentVolume string `json:"savedPersistentVolume,omitempty"`
+
+	// The last error encountered during the relocation, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CnsVolumeRelocation is the Schema for the cnsvolumerelocations API
+// +k8s:openapi-gen=true
+// +kubebuilder:resource:scope=Cluster
+type CnsVolumeRelocation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CnsVolumeRelocationSpec   `json:"spec,omitempty"`
+	Status CnsVolumeRelocationStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CnsVolumeRelocationList contains a list of CnsVolumeRelocation
+type CnsVolumeRelocationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CnsVolumeRelocation `json:"items"`
+}
@@ -21,3 +21,18 @@ const EmbedCnsFileAccessConfigCRFileName = "cnsfileaccessconfig_crd.yaml"
 var EmbedCnsRegisterVolumeCRFile embed.FS
 
 const EmbedCnsRegisterVolumeCRFileName = "cnsregistervolume_crd.yaml"
+
+//go:embed cnsregistervolumebatch_crd.yaml
+var EmbedCnsRegisterVolumeBatchCRFile embed.FS
+
+const EmbedCnsRegisterVolumeBatchCRFileName = "cnsregistervolumebatch_crd.yaml"
+
+//go:embed cnscrossvcentervolumerelocation_crd.yaml
+var EmbedCnsCrossVCenterVolumeRelocationCRFile embed.FS
+
+const EmbedCnsCrossVCenterVolumeRelocationCRFileName = "cnscrossvcentervolumerelocation_crd.yaml"
+
+//go:embed cnsvolumerelocation_crd.yaml
+var EmbedCnsVolumeRelocationCRFile embed.FS
+
+const EmbedCnsVolumeRelocationCRFileName = "cnsvolumerelocation_crd.yaml"
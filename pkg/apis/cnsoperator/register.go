@@ -25,10 +25,13 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	cnscrossvcentervolumerelocationv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnscrossvcentervolumerelocation/v1alpha1"
 	cnsfileaccessconfigv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsfileaccessconfig/v1alpha1"
 	cnsnodevmattachmentv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsnodevmattachment/v1alpha1"
 	cnsregistervolumev1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsregistervolume/v1alpha1"
+	cnsregistervolumebatchv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsregistervolumebatch/v1alpha1"
 	cnsvolumemetadatav1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsvolumemetadata/v1alpha1"
+	cnsvolumerelocationv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsvolumerelocation/v1alpha1"
 )
 
 // GroupName represents the group for cns operator apis
@@ -50,8 +53,14 @@ var (
 	CnsVolumeMetadataPlural = "cnsvolumemetadatas"
 	// CnsRegisterVolumePlural is plural of CnsRegisterVolume
 	CnsRegisterVolumePlural = "cnsregistervolumes"
+	// CnsRegisterVolumeBatchPlural is plural of CnsRegisterVolumeBatch
+	CnsRegisterVolumeBatchPlural = "cnsregistervolumebatches"
 	// CnsFileAccessConfigPlural is plural of CnsFileAccessConfig
 	CnsFileAccessConfigPlural = "cnsfileaccessconfigs"
+	// CnsCrossVCenterVolumeRelocationPlural is plural of CnsCrossVCenterVolumeRelocation
+	CnsCrossVCenterVolumeRelocationPlural = "cnscrossvcentervolumerelocations"
+	// CnsVolumeRelocationPlural is plural of CnsVolumeRelocation
+	CnsVolumeRelocationPlural = "cnsvolumerelocations"
 )
 
 var (
@@ -86,6 +95,12 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&cnsregistervolumev1alpha1.CnsRegisterVolumeList{},
 	)
 
+	scheme.AddKnownTypes(
+		SchemeGroupVersion,
+		&cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatch{},
+		&cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatchList{},
+	)
+
 	scheme.AddKnownTypes(
 		SchemeGroupVersion,
 		&cnsvolumemetadatav1alpha1.CnsVolumeMetadata{},
@@ -98,6 +113,18 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&cnsnodevmattachmentv1alpha1.CnsNodeVmAttachmentList{},
 	)
 
+	scheme.AddKnownTypes(
+		SchemeGroupVersion,
+		&cnscrossvcentervolumerelocationv1alpha1.CnsCrossVCenterVolumeRelocation{},
+		&cnscrossvcentervolumerelocationv1alpha1.CnsCrossVCenterVolumeRelocationList{},
+	)
+
+	scheme.AddKnownTypes(
+		SchemeGroupVersion,
+		&cnsvolumerelocationv1alpha1.CnsVolumeRelocation{},
+		&cnsvolumerelocationv1alpha1.CnsVolumeRelocationList{},
+	)
+
 	scheme.AddKnownTypes(
 		SchemeGroupVersion,
 		&metav1.Status{},
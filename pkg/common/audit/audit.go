@@ -0,0 +1,219 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+const (
+	// EnvAuditLogFile is the environment variable holding the path of a file
+	// the audit events are appended to, in addition to the standard output.
+	EnvAuditLogFile = "AUDIT_LOG_FILE"
+	// EnvAuditLogWebhookURL is the environment variable holding the URL the
+	// audit events are posted to, in addition to the standard output.
+	EnvAuditLogWebhookURL = "AUDIT_LOG_WEBHOOK_URL"
+
+	// Components performing the operations recorded in the audit log.
+	ComponentController = "controller"
+	ComponentSyncer     = "syncer"
+
+	// Operations recorded in the audit log.
+	OperationDeleteVolume           = "DeleteVolume"
+	OperationDeleteSnapshot         = "DeleteSnapshot"
+	OperationControllerUnpublish    = "ControllerUnpublishVolume"
+	OperationExpandVolume           = "ExpandVolume"
+	OperationFullSyncDeleteVolume   = "FullSyncDeleteVolume"
+	OperationFullSyncDeleteSnapshot = "FullSyncDeleteOrphanSnapshot"
+	outcomeSuccess                  = "success"
+	outcomeFailure                  = "failure"
+	webhookQueueSize                = 1000
+	webhookTimeout                  = 10 * time.Second
+)
+
+// Event is an entry of the audit log.
+type Event struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	// Component is the driver component performing the operation.
+	Component    string `json:"component,omitempty"`
+	VolumeID     string `json:"volumeId,omitempty"`
+	SnapshotID   string `json:"snapshotId,omitempty"`
+	NodeID       string `json:"nodeId,omitempty"`
+	CapacityInMb int64  `json:"capacityInMb,omitempty"`
+	// KubernetesObjects references the Kubernetes objects of the volume, as
+	// Kind/Name or Kind/Namespace/Name.
+	KubernetesObjects []string `json:"kubernetesObjects,omitempty"`
+	VCenter           string   `json:"vCenter,omitempty"`
+	// TaskID and TaskOpID identify the last vCenter task of the operation.
+	TaskID   string `json:"taskId,omitempty"`
+	TaskOpID string `json:"taskOpId,omitempty"`
+	// OpID is the operation ID sent by the driver with its vCenter calls.
+	OpID    string `json:"opId,omitempty"`
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+}
+
+// taskRecord holds the vCenter task of an audited operation.
+type taskRecord struct {
+	lock     sync.Mutex
+	vCenter  string
+	taskID   string
+	taskOpID string
+}
+
+// taskRecordKey is the context key of the task record.
+type taskRecordKey struct{}
+
+var (
+	sinksOnce sync.Once
+	// sinksLock serializes the writes to the sinks.
+	sinksLock sync.Mutex
+	sinks     []io.Writer
+	// webhookQueue holds the events waiting to be posted to the webhook.
+	webhookQueue chan []byte
+	// webhookEnqueueTimeout is how long an event waits for room in the
+	// webhook queue before it is dropped.
+	webhookEnqueueTimeout = 5 * time.Second
+)
+
+// NewContext returns a child context recording the vCenter tasks of the
+// audited operation performed with it.
+func NewContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, taskRecordKey{}, &taskRecord{})
+}
+
+// RecordTask records the given vCenter task in the audited operation of the
+// given context, if any.
+func RecordTask(ctx context.Context, vCenter string, taskID string, taskOpID string) {
+	record, ok := ctx.Value(taskRecordKey{}).(*taskRecord)
+	if !ok {
+		return
+	}
+	record.lock.Lock()
+	defer record.lock.Unlock()
+	record.vCenter = vCenter
+	record.taskID = taskID
+	if taskOpID != "" {
+		record.taskOpID = taskOpID
+	}
+}
+
+// initSinks opens the audit log sinks. Events are always written to the
+// standard output, so that they form a stream separate from the logs, which
+// are written to the standard error.
+func initSinks(ctx context.Context) {
+	log := logger.GetLogger(ctx)
+	sinks = []io.Writer{os.Stdout}
+	if path := os.Getenv(EnvAuditLogFile); path != "" {
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			log.Errorf("failed to open audit log file %s. Err: %v", path, err)
+		} else {
+			sinks = append(sinks, file)
+		}
+	}
+	if url := os.Getenv(EnvAuditLogWebhookURL); url != "" {
+		webhookQueue = make(chan []byte, webhookQueueSize)
+		go postToWebhook(url)
+	}
+}
+
+// postToWebhook posts the queued events to the given URL.
+func postToWebhook(url string) {
+	_, log := logger.GetNewContextWithLogger()
+	client := &http.Client{Timeout: webhookTimeout}
+	for line := range webhookQueue {
+		res, err := client.Post(url, "application/json", bytes.NewReader(line))
+		if err != nil {
+			log.Errorf("failed to post audit event to webhook. Err: %v", err)
+			continue
+		}
+		_ = res.Body.Close()
+		if res.StatusCode >= http.StatusBadRequest {
+			log.Errorf("failed to post audit event to webhook. Status: %s", res.Status)
+		}
+	}
+}
+
+// Log completes the given event with the vCenter task recorded in the given
+// context and the outcome of the operation, and writes it to the audit log
+// sinks as a JSON line.
+func Log(ctx context.Context, event *Event, err error) {
+	log := logger.GetLogger(ctx)
+	sinksOnce.Do(func() { initSinks(ctx) })
+	event.Time = time.Now().UTC()
+	if record, ok := ctx.Value(taskRecordKey{}).(*taskRecord); ok {
+		record.lock.Lock()
+		event.VCenter, event.TaskID, event.TaskOpID = record.vCenter, record.taskID, record.taskOpID
+		record.lock.Unlock()
+	}
+	event.OpID = logger.GetOpID(ctx)
+	event.Outcome = outcomeSuccess
+	if err != nil {
+		event.Outcome = outcomeFailure
+		event.Error = err.Error()
+	}
+	line, marshalErr := json.Marshal(event)
+	if marshalErr != nil {
+		log.Errorf("failed to marshal audit event %+v. Err: %v", event, marshalErr)
+		return
+	}
+	line = append(line, '\n')
+	sinksLock.Lock()
+	for _, sink := range sinks {
+		if _, writeErr := sink.Write(line); writeErr != nil {
+			log.Errorf("failed to write audit event. Err: %v", writeErr)
+		}
+	}
+	sinksLock.Unlock()
+	if webhookQueue != nil {
+		enqueueToWebhook(ctx, webhookQueue, line)
+	}
+}
+
+// enqueueToWebhook queues the given event to be posted to the webhook. When
+// the queue is full, it waits up to webhookEnqueueTimeout for the webhook to
+// catch up, then drops the event and counts it in the
+// AuditEventsDroppedCounter metric. The event is still in the other sinks.
+func enqueueToWebhook(ctx context.Context, queue chan<- []byte, line []byte) {
+	log := logger.GetLogger(ctx)
+	select {
+	case queue <- line:
+		return
+	default:
+	}
+	timer := time.NewTimer(webhookEnqueueTimeout)
+	defer timer.Stop()
+	select {
+	case queue <- line:
+	case <-timer.C:
+		prometheus.AuditEventsDroppedCounter.Inc()
+		log.Errorf("audit webhook queue is still full after %v, dropping audit event %s",
+			webhookEnqueueTimeout, line)
+	}
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+)
+
+func TestEnqueueToWebhook(t *testing.T) {
+	ctx := context.Background()
+	defer func(timeout time.Duration) { webhookEnqueueTimeout = timeout }(webhookEnqueueTimeout)
+	webhookEnqueueTimeout = 100 * time.Millisecond
+	queue := make(chan []byte, 1)
+	dropped := testutil.ToFloat64(prometheus.AuditEventsDroppedCounter)
+
+	enqueueToWebhook(ctx, queue, []byte("first"))
+	if len(queue) != 1 {
+		t.Fatalf("expected the event to be queued")
+	}
+
+	// The queue is full, the event is queued once the webhook catches up.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		<-queue
+	}()
+	enqueueToWebhook(ctx, queue, []byte("second"))
+	if line := <-queue; string(line) != "second" {
+		t.Fatalf("expected the second event to be queued, got %q", line)
+	}
+	if got := testutil.ToFloat64(prometheus.AuditEventsDroppedCounter); got != dropped {
+		t.Fatalf("expected no dropped event, got %v", got-dropped)
+	}
+
+	// The queue stays full, the event is dropped and counted.
+	queue <- []byte("third")
+	start := time.Now()
+	enqueueToWebhook(ctx, queue, []byte("fourth"))
+	if elapsed := time.Since(start); elapsed < webhookEnqueueTimeout {
+		t.Fatalf("expected the event to wait %v before being dropped, waited %v", webhookEnqueueTimeout, elapsed)
+	}
+	if got := testutil.ToFloat64(prometheus.AuditEventsDroppedCounter); got != dropped+1 {
+		t.Fatalf("expected one dropped event, got %v", got-dropped)
+	}
+	if line := <-queue; string(line) != "third" {
+		t.Fatalf("expected the third event to stay queued, got %q", line)
+	}
+}
@@ -31,12 +31,14 @@ import (
 	"github.com/vmware/govmomi/vim25/soap"
 	vim25types "github.com/vmware/govmomi/vim25/types"
 	"github.com/vmware/govmomi/vslm"
+	vslmtypes "github.com/vmware/govmomi/vslm/types"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
 	csifault "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/fault"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/tracing"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/cnsvolumeoperationrequest"
 )
@@ -54,6 +56,9 @@ const (
 	// maxLengthOfVolumeNameInCNS is the maximum length of CNS volume name.
 	maxLengthOfVolumeNameInCNS = 80
 
+	// vslmTaskTimeout is the maximum time to wait for a Vslm task to complete.
+	vslmTaskTimeout = 5 * time.Minute
+
 	// Alias for TaskInvocationStatus constants.
 	taskInvocationStatusInProgress = cnsvolumeoperationrequest.TaskInvocationStatusInProgress
 	taskInvocationStatusSuccess    = cnsvolumeoperationrequest.TaskInvocationStatusSuccess
@@ -81,6 +86,12 @@ type Manager interface {
 	DeleteVolume(ctx context.Context, volumeID string, deleteDisk bool) (string, error)
 	// UpdateVolumeMetadata updates a volume metadata given its spec.
 	UpdateVolumeMetadata(ctx context.Context, spec *cnstypes.CnsVolumeMetadataUpdateSpec) error
+	// BatchUpdateVolumeMetadata updates the metadata of multiple volumes using
+	// a single CNS task. It returns the errors for the volumes whose metadata
+	// could not be updated, keyed by volume ID. The error is set if the task
+	// itself failed, in which case none of the volumes were updated.
+	BatchUpdateVolumeMetadata(ctx context.Context, specs []cnstypes.CnsVolumeMetadataUpdateSpec) (
+		map[string]error, error)
 	// QueryVolumeInfo calls the CNS QueryVolumeInfo API and return a task, from
 	// which CnsQueryVolumeInfoResult is extracted.
 	QueryVolumeInfo(ctx context.Context, volumeIDList []cnstypes.CnsVolumeId) (*cnstypes.CnsQueryVolumeInfoResult, error)
@@ -112,8 +123,13 @@ type Manager interface {
 	RetrieveVStorageObject(ctx context.Context, volumeID string) (*vim25types.VStorageObject, error)
 	// ProtectVolumeFromVMDeletion sets keepAfterDeleteVm control flag on migrated volume
 	ProtectVolumeFromVMDeletion(ctx context.Context, volumeID string) error
-	// CreateSnapshot helps create a snapshot for a block volume
-	CreateSnapshot(ctx context.Context, volumeID string, desc string) (*CnsSnapshotInfo, error)
+	// UpdateVStorageObjectMetadata adds or updates the given key-value metadata of the FCD
+	// backing the given volume and deletes the metadata with the given keys.
+	UpdateVStorageObjectMetadata(ctx context.Context, volumeID string, metadata []vim25types.KeyValue,
+		deleteKeys []string) error
+	// CreateSnapshot helps create a snapshot for a block volume.
+	// When CreateSnapshot fails, the second return value (faultType) and third return value (error) are set.
+	CreateSnapshot(ctx context.Context, volumeID string, desc string) (*CnsSnapshotInfo, string, error)
 	// DeleteSnapshot helps delete a snapshot for a block volume
 	DeleteSnapshot(ctx context.Context, volumeID string, snapshotID string) error
 	// QuerySnapshots retrieves the list of snapshots based on the query filter.
@@ -286,14 +302,17 @@ func (m *defaultManager) GetOperationStore() cnsvolumeoperationrequest.VolumeOpe
 // as part of volume idempotency feature
 func (m *defaultManager) MonitorCreateVolumeTask(ctx context.Context,
 	volumeOperationDetails **cnsvolumeoperationrequest.VolumeOperationRequestDetails, task *object.Task,
-	volNameFromInputSpec string, clusterID string) (*CnsVolumeInfo, string, error) {
-	var faultType string
+	volNameFromInputSpec string, clusterID string) (volumeInfo *CnsVolumeInfo, faultType string, err error) {
+	ctx, span := tracing.StartSpan(ctx, "CNS.MonitorCreateVolumeTask")
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 	log := logger.GetLogger(ctx)
 	var vCenterServerForVolumeOperationCR string
 	if m.multivCenterTopologyDeployment {
 		vCenterServerForVolumeOperationCR = m.virtualCenter.Config.Host
 	}
-	taskInfo, err := task.WaitForResult(ctx, nil)
+	taskInfo, err := m.waitForTask(ctx, task)
 	if err != nil {
 		if cnsvsphere.IsManagedObjectNotFound(err, task.Reference()) {
 			log.Debugf("CreateVolume task %s not found in vCenter %s. Querying CNS "+
@@ -494,7 +513,7 @@ func (m *defaultManager) createVolumeWithImprovedIdempotency(ctx context.Context
 				vCenterServerForVolumeOperationCR, "",
 				taskInvocationStatusError, err.Error())
 			faultType = ExtractFaultTypeFromErr(ctx, err)
-			return nil, faultType, err
+			return nil, faultType, fmt.Errorf("%w: %v", ErrTaskNotStarted, err)
 		}
 		if !isStaticallyProvisioned(spec) {
 			// Persist task details only for dynamically provisioned volumes.
@@ -535,7 +554,7 @@ func (m *defaultManager) createVolume(ctx context.Context, spec *cnstypes.CnsVol
 		task, err = invokeCNSCreateVolume(ctx, m.virtualCenter, spec)
 		if err != nil {
 			faultType = ExtractFaultTypeFromErr(ctx, err)
-			return nil, faultType, err
+			return nil, faultType, fmt.Errorf("%w: %v", ErrTaskNotStarted, err)
 		}
 		if !isStaticallyProvisioned(spec) {
 			var taskDetails createVolumeTaskDetails
@@ -556,7 +575,7 @@ func (m *defaultManager) createVolume(ctx context.Context, spec *cnstypes.CnsVol
 	}
 
 	// Get the taskInfo.
-	taskInfo, err := cns.GetTaskInfo(ctx, task)
+	taskInfo, err := m.waitForTask(ctx, task)
 	if err != nil || taskInfo == nil {
 		log.Errorf("failed to get taskInfo for CreateVolume task with err: %v", err)
 		if err != nil {
@@ -611,8 +630,12 @@ func (m *defaultManager) createVolume(ctx context.Context, spec *cnstypes.CnsVol
 }
 
 // CreateVolume creates a new volume given its spec.
-func (m *defaultManager) CreateVolume(ctx context.Context, spec *cnstypes.CnsVolumeCreateSpec) (*CnsVolumeInfo,
-	string, error) {
+func (m *defaultManager) CreateVolume(ctx context.Context, spec *cnstypes.CnsVolumeCreateSpec) (
+	volumeInfo *CnsVolumeInfo, faultType string, err error) {
+	ctx, span := tracing.StartSpan(ctx, "CNS.CreateVolume")
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 	internalCreateVolume := func() (*CnsVolumeInfo, string, error) {
 		log := logger.GetLogger(ctx)
 		var faultType string
@@ -626,7 +649,7 @@ func (m *defaultManager) CreateVolume(ctx context.Context, spec *cnstypes.CnsVol
 		if err != nil {
 			log.Errorf("failed to setup connection to CNS with error: %v", err)
 			faultType = ExtractFaultTypeFromErr(ctx, err)
-			return nil, faultType, err
+			return nil, faultType, fmt.Errorf("%w: %v", ErrTaskNotStarted, err)
 		}
 		// Call CreateVolume implementation based on FSS value.
 		if m.idempotencyHandlingEnabled {
@@ -651,7 +674,12 @@ func (m *defaultManager) CreateVolume(ctx context.Context, spec *cnstypes.CnsVol
 
 // AttachVolume attaches a volume to a virtual machine given the spec.
 func (m *defaultManager) AttachVolume(ctx context.Context,
-	vm *cnsvsphere.VirtualMachine, volumeID string, checkNVMeController bool) (string, string, error) {
+	vm *cnsvsphere.VirtualMachine, volumeID string, checkNVMeController bool) (diskUUID string,
+	faultType string, err error) {
+	ctx, span := tracing.StartSpan(ctx, "CNS.AttachVolume")
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 	internalAttachVolume := func() (string, string, error) {
 		log := logger.GetLogger(ctx)
 		var faultType string
@@ -684,7 +712,7 @@ func (m *defaultManager) AttachVolume(ctx context.Context,
 			return "", faultType, err
 		}
 		// Get the taskInfo.
-		taskInfo, err := cns.GetTaskInfo(ctx, task)
+		taskInfo, err := m.waitForTask(ctx, task)
 		if err != nil || taskInfo == nil {
 			log.Errorf("failed to get taskInfo for AttachVolume task from vCenter %q with err: %v",
 				m.virtualCenter.Config.Host, err)
@@ -749,8 +777,12 @@ func (m *defaultManager) AttachVolume(ctx context.Context,
 }
 
 // DetachVolume detaches a volume from the virtual machine given the spec.
-func (m *defaultManager) DetachVolume(ctx context.Context, vm *cnsvsphere.VirtualMachine, volumeID string) (string,
-	error) {
+func (m *defaultManager) DetachVolume(ctx context.Context, vm *cnsvsphere.VirtualMachine, volumeID string) (
+	faultType string, err error) {
+	ctx, span := tracing.StartSpan(ctx, "CNS.DetachVolume")
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 	internalDetachVolume := func() (string, error) {
 		log := logger.GetLogger(ctx)
 		var faultType string
@@ -805,7 +837,7 @@ func (m *defaultManager) DetachVolume(ctx context.Context, vm *cnsvsphere.Virtua
 				volumeID, vm, err)
 		}
 		// Get the taskInfo.
-		taskInfo, err := cns.GetTaskInfo(ctx, task)
+		taskInfo, err := m.waitForTask(ctx, task)
 		if err != nil || taskInfo == nil {
 			log.Errorf("failed to get taskInfo for DetachVolume task from vCenter %q with err: %v",
 				m.virtualCenter.Config.Host, err)
@@ -856,7 +888,7 @@ func (m *defaultManager) DetachVolume(ctx context.Context, vm *cnsvsphere.Virtua
 		return "", nil
 	}
 	start := time.Now()
-	faultType, err := internalDetachVolume()
+	faultType, err = internalDetachVolume()
 	log := logger.GetLogger(ctx)
 	log.Debugf("internalDetachVolume: returns fault %q for volume %q", faultType, volumeID)
 	if err != nil {
@@ -870,7 +902,12 @@ func (m *defaultManager) DetachVolume(ctx context.Context, vm *cnsvsphere.Virtua
 }
 
 // DeleteVolume deletes a volume given its spec.
-func (m *defaultManager) DeleteVolume(ctx context.Context, volumeID string, deleteDisk bool) (string, error) {
+func (m *defaultManager) DeleteVolume(ctx context.Context, volumeID string, deleteDisk bool) (faultType string,
+	err error) {
+	ctx, span := tracing.StartSpan(ctx, "CNS.DeleteVolume")
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 	internalDeleteVolume := func() (string, error) {
 		log := logger.GetLogger(ctx)
 		var faultType string
@@ -893,7 +930,7 @@ func (m *defaultManager) DeleteVolume(ctx context.Context, volumeID string, dele
 		return m.deleteVolume(ctx, volumeID, deleteDisk)
 	}
 	start := time.Now()
-	faultType, err := internalDeleteVolume()
+	faultType, err = internalDeleteVolume()
 	log := logger.GetLogger(ctx)
 	log.Debugf("internalDeleteVolume: returns fault %q for volume %q", faultType, volumeID)
 	if err != nil {
@@ -929,7 +966,7 @@ func (m *defaultManager) deleteVolume(ctx context.Context, volumeID string, dele
 		return faultType, err
 	}
 	// Get the taskInfo.
-	taskInfo, err := cns.GetTaskInfo(ctx, task)
+	taskInfo, err := m.waitForTask(ctx, task)
 	if err != nil || taskInfo == nil {
 		log.Errorf("failed to get DeleteVolume taskInfo from vCenter %q with err: %v",
 			m.virtualCenter.Config.Host, err)
@@ -1065,7 +1102,7 @@ func (m *defaultManager) deleteVolumeWithImprovedIdempotency(ctx context.Context
 	}
 
 	// Get the taskInfo.
-	taskInfo, err := cns.GetTaskInfo(ctx, task)
+	taskInfo, err := m.waitForTask(ctx, task)
 	if err != nil || taskInfo == nil {
 		log.Errorf("failed to get taskInfo for DeleteVolume task from vCenter %q with err: %v",
 			m.virtualCenter.Config.Host, err)
@@ -1120,7 +1157,12 @@ func (m *defaultManager) deleteVolumeWithImprovedIdempotency(ctx context.Context
 }
 
 // UpdateVolumeMetadata updates a volume given its spec.
-func (m *defaultManager) UpdateVolumeMetadata(ctx context.Context, spec *cnstypes.CnsVolumeMetadataUpdateSpec) error {
+func (m *defaultManager) UpdateVolumeMetadata(ctx context.Context, spec *cnstypes.CnsVolumeMetadataUpdateSpec) (
+	err error) {
+	ctx, span := tracing.StartSpan(ctx, "CNS.UpdateVolumeMetadata")
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 	internalUpdateVolumeMetadata := func() error {
 		log := logger.GetLogger(ctx)
 		err := validateManager(ctx, m)
@@ -1164,7 +1206,7 @@ func (m *defaultManager) UpdateVolumeMetadata(ctx context.Context, spec *cnstype
 			return err
 		}
 		// Get the taskInfo.
-		taskInfo, err := cns.GetTaskInfo(ctx, task)
+		taskInfo, err := m.waitForTask(ctx, task)
 		if err != nil || taskInfo == nil {
 			log.Errorf("failed to get UpdateVolume taskInfo from vCenter %q with err: %v",
 				m.virtualCenter.Config.Host, err)
@@ -1192,7 +1234,7 @@ func (m *defaultManager) UpdateVolumeMetadata(ctx context.Context, spec *cnstype
 		return nil
 	}
 	start := time.Now()
-	err := internalUpdateVolumeMetadata()
+	err = internalUpdateVolumeMetadata()
 	if err != nil {
 		prometheus.CnsControlOpsHistVec.WithLabelValues(prometheus.PrometheusCnsUpdateVolumeMetadataOpType,
 			prometheus.PrometheusFailStatus).Observe(time.Since(start).Seconds())
@@ -1203,8 +1245,100 @@ func (m *defaultManager) UpdateVolumeMetadata(ctx context.Context, spec *cnstype
 	return err
 }
 
+// BatchUpdateVolumeMetadata updates the metadata of multiple volumes using a
+// single CNS task.
+func (m *defaultManager) BatchUpdateVolumeMetadata(ctx context.Context,
+	specs []cnstypes.CnsVolumeMetadataUpdateSpec) (volumeErrs map[string]error, err error) {
+	ctx, span := tracing.StartSpan(ctx, "CNS.BatchUpdateVolumeMetadata")
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
+	internalBatchUpdateVolumeMetadata := func() (map[string]error, error) {
+		log := logger.GetLogger(ctx)
+		err := validateManager(ctx, m)
+		if err != nil {
+			return nil, err
+		}
+		// Set up the VC connection.
+		err = m.virtualCenter.ConnectCns(ctx)
+		if err != nil {
+			log.Errorf("ConnectCns failed with err: %+v", err)
+			return nil, err
+		}
+		// If the VSphereUser in the VolumeMetadataUpdateSpecs is different from
+		// session user, update the VolumeMetadataUpdateSpecs.
+		s, err := m.virtualCenter.Client.SessionManager.UserSession(ctx)
+		if err != nil {
+			log.Errorf("failed to get usersession with err: %v", err)
+			return nil, err
+		}
+		if s == nil {
+			return nil, errors.New("nil session obtained from session manager")
+		}
+		for i := range specs {
+			if s.UserName != specs[i].Metadata.ContainerCluster.VSphereUser {
+				log.Debugf("Update VSphereUser from %s to %s", specs[i].Metadata.ContainerCluster.VSphereUser,
+					s.UserName)
+				specs[i].Metadata.ContainerCluster.VSphereUser = s.UserName
+			}
+		}
+		task, err := m.virtualCenter.CnsClient.UpdateVolumeMetadata(ctx, specs)
+		if err != nil {
+			log.Errorf("CNS UpdateVolume failed from vCenter %q with err: %v", m.virtualCenter.Config.Host, err)
+			return nil, err
+		}
+		// Get the taskInfo.
+		taskInfo, err := m.waitForTask(ctx, task)
+		if err != nil || taskInfo == nil {
+			log.Errorf("failed to get UpdateVolume taskInfo from vCenter %q with err: %v",
+				m.virtualCenter.Config.Host, err)
+			return nil, err
+		}
+		log.Infof("BatchUpdateVolumeMetadata: updating metadata of %d volumes, opId: %q",
+			len(specs), taskInfo.ActivationId)
+		// Get the task results for the given task.
+		taskResults, err := cns.GetTaskResultArray(ctx, taskInfo)
+		if err != nil {
+			log.Errorf("unable to find UpdateVolume results from vCenter %q: taskID %q, opId %q",
+				m.virtualCenter.Config.Host, taskInfo.Task.Value, taskInfo.ActivationId)
+			return nil, err
+		}
+		if len(taskResults) == 0 {
+			return nil, logger.LogNewErrorf(log, "taskResult is empty for UpdateVolume task: %q, opId: %q",
+				taskInfo.Task.Value, taskInfo.ActivationId)
+		}
+		volumeErrors := make(map[string]error)
+		for _, taskResult := range taskResults {
+			volumeOperationRes := taskResult.GetCnsVolumeOperationResult()
+			if volumeOperationRes.Fault != nil {
+				volumeErrors[volumeOperationRes.VolumeId.Id] = logger.LogNewErrorf(log,
+					"failed to update volume %q. fault: %q, opID: %q", volumeOperationRes.VolumeId.Id,
+					spew.Sdump(volumeOperationRes.Fault), taskInfo.ActivationId)
+			}
+		}
+		log.Infof("BatchUpdateVolumeMetadata: metadata of %d out of %d volumes updated successfully. opId: %q",
+			len(specs)-len(volumeErrors), len(specs), taskInfo.ActivationId)
+		return volumeErrors, nil
+	}
+	start := time.Now()
+	volumeErrors, err := internalBatchUpdateVolumeMetadata()
+	if err != nil || len(volumeErrors) > 0 {
+		prometheus.CnsControlOpsHistVec.WithLabelValues(prometheus.PrometheusCnsBatchUpdateVolumeMetadataOpType,
+			prometheus.PrometheusFailStatus).Observe(time.Since(start).Seconds())
+	} else {
+		prometheus.CnsControlOpsHistVec.WithLabelValues(prometheus.PrometheusCnsBatchUpdateVolumeMetadataOpType,
+			prometheus.PrometheusPassStatus).Observe(time.Since(start).Seconds())
+	}
+	return volumeErrors, err
+}
+
 // ExpandVolume expands a volume given its spec.
-func (m *defaultManager) ExpandVolume(ctx context.Context, volumeID string, size int64) (string, error) {
+func (m *defaultManager) ExpandVolume(ctx context.Context, volumeID string, size int64) (faultType string,
+	err error) {
+	ctx, span := tracing.StartSpan(ctx, "CNS.ExpandVolume")
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 	internalExpandVolume := func() (string, error) {
 		log := logger.GetLogger(ctx)
 		var faultType string
@@ -1228,7 +1362,7 @@ func (m *defaultManager) ExpandVolume(ctx context.Context, volumeID string, size
 
 	}
 	start := time.Now()
-	faultType, err := internalExpandVolume()
+	faultType, err = internalExpandVolume()
 	log := logger.GetLogger(ctx)
 	log.Debugf("internalExpandVolume: returns fault %q for volume %q", faultType, volumeID)
 	if err != nil {
@@ -1267,7 +1401,7 @@ func (m *defaultManager) expandVolume(ctx context.Context, volumeID string, size
 		return faultType, err
 	}
 	// Get the taskInfo.
-	taskInfo, err := cns.GetTaskInfo(ctx, task)
+	taskInfo, err := m.waitForTask(ctx, task)
 	if err != nil || taskInfo == nil {
 		log.Errorf("failed to get taskInfo for ExtendVolume task from vCenter %q with err: %v",
 			m.virtualCenter.Config.Host, err)
@@ -1397,7 +1531,7 @@ func (m *defaultManager) expandVolumeWithImprovedIdempotency(ctx context.Context
 		}
 	}
 
-	taskInfo, err := task.WaitForResult(ctx, nil)
+	taskInfo, err := m.waitForTask(ctx, task)
 	if err != nil {
 		if cnsvsphere.IsManagedObjectNotFound(err, task.Reference()) {
 			log.Debugf("ExtendVolume task %s not found in vCenter. Querying CNS "+
@@ -1483,7 +1617,11 @@ func (m *defaultManager) expandVolumeWithImprovedIdempotency(ctx context.Context
 
 // QueryVolume returns volumes matching the given filter.
 func (m *defaultManager) QueryVolume(ctx context.Context,
-	queryFilter cnstypes.CnsQueryFilter) (*cnstypes.CnsQueryResult, error) {
+	queryFilter cnstypes.CnsQueryFilter) (queryResult *cnstypes.CnsQueryResult, err error) {
+	ctx, span := tracing.StartSpan(ctx, "CNS.QueryVolume")
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 	internalQueryVolume := func() (*cnstypes.CnsQueryResult, error) {
 		log := logger.GetLogger(ctx)
 		err := validateManager(ctx, m)
@@ -1519,7 +1657,11 @@ func (m *defaultManager) QueryVolume(ctx context.Context,
 
 // QueryAllVolume returns all volumes matching the given filter and selection.
 func (m *defaultManager) QueryAllVolume(ctx context.Context, queryFilter cnstypes.CnsQueryFilter,
-	querySelection cnstypes.CnsQuerySelection) (*cnstypes.CnsQueryResult, error) {
+	querySelection cnstypes.CnsQuerySelection) (queryResult *cnstypes.CnsQueryResult, err error) {
+	ctx, span := tracing.StartSpan(ctx, "CNS.QueryAllVolume")
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 	internalQueryAllVolume := func() (*cnstypes.CnsQueryResult, error) {
 		log := logger.GetLogger(ctx)
 		err := validateManager(ctx, m)
@@ -1556,7 +1698,11 @@ func (m *defaultManager) QueryAllVolume(ctx context.Context, queryFilter cnstype
 // QueryVolumeInfo calls the CNS QueryVolumeInfo API and return a task, from
 // which CnsQueryVolumeInfoResult is extracted.
 func (m *defaultManager) QueryVolumeInfo(ctx context.Context,
-	volumeIDList []cnstypes.CnsVolumeId) (*cnstypes.CnsQueryVolumeInfoResult, error) {
+	volumeIDList []cnstypes.CnsVolumeId) (queryResult *cnstypes.CnsQueryVolumeInfoResult, err error) {
+	ctx, span := tracing.StartSpan(ctx, "CNS.QueryVolumeInfo")
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 	internalQueryVolumeInfo := func() (*cnstypes.CnsQueryVolumeInfoResult, error) {
 		log := logger.GetLogger(ctx)
 		err := validateManager(ctx, m)
@@ -1577,7 +1723,7 @@ func (m *defaultManager) QueryVolumeInfo(ctx context.Context,
 		}
 
 		// Get the taskInfo.
-		taskInfo, err := cns.GetTaskInfo(ctx, queryVolumeInfoTask)
+		taskInfo, err := m.waitForTask(ctx, queryVolumeInfoTask)
 		if err != nil || taskInfo == nil {
 			log.Errorf("failed to get QueryVolumeInfo taskInfo from vCenter %q with err: %v",
 				m.virtualCenter.Config.Host, err)
@@ -1618,7 +1764,11 @@ func (m *defaultManager) QueryVolumeInfo(ctx context.Context,
 }
 
 func (m *defaultManager) RelocateVolume(ctx context.Context,
-	relocateSpecList ...cnstypes.BaseCnsVolumeRelocateSpec) (*object.Task, error) {
+	relocateSpecList ...cnstypes.BaseCnsVolumeRelocateSpec) (task *object.Task, err error) {
+	ctx, span := tracing.StartSpan(ctx, "CNS.RelocateVolume")
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 	internalRelocateVolume := func() (*object.Task, error) {
 		log := logger.GetLogger(ctx)
 		err := validateManager(ctx, m)
@@ -1653,7 +1803,12 @@ func (m *defaultManager) RelocateVolume(ctx context.Context,
 }
 
 // ConfigureVolumeACLs configures net permissions for a given CnsVolumeACLConfigureSpec.
-func (m *defaultManager) ConfigureVolumeACLs(ctx context.Context, spec cnstypes.CnsVolumeACLConfigureSpec) error {
+func (m *defaultManager) ConfigureVolumeACLs(ctx context.Context, spec cnstypes.CnsVolumeACLConfigureSpec) (
+	err error) {
+	ctx, span := tracing.StartSpan(ctx, "CNS.ConfigureVolumeACLs")
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 	internalConfigureVolumeACLs := func() error {
 		log := logger.GetLogger(ctx)
 		err := validateManager(ctx, m)
@@ -1677,7 +1832,7 @@ func (m *defaultManager) ConfigureVolumeACLs(ctx context.Context, spec cnstypes.
 		}
 
 		// Get the taskInfo.
-		taskInfo, err = cns.GetTaskInfo(ctx, task)
+		taskInfo, err = m.waitForTask(ctx, task)
 		if err != nil {
 			log.Errorf("failed to get ConfigureVolumeACLs taskInfo from vCenter %q with err: %v",
 				m.virtualCenter.Config.Host, err)
@@ -1708,7 +1863,7 @@ func (m *defaultManager) ConfigureVolumeACLs(ctx context.Context, spec cnstypes.
 		return nil
 	}
 	start := time.Now()
-	err := internalConfigureVolumeACLs()
+	err = internalConfigureVolumeACLs()
 	if err != nil {
 		prometheus.CnsControlOpsHistVec.WithLabelValues(prometheus.PrometheusCnsConfigureVolumeACLOpType,
 			prometheus.PrometheusFailStatus).Observe(time.Since(start).Seconds())
@@ -1725,9 +1880,14 @@ func (m *defaultManager) ConfigureVolumeACLs(ctx context.Context, spec cnstypes.
 // containing the vmdkPath and name is any given string for the FCD.
 // RegisterDisk API takes this name as optional parameter, so it need not be
 // a unique string or anything.
-func (m *defaultManager) RegisterDisk(ctx context.Context, path string, name string) (string, error) {
+func (m *defaultManager) RegisterDisk(ctx context.Context, path string, name string) (volumeID string,
+	err error) {
+	ctx, span := tracing.StartSpan(ctx, "CNS.RegisterDisk")
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 	log := logger.GetLogger(ctx)
-	err := validateManager(ctx, m)
+	err = validateManager(ctx, m)
 	if err != nil {
 		log.Errorf("failed to validate volume manager with err: %+v", err)
 		return "", err
@@ -1755,9 +1915,13 @@ func (m *defaultManager) RegisterDisk(ctx context.Context, path string, name str
 // RetrieveVStorageObject helps in retreiving virtual disk information for
 // a given volume id.
 func (m *defaultManager) RetrieveVStorageObject(ctx context.Context,
-	volumeID string) (*vim25types.VStorageObject, error) {
+	volumeID string) (vStorageObject *vim25types.VStorageObject, err error) {
+	ctx, span := tracing.StartSpan(ctx, "CNS.RetrieveVStorageObject")
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 	log := logger.GetLogger(ctx)
-	err := validateManager(ctx, m)
+	err = validateManager(ctx, m)
 	if err != nil {
 		log.Errorf("failed to validate volume manager with err: %+v", err)
 		return nil, err
@@ -1769,7 +1933,7 @@ func (m *defaultManager) RetrieveVStorageObject(ctx context.Context,
 		return nil, err
 	}
 	globalObjectManager := vslm.NewGlobalObjectManager(m.virtualCenter.VslmClient)
-	vStorageObject, err := globalObjectManager.Retrieve(ctx, vim25types.ID{Id: volumeID})
+	vStorageObject, err = globalObjectManager.Retrieve(ctx, vim25types.ID{Id: volumeID})
 	if err != nil {
 		log.Errorf("failed to retrieve virtual disk for volumeID %q with err: %v", volumeID, err)
 		return nil, err
@@ -1785,9 +1949,13 @@ func (m *defaultManager) RetrieveVStorageObject(ctx context.Context,
 // fields would be returned as part of the CnsQueryResult if the querySelection
 // parameters are not specified.
 func (m *defaultManager) QueryVolumeAsync(ctx context.Context, queryFilter cnstypes.CnsQueryFilter,
-	querySelection *cnstypes.CnsQuerySelection) (*cnstypes.CnsQueryResult, error) {
+	querySelection *cnstypes.CnsQuerySelection) (queryResult *cnstypes.CnsQueryResult, err error) {
+	ctx, span := tracing.StartSpan(ctx, "CNS.QueryVolumeAsync")
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 	log := logger.GetLogger(ctx)
-	err := validateManager(ctx, m)
+	err = validateManager(ctx, m)
 	if err != nil {
 		log.Errorf("validateManager failed with err: %+v", err)
 		return nil, err
@@ -1817,7 +1985,7 @@ func (m *defaultManager) QueryVolumeAsync(ctx context.Context, queryFilter cnsty
 		log.Errorf("CNS QueryVolumeAsync failed from vCenter %q with err: %v", m.virtualCenter.Config.Host, err)
 		return nil, err
 	}
-	queryVolumeAsyncTaskInfo, err := cns.GetTaskInfo(ctx, queryVolumeAsyncTask)
+	queryVolumeAsyncTaskInfo, err := m.waitForTask(ctx, queryVolumeAsyncTask)
 	if err != nil {
 		log.Errorf("CNS QueryVolumeAsync failed to get TaskInfo with err: %v", err)
 		return nil, err
@@ -1844,7 +2012,11 @@ func (m *defaultManager) QueryVolumeAsync(ctx context.Context, queryFilter cnsty
 }
 
 func (m *defaultManager) QuerySnapshots(ctx context.Context, snapshotQueryFilter cnstypes.CnsSnapshotQueryFilter) (
-	*cnstypes.CnsSnapshotQueryResult, error) {
+	queryResult *cnstypes.CnsSnapshotQueryResult, err error) {
+	ctx, span := tracing.StartSpan(ctx, "CNS.QuerySnapshots")
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 	internalQuerySnapshots := func() (*cnstypes.CnsSnapshotQueryResult, error) {
 		log := logger.GetLogger(ctx)
 		err := validateManager(ctx, m)
@@ -1863,7 +2035,7 @@ func (m *defaultManager) QuerySnapshots(ctx context.Context, snapshotQueryFilter
 			log.Errorf("Failed to get the task of CNS QuerySnapshots with err: %v", err)
 			return nil, err
 		}
-		querySnapshotsTaskInfo, err := cns.GetTaskInfo(ctx, querySnapshotsTask)
+		querySnapshotsTaskInfo, err := m.waitForTask(ctx, querySnapshotsTask)
 		if err != nil {
 			log.Errorf("failed to get taskInfo for QuerySnapshots task from vCenter %q with err: %v",
 				m.virtualCenter.Config.Host, err)
@@ -1892,7 +2064,7 @@ func (m *defaultManager) QuerySnapshots(ctx context.Context, snapshotQueryFilter
 // Helper function for create snapshot with different behaviors in the idempotency handling
 // depends on whether the improved idempotency FSS is enabled.
 func (m *defaultManager) createSnapshotWithImprovedIdempotencyCheck(ctx context.Context, volumeID string,
-	snapshotName string) (*CnsSnapshotInfo, error) {
+	snapshotName string) (*CnsSnapshotInfo, string, error) {
 	log := logger.GetLogger(ctx)
 	var (
 		// Reference to the CreateSnapshot task on CNS.
@@ -1907,7 +2079,7 @@ func (m *defaultManager) createSnapshotWithImprovedIdempotencyCheck(ctx context.
 
 	if m.idempotencyHandlingEnabled {
 		if m.operationStore == nil {
-			return nil, logger.LogNewError(log, "operation store cannot be nil")
+			return nil, csifault.CSIInternalFault, logger.LogNewError(log, "operation store cannot be nil")
 		}
 
 		volumeOperationDetails, err = m.operationStore.GetRequestDetails(ctx, instanceName)
@@ -1925,7 +2097,7 @@ func (m *defaultManager) createSnapshotWithImprovedIdempotencyCheck(ctx context.
 					SourceVolumeID:            volumeOperationDetails.VolumeID,
 					SnapshotDescription:       volumeOperationDetails.Name,
 					SnapshotCreationTimestamp: volumeOperationDetails.OperationDetails.TaskInvocationTimestamp.Time,
-				}, nil
+				}, "", nil
 			}
 			// Validate if previous operation is pending.
 			if volumeOperationDetails.OperationDetails.TaskStatus == taskInvocationStatusInProgress &&
@@ -1945,7 +2117,7 @@ func (m *defaultManager) createSnapshotWithImprovedIdempotencyCheck(ctx context.
 				instanceName, volumeID, "", 0, metav1.Now(), "", "", "",
 				taskInvocationStatusInProgress, "")
 		default:
-			return nil, err
+			return nil, csifault.CSIInternalFault, err
 		}
 	} else {
 		// get snapshot task details from an in-memory map
@@ -1972,7 +2144,8 @@ func (m *defaultManager) createSnapshotWithImprovedIdempotencyCheck(ctx context.
 					volumeOperationDetails.OperationDetails.TaskInvocationTimestamp, "", "", "",
 					taskInvocationStatusError, err.Error())
 			}
-			return nil, logger.LogNewErrorf(log, "failed to create snapshot with error: %v", err)
+			return nil, ExtractFaultTypeFromErr(ctx, err), logger.LogNewErrorf(log,
+				"failed to create snapshot with error: %v", err)
 		}
 
 		if m.idempotencyHandlingEnabled {
@@ -2002,7 +2175,7 @@ func (m *defaultManager) createSnapshotWithImprovedIdempotencyCheck(ctx context.
 	}
 
 	// Get the taskInfo and more!
-	createSnapshotsTaskInfo, err := createSnapshotsTask.WaitForResult(ctx, nil)
+	createSnapshotsTaskInfo, err := m.waitForTask(ctx, createSnapshotsTask)
 	if err != nil {
 		if cnsvsphere.IsManagedObjectNotFound(err, createSnapshotsTask.Reference()) {
 			log.Infof("CreateSnapshot task %s not found in vCenter. Querying CNS "+
@@ -2025,7 +2198,7 @@ func (m *defaultManager) createSnapshotWithImprovedIdempotencyCheck(ctx context.
 					SourceVolumeID:            volumeID,
 					SnapshotDescription:       snapshotName,
 					SnapshotCreationTimestamp: queriedCnsSnapshot.CreateTime,
-				}, nil
+				}, "", nil
 			} else {
 				errMsg := fmt.Sprintf("Snapshot with name %s on volume %q is not present in CNS. "+
 					"Marking task %s as failed.", snapshotName, volumeID, createSnapshotsTask.Reference().Value)
@@ -2033,19 +2206,21 @@ func (m *defaultManager) createSnapshotWithImprovedIdempotencyCheck(ctx context.
 					volumeOperationDetails.OperationDetails.TaskInvocationTimestamp,
 					createSnapshotsTask.Reference().Value, "",
 					"", taskInvocationStatusError, errMsg)
-				return nil, logger.LogNewError(log, errMsg)
+				return nil, csifault.CSIInternalFault, logger.LogNewError(log, errMsg)
 			}
 		}
-		return nil, logger.LogNewErrorf(log, "Failed to get taskInfo for CreateSnapshots task "+
-			"from vCenter %q with err: %v", m.virtualCenter.Config.Host, err)
+		return nil, ExtractFaultTypeFromErr(ctx, err), logger.LogNewErrorf(log,
+			"Failed to get taskInfo for CreateSnapshots task "+
+				"from vCenter %q with err: %v", m.virtualCenter.Config.Host, err)
 	}
 	log.Infof("CreateSnapshots: VolumeID: %q, opId: %q", volumeID, createSnapshotsTaskInfo.ActivationId)
 
 	// Get the taskResult
 	createSnapshotsTaskResult, err := cns.GetTaskResult(ctx, createSnapshotsTaskInfo)
 	if err != nil || createSnapshotsTaskResult == nil {
-		return nil, logger.LogNewErrorf(log, "unable to find the task result for CreateSnapshots task "+
-			"from vCenter %q. taskID: %q, opId: %q createResults: %+v",
+		return nil, csifault.CSITaskResultEmptyFault, logger.LogNewErrorf(log,
+			"unable to find the task result for CreateSnapshots task "+
+				"from vCenter %q. taskID: %q, opId: %q createResults: %+v",
 			m.virtualCenter.Config.Host, createSnapshotsTaskInfo.Task.Value, createSnapshotsTaskInfo.ActivationId,
 			createSnapshotsTaskResult)
 	}
@@ -2077,7 +2252,8 @@ func (m *defaultManager) createSnapshotWithImprovedIdempotencyCheck(ctx context.
 			}
 		}
 
-		return nil, logger.LogNewError(log, errMsg)
+		return nil, ExtractFaultTypeFromVolumeResponseResult(ctx, createSnapshotsOperationRes),
+			logger.LogNewError(log, errMsg)
 	}
 
 	snapshotCreateResult := interface{}(createSnapshotsTaskResult).(*cnstypes.CnsSnapshotCreateResult)
@@ -2101,31 +2277,36 @@ func (m *defaultManager) createSnapshotWithImprovedIdempotencyCheck(ctx context.
 		"SnapshotCreateTime: %q, opId: %q", cnsSnapshotInfo.SourceVolumeID, cnsSnapshotInfo.SnapshotID,
 		cnsSnapshotInfo.SnapshotCreationTimestamp, createSnapshotsTaskInfo.ActivationId)
 
-	return cnsSnapshotInfo, nil
+	return cnsSnapshotInfo, "", nil
 }
 
 // The snapshotName parameter denotes the snapshot description required by CNS CreateSnapshot API.
 // This parameter is expected to be filled with the CSI CreateSnapshotRequest Name,
 // which is generated by the CSI snapshotter sidecar.
 func (m *defaultManager) CreateSnapshot(
-	ctx context.Context, volumeID string, snapshotName string) (*CnsSnapshotInfo, error) {
-	internalCreateSnapshot := func() (*CnsSnapshotInfo, error) {
+	ctx context.Context, volumeID string, snapshotName string) (snapshotInfo *CnsSnapshotInfo, faultType string,
+	err error) {
+	ctx, span := tracing.StartSpan(ctx, "CNS.CreateSnapshot")
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
+	internalCreateSnapshot := func() (*CnsSnapshotInfo, string, error) {
 		log := logger.GetLogger(ctx)
 		err := validateManager(ctx, m)
 		if err != nil {
-			return nil, err
+			return nil, csifault.CSIInternalFault, err
 		}
 		// Set up the VC connection
 		err = m.virtualCenter.ConnectCns(ctx)
 		if err != nil {
-			return nil, logger.LogNewErrorf(log, "ConnectCns failed with err: %+v", err)
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorf(log, "ConnectCns failed with err: %+v", err)
 		}
 
 		return m.createSnapshotWithImprovedIdempotencyCheck(ctx, volumeID, snapshotName)
 	}
 
 	start := time.Now()
-	cnsSnapshotInfo, err := internalCreateSnapshot()
+	snapshotInfo, faultType, err = internalCreateSnapshot()
 	if err != nil {
 		prometheus.CnsControlOpsHistVec.WithLabelValues(prometheus.PrometheusCnsCreateSnapshotOpType,
 			prometheus.PrometheusFailStatus).Observe(time.Since(start).Seconds())
@@ -2133,7 +2314,7 @@ func (m *defaultManager) CreateSnapshot(
 		prometheus.CnsControlOpsHistVec.WithLabelValues(prometheus.PrometheusCnsCreateSnapshotOpType,
 			prometheus.PrometheusPassStatus).Observe(time.Since(start).Seconds())
 	}
-	return cnsSnapshotInfo, err
+	return snapshotInfo, faultType, err
 }
 
 // Helper function for create snapshot with different behaviors in the idempotency handling
@@ -2230,7 +2411,7 @@ func (m *defaultManager) deleteSnapshotWithImprovedIdempotencyCheck(
 	}
 
 	// Get the taskInfo
-	deleteSnapshotsTaskInfo, err := deleteSnapshotTask.WaitForResult(ctx, nil)
+	deleteSnapshotsTaskInfo, err := m.waitForTask(ctx, deleteSnapshotTask)
 	if err != nil {
 		if cnsvsphere.IsManagedObjectNotFound(err, deleteSnapshotTask.Reference()) {
 			log.Infof("Snapshot %q on volume %q might have already been deleted "+
@@ -2321,7 +2502,11 @@ func (m *defaultManager) deleteSnapshotWithImprovedIdempotencyCheck(
 	return nil
 }
 
-func (m *defaultManager) DeleteSnapshot(ctx context.Context, volumeID string, snapshotID string) error {
+func (m *defaultManager) DeleteSnapshot(ctx context.Context, volumeID string, snapshotID string) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "CNS.DeleteSnapshot")
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 	internalDeleteSnapshot := func() error {
 		log := logger.GetLogger(ctx)
 		err := validateManager(ctx, m)
@@ -2339,7 +2524,7 @@ func (m *defaultManager) DeleteSnapshot(ctx context.Context, volumeID string, sn
 	}
 
 	start := time.Now()
-	err := internalDeleteSnapshot()
+	err = internalDeleteSnapshot()
 	if err != nil {
 		prometheus.CnsControlOpsHistVec.WithLabelValues(prometheus.PrometheusCnsDeleteSnapshotOpType,
 			prometheus.PrometheusFailStatus).Observe(time.Since(start).Seconds())
@@ -2351,9 +2536,13 @@ func (m *defaultManager) DeleteSnapshot(ctx context.Context, volumeID string, sn
 }
 
 // ProtectVolumeFromVMDeletion helps set keepAfterDeleteVm control flag for given volumeID
-func (m *defaultManager) ProtectVolumeFromVMDeletion(ctx context.Context, volumeID string) error {
+func (m *defaultManager) ProtectVolumeFromVMDeletion(ctx context.Context, volumeID string) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "CNS.ProtectVolumeFromVMDeletion")
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 	log := logger.GetLogger(ctx)
-	err := validateManager(ctx, m)
+	err = validateManager(ctx, m)
 	if err != nil {
 		log.Errorf("failed to validate volume manager with err: %+v", err)
 		return err
@@ -2374,3 +2563,49 @@ func (m *defaultManager) ProtectVolumeFromVMDeletion(ctx context.Context, volume
 	log.Infof("Successfully set keepAfterDeleteVm control flag for volumeID: %q", volumeID)
 	return nil
 }
+
+// UpdateVStorageObjectMetadata adds or updates the given key-value metadata of
+// the FCD backing the given volume and deletes the metadata with the given
+// keys.
+func (m *defaultManager) UpdateVStorageObjectMetadata(ctx context.Context, volumeID string,
+	metadata []vim25types.KeyValue, deleteKeys []string) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "CNS.UpdateVStorageObjectMetadata")
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
+	log := logger.GetLogger(ctx)
+	err = validateManager(ctx, m)
+	if err != nil {
+		log.Errorf("failed to validate volume manager with err: %+v", err)
+		return err
+	}
+	// Set up the VC connection
+	err = m.virtualCenter.ConnectVslm(ctx)
+	if err != nil {
+		log.Errorf("ConnectVslm failed with err: %+v", err)
+		return err
+	}
+	globalObjectManager := vslm.NewGlobalObjectManager(m.virtualCenter.VslmClient)
+	task, err := globalObjectManager.UpdateMetadata(ctx, vim25types.ID{Id: volumeID}, metadata, deleteKeys)
+	if err != nil {
+		log.Errorf("failed to update metadata of volumeID %q with err: %v", volumeID, err)
+		return err
+	}
+	_, err = task.Wait(ctx, vslmTaskTimeout)
+	if err != nil {
+		log.Errorf("failed to update metadata of volumeID %q with err: %v", volumeID, err)
+		return err
+	}
+	taskInfo, err := task.QueryInfo(ctx)
+	if err != nil {
+		log.Errorf("failed to get the info of the task updating the metadata of volumeID %q with err: %v",
+			volumeID, err)
+		return err
+	}
+	if taskInfo.State != vslmtypes.VslmTaskInfoStateSuccess {
+		return logger.LogNewErrorf(log, "task updating the metadata of volumeID %q is in state %q",
+			volumeID, taskInfo.State)
+	}
+	log.Debugf("Successfully updated metadata of volumeID: %q", volumeID)
+	return nil
+}
@@ -31,9 +31,13 @@ import (
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/soap"
 	"github.com/vmware/govmomi/vim25/types"
+	"go.opentelemetry.io/otel/attribute"
 
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/audit"
 	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
 	csifault "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/fault"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/tracing"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
 )
 
@@ -41,6 +45,10 @@ const (
 	vimFaultPrefix = "vim.fault."
 )
 
+// ErrTaskNotStarted is wrapped by the errors of CreateVolume when its CNS
+// task could not be started, in which case no volume is created.
+var ErrTaskNotStarted = errors.New("CNS task not started")
+
 func validateManager(ctx context.Context, m *defaultManager) error {
 	log := logger.GetLogger(ctx)
 	if m.virtualCenter == nil {
@@ -49,6 +57,26 @@ func validateManager(ctx context.Context, m *defaultManager) error {
 	return nil
 }
 
+// waitForTask waits for the given vCenter task to complete and returns its
+// task info. The task is counted in the in-flight tasks metric of the vCenter
+// while it is waited on, and recorded in the audited operation of the given
+// context if any.
+func (m *defaultManager) waitForTask(ctx context.Context, task *object.Task) (*types.TaskInfo, error) {
+	ctx, span := tracing.StartSpan(ctx, "vCenter.WaitForTask", attribute.String("vcenter", m.virtualCenter.Config.Host),
+		attribute.String("task", task.Reference().Value))
+	inFlight := prometheus.VCenterInFlightTasksGaugeVec.WithLabelValues(m.virtualCenter.Config.Host)
+	inFlight.Inc()
+	defer inFlight.Dec()
+	taskInfo, err := task.WaitForResult(ctx, nil)
+	tracing.EndSpan(span, err)
+	taskOpID := ""
+	if taskInfo != nil {
+		taskOpID = taskInfo.ActivationId
+	}
+	audit.RecordTask(ctx, m.virtualCenter.Config.Host, task.Reference().Value, taskOpID)
+	return taskInfo, err
+}
+
 // IsDiskAttached checks if the volume is attached to the VM.
 // If the volume is attached to the VM, return disk uuid of the volume,
 // else return empty string.
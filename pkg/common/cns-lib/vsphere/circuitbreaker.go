@@ -0,0 +1,243 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/flowcontrol"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+const (
+	// circuitBreakerClosed is the state of a circuit breaker letting all the
+	// requests through.
+	circuitBreakerClosed = "closed"
+	// circuitBreakerOpen is the state of a circuit breaker rejecting all the
+	// requests.
+	circuitBreakerOpen = "open"
+	// circuitBreakerHalfOpen is the state of a circuit breaker letting a
+	// single trial request through, which closes it if it succeeds.
+	circuitBreakerHalfOpen = "half-open"
+
+	// circuitBreakerFailureThreshold is the number of consecutive failed
+	// requests to a vCenter which opens its circuit breaker.
+	circuitBreakerFailureThreshold = 5
+	// circuitBreakerOpenDuration is the duration for which an open circuit
+	// breaker rejects the requests before letting a trial request through.
+	circuitBreakerOpenDuration = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned for the vCenter API requests rejected while the
+// circuit breaker of the vCenter is open.
+var ErrCircuitOpen = errors.New("vCenter is unavailable, circuit breaker is open")
+
+// circuitBreaker rejects the requests to a vCenter after
+// circuitBreakerFailureThreshold consecutive requests failed because vCenter
+// could not be reached, until a trial request succeeds.
+type circuitBreaker struct {
+	lock     sync.Mutex
+	host     string
+	state    string
+	failures int
+	openedAt time.Time
+	// trialInFlight is true while the trial request of a half-open circuit
+	// breaker is in flight.
+	trialInFlight bool
+}
+
+// newCircuitBreaker returns a closed circuit breaker for the given vCenter.
+func newCircuitBreaker(host string) *circuitBreaker {
+	cb := &circuitBreaker{host: host}
+	cb.setState(circuitBreakerClosed)
+	return cb
+}
+
+// setState sets the state of the circuit breaker and observes it in the
+// circuit breaker state metric. The lock must be held.
+func (cb *circuitBreaker) setState(state string) {
+	cb.state = state
+	for _, s := range []string{circuitBreakerClosed, circuitBreakerOpen, circuitBreakerHalfOpen} {
+		value := 0.0
+		if s == state {
+			value = 1
+		}
+		prometheus.VCenterCircuitBreakerStateGaugeVec.WithLabelValues(cb.host, s).Set(value)
+	}
+}
+
+// allow returns true if a request can be sent at the given time. An open
+// circuit breaker turns half-open once circuitBreakerOpenDuration elapsed, and
+// then lets a single trial request through.
+func (cb *circuitBreaker) allow(now time.Time) bool {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	if cb.state == circuitBreakerOpen && now.Sub(cb.openedAt) >= circuitBreakerOpenDuration {
+		cb.setState(circuitBreakerHalfOpen)
+	}
+	switch cb.state {
+	case circuitBreakerClosed:
+		return true
+	case circuitBreakerHalfOpen:
+		if cb.trialInFlight {
+			return false
+		}
+		cb.trialInFlight = true
+		return true
+	default:
+		return false
+	}
+}
+
+// record records the outcome of a request sent at the given time and returns
+// the new state of the circuit breaker if it changed, or an empty string.
+func (cb *circuitBreaker) record(failed bool, now time.Time) string {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	previousState := cb.state
+	cb.trialInFlight = false
+	if !failed {
+		cb.failures = 0
+		if cb.state != circuitBreakerClosed {
+			cb.setState(circuitBreakerClosed)
+		}
+	} else {
+		cb.failures++
+		if cb.state == circuitBreakerHalfOpen ||
+			(cb.state == circuitBreakerClosed && cb.failures >= circuitBreakerFailureThreshold) {
+			cb.openedAt = now
+			cb.setState(circuitBreakerOpen)
+		}
+	}
+	if cb.state == previousState {
+		return ""
+	}
+	return cb.state
+}
+
+// release gives back the trial of a half-open circuit breaker, when the trial
+// request could not be sent.
+func (cb *circuitBreaker) release() {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	cb.trialInFlight = false
+}
+
+// isVCenterUnreachable returns true if the given outcome of a request shows
+// that vCenter could not be reached. SOAP faults, returned with an HTTP 500
+// status code, are not counted as vCenter is reachable.
+func isVCenterUnreachable(ctx context.Context, res *http.Response, err error) bool {
+	if err != nil {
+		// Requests canceled by the caller are not counted.
+		return ctx.Err() == nil
+	}
+	switch res.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// vCenterLimiter holds the rate limiter and the circuit breaker shared by the
+// API clients of a vCenter.
+type vCenterLimiter struct {
+	// rateLimiter is nil if the requests are not rate limited.
+	rateLimiter flowcontrol.RateLimiter
+	qps         float32
+	burst       int
+	breaker     *circuitBreaker
+}
+
+var (
+	vCenterLimitersLock sync.Mutex
+	// vCenterLimiters holds the limiters by vCenter host.
+	vCenterLimiters = make(map[string]*vCenterLimiter)
+)
+
+// getVCenterLimiter returns the limiter of the given vCenter.
+func getVCenterLimiter(host string) *vCenterLimiter {
+	vCenterLimitersLock.Lock()
+	defer vCenterLimitersLock.Unlock()
+	limiter, ok := vCenterLimiters[host]
+	if !ok {
+		limiter = &vCenterLimiter{breaker: newCircuitBreaker(host)}
+		vCenterLimiters[host] = limiter
+	}
+	return limiter
+}
+
+// setVCenterRateLimit limits the API requests to the given vCenter, across
+// all its API clients, to qps requests per second with the given burst. The
+// requests are not rate limited if qps is 0.
+func setVCenterRateLimit(ctx context.Context, host string, qps float32, burst int) {
+	log := logger.GetLogger(ctx)
+	limiter := getVCenterLimiter(host)
+	vCenterLimitersLock.Lock()
+	defer vCenterLimitersLock.Unlock()
+	if limiter.qps == qps && limiter.burst == burst {
+		return
+	}
+	limiter.qps, limiter.burst = qps, burst
+	if qps <= 0 {
+		log.Infof("Not limiting the rate of the API requests to vCenter %q", host)
+		limiter.rateLimiter = nil
+		return
+	}
+	log.Infof("Limiting the API requests to vCenter %q to %v per second with a burst of %d", host, qps, burst)
+	limiter.rateLimiter = flowcontrol.NewTokenBucketRateLimiter(qps, burst)
+}
+
+// wait waits for the rate limiter of the vCenter to allow a request. It
+// returns ErrCircuitOpen if the circuit breaker of the vCenter is open.
+func (l *vCenterLimiter) wait(ctx context.Context) error {
+	if !l.breaker.allow(time.Now()) {
+		return ErrCircuitOpen
+	}
+	vCenterLimitersLock.Lock()
+	rateLimiter := l.rateLimiter
+	vCenterLimitersLock.Unlock()
+	if rateLimiter != nil {
+		if err := rateLimiter.Wait(ctx); err != nil {
+			l.breaker.release()
+			return err
+		}
+	}
+	return nil
+}
+
+// done records the outcome of a request in the circuit breaker of the
+// vCenter.
+func (l *vCenterLimiter) done(ctx context.Context, res *http.Response, err error) {
+	log := logger.GetLogger(ctx)
+	if state := l.breaker.record(isVCenterUnreachable(ctx, res, err), time.Now()); state != "" {
+		log.Warnf("Circuit breaker of vCenter %q is now %s", l.breaker.host, state)
+	}
+}
+
+// IsCircuitOpenError returns true if the given error comes from a vCenter API
+// request rejected by an open circuit breaker. The message of the error is
+// also checked, as the errors are often formatted into new ones.
+func IsCircuitOpenError(err error) bool {
+	return err != nil && (errors.Is(err, ErrCircuitOpen) || strings.Contains(err.Error(), ErrCircuitOpen.Error()))
+}
@@ -32,6 +32,7 @@ func NewCnsClient(ctx context.Context, c *vim25.Client) (*cns.Client, error) {
 		log.Errorf("failed to create a new client for CNS. err: %v", err)
 		return nil, err
 	}
+	instrumentSoapClient(cnsClient.Client, APIClientCns)
 	return cnsClient, nil
 }
 
@@ -0,0 +1,172 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/vmware/govmomi"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+// previousSessionLogoutDelay is the delay after which the session logged in
+// with the previous credentials of a vCenter is logged out, once a session is
+// logged in with its new credentials. It lets the in-flight tasks complete.
+const previousSessionLogoutDelay = 30 * time.Minute
+
+// credentials are the credentials to log in to a vCenter.
+type credentials struct {
+	username string
+	password string
+}
+
+// rotatedCredentials are the credentials of a vCenter set from a Kubernetes
+// Secret, along with its previous credentials while they are rotated.
+type rotatedCredentials struct {
+	current  credentials
+	previous *credentials
+}
+
+var (
+	credentialsLock sync.Mutex
+	// vCenterCredentials holds the credentials set from Kubernetes Secrets by
+	// vCenter host.
+	vCenterCredentials = make(map[string]*rotatedCredentials)
+	// tokenUsernames holds the usernames of the sessions logged in with a SAML
+	// token, e.g. as a solution user, by vCenter host.
+	tokenUsernames = make(map[string]string)
+)
+
+// storeTokenUsername stores the username of the session of the given vCenter
+// logged in with a SAML token.
+func storeTokenUsername(host string, username string) {
+	credentialsLock.Lock()
+	defer credentialsLock.Unlock()
+	tokenUsernames[host] = username
+}
+
+// storeCredentials stores the given credentials as the current credentials of
+// the given vCenter, and its current credentials as the previous ones. It
+// returns false if the credentials did not change.
+func storeCredentials(host string, creds credentials) bool {
+	credentialsLock.Lock()
+	defer credentialsLock.Unlock()
+	stored, ok := vCenterCredentials[host]
+	if !ok {
+		vCenterCredentials[host] = &rotatedCredentials{current: creds}
+		return true
+	}
+	if stored.current == creds {
+		return false
+	}
+	previous := stored.current
+	stored.current, stored.previous = creds, &previous
+	return true
+}
+
+// getLoginCredentials returns the credentials to log in to the virtual
+// center, in the order they should be tried. The credentials set from a
+// Kubernetes Secret, followed by the previous ones while they are rotated,
+// take precedence over the configured ones.
+func (vc *VirtualCenter) getLoginCredentials() []credentials {
+	credentialsLock.Lock()
+	defer credentialsLock.Unlock()
+	stored, ok := vCenterCredentials[vc.Config.Host]
+	if !ok {
+		return []credentials{{username: vc.Config.Username, password: vc.Config.Password}}
+	}
+	creds := []credentials{stored.current}
+	if stored.previous != nil {
+		creds = append(creds, *stored.previous)
+	}
+	return creds
+}
+
+// GetUsername returns the username the driver logs in to the given vCenter
+// with. It is the subject of the SAML token the driver logged in with, e.g.
+// as a solution user, the username read from the credentials Secret of the
+// vCenter, if any, and the given configured username otherwise. It must be
+// used instead of the configured username, which is empty when the driver
+// logs in as a solution user or the credentials are read from a Secret.
+func GetUsername(host string, configuredUsername string) string {
+	credentialsLock.Lock()
+	defer credentialsLock.Unlock()
+	if username, ok := tokenUsernames[host]; ok {
+		return username
+	}
+	if stored, ok := vCenterCredentials[host]; ok {
+		return stored.current.username
+	}
+	return configuredUsername
+}
+
+// UnsetCredentials removes the credentials of the given vCenter read from a
+// Kubernetes Secret, once the vCenter no longer references the Secret, so
+// that its configured credentials are used again.
+func UnsetCredentials(host string) {
+	credentialsLock.Lock()
+	defer credentialsLock.Unlock()
+	delete(vCenterCredentials, host)
+}
+
+// SetCredentials sets the credentials of the given vCenter, read from a
+// Kubernetes Secret, overriding the configured ones. If they changed and the
+// vCenter is connected, a new session is logged in with them. The previous
+// session is logged out after a delay, so that the in-flight tasks do not
+// fail, and the previous credentials are still tried if the new ones are
+// rejected, e.g. while they are rotated on vCenter.
+func SetCredentials(ctx context.Context, host string, username string, password string) error {
+	log := logger.GetLogger(ctx)
+	if !storeCredentials(host, credentials{username: username, password: password}) {
+		return nil
+	}
+	log.Infof("Credentials of vCenter %q changed", host)
+	for _, vc := range GetVirtualCenterManager(ctx).GetAllVirtualCenters() {
+		if vc.Config.Host != host {
+			continue
+		}
+		clientMutex.Lock()
+		previousClient := vc.Client
+		clientMutex.Unlock()
+		if previousClient == nil {
+			// The new credentials are used when the virtual center connects.
+			return nil
+		}
+		if err := vc.connect(ctx, true); err != nil {
+			return logger.LogNewErrorf(log, "failed to log in to vCenter %q with the new credentials. Err: %v",
+				host, err)
+		}
+		log.Infof("Logged in to vCenter %q with the new credentials", host)
+		go logoutAfterDelay(host, previousClient, previousSessionLogoutDelay)
+	}
+	return nil
+}
+
+// logoutAfterDelay logs out the given client of the given vCenter after the
+// given delay.
+func logoutAfterDelay(host string, client *govmomi.Client, delay time.Duration) {
+	time.Sleep(delay)
+	ctx, log := logger.GetNewContextWithLogger()
+	if err := client.Logout(ctx); err != nil {
+		log.Warnf("failed to log out the previous session of vCenter %q. Err: %v", host, err)
+		return
+	}
+	log.Infof("Logged out the previous session of vCenter %q", host)
+}
@@ -58,3 +58,38 @@ func (ds *Datastore) GetDatastoreURLAndType(ctx context.Context) (string, string
 	}
 	return dsMo.Summary.Url, dsMo.Summary.Type, nil
 }
+
+// GetVirtualDiskPaths returns the paths of the virtual disks present in the
+// given folder on the datastore. The returned paths are relative to the folder.
+func (ds *Datastore) GetVirtualDiskPaths(ctx context.Context, folderPath string) ([]string, error) {
+	log := logger.GetLogger(ctx)
+	browser, err := ds.Browser(ctx)
+	if err != nil {
+		log.Errorf("failed to get datastore browser for datastore %q. err: %v", ds.Name(), err)
+		return nil, err
+	}
+	searchSpec := &types.HostDatastoreBrowserSearchSpec{
+		Query:        []types.BaseFileQuery{&types.VmDiskFileQuery{}},
+		MatchPattern: []string{"*.vmdk"},
+	}
+	task, err := browser.SearchDatastore(ctx, ds.Path(folderPath), searchSpec)
+	if err != nil {
+		log.Errorf("failed to search folder %q on datastore %q. err: %v", folderPath, ds.Name(), err)
+		return nil, err
+	}
+	taskInfo, err := task.WaitForResult(ctx, nil)
+	if err != nil {
+		log.Errorf("failed to search folder %q on datastore %q. err: %v", folderPath, ds.Name(), err)
+		return nil, err
+	}
+	searchResults, ok := taskInfo.Result.(types.HostDatastoreBrowserSearchResults)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result %+v of datastore search in folder %q", taskInfo.Result, folderPath)
+	}
+	var diskPaths []string
+	for _, file := range searchResults.File {
+		diskPaths = append(diskPaths, file.GetFileInfo().Path)
+	}
+	log.Debugf("Found virtual disks %v in folder %q on datastore %q", diskPaths, folderPath, ds.Name())
+	return diskPaths, nil
+}
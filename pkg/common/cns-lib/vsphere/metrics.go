@@ -0,0 +1,166 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/vmware/govmomi/vim25/soap"
+	"go.opentelemetry.io/otel/attribute"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/tracing"
+)
+
+const (
+	// APIClientVim is the API client label of the vSphere API client.
+	APIClientVim = "vim"
+	// APIClientCns is the API client label of the CNS client.
+	APIClientCns = "cns"
+	// APIClientPbm is the API client label of the PBM client.
+	APIClientPbm = "pbm"
+	// APIClientVslm is the API client label of the VSLM client.
+	APIClientVslm = "vslm"
+	// APIClientVsan is the API client label of the vSAN client.
+	APIClientVsan = "vsan"
+
+	// soapBodyElement is the local name of the body element of a SOAP request.
+	soapBodyElement = "Body"
+)
+
+// metricsTransport is an http.RoundTripper observing the latency, the errors
+// and the number of in-flight requests of an API client of a vCenter. It also
+// starts a span for each request, and applies the rate limit and the circuit
+// breaker shared by the API clients of the vCenter.
+type metricsTransport struct {
+	transport http.RoundTripper
+	host      string
+	apiClient string
+}
+
+// instrumentSoapClient makes the given SOAP client observe its requests in
+// the vCenter API metrics, labelled with the given API client.
+func instrumentSoapClient(c *soap.Client, apiClient string) {
+	if c == nil {
+		return
+	}
+	if _, ok := c.Transport.(*metricsTransport); ok {
+		return
+	}
+	c.Transport = &metricsTransport{
+		transport: c.Transport,
+		host:      c.URL().Hostname(),
+		apiClient: apiClient,
+	}
+}
+
+// RoundTrip executes the given request and observes it in the vCenter API
+// metrics. A SOAP fault is returned with an HTTP 500 status code and is
+// counted as a failure.
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := "unknown"
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+		if m := getSoapMethodName(body); m != "" {
+			method = m
+		}
+	}
+	ctx, span := tracing.StartSpan(req.Context(), t.apiClient+"."+method, attribute.String("vcenter", t.host))
+	req = req.WithContext(ctx)
+	limiter := getVCenterLimiter(t.host)
+	if err := limiter.wait(ctx); err != nil {
+		if errors.Is(err, ErrCircuitOpen) {
+			prometheus.VCenterAPIRejectedRequestsCounterVec.WithLabelValues(t.host, t.apiClient).Inc()
+		}
+		tracing.EndSpan(span, err)
+		return nil, err
+	}
+	inFlight := prometheus.VCenterAPIInFlightRequestsGaugeVec.WithLabelValues(t.host, t.apiClient)
+	inFlight.Inc()
+	defer inFlight.Dec()
+	start := time.Now()
+	res, err := t.transport.RoundTrip(req)
+	limiter.done(ctx, res, err)
+	status := prometheus.PrometheusPassStatus
+	if err != nil || res.StatusCode >= http.StatusBadRequest {
+		status = prometheus.PrometheusFailStatus
+	}
+	spanErr := err
+	if spanErr == nil && res.StatusCode >= http.StatusBadRequest {
+		spanErr = fmt.Errorf("%s %s failed with status %s", t.apiClient, method, res.Status)
+	}
+	tracing.EndSpan(span, spanErr)
+	prometheus.VCenterAPIHistVec.WithLabelValues(t.host, t.apiClient, method, status).Observe(
+		time.Since(start).Seconds())
+	return res, err
+}
+
+// observeVCenterSession observes the creation of a vCenter session of the
+// given type in the vCenter session metric.
+func observeVCenterSession(host string, sessionType string, err error) {
+	status := prometheus.PrometheusPassStatus
+	if err != nil {
+		status = prometheus.PrometheusFailStatus
+	}
+	prometheus.VCenterSessionCounterVec.WithLabelValues(host, sessionType, status).Inc()
+}
+
+// getSoapMethodName returns the name of the method called by the given SOAP
+// request body, or an empty string if it cannot be found. The method is the
+// first child element of the Body element of the envelope, whatever their
+// namespace prefixes, so that elements of the Header, e.g. WS-Security
+// tokens, are ignored.
+func getSoapMethodName(body []byte) string {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	depth := 0
+	inBody := false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			if inBody {
+				return element.Name.Local
+			}
+			depth++
+			// The Body element is a child of the Envelope element.
+			if depth == 2 && element.Name.Local == soapBodyElement {
+				inBody = true
+			}
+		case xml.EndElement:
+			if inBody {
+				return ""
+			}
+			depth--
+		}
+	}
+}
@@ -20,9 +20,11 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/pbm"
 	pbmmethods "github.com/vmware/govmomi/pbm/methods"
 	pbmtypes "github.com/vmware/govmomi/pbm/types"
+	"github.com/vmware/govmomi/vim25/methods"
 	vimtypes "github.com/vmware/govmomi/vim25/types"
 
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
@@ -66,6 +68,7 @@ func (vc *VirtualCenter) ConnectPbm(ctx context.Context) error {
 			log.Errorf("failed to create pbm client with err: %v", err)
 			return err
 		}
+		instrumentSoapClient(vc.PbmClient.Client, APIClientPbm)
 	}
 	return nil
 }
@@ -132,6 +135,67 @@ func (vc *VirtualCenter) PbmCheckCompatibility(ctx context.Context,
 	return res.Returnval, nil
 }
 
+// PbmCheckVolumeCompliance checks the compliance of the given block volumes
+// with their storage policies and returns the compliance results, keyed by
+// the volume ID.
+func (vc *VirtualCenter) PbmCheckVolumeCompliance(ctx context.Context,
+	volumeIDs []string) (map[string]pbmtypes.PbmComplianceResult, error) {
+	log := logger.GetLogger(ctx)
+	err := vc.ConnectPbm(ctx)
+	if err != nil {
+		log.Errorf("Error occurred while connecting to PBM, err: %+v", err)
+		return nil, err
+	}
+	entities := make([]pbmtypes.PbmServerObjectRef, 0, len(volumeIDs))
+	for _, volumeID := range volumeIDs {
+		entities = append(entities, pbmtypes.PbmServerObjectRef{
+			ObjectType: string(pbmtypes.PbmObjectTypeVirtualDiskUUID),
+			Key:        volumeID,
+			ServerUuid: vc.Client.ServiceContent.About.InstanceUuid,
+		})
+	}
+	req := pbmtypes.PbmCheckCompliance{
+		This:     vc.PbmClient.ServiceContent.ComplianceManager,
+		Entities: entities,
+	}
+	res, err := pbmmethods.PbmCheckCompliance(ctx, vc.PbmClient, &req)
+	if err != nil {
+		return nil, err
+	}
+	results := make(map[string]pbmtypes.PbmComplianceResult, len(res.Returnval))
+	for _, result := range res.Returnval {
+		results[result.Entity.Key] = result
+	}
+	return results, nil
+}
+
+// UpdateVolumePolicy reapplies the given storage policy to the given block
+// volume on the given datastore.
+func (vc *VirtualCenter) UpdateVolumePolicy(ctx context.Context, volumeID string,
+	datastore vimtypes.ManagedObjectReference, profileID string) error {
+	log := logger.GetLogger(ctx)
+	err := vc.Connect(ctx)
+	if err != nil {
+		log.Errorf("failed to connect to Virtual Center %q with err: %v", vc.Config.Host, err)
+		return err
+	}
+	req := vimtypes.UpdateVStorageObjectPolicy_Task{
+		This:      *vc.Client.ServiceContent.VStorageObjectManager,
+		Id:        vimtypes.ID{Id: volumeID},
+		Datastore: datastore,
+		Profile: []vimtypes.BaseVirtualMachineProfileSpec{
+			&vimtypes.VirtualMachineDefinedProfileSpec{
+				ProfileId: profileID,
+			},
+		},
+	}
+	res, err := methods.UpdateVStorageObjectPolicy_Task(ctx, vc.Client, &req)
+	if err != nil {
+		return err
+	}
+	return object.NewTask(vc.Client.Client, res.Returnval).Wait(ctx)
+}
+
 // PbmRetrieveContent fetches the policy content of all given policies from SPBM.
 func (vc *VirtualCenter) PbmRetrieveContent(ctx context.Context, policyIds []string) ([]SpbmPolicyContent, error) {
 
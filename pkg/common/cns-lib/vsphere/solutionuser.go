@@ -0,0 +1,213 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/sts"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/soap"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+const (
+	// samlTokenLifetime is the lifetime of the SAML tokens issued by the STS
+	// of vCenter to log in with a certificate.
+	samlTokenLifetime = time.Hour
+	// samlTokenRenewBefore is the duration before the expiry of the SAML
+	// token of a session at which a new session is logged in.
+	samlTokenRenewBefore = 10 * time.Minute
+	// samlTokenRenewRetryInterval is the interval at which the session is
+	// renewed again after a failed renewal.
+	samlTokenRenewRetryInterval = time.Minute
+)
+
+// getSolutionUserCertificate returns the certificate and private key of the
+// solution user configured for the virtual center, or nil if none is
+// configured. They are read from their files at each login, so that the
+// rotated files are used.
+func (vc *VirtualCenter) getSolutionUserCertificate() (*tls.Certificate, error) {
+	if vc.Config.SolutionUserCertFile == "" {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(vc.Config.SolutionUserCertFile, vc.Config.SolutionUserKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// issueSAMLToken issues a holder-of-key SAML token for the given certificate
+// from the STS of vCenter, valid for samlTokenLifetime.
+func issueSAMLToken(ctx context.Context, client *vim25.Client, cert *tls.Certificate,
+	delegatable bool) (*sts.Signer, error) {
+	tokens, err := sts.NewClient(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	return tokens.Issue(ctx, sts.TokenRequest{
+		Certificate: cert,
+		Delegatable: delegatable,
+		Lifetime:    samlTokenLifetime,
+	})
+}
+
+// loginByToken logs in with a SAML token issued for the given certificate,
+// and schedules the renewal of the session before the token expires. It is
+// called with clientMutex held.
+func (vc *VirtualCenter) loginByToken(ctx context.Context, client *govmomi.Client, cert *tls.Certificate) error {
+	log := logger.GetLogger(ctx)
+	issuedAt := time.Now()
+	signer, err := issueSAMLToken(ctx, client.Client, cert, false)
+	if err != nil {
+		log.Errorf("failed to issue SAML token with err: %v", err)
+		return err
+	}
+	header := soap.Header{Security: signer}
+	if err := client.SessionManager.LoginByToken(client.Client.WithHeader(ctx, header)); err != nil {
+		return err
+	}
+	username, err := getSAMLTokenSubject(signer.Token)
+	if err != nil {
+		log.Warnf("failed to get the subject of the SAML token of vCenter %q, using the certificate subject. Err: %v",
+			vc.Config.Host, err)
+		if username, err = getCertificateSubject(cert); err != nil {
+			log.Errorf("failed to get the subject of the certificate of vCenter %q. Err: %v", vc.Config.Host, err)
+			return err
+		}
+	}
+	storeTokenUsername(vc.Config.Host, username)
+	vc.samlTokenExpiry = issuedAt.Add(samlTokenLifetime)
+	if !vc.renewingSAMLToken {
+		vc.renewingSAMLToken = true
+		go vc.renewSessionBeforeTokenExpiry()
+	}
+	return nil
+}
+
+// getSAMLTokenSubject returns the name identifier of the subject of the given
+// SAML token, i.e. the user it was issued for.
+func getSAMLTokenSubject(token string) (string, error) {
+	decoder := xml.NewDecoder(strings.NewReader(token))
+	inSubject := false
+	for {
+		t, err := decoder.Token()
+		if err == io.EOF {
+			return "", errors.New("SAML token has no subject")
+		}
+		if err != nil {
+			return "", err
+		}
+		switch e := t.(type) {
+		case xml.StartElement:
+			if e.Name.Local == "Subject" {
+				inSubject = true
+			} else if inSubject && e.Name.Local == "NameID" {
+				var nameID string
+				if err := decoder.DecodeElement(&nameID, &e); err != nil {
+					return "", err
+				}
+				if nameID = strings.TrimSpace(nameID); nameID == "" {
+					return "", errors.New("SAML token has an empty subject")
+				}
+				return nameID, nil
+			}
+		case xml.EndElement:
+			if e.Name.Local == "Subject" {
+				inSubject = false
+			}
+		}
+	}
+}
+
+// getCertificateSubject returns the common name of the subject of the given
+// certificate, which is the name of the solution user it belongs to.
+func getCertificateSubject(cert *tls.Certificate) (string, error) {
+	if len(cert.Certificate) == 0 {
+		return "", errors.New("no certificate")
+	}
+	x509Cert, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return "", err
+	}
+	if x509Cert.Subject.CommonName == "" {
+		return "", errors.New("certificate subject has no common name")
+	}
+	return x509Cert.Subject.CommonName, nil
+}
+
+// getSAMLTokenRenewalDelay returns the delay after which a session logged in
+// with a SAML token expiring at the given time is renewed.
+func getSAMLTokenRenewalDelay(expiry time.Time, now time.Time) time.Duration {
+	delay := expiry.Add(-samlTokenRenewBefore).Sub(now)
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
+
+// renewSessionBeforeTokenExpiry logs in a new session samlTokenRenewBefore
+// the SAML token of the current session expires, as vCenter terminates the
+// session when its token expires. The previous session is logged out after a
+// delay, so that the in-flight tasks do not fail. It returns when the virtual
+// center is disconnected.
+func (vc *VirtualCenter) renewSessionBeforeTokenExpiry() {
+	ctx, log := logger.GetNewContextWithLogger()
+	delay := time.Duration(0)
+	for {
+		clientMutex.Lock()
+		expiry, connected := vc.samlTokenExpiry, vc.Client != nil
+		if !connected {
+			vc.renewingSAMLToken = false
+		}
+		clientMutex.Unlock()
+		if !connected {
+			log.Infof("vCenter %q is disconnected, stopping the renewal of its SAML token", vc.Config.Host)
+			return
+		}
+		if delay == 0 {
+			delay = getSAMLTokenRenewalDelay(expiry, time.Now())
+		}
+		time.Sleep(delay)
+		delay = 0
+		clientMutex.Lock()
+		previousClient, renewed := vc.Client, vc.samlTokenExpiry != expiry
+		clientMutex.Unlock()
+		if previousClient == nil || renewed {
+			// The session was disconnected or renewed meanwhile.
+			continue
+		}
+		if err := vc.connect(ctx, true); err != nil {
+			log.Errorf("failed to renew the session of vCenter %q before its SAML token expires. Err: %v",
+				vc.Config.Host, err)
+			delay = samlTokenRenewRetryInterval
+			continue
+		}
+		log.Infof("Renewed the session of vCenter %q before its SAML token expires", vc.Config.Host)
+		go logoutAfterDelay(vc.Config.Host, previousClient, samlTokenRenewBefore)
+	}
+}
@@ -206,12 +206,16 @@ func GetVirtualCenterConfig(ctx context.Context, cfg *config.Config) (*VirtualCe
 		Thumbprint:                       vcThumbprint,
 		Username:                         cfg.VirtualCenter[host].User,
 		Password:                         cfg.VirtualCenter[host].Password,
+		SolutionUserCertFile:             cfg.VirtualCenter[host].SolutionUserCertFile,
+		SolutionUserKeyFile:              cfg.VirtualCenter[host].SolutionUserKeyFile,
 		Insecure:                         cfg.VirtualCenter[host].InsecureFlag,
 		TargetvSANFileShareDatastoreURLs: targetDatastoreUrlsForFile,
 		TargetvSANFileShareClusters:      targetvSANClustersForFile,
 		VCClientTimeout:                  vcClientTimeout,
 		QueryLimit:                       cfg.Global.QueryLimit,
 		ListVolumeThreshold:              cfg.Global.ListVolumeThreshold,
+		APIQPS:                           cfg.VirtualCenter[host].APIQPS,
+		APIBurst:                         cfg.VirtualCenter[host].APIBurst,
 	}
 
 	log.Debugf("Setting the queryLimit = %v, ListVolumeThreshold = %v", vcConfig.QueryLimit, vcConfig.ListVolumeThreshold)
@@ -281,12 +285,16 @@ func GetVirtualCenterConfigs(ctx context.Context, cfg *config.Config) ([]*Virtua
 			Thumbprint:                       vcThumbprint,
 			Username:                         cfg.VirtualCenter[vCenterIP].User,
 			Password:                         cfg.VirtualCenter[vCenterIP].Password,
+			SolutionUserCertFile:             cfg.VirtualCenter[vCenterIP].SolutionUserCertFile,
+			SolutionUserKeyFile:              cfg.VirtualCenter[vCenterIP].SolutionUserKeyFile,
 			Insecure:                         cfg.VirtualCenter[vCenterIP].InsecureFlag,
 			TargetvSANFileShareDatastoreURLs: targetDatastoreUrlsForFile,
 			TargetvSANFileShareClusters:      targetvSANClustersForFile,
 			VCClientTimeout:                  vcClientTimeout,
 			QueryLimit:                       cfg.Global.QueryLimit,
 			ListVolumeThreshold:              cfg.Global.ListVolumeThreshold,
+			APIQPS:                           cfg.VirtualCenter[vCenterIP].APIQPS,
+			APIBurst:                         cfg.VirtualCenter[vCenterIP].APIBurst,
 		}
 
 		log.Debugf("Setting the queryLimit = %v, ListVolumeThreshold = %v", vcConfig.QueryLimit, vcConfig.ListVolumeThreshold)
@@ -353,26 +361,28 @@ func CompareKubernetesMetadata(ctx context.Context, k8sMetaData *cnstypes.CnsKub
 	return labelsMatch
 }
 
-// Signer decodes the certificate and private key and returns SAML token needed
-// for authentication.
-func signer(ctx context.Context, client *vim25.Client, username string, password string) (*sts.Signer, error) {
-	pemBlock, _ := pem.Decode([]byte(username))
-	if pemBlock == nil {
-		return nil, nil
-	}
-	certificate, err := tls.X509KeyPair([]byte(username), []byte(password))
-	if err != nil {
-		return nil, fmt.Errorf("failed to load X509 key pair. Error: %+v", err)
-	}
-	tokens, err := sts.NewClient(ctx, client)
+// signer returns the SAML token needed for authentication, issued for the
+// certificate of the solution user of the virtual center, or for the
+// certificate and private key given as username and password. It returns nil
+// if the virtual center authenticates with a username and password.
+func (vc *VirtualCenter) signer(ctx context.Context, client *vim25.Client, username string,
+	password string) (*sts.Signer, error) {
+	certificate, err := vc.getSolutionUserCertificate()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create STS client. err: %+v", err)
+		return nil, fmt.Errorf("failed to load the solution user certificate. Error: %+v", err)
 	}
-	req := sts.TokenRequest{
-		Certificate: &certificate,
-		Delegatable: true,
+	if certificate == nil {
+		pemBlock, _ := pem.Decode([]byte(username))
+		if pemBlock == nil {
+			return nil, nil
+		}
+		keyPair, err := tls.X509KeyPair([]byte(username), []byte(password))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load X509 key pair. Error: %+v", err)
+		}
+		certificate = &keyPair
 	}
-	signer, err := tokens.Issue(ctx, req)
+	signer, err := issueSAMLToken(ctx, client, certificate, true)
 	if err != nil {
 		return nil, fmt.Errorf("failed to issue SAML token. err: %+v", err)
 	}
@@ -386,12 +396,13 @@ func GetTagManager(ctx context.Context, vc *VirtualCenter) (*tags.Manager, error
 		return nil, fmt.Errorf("vCenter not initialized")
 	}
 	restClient := rest.NewClient(vc.Client.Client)
-	signer, err := signer(ctx, vc.Client.Client, vc.Config.Username, vc.Config.Password)
+	creds := vc.getLoginCredentials()[0]
+	signer, err := vc.signer(ctx, vc.Client.Client, creds.username, creds.password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create the Signer. Error: %v", err)
 	}
 	if signer == nil {
-		user := url.UserPassword(vc.Config.Username, vc.Config.Password)
+		user := url.UserPassword(creds.username, creds.password)
 		err = restClient.Login(ctx, user)
 	} else {
 		err = restClient.LoginByToken(restClient.WithSigner(ctx, signer))
@@ -2,7 +2,15 @@ package vsphere
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/vmware/govmomi/object"
@@ -58,3 +66,152 @@ func TestFilterSuspendedDatastoresWhenDatastoreIsNotSuspended(t *testing.T) {
 	outputDsInfo := FilterSuspendedDatastores(context.TODO(), dsInfo)
 	assert.Equal(t, 1, len(outputDsInfo))
 }
+
+func TestGetSoapMethodName(t *testing.T) {
+	tests := map[string]string{
+		`<?xml version="1.0" encoding="UTF-8"?><Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/">` +
+			`<Body><RetrieveProperties xmlns="urn:vim25"><_this type="PropertyCollector">pc</_this>` +
+			`</RetrieveProperties></Body></Envelope>`: "RetrieveProperties",
+		`<Envelope><Body><CnsQueryAllVolume/></Body></Envelope>`: "CnsQueryAllVolume",
+		`<Envelope><Body></Body></Envelope>`:                     "",
+		`not a soap request`:                                     "",
+		`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:urn="urn:vim25">` +
+			`<soapenv:Body><urn:RetrieveServiceContent><urn:_this type="ServiceInstance">ServiceInstance` +
+			`</urn:_this></urn:RetrieveServiceContent></soapenv:Body></soapenv:Envelope>`: "RetrieveServiceContent",
+		// Signed envelope with a SAML token in the WS-Security header.
+		`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Header>` +
+			`<wsse:Security xmlns:wsse="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd">` +
+			`<saml2:Assertion xmlns:saml2="urn:oasis:names:tc:SAML:2.0:assertion"><saml2:Subject>user</saml2:Subject>` +
+			`</saml2:Assertion><ds:Signature xmlns:ds="http://www.w3.org/2000/09/xmldsig#"><ds:SignedInfo>` +
+			`<ds:Reference URI="#Body"/></ds:SignedInfo><ds:SignatureValue>c2lnbmF0dXJl</ds:SignatureValue>` +
+			`</ds:Signature></wsse:Security></soap:Header><soap:Body ` +
+			`xmlns:wsu="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd" ` +
+			`wsu:Id="Body"><CnsCreateVolume xmlns="urn:vsan"><_this type="CnsVolumeManager">cns-volume-manager` +
+			`</_this></CnsCreateVolume></soap:Body></soap:Envelope>`: "CnsCreateVolume",
+	}
+	for body, expected := range tests {
+		if method := getSoapMethodName([]byte(body)); method != expected {
+			t.Errorf("expected method %q for body %s, got %q", expected, body, method)
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	cb := newCircuitBreaker("vc-circuit-breaker-test")
+	for i := 0; i < circuitBreakerFailureThreshold; i++ {
+		if !cb.allow(now) {
+			t.Fatalf("expected closed circuit breaker to allow request %d", i)
+		}
+		cb.record(true, now)
+	}
+	if cb.state != circuitBreakerOpen || cb.allow(now) {
+		t.Fatalf("expected circuit breaker to be open and reject requests, state is %q", cb.state)
+	}
+	now = now.Add(circuitBreakerOpenDuration)
+	if !cb.allow(now) || cb.state != circuitBreakerHalfOpen {
+		t.Fatalf("expected half-open circuit breaker to allow a trial request, state is %q", cb.state)
+	}
+	if cb.allow(now) {
+		t.Fatal("expected half-open circuit breaker to reject requests while the trial is in flight")
+	}
+	if state := cb.record(true, now); state != circuitBreakerOpen {
+		t.Fatalf("expected failed trial to open the circuit breaker, got %q", state)
+	}
+	now = now.Add(circuitBreakerOpenDuration)
+	cb.allow(now)
+	if state := cb.record(false, now); state != circuitBreakerClosed {
+		t.Fatalf("expected successful trial to close the circuit breaker, got %q", state)
+	}
+}
+
+func TestGetLoginCredentials(t *testing.T) {
+	vc := &VirtualCenter{Config: &VirtualCenterConfig{Host: "vc-credentials-test", Username: "config-user",
+		Password: "config-password"}}
+	if creds := vc.getLoginCredentials(); len(creds) != 1 || creds[0].username != "config-user" {
+		t.Fatalf("expected the configured credentials, got %+v", creds)
+	}
+	if !storeCredentials(vc.Config.Host, credentials{username: "user", password: "password-1"}) {
+		t.Fatal("expected the first credentials to be stored")
+	}
+	if storeCredentials(vc.Config.Host, credentials{username: "user", password: "password-1"}) {
+		t.Fatal("expected the unchanged credentials not to be stored")
+	}
+	if creds := vc.getLoginCredentials(); len(creds) != 1 || creds[0].password != "password-1" {
+		t.Fatalf("expected the credentials from the Secret, got %+v", creds)
+	}
+	storeCredentials(vc.Config.Host, credentials{username: "user", password: "password-2"})
+	creds := vc.getLoginCredentials()
+	if len(creds) != 2 || creds[0].password != "password-2" || creds[1].password != "password-1" {
+		t.Fatalf("expected the new credentials followed by the previous ones, got %+v", creds)
+	}
+}
+
+func TestGetSAMLTokenRenewalDelay(t *testing.T) {
+	now := time.Now()
+	if delay := getSAMLTokenRenewalDelay(now.Add(samlTokenLifetime), now); delay !=
+		samlTokenLifetime-samlTokenRenewBefore {
+		t.Fatalf("expected renewal %v before the expiry, got a delay of %v", samlTokenRenewBefore, delay)
+	}
+	if delay := getSAMLTokenRenewalDelay(now.Add(samlTokenRenewBefore/2), now); delay != 0 {
+		t.Fatalf("expected immediate renewal of a token about to expire, got a delay of %v", delay)
+	}
+}
+
+func TestGetUsername(t *testing.T) {
+	host := "vc-username-test"
+	if username := GetUsername(host, "config-user"); username != "config-user" {
+		t.Fatalf("expected the configured username, got %q", username)
+	}
+	storeCredentials(host, credentials{username: "secret-user", password: "password"})
+	if username := GetUsername(host, ""); username != "secret-user" {
+		t.Fatalf("expected the username from the Secret, got %q", username)
+	}
+	UnsetCredentials(host)
+	if username := GetUsername(host, "config-user"); username != "config-user" {
+		t.Fatalf("expected the configured username once the Secret is no longer referenced, got %q", username)
+	}
+}
+
+func TestGetSAMLTokenSubject(t *testing.T) {
+	token := `<saml2:Assertion xmlns:saml2="urn:oasis:names:tc:SAML:2.0:assertion">` +
+		`<saml2:Issuer>https://vc/websso/SAML2/Metadata/vsphere.local</saml2:Issuer>` +
+		`<saml2:Subject><saml2:NameID Format="http://schemas.xmlsoap.org/claims/UPN">` +
+		`csi-solution-user@vsphere.local</saml2:NameID></saml2:Subject></saml2:Assertion>`
+	subject, err := getSAMLTokenSubject(token)
+	if err != nil || subject != "csi-solution-user@vsphere.local" {
+		t.Fatalf("expected the subject of the SAML token, got %q, err %v", subject, err)
+	}
+	if _, err = getSAMLTokenSubject(`<saml2:Assertion xmlns:saml2="urn:oasis:names:tc:SAML:2.0:assertion"/>`); err == nil {
+		t.Fatal("expected an error for a SAML token without subject")
+	}
+}
+
+func TestGetCertificateSubject(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "csi-solution-user"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := getCertificateSubject(&tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key})
+	if err != nil || subject != "csi-solution-user" {
+		t.Fatalf("expected the subject of the certificate, got %q, err %v", subject, err)
+	}
+}
+
+func TestGetUsernameOfTokenSession(t *testing.T) {
+	host := "vc-token-username-test"
+	storeTokenUsername(host, "csi-solution-user@vsphere.local")
+	if username := GetUsername(host, ""); username != "csi-solution-user@vsphere.local" {
+		t.Fatalf("expected the subject of the SAML token, got %q", username)
+	}
+}
@@ -41,7 +41,6 @@ import (
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/pbm"
 	"github.com/vmware/govmomi/session"
-	"github.com/vmware/govmomi/sts"
 	"github.com/vmware/govmomi/vim25"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/soap"
@@ -69,6 +68,12 @@ type VirtualCenter struct {
 	VsanClient *vsan.Client
 	// VslmClient represents the Vslm client instance.
 	VslmClient *vslm.Client
+	// samlTokenExpiry is the expiry of the SAML token of the session, when
+	// logged in by token.
+	samlTokenExpiry time.Time
+	// renewingSAMLToken is true while the session is renewed before its SAML
+	// token expires.
+	renewingSAMLToken bool
 }
 
 var (
@@ -131,6 +136,18 @@ type VirtualCenterConfig struct {
 	// ListVolumeThreshold specifies the maximum number of differences in volume that
 	// can exist between CNS and kubernetes
 	ListVolumeThreshold int
+	// APIQPS is the maximum number of API requests per second to the virtual
+	// center, 0 if they are not rate limited.
+	APIQPS float32
+	// APIBurst is the maximum burst of API requests to the virtual center.
+	APIBurst int
+	// SolutionUserCertFile is the path to the certificate of the solution
+	// user logging in to the virtual center with a SAML token, instead of
+	// Username and Password.
+	SolutionUserCertFile string
+	// SolutionUserKeyFile is the path to the private key of the solution
+	// user.
+	SolutionUserKeyFile string
 }
 
 // clientMutex is used for exclusive connection creation.
@@ -160,6 +177,8 @@ func (vc *VirtualCenter) newClient(ctx context.Context) (*govmomi.Client, error)
 		log.Debugf("using thumbprint %s for url %s ", vc.Config.Thumbprint, url.Host)
 	}
 
+	setVCenterRateLimit(ctx, url.Hostname(), vc.Config.APIQPS, vc.Config.APIBurst)
+	instrumentSoapClient(soapClient, APIClientVim)
 	soapClient.Timeout = time.Duration(vc.Config.VCClientTimeout) * time.Minute
 	log.Debugf("Setting vCenter soap client timeout to %v", soapClient.Timeout)
 	vimClient, err := vim25.NewClient(ctx, soapClient)
@@ -205,42 +224,53 @@ func (vc *VirtualCenter) newClient(ctx context.Context) (*govmomi.Client, error)
 	return client, nil
 }
 
-// login calls SessionManager.LoginByToken if certificate and private key are
-// configured. Otherwise, calls SessionManager.Login with user and password.
+// login logs in as the solution user of the virtual center if one is
+// configured. Otherwise, logs in with the credentials of the virtual center,
+// trying the previous credentials if the current ones are rejected while they
+// are rotated.
 func (vc *VirtualCenter) login(ctx context.Context, client *govmomi.Client) error {
 	log := logger.GetLogger(ctx)
-	var err error
-
-	b, _ := pem.Decode([]byte(vc.Config.Username))
-	if b == nil {
-		return client.SessionManager.Login(ctx,
-			neturl.UserPassword(vc.Config.Username, vc.Config.Password))
-	}
-
-	cert, err := tls.X509KeyPair([]byte(vc.Config.Username), []byte(vc.Config.Password))
+	cert, err := vc.getSolutionUserCertificate()
 	if err != nil {
-		log.Errorf("failed to load X509 key pair with err: %v", err)
+		log.Errorf("failed to load the solution user certificate of vCenter %q with err: %v", vc.Config.Host, err)
 		return err
 	}
-
-	tokens, err := sts.NewClient(ctx, client.Client)
-	if err != nil {
-		log.Errorf("failed to create STS client with err: %v", err)
-		return err
+	if cert != nil {
+		return vc.loginByToken(ctx, client, cert)
+	}
+	creds := vc.getLoginCredentials()
+	for i, c := range creds {
+		if err = vc.loginWithCredentials(ctx, client, c.username, c.password); err == nil {
+			return nil
+		}
+		if i < len(creds)-1 {
+			log.Warnf("failed to log in to vCenter %q, trying the previous credentials. Err: %v",
+				vc.Config.Host, err)
+		}
 	}
+	return err
+}
+
+// loginWithCredentials calls SessionManager.LoginByToken if the given username
+// and password are a certificate and a private key. Otherwise, calls
+// SessionManager.Login with them.
+func (vc *VirtualCenter) loginWithCredentials(ctx context.Context, client *govmomi.Client,
+	username string, password string) error {
+	log := logger.GetLogger(ctx)
+	var err error
 
-	req := sts.TokenRequest{
-		Certificate: &cert,
+	b, _ := pem.Decode([]byte(username))
+	if b == nil {
+		return client.SessionManager.Login(ctx,
+			neturl.UserPassword(username, password))
 	}
 
-	signer, err := tokens.Issue(ctx, req)
+	cert, err := tls.X509KeyPair([]byte(username), []byte(password))
 	if err != nil {
-		log.Errorf("failed to issue SAML token with err: %v", err)
+		log.Errorf("failed to load X509 key pair with err: %v", err)
 		return err
 	}
-
-	header := soap.Header{Security: signer}
-	return client.SessionManager.LoginByToken(client.Client.WithHeader(ctx, header))
+	return vc.loginByToken(ctx, client, &cert)
 }
 
 // Connect establishes a new connection with vSphere with updated credentials.
@@ -273,7 +303,9 @@ func (vc *VirtualCenter) connect(ctx context.Context, requestNewSession bool) er
 	// If client was never initialized, initialize one.
 	var err error
 	if vc.Client == nil {
-		if vc.Client, err = vc.newClient(ctx); err != nil {
+		vc.Client, err = vc.newClient(ctx)
+		observeVCenterSession(vc.Config.Host, "new", err)
+		if err != nil {
 			log.Errorf("failed to create govmomi client with err: %v", err)
 			if !vc.Config.Insecure {
 				log.Errorf("failed to connect to vCenter using CA file: %q", vc.Config.CAFile)
@@ -297,7 +329,9 @@ func (vc *VirtualCenter) connect(ctx context.Context, requestNewSession bool) er
 	}
 	// If session has expired, create a new instance.
 	log.Warnf("Creating a new client session as the existing one isn't valid or not authenticated")
-	if vc.Client, err = vc.newClient(ctx); err != nil {
+	vc.Client, err = vc.newClient(ctx)
+	observeVCenterSession(vc.Config.Host, "reconnect", err)
+	if err != nil {
 		log.Errorf("failed to create govmomi client with err: %v", err)
 		if !vc.Config.Insecure {
 			log.Errorf("failed to connect to vCenter using CA file: %q", vc.Config.CAFile)
@@ -310,6 +344,7 @@ func (vc *VirtualCenter) connect(ctx context.Context, requestNewSession bool) er
 			log.Errorf("failed to create pbm client with err: %v", err)
 			return err
 		}
+		instrumentSoapClient(vc.PbmClient.Client, APIClientPbm)
 	}
 	// Recreate CNSClient if created using timed out VC Client.
 	if vc.CnsClient != nil {
@@ -333,6 +368,7 @@ func (vc *VirtualCenter) connect(ctx context.Context, requestNewSession bool) er
 			log.Errorf("failed to create vsan client with err: %v", err)
 			return err
 		}
+		instrumentSoapClient(vc.VsanClient.Client, APIClientVsan)
 	}
 	return nil
 }
@@ -36,6 +36,7 @@ func (vc *VirtualCenter) ConnectVsan(ctx context.Context) error {
 			log.Errorf("failed to create vsan client with err: %v", err)
 			return err
 		}
+		instrumentSoapClient(vc.VsanClient.Client, APIClientVsan)
 	}
 	return nil
 }
@@ -32,6 +32,7 @@ func NewVslmClient(ctx context.Context, c *vim25.Client) (*vslm.Client, error) {
 		log.Errorf("failed to create a new client for Vslm. err: %v", err)
 		return nil, err
 	}
+	instrumentSoapClient(vslmClient.Client, APIClientVslm)
 	return vslmClient, nil
 }
 
@@ -21,11 +21,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"strconv"
 	"strings"
 
 	"gopkg.in/gcfg.v1"
+	"gopkg.in/warnings.v0"
 	corev1 "k8s.io/api/core/v1"
 
 	cnstypes "github.com/vmware/govmomi/cns/types"
@@ -188,6 +190,18 @@ func FromEnv(ctx context.Context, cfg *Config) error {
 	if cfg == nil {
 		return fmt.Errorf("config object cannot be nil")
 	}
+	setFromEnv(ctx, cfg)
+	err := validateConfig(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// setFromEnv sets the values obtained from environment variables in the
+// provided configuration object.
+func setFromEnv(ctx context.Context, cfg *Config) {
 	log := logger.GetLogger(ctx)
 	// Init.
 	if cfg.VirtualCenter == nil {
@@ -310,38 +324,44 @@ func FromEnv(ctx context.Context, cfg *Config) error {
 			Datacenters:  cfg.Global.Datacenters,
 		}
 	}
-	err := validateConfig(ctx, cfg)
-	if err != nil {
-		return err
-	}
+}
 
+// validateConfig validates the given config and sets the default values for
+// the fields which are not specified. It returns the first error found.
+func validateConfig(ctx context.Context, cfg *Config) error {
+	if errs := getConfigErrors(ctx, cfg); len(errs) > 0 {
+		return errs[0]
+	}
 	return nil
 }
 
-func validateConfig(ctx context.Context, cfg *Config) error {
+// getConfigErrors validates the given config, sets the default values for the
+// fields which are not specified, and returns all the errors found.
+func getConfigErrors(ctx context.Context, cfg *Config) []error {
 	log := logger.GetLogger(ctx)
+	var errs []error
+	addError := func(err error) {
+		log.Error(err)
+		errs = append(errs, err)
+	}
 	// Fix default global values.
 	if cfg.Global.VCenterPort == "" {
 		cfg.Global.VCenterPort = DefaultVCenterPort
 	}
 	// Must have at least one vCenter defined.
 	if len(cfg.VirtualCenter) == 0 {
-		log.Error(ErrMissingVCenter)
-		return ErrMissingVCenter
+		addError(ErrMissingVCenter)
 	}
 	// Cluster ID should not exceed 64 characters.
 	if len(cfg.Global.ClusterID) > 64 {
-		log.Error(ErrClusterIDCharLimit)
-		return ErrClusterIDCharLimit
+		addError(ErrClusterIDCharLimit)
 	}
 	// SupervisorID should not exceed 64 characters.
 	if len(cfg.Global.SupervisorID) > 64 {
-		log.Error(ErrSupervisorIDCharLimit)
-		return ErrSupervisorIDCharLimit
+		addError(ErrSupervisorIDCharLimit)
 	}
 	if len(cfg.VirtualCenter) > 1 && strings.TrimSpace(cfg.Labels.TopologyCategories) == "" {
-		log.Error(ErrMissingTopologyCategoriesForMultiVCenterSetup)
-		return ErrMissingTopologyCategoriesForMultiVCenterSetup
+		addError(ErrMissingTopologyCategoriesForMultiVCenterSetup)
 	}
 	var setCfgGlobalvCenter bool
 	if len(cfg.VirtualCenter) == 1 {
@@ -350,22 +370,27 @@ func validateConfig(ctx context.Context, cfg *Config) error {
 	for vcServer, vcConfig := range cfg.VirtualCenter {
 		log.Debugf("Initializing vc server %s", vcServer)
 		if vcServer == "" {
-			log.Error(ErrInvalidVCenterIP)
-			return ErrInvalidVCenterIP
+			addError(ErrInvalidVCenterIP)
+			continue
 		}
 
+		if (vcConfig.SolutionUserCertFile == "") != (vcConfig.SolutionUserKeyFile == "") {
+			addError(fmt.Errorf("solution-user-cert-file and solution-user-key-file must be "+
+				"set together for vc %s", vcServer))
+		}
+		// The credentials are read from the Secret if one is referenced, and
+		// not needed when logging in as a solution user.
+		hasCredentialsSource := vcConfig.SecretName != "" || vcConfig.SolutionUserCertFile != ""
 		if vcConfig.User == "" {
 			vcConfig.User = cfg.Global.User
-			if vcConfig.User == "" {
-				log.Errorf("vcConfig.User is empty for vc %s!", vcServer)
-				return ErrUsernameMissing
+			if vcConfig.User == "" && !hasCredentialsSource {
+				addError(fmt.Errorf("%w for vc %s", ErrUsernameMissing, vcServer))
 			}
 		}
 		if vcConfig.Password == "" {
 			vcConfig.Password = cfg.Global.Password
-			if vcConfig.Password == "" {
-				log.Errorf("vcConfig.Password is empty for vc %s!", vcServer)
-				return ErrPasswordMissing
+			if vcConfig.Password == "" && !hasCredentialsSource {
+				addError(fmt.Errorf("%w for vc %s", ErrPasswordMissing, vcServer))
 			}
 		}
 		if vcConfig.VCenterPort == "" {
@@ -380,6 +405,13 @@ func validateConfig(ctx context.Context, cfg *Config) error {
 		if !insecure {
 			vcConfig.InsecureFlag = cfg.Global.InsecureFlag
 		}
+		if vcConfig.APIQPS < 0 || vcConfig.APIBurst < 0 {
+			addError(fmt.Errorf("invalid api-qps %v or api-burst %d for vc %s, must not be negative",
+				vcConfig.APIQPS, vcConfig.APIBurst, vcServer))
+		}
+		if vcConfig.APIQPS > 0 && vcConfig.APIBurst == 0 {
+			vcConfig.APIBurst = int(math.Ceil(float64(vcConfig.APIQPS)))
+		}
 		if setCfgGlobalvCenter && cfg.Global.VCenterIP == "" {
 			cfg.Global.VCenterIP = vcServer
 		}
@@ -389,7 +421,7 @@ func validateConfig(ctx context.Context, cfg *Config) error {
 
 	clusterFlavor, err := GetClusterFlavor(ctx)
 	if err != nil {
-		return err
+		errs = append(errs, err)
 	}
 	if cfg.NetPermissions == nil {
 		// If no net permissions are given, assume default.
@@ -398,19 +430,7 @@ func validateConfig(ctx context.Context, cfg *Config) error {
 			cfg.NetPermissions = map[string]*NetPermissionConfig{"#": GetDefaultNetPermission()}
 		}
 	} else {
-		for key, netPerm := range cfg.NetPermissions {
-			if netPerm.Permissions == "" {
-				netPerm.Permissions = vsanfstypes.VsanFileShareAccessTypeREAD_WRITE
-			} else if netPerm.Permissions != vsanfstypes.VsanFileShareAccessTypeNO_ACCESS &&
-				netPerm.Permissions != vsanfstypes.VsanFileShareAccessTypeREAD_ONLY &&
-				netPerm.Permissions != vsanfstypes.VsanFileShareAccessTypeREAD_WRITE {
-				log.Errorf("Invalid value %s for Permissions under NetPermission Config %s", netPerm.Permissions, key)
-				return ErrInvalidNetPermission
-			}
-			if netPerm.Ips == "" {
-				netPerm.Ips = "*"
-			}
-		}
+		errs = append(errs, getNetPermissionsErrors(ctx, cfg.NetPermissions)...)
 	}
 
 	if cfg.Global.CnsRegisterVolumesCleanupIntervalInMin == 0 {
@@ -438,15 +458,14 @@ func validateConfig(ctx context.Context, cfg *Config) error {
 	// parameter. Specifying all the 3 parameters is not allowed.
 	if strings.TrimSpace(cfg.Labels.TopologyCategories) != "" &&
 		(strings.TrimSpace(cfg.Labels.Zone) != "" || strings.TrimSpace(cfg.Labels.Region) != "") {
-		return logger.LogNewErrorf(log,
-			"zone and region parameters should be skipped when topologyCategories is specified.")
+		addError(errors.New("zone and region parameters should be skipped when topologyCategories is specified"))
 	}
 
 	// Validate length of topologyCategories in Labels section
 	if strings.TrimSpace(cfg.Labels.TopologyCategories) != "" {
 		if len(strings.Split(cfg.Labels.TopologyCategories, ",")) > MaxNumberOfTopologyCategories {
-			return logger.LogNewErrorf(log, "maximum limit of topology categories exceeded. Only %d allowed.",
-				MaxNumberOfTopologyCategories)
+			addError(fmt.Errorf("maximum limit of topology categories exceeded. Only %d allowed",
+				MaxNumberOfTopologyCategories))
 		}
 	}
 
@@ -456,8 +475,8 @@ func validateConfig(ctx context.Context, cfg *Config) error {
 	for key, categoryInfo := range cfg.TopologyCategory {
 		topoDomain := strings.Split(categoryInfo.Label, "/")[0]
 		if topoDomain != betaDomain && topoDomain != gaDomain && topoDomain != TopologyLabelsDomain {
-			return logger.LogNewErrorf(log, "unrecognised topology label %q used for topology category %q",
-				categoryInfo.Label, key)
+			addError(fmt.Errorf("unrecognised topology label %q used for topology category %q",
+				categoryInfo.Label, key))
 		}
 	}
 
@@ -470,18 +489,73 @@ func validateConfig(ctx context.Context, cfg *Config) error {
 		cfg.Global.ListVolumeThreshold = DefaultListVolumeThreshold
 		log.Debugf("Setting default list volume threshold to %v", cfg.Global.ListVolumeThreshold)
 	}
+	return errs
+}
+
+// validateNetPermissions validates the given net permissions and sets the
+// default values for the fields which are not specified. It returns the first
+// error found.
+func validateNetPermissions(ctx context.Context, netPermissions map[string]*NetPermissionConfig) error {
+	if errs := getNetPermissionsErrors(ctx, netPermissions); len(errs) > 0 {
+		return errs[0]
+	}
 	return nil
 }
 
+// getNetPermissionsErrors validates the given net permissions, sets the
+// default values for the fields which are not specified, and returns all the
+// errors found.
+func getNetPermissionsErrors(ctx context.Context, netPermissions map[string]*NetPermissionConfig) []error {
+	log := logger.GetLogger(ctx)
+	var errs []error
+	for key, netPerm := range netPermissions {
+		if netPerm.Permissions == "" {
+			netPerm.Permissions = vsanfstypes.VsanFileShareAccessTypeREAD_WRITE
+		} else if netPerm.Permissions != vsanfstypes.VsanFileShareAccessTypeNO_ACCESS &&
+			netPerm.Permissions != vsanfstypes.VsanFileShareAccessTypeREAD_ONLY &&
+			netPerm.Permissions != vsanfstypes.VsanFileShareAccessTypeREAD_WRITE {
+			log.Errorf("Invalid value %s for Permissions under NetPermission Config %s", netPerm.Permissions, key)
+			errs = append(errs, fmt.Errorf("%w %q: %s", ErrInvalidNetPermission, key, netPerm.Permissions))
+		}
+		if netPerm.Ips == "" {
+			netPerm.Ips = "*"
+		}
+	}
+	return errs
+}
+
+// ParseNetPermissions parses the NetPermissions sections in the given data,
+// which uses the same format as the vSphere CSI driver config file, e.g.
+//
+//	[NetPermissions "A"]
+//	ips = "10.20.20.0/24"
+//	permissions = "READ_ONLY"
+//	rootsquash = true
+func ParseNetPermissions(ctx context.Context, data string) (map[string]*NetPermissionConfig, error) {
+	log := logger.GetLogger(ctx)
+	cfg := &struct {
+		NetPermissions map[string]*NetPermissionConfig
+	}{}
+	if err := gcfg.FatalOnly(gcfg.ReadStringInto(cfg, data)); err != nil {
+		log.Errorf("error while parsing net permissions: %+v", err)
+		return nil, err
+	}
+	if err := validateNetPermissions(ctx, cfg.NetPermissions); err != nil {
+		return nil, err
+	}
+	return cfg.NetPermissions, nil
+}
+
 // ReadConfig parses vSphere cloud config file and stores it into VSphereConfig.
-// Environment variables are also checked.
+// The config file can be in INI, JSON or YAML format, which is detected from
+// its content. Environment variables are also checked.
 func ReadConfig(ctx context.Context, config io.Reader) (*Config, error) {
 	log := logger.GetLogger(ctx)
 	if config == nil {
 		return nil, fmt.Errorf("no vSphere CSI driver config file given")
 	}
 	cfg := &Config{}
-	if err := gcfg.FatalOnly(gcfg.ReadInto(cfg, config)); err != nil {
+	if err := gcfg.FatalOnly(readConfigInto(cfg, config)); err != nil {
 		log.Errorf("error while reading config file: %+v", err)
 		return nil, err
 	}
@@ -492,6 +566,35 @@ func ReadConfig(ctx context.Context, config io.Reader) (*Config, error) {
 	return cfg, nil
 }
 
+// ValidateConfigFile reads the config file at the given path, in any of the
+// supported formats, with the environment variables overriding it as when the
+// driver starts, and returns all the errors found in it. The unknown sections
+// and variables, which the driver ignores, are reported as errors too. The
+// config file of a guest cluster is validated if guestCluster is true.
+func ValidateConfigFile(ctx context.Context, cfgPath string, guestCluster bool) []error {
+	file, err := os.Open(cfgPath)
+	if err != nil {
+		return []error{err}
+	}
+	defer file.Close()
+	cfg := &Config{}
+	var errs []error
+	if err := readConfigInto(cfg, file); err != nil {
+		if fatalErr := gcfg.FatalOnly(err); fatalErr != nil {
+			return []error{fatalErr}
+		}
+		errs = append(errs, warnings.WarningsOnly(err)...)
+	}
+	if guestCluster {
+		if err := FromEnvToGC(ctx, cfg); err != nil {
+			errs = append(errs, err)
+		}
+		return errs
+	}
+	setFromEnv(ctx, cfg)
+	return append(errs, getConfigErrors(ctx, cfg)...)
+}
+
 // GetCnsconfig returns Config from specified config file path.
 func GetCnsconfig(ctx context.Context, cfgPath string) (*Config, error) {
 	log := logger.GetLogger(ctx)
@@ -569,7 +672,7 @@ func ReadGCConfig(ctx context.Context, config io.Reader) (*Config, error) {
 		return nil, fmt.Errorf("guest cluster config file is not present")
 	}
 	cfg := &Config{}
-	if err := gcfg.FatalOnly(gcfg.ReadInto(cfg, config)); err != nil {
+	if err := gcfg.FatalOnly(readConfigInto(cfg, config)); err != nil {
 		return nil, err
 	}
 	// Env Vars should override config file entries if present.
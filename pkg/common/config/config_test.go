@@ -19,7 +19,9 @@ package config
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -159,6 +161,24 @@ func TestValidateConfigWithInvalidClusterId(t *testing.T) {
 	}
 }
 
+func TestValidateConfigWithSolutionUser(t *testing.T) {
+	cfg := &Config{
+		VirtualCenter: map[string]*VirtualCenterConfig{
+			"1.1.1.1": {
+				SolutionUserCertFile: "/etc/vmware/solution-user/tls.crt",
+				SolutionUserKeyFile:  "/etc/vmware/solution-user/tls.key",
+			},
+		},
+	}
+	if err := validateConfig(ctx, cfg); err != nil {
+		t.Errorf("failed to validate config %+v without user and password. Received error: %v", *cfg, err)
+	}
+	cfg.VirtualCenter["1.1.1.1"].SolutionUserKeyFile = ""
+	if err := validateConfig(ctx, cfg); err == nil {
+		t.Errorf("Expected error due to missing solution-user-key-file. Config given - %+v", *cfg)
+	}
+}
+
 func TestSnapshotConfigWhenMaxUnspecified(t *testing.T) {
 	cfg := &Config{
 		VirtualCenter: idealVCConfig,
@@ -189,6 +209,109 @@ func TestSnapshotConfigWhenMaxSpecifiedAsEnv(t *testing.T) {
 	}
 }
 
+func TestReadConfigInAllFormats(t *testing.T) {
+	ini := `
+[Global]
+cluster-id = "cluster-1"
+
+[VirtualCenter "1.1.1.1"]
+user = "Admin"
+password = "Pass\"word"
+datacenters = "dc1"
+api-qps = 2.5
+
+[NetPermissions "A"]
+ips = "10.20.20.0/24"
+permissions = "READ_ONLY"
+`
+	json := `{
+  "$schema": "csi-vsphere-config.schema.json",
+  "Global": {"cluster-id": "cluster-1"},
+  "VirtualCenter": {"1.1.1.1": {"user": "Admin", "password": "Pass\"word", "datacenters": "dc1", "api-qps": 2.5}},
+  "NetPermissions": {"A": {"ips": "10.20.20.0/24", "permissions": "READ_ONLY"}}
+}`
+	yaml := `
+# vSphere CSI driver config
+Global:
+  cluster-id: cluster-1
+VirtualCenter:
+  1.1.1.1:
+    user: Admin
+    password: Pass"word
+    datacenters: dc1
+    api-qps: 2.5
+NetPermissions:
+  A:
+    ips: 10.20.20.0/24
+    permissions: READ_ONLY
+`
+	os.Setenv("CLUSTER_FLAVOR", "VANILLA")
+	expected, err := ReadConfig(ctx, strings.NewReader(ini))
+	if err != nil {
+		t.Fatalf("failed to read INI config. Err: %v", err)
+	}
+	for format, data := range map[string]string{ConfigFormatJSON: json, ConfigFormatYAML: yaml} {
+		if detected := detectConfigFormat([]byte(data)); detected != format {
+			t.Errorf("expected format %s, detected %s", format, detected)
+		}
+		cfg, err := ReadConfig(ctx, strings.NewReader(data))
+		if err != nil {
+			t.Fatalf("failed to read %s config. Err: %v", format, err)
+		}
+		if !isConfigEqual(cfg, expected) || cfg.Global != expected.Global {
+			t.Errorf("%s config %+v differs from INI config %+v", format, cfg, expected)
+		}
+	}
+}
+
+func TestValidateConfigFileReportsAllErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "csi-vsphere.conf")
+	data := `
+Global:
+  cluster-id: test-cluster-with-a-long-name-with-more-than-sixty-four-characters
+  unknown-variable: value
+VirtualCenter:
+  1.1.1.1:
+    user: Admin
+  2.2.2.2:
+    user: Admin
+    password: Password
+NetPermissions:
+  A:
+    permissions: WRITE_ONLY
+`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("failed to write config file. Err: %v", err)
+	}
+	os.Setenv("CLUSTER_FLAVOR", "VANILLA")
+	// Unknown variable, cluster id length, missing topology categories for
+	// multi vCenter, missing password and invalid net permissions.
+	if errs := ValidateConfigFile(ctx, path, false); len(errs) != 5 {
+		t.Errorf("expected 5 errors, got %d: %v", len(errs), errs)
+	}
+}
+
+func TestConfigSchemaIsPublished(t *testing.T) {
+	schema, err := GetConfigSchema()
+	if err != nil {
+		t.Fatalf("failed to generate config schema. Err: %v", err)
+	}
+	path := filepath.Join("..", "..", "..", "docs", "book", "config_schema", ConfigSchemaVersion,
+		"csi-vsphere-config.schema.json")
+	if os.Getenv("UPDATE_CONFIG_SCHEMA") == "true" {
+		if err := os.WriteFile(path, schema, 0644); err != nil {
+			t.Fatalf("failed to write config schema. Err: %v", err)
+		}
+	}
+	published, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read published config schema. Err: %v", err)
+	}
+	if string(published) != string(schema) {
+		t.Errorf("published config schema %s is outdated, run the tests with UPDATE_CONFIG_SCHEMA=true", path)
+	}
+}
+
 func isConfigEqual(actual *Config, expected *Config) bool {
 	// TODO: Compare Global struct
 	// Compare VC Config
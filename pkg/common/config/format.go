@@ -0,0 +1,187 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/gcfg.v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ConfigFormatINI is the gcfg INI format of the vSphere CSI driver config
+	// file.
+	ConfigFormatINI = "ini"
+	// ConfigFormatJSON is the JSON format of the vSphere CSI driver config
+	// file, following the config schema.
+	ConfigFormatJSON = "json"
+	// ConfigFormatYAML is the YAML format of the vSphere CSI driver config
+	// file, following the config schema.
+	ConfigFormatYAML = "yaml"
+
+	// schemaKey is the top-level key of a JSON or YAML config file which may
+	// reference the config schema, and is ignored when reading it.
+	schemaKey = "$schema"
+)
+
+// detectConfigFormat returns the format of the given config file content. An
+// INI file starts with a section header and a JSON file with an object,
+// after the blank and comment lines. Any other content is read as YAML.
+func detectConfigFormat(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		switch line[0] {
+		case '[':
+			return ConfigFormatINI
+		case '{':
+			return ConfigFormatJSON
+		}
+		return ConfigFormatYAML
+	}
+	return ConfigFormatINI
+}
+
+// readConfigInto reads the given config file, in any of the supported
+// formats, into the given config. The errors are returned as by gcfg, with
+// the warnings about unknown sections and variables.
+func readConfigInto(cfg *Config, config io.Reader) error {
+	data, err := io.ReadAll(config)
+	if err != nil {
+		return err
+	}
+	if detectConfigFormat(data) == ConfigFormatINI {
+		return gcfg.ReadStringInto(cfg, string(data))
+	}
+	ini, err := convertToINI(data)
+	if err != nil {
+		return err
+	}
+	return gcfg.ReadStringInto(cfg, ini)
+}
+
+// convertToINI converts the given JSON or YAML config file content to the
+// equivalent INI config. Each top-level key is a section, holding either its
+// variables or, for the sections with subsections like VirtualCenter, the
+// variables of each subsection by subsection name. Lists are converted to
+// multi-valued variables.
+func convertToINI(data []byte) (string, error) {
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse config: %v", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(jsonData))
+	decoder.UseNumber()
+	var sections map[string]interface{}
+	if err := decoder.Decode(&sections); err != nil {
+		return "", fmt.Errorf("config must be an object of sections: %v", err)
+	}
+	var ini strings.Builder
+	for _, section := range sortedKeys(sections) {
+		if section == schemaKey {
+			continue
+		}
+		content, ok := sections[section].(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("section %q must be an object", section)
+		}
+		if !hasSubsections(section) {
+			if err := writeINISection(&ini, section, "", content); err != nil {
+				return "", err
+			}
+			continue
+		}
+		for _, subsection := range sortedKeys(content) {
+			variables, ok := content[subsection].(map[string]interface{})
+			if !ok {
+				return "", fmt.Errorf("subsection %q of section %q must be an object", subsection, section)
+			}
+			if err := writeINISection(&ini, section, subsection, variables); err != nil {
+				return "", err
+			}
+		}
+	}
+	return ini.String(), nil
+}
+
+// hasSubsections returns true if the given section of the config, matched
+// ignoring case as by gcfg, has named subsections.
+func hasSubsections(section string) bool {
+	field, ok := reflect.TypeOf(Config{}).FieldByNameFunc(func(name string) bool {
+		return strings.EqualFold(name, section)
+	})
+	return ok && field.Type.Kind() == reflect.Map
+}
+
+// writeINISection writes the given section or subsection with the given
+// variables in INI format.
+func writeINISection(ini *strings.Builder, section string, subsection string,
+	variables map[string]interface{}) error {
+	location := section
+	if subsection != "" {
+		location = fmt.Sprintf("%s %q", section, subsection)
+		fmt.Fprintf(ini, "[%s %s]\n", section, quoteINIValue(subsection))
+	} else {
+		fmt.Fprintf(ini, "[%s]\n", section)
+	}
+	for _, name := range sortedKeys(variables) {
+		values, ok := variables[name].([]interface{})
+		if !ok {
+			values = []interface{}{variables[name]}
+		}
+		for _, value := range values {
+			switch v := value.(type) {
+			case nil:
+				continue
+			case string:
+				fmt.Fprintf(ini, "%s = %s\n", name, quoteINIValue(v))
+			case bool, json.Number:
+				fmt.Fprintf(ini, "%s = %v\n", name, v)
+			default:
+				return fmt.Errorf("variable %q of section %s must be a string, a number, a boolean or a list "+
+					"of them", name, location)
+			}
+		}
+	}
+	return nil
+}
+
+// quoteINIValue returns the given value as an INI quoted string.
+func quoteINIValue(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// sortedKeys returns the keys of the given map in order, so that the
+// converted config and its errors are stable.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+
+	vsanfstypes "github.com/vmware/govmomi/vsan/vsanfs/types"
+)
+
+// ConfigSchemaVersion is the version of the JSON schema of the JSON and YAML
+// config files. It changes when a config change is not backward compatible.
+const ConfigSchemaVersion = "v1"
+
+// GetConfigSchema returns the JSON schema of the JSON and YAML config files,
+// generated from Config. The schema is published in
+// docs/book/config_schema/<version>/csi-vsphere-config.schema.json.
+func GetConfigSchema() ([]byte, error) {
+	schema := getSectionSchema(reflect.TypeOf(Config{}))
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = "vSphere CSI driver config " + ConfigSchemaVersion
+	schema["properties"].(map[string]interface{})[schemaKey] = map[string]interface{}{
+		"type":        "string",
+		"description": "Reference to the config schema, ignored by the driver.",
+	}
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// getSectionSchema returns the JSON schema of the given config struct, whose
+// properties are named as the gcfg variables or sections of its fields.
+func getSectionSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("gcfg")
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = getFieldSchema(field.Type)
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+// getFieldSchema returns the JSON schema of a config field of the given type.
+func getFieldSchema(t reflect.Type) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == reflect.TypeOf(vsanfstypes.VsanFileShareAccessType("")):
+		return map[string]interface{}{
+			"type": "string",
+			"enum": []string{string(vsanfstypes.VsanFileShareAccessTypeREAD_WRITE),
+				string(vsanfstypes.VsanFileShareAccessTypeREAD_ONLY),
+				string(vsanfstypes.VsanFileShareAccessTypeNO_ACCESS)},
+		}
+	case t.Kind() == reflect.Struct:
+		return getSectionSchema(t)
+	case t.Kind() == reflect.Map:
+		// Sections with named subsections.
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": getFieldSchema(t.Elem()),
+		}
+	case t.Kind() == reflect.Slice:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": getFieldSchema(t.Elem()),
+		}
+	case t.Kind() == reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
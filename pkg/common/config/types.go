@@ -148,6 +148,28 @@ type VirtualCenterConfig struct {
 	TargetvSANFileShareDatastoreURLs string `gcfg:"targetvSANFileShareDatastoreURLs"`
 	// TargetvSANFileShareClusters represents file service enabled vSAN clusters on which file volumes can be created.
 	TargetvSANFileShareClusters string `gcfg:"targetvSANFileShareClusters"`
+	// APIQPS is the maximum number of API requests per second to vCenter,
+	// shared by the CNS, PBM, VSLM, vSAN and vSphere API clients. The requests
+	// are not rate limited if it is not set.
+	APIQPS float32 `gcfg:"api-qps"`
+	// APIBurst is the maximum burst of API requests to vCenter when APIQPS is
+	// set. Defaults to APIQPS rounded up.
+	APIBurst int `gcfg:"api-burst"`
+	// SecretName is the name of a Secret holding the vCenter credentials, in
+	// its "username" and "password" keys. They override the configured user
+	// and password and are rotated when the Secret is updated.
+	SecretName string `gcfg:"secret-name"`
+	// SecretNamespace is the namespace of the Secret holding the vCenter
+	// credentials. Defaults to the namespace of the driver.
+	SecretNamespace string `gcfg:"secret-namespace"`
+	// SolutionUserCertFile is the path to the PEM certificate of a vCenter
+	// solution user. When set with SolutionUserKeyFile, the driver logs in
+	// with SAML tokens issued by the vCenter STS for the solution user instead
+	// of a user and password.
+	SolutionUserCertFile string `gcfg:"solution-user-cert-file"`
+	// SolutionUserKeyFile is the path to the PEM private key of the vCenter
+	// solution user.
+	SolutionUserKeyFile string `gcfg:"solution-user-key-file"`
 }
 
 // GCConfig contains information used by guest cluster to access a supervisor
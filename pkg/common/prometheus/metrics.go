@@ -62,6 +62,9 @@ const (
 	PrometheusCnsDetachVolumeOpType = "detach-volume"
 	// PrometheusCnsUpdateVolumeMetadataOpType represents the UpdateVolumeMetadata operation.
 	PrometheusCnsUpdateVolumeMetadataOpType = "update-volume-metadata"
+	// PrometheusCnsBatchUpdateVolumeMetadataOpType represents the UpdateVolumeMetadata
+	// operation on multiple volumes.
+	PrometheusCnsBatchUpdateVolumeMetadataOpType = "batch-update-volume-metadata"
 	// PrometheusCnsExpandVolumeOpType represents the ExpandVolume operation.
 	PrometheusCnsExpandVolumeOpType = "expand-volume"
 	// PrometheusCnsQueryVolumeOpType represents the QueryVolume operation.
@@ -84,6 +87,14 @@ const (
 	PrometheusAccessibleVolumes = "accessible-volumes"
 	// PrometheusInaccessibleVolumes represents inaccessible volumes.
 	PrometheusInaccessibleVolumes = "inaccessible-volumes"
+	// PrometheusReducedAvailabilityVolumes represents volumes with reduced
+	// availability.
+	PrometheusReducedAvailabilityVolumes = "reduced-availability-volumes"
+	// PrometheusNonCompliantVolumes represents volumes not compliant with
+	// their storage policy.
+	PrometheusNonCompliantVolumes = "non-compliant-volumes"
+	// PrometheusResyncingVolumes represents volumes being resynchronized.
+	PrometheusResyncingVolumes = "resyncing-volumes"
 
 	// PrometheusPassStatus represents a successful API run.
 	PrometheusPassStatus = "pass"
@@ -134,12 +145,13 @@ var (
 		// Possible status - "pass", "fail"
 		[]string{"optype", "status"})
 
-	// VolumeHealthGaugeVec is a gauge metric to observe the number of accessible and inaccessible volumes.
+	// VolumeHealthGaugeVec is a gauge metric to observe the number of volumes in each health state.
 	VolumeHealthGaugeVec = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "vsphere_volume_health_gauge",
-		Help: "Gauge for total number of accessible and inaccessible volumes",
+		Help: "Gauge for total number of volumes in each health state",
 	},
-		// Possible volume_health_type - "accessible-volumes", "inaccessible-volumes"
+		// Possible volume_health_type - "accessible-volumes", "inaccessible-volumes",
+		// "reduced-availability-volumes", "non-compliant-volumes", "resyncing-volumes"
 		[]string{"volume_health_type"})
 
 	// FullSyncOpsHistVec is a histogram vector metric to observe CSI Full Sync.
@@ -153,4 +165,84 @@ var (
 	},
 		// Possible status - "pass", "fail"
 		[]string{"status"})
+	// MetadataUpdateQueueDepth is a gauge metric to observe the number of
+	// volumes with pending metadata updates in the syncer.
+	MetadataUpdateQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "vsphere_syncer_metadata_update_queue_depth",
+		Help: "Number of volumes with pending metadata updates",
+	})
+	// MetadataUpdateQueueLatencyHistVec is a histogram vector metric to observe
+	// the time from when a metadata update is queued by the syncer until it is
+	// submitted to CNS.
+	MetadataUpdateQueueLatencyHistVec = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "vsphere_syncer_metadata_update_queue_latency_histogram",
+		Help:    "Histogram vector for the latency of queued volume metadata updates.",
+		Buckets: []float64{1, 2, 5, 10, 15, 20, 25, 30, 60, 120, 180},
+	},
+		// Possible status - "pass", "fail"
+		[]string{"status"})
+	// VCenterSessionCounterVec is a counter vector metric to observe the
+	// vCenter sessions created by the driver.
+	VCenterSessionCounterVec = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "vsphere_vcenter_session_total",
+		Help: "Number of vCenter sessions created.",
+	},
+		// Possible type - "new", "reconnect"
+		// Possible status - "pass", "fail"
+		[]string{"vcenter", "type", "status"})
+	// VCenterAPIHistVec is a histogram vector metric to observe the latency of
+	// the vCenter API calls made by each API client.
+	VCenterAPIHistVec = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "vsphere_vcenter_api_histogram",
+		Help:    "Histogram vector for vCenter API calls.",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30, 60},
+	},
+		// Possible client - "vim", "cns", "pbm", "vslm", "vsan"
+		// Possible status - "pass", "fail"
+		[]string{"vcenter", "client", "method", "status"})
+	// VCenterAPIInFlightRequestsGaugeVec is a gauge vector metric to observe
+	// the number of in-flight vCenter API calls of each API client.
+	VCenterAPIInFlightRequestsGaugeVec = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vsphere_vcenter_api_inflight_requests",
+		Help: "Number of in-flight vCenter API calls.",
+	},
+		// Possible client - "vim", "cns", "pbm", "vslm", "vsan"
+		[]string{"vcenter", "client"})
+	// VCenterInFlightTasksGaugeVec is a gauge vector metric to observe the
+	// number of vCenter tasks the driver is waiting on.
+	VCenterInFlightTasksGaugeVec = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vsphere_vcenter_inflight_tasks",
+		Help: "Number of vCenter tasks waited on.",
+	}, []string{"vcenter"})
+	// VCenterCircuitBreakerStateGaugeVec is a gauge vector metric to observe
+	// the state of the circuit breaker of each vCenter. The gauge of the
+	// current state is 1 and the gauges of the other states are 0.
+	VCenterCircuitBreakerStateGaugeVec = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vsphere_vcenter_circuit_breaker_state",
+		Help: "State of the vCenter circuit breaker.",
+	},
+		// Possible state - "closed", "open", "half-open"
+		[]string{"vcenter", "state"})
+	// VCenterAPIRejectedRequestsCounterVec is a counter vector metric to
+	// observe the vCenter API calls rejected while the circuit breaker of the
+	// vCenter is open.
+	VCenterAPIRejectedRequestsCounterVec = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "vsphere_vcenter_api_rejected_requests_total",
+		Help: "Number of vCenter API calls rejected by the circuit breaker.",
+	},
+		// Possible client - "vim", "cns", "pbm", "vslm", "vsan"
+		[]string{"vcenter", "client"})
+	// OrphanSnapshotsGauge is a gauge metric to observe the number of CNS
+	// snapshots found by full sync which are not backing any
+	// VolumeSnapshotContent.
+	OrphanSnapshotsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "vsphere_syncer_orphan_snapshots",
+		Help: "Number of CNS snapshots not backing any VolumeSnapshotContent",
+	})
+	// AuditEventsDroppedCounter is a counter metric to observe the audit
+	// events not posted to the audit webhook because its queue stayed full.
+	AuditEventsDroppedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "vsphere_audit_webhook_events_dropped_total",
+		Help: "Number of audit events dropped because the audit webhook queue was full.",
+	})
 )
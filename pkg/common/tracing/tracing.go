@@ -0,0 +1,110 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"os"
+	"strconv"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpgrpc"
+	"go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+const (
+	// EnvOTLPEndpoint is the environment variable holding the host:port of the
+	// OTLP gRPC collector the spans are exported to. Tracing is disabled if it
+	// is not set.
+	EnvOTLPEndpoint = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	// EnvOTLPInsecure is the environment variable which, when set to true,
+	// disables TLS for the connection to the OTLP collector.
+	EnvOTLPInsecure = "OTEL_EXPORTER_OTLP_INSECURE"
+
+	// tracerName is the name of the tracer of the driver.
+	tracerName = "sigs.k8s.io/vsphere-csi-driver"
+)
+
+// propagator propagates the W3C trace context and baggage.
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// InitTracing exports the spans of the given service to the OTLP collector
+// set in OTEL_EXPORTER_OTLP_ENDPOINT and propagates the W3C trace context and
+// baggage. If the variable is not set, tracing is disabled: spans are not
+// recorded and the trace context sent by clients is ignored. The returned
+// function flushes the pending spans and stops the exporter.
+func InitTracing(ctx context.Context, serviceName string) (func(context.Context) error, error) {
+	log := logger.GetLogger(ctx)
+	endpoint := os.Getenv(EnvOTLPEndpoint)
+	if endpoint == "" {
+		log.Infof("Tracing is disabled as %s is not set", EnvOTLPEndpoint)
+		return func(context.Context) error { return nil }, nil
+	}
+	opts := []otlpgrpc.Option{otlpgrpc.WithEndpoint(endpoint)}
+	if v := os.Getenv(EnvOTLPInsecure); v != "" {
+		if insecure, err := strconv.ParseBool(v); err == nil && insecure {
+			opts = append(opts, otlpgrpc.WithInsecure())
+		} else if err != nil {
+			log.Warnf("Value set in env variable %s %s is invalid, will use TLS", EnvOTLPInsecure, v)
+		}
+	}
+	exporter, err := otlp.NewExporter(ctx, otlpgrpc.NewDriver(opts...))
+	if err != nil {
+		return nil, logger.LogNewErrorf(log, "failed to create OTLP exporter for %s. Err: %v", endpoint, err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(sdkresource.NewWithAttributes(semconv.ServiceNameKey.String(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+	log.Infof("Exporting spans of %s to OTLP collector %s", serviceName, endpoint)
+	return provider.Shutdown, nil
+}
+
+// StartSpan starts a span with the given name and attributes, child of the
+// span in the given context if any.
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan records the given error, if any, on the span and ends it.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// UnaryServerInterceptor returns a gRPC interceptor which starts a span for
+// each RPC, child of the W3C trace context sent by the client if tracing is
+// enabled.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return otelgrpc.UnaryServerInterceptor(otelgrpc.WithPropagators(otel.GetTextMapPropagator()))
+}
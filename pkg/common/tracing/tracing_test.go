@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestInitTracingDisabled(t *testing.T) {
+	ctx := context.Background()
+	savedEndpoint, endpointSet := os.LookupEnv(EnvOTLPEndpoint)
+	os.Unsetenv(EnvOTLPEndpoint)
+	defer func() {
+		if endpointSet {
+			os.Setenv(EnvOTLPEndpoint, savedEndpoint)
+		}
+	}()
+
+	shutdown, err := InitTracing(ctx, "test")
+	if err != nil {
+		t.Fatalf("failed to init tracing: %v", err)
+	}
+	defer func() {
+		if err := shutdown(ctx); err != nil {
+			t.Errorf("failed to shut down tracing: %v", err)
+		}
+	}()
+
+	// The trace context sent by clients must not be propagated when tracing
+	// is disabled.
+	in := propagation.HeaderCarrier{}
+	in.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	out := propagation.HeaderCarrier{}
+	otel.GetTextMapPropagator().Inject(otel.GetTextMapPropagator().Extract(ctx, in), out)
+	if len(out) != 0 {
+		t.Errorf("expected no trace context to be propagated, got %v", out)
+	}
+}
+
+func TestEndSpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	savedProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer otel.SetTracerProvider(savedProvider)
+
+	_, span := StartSpan(context.Background(), "succeeded")
+	EndSpan(span, nil)
+	_, span = StartSpan(context.Background(), "failed")
+	EndSpan(span, errors.New("CNS fault"))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].StatusCode != codes.Unset || len(spans[0].MessageEvents) != 0 {
+		t.Errorf("expected no error on span %q, got status %v", spans[0].Name, spans[0].StatusCode)
+	}
+	if spans[1].StatusCode != codes.Error || spans[1].StatusMessage != "CNS fault" {
+		t.Errorf("expected error status on span %q, got %v %q", spans[1].Name, spans[1].StatusCode,
+			spans[1].StatusMessage)
+	}
+	if len(spans[1].MessageEvents) != 1 || spans[1].MessageEvents[0].Name != "exception" {
+		t.Errorf("expected the error to be recorded on span %q, got %v", spans[1].Name,
+			spans[1].MessageEvents)
+	}
+}
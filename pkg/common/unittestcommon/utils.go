@@ -22,6 +22,7 @@ import (
 	"strconv"
 	"sync"
 
+	"github.com/container-storage-interface/spec/lib/go/csi"
 	"google.golang.org/grpc/codes"
 	storagev1 "k8s.io/api/storage/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -124,6 +125,13 @@ func (cntrlTopology *mockControllerVolumeTopology) GetTopologyInfoFromNodes(ctx
 	return nil, logger.LogNewError(log, "GetTopologyInfoFromNodes is not yet implemented.")
 }
 
+// GetvCentersInTopology retrieves the vCenters of nodes which satisfy a given topology requirement.
+func (cntrlTopology *mockControllerVolumeTopology) GetvCentersInTopology(ctx context.Context,
+	topologyRequirement *csi.TopologyRequirement) ([]string, error) {
+	log := logger.GetLogger(ctx)
+	return nil, logger.LogNewError(log, "GetvCentersInTopology is not yet implemented.")
+}
+
 // InitTopologyServiceInController returns a singleton implementation of the
 // commoncotypes.ControllerTopologyService interface for the FakeK8SOrchestrator.
 func (c *FakeK8SOrchestrator) InitTopologyServiceInController(ctx context.Context) (
@@ -279,3 +287,30 @@ func (c *FakeK8SOrchestrator) CreateConfigMap(ctx context.Context, name string,
 	data map[string]string, isImmutable bool) error {
 	return nil
 }
+
+// RecordVolumeEvent records a Warning event on the PV and PVC of the given volume.
+func (c *FakeK8SOrchestrator) RecordVolumeEvent(ctx context.Context, volumeID string, reason string,
+	faultType string, message string) {
+}
+
+// RecordPVCEvent records a Warning event on the given PVC.
+func (c *FakeK8SOrchestrator) RecordPVCEvent(ctx context.Context, name string, namespace string,
+	volumeName string, reason string, faultType string, message string) {
+}
+
+// RecordVolumeSnapshotEvent records a Warning event on the given VolumeSnapshot.
+func (c *FakeK8SOrchestrator) RecordVolumeSnapshotEvent(ctx context.Context, name string, namespace string,
+	reason string, faultType string, message string) {
+}
+
+// GetVolumeObjectReferences returns the references of the PV of the given
+// volume and of the PVC bound to it.
+func (c *FakeK8SOrchestrator) GetVolumeObjectReferences(ctx context.Context, volumeID string) []string {
+	return nil
+}
+
+// AreInformersSynced returns true if the caches of the Kubernetes informers
+// used by the orchestrator are synced.
+func (c *FakeK8SOrchestrator) AreInformersSynced(ctx context.Context) bool {
+	return true
+}
@@ -76,6 +76,24 @@ type COCommonInterface interface {
 	// parameter values.
 	CreateConfigMap(ctx context.Context, name string, namespace string, data map[string]string,
 		isImmutable bool) error
+	// RecordVolumeEvent records a Warning event with the given reason and fault
+	// type on the PV of the given volume and on the PVC bound to it.
+	RecordVolumeEvent(ctx context.Context, volumeID string, reason string, faultType string, message string)
+	// RecordPVCEvent records a Warning event with the given reason and fault
+	// type on the PVC with the given name and namespace or, if they are not
+	// known, on the PVC provisioned as the volume with the given name.
+	RecordPVCEvent(ctx context.Context, name string, namespace string, volumeName string, reason string,
+		faultType string, message string)
+	// RecordVolumeSnapshotEvent records a Warning event with the given reason
+	// and fault type on the VolumeSnapshot with the given name and namespace.
+	RecordVolumeSnapshotEvent(ctx context.Context, name string, namespace string, reason string,
+		faultType string, message string)
+	// GetVolumeObjectReferences returns the references of the PV of the given
+	// volume and of the PVC bound to it.
+	GetVolumeObjectReferences(ctx context.Context, volumeID string) []string
+	// AreInformersSynced returns true if the caches of the Kubernetes
+	// informers used by the orchestrator are synced.
+	AreInformersSynced(ctx context.Context) bool
 }
 
 // GetContainerOrchestratorInterface returns orchestrator object for a given
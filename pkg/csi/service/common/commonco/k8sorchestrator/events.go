@@ -0,0 +1,153 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8sorchestrator
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
+)
+
+// pvcUIDLength is the length of a PVC UID, the suffix of the name of the
+// volumes generated by the provisioner.
+const pvcUIDLength = 36
+
+// initEventRecorder initializes the recorder for the events on CNS operation
+// failures, along with the PVC lister and the PVC UID indexer used to find the
+// PVCs to record the events on. PVs are found using the PV indexer. It must be
+// called before the informers are started.
+func initEventRecorder(ctx context.Context) {
+	log := logger.GetLogger(ctx)
+	k8sOrchestratorInstance.eventRecorder = k8s.NewEventRecorder(k8sOrchestratorInstance.k8sClient, csitypes.Name)
+	k8sOrchestratorInstance.pvcLister = k8sOrchestratorInstance.informerManager.GetPVCLister()
+	pvcIndexer, err := k8sOrchestratorInstance.informerManager.GetPVCUIDIndexer()
+	if err != nil {
+		log.Warnf("Failed to index PVCs by UID. Events will be recorded on the PVCs of failed volume "+
+			"creations only if the provisioner runs with --extra-create-metadata. Error: %v", err)
+	}
+	k8sOrchestratorInstance.pvcIndexer = pvcIndexer
+	log.Infof("Initialized event recorder for CNS operation failures")
+}
+
+// isEventRecordingEnabled returns true if events on CNS operation failures
+// should be recorded.
+func (c *K8sOrchestrator) isEventRecordingEnabled(ctx context.Context) bool {
+	return c.eventRecorder != nil && c.IsFSSEnabled(ctx, common.CnsFailureEvents)
+}
+
+// RecordVolumeEvent records a Warning event with the given reason and fault
+// type on the PV of the given volume and on the PVC bound to it.
+func (c *K8sOrchestrator) RecordVolumeEvent(ctx context.Context, volumeID string, reason string,
+	faultType string, message string) {
+	log := logger.GetLogger(ctx)
+	if !c.isEventRecordingEnabled(ctx) {
+		return
+	}
+	pv := c.getPVByVolumeHandle(ctx, volumeID)
+	if pv == nil {
+		log.Debugf("PV for volume %q not found. Not recording event %q", volumeID, reason)
+		return
+	}
+	k8s.RecordFaultEvent(c.eventRecorder, pv, reason, faultType, message)
+	if pv.Spec.ClaimRef != nil {
+		c.RecordPVCEvent(ctx, pv.Spec.ClaimRef.Name, pv.Spec.ClaimRef.Namespace, "", reason, faultType, message)
+	}
+}
+
+// RecordPVCEvent records a Warning event with the given reason and fault type
+// on the PVC with the given name and namespace. They are set in CreateVolume
+// requests only when the provisioner runs with --extra-create-metadata, so
+// when they are empty the PVC is found by the UID in the given volume name,
+// which the provisioner generates as <prefix>-<PVC UID>.
+func (c *K8sOrchestrator) RecordPVCEvent(ctx context.Context, name string, namespace string, volumeName string,
+	reason string, faultType string, message string) {
+	log := logger.GetLogger(ctx)
+	if !c.isEventRecordingEnabled(ctx) {
+		return
+	}
+	var pvc *v1.PersistentVolumeClaim
+	if name != "" && namespace != "" {
+		var err error
+		pvc, err = c.pvcLister.PersistentVolumeClaims(namespace).Get(name)
+		if err != nil {
+			log.Debugf("failed to get PVC %s/%s to record event %q. Error: %+v", namespace, name, reason, err)
+			return
+		}
+	} else {
+		pvc = c.getPVCByVolumeName(ctx, volumeName)
+		if pvc == nil {
+			log.Debugf("PVC of volume %q not found. Not recording event %q", volumeName, reason)
+			return
+		}
+	}
+	k8s.RecordFaultEvent(c.eventRecorder, pvc, reason, faultType, message)
+}
+
+// getPVCByVolumeName returns the PVC provisioned as the volume with the given
+// name, <prefix>-<PVC UID>, from the PVC UID indexer. It returns nil if the
+// PVC is not found.
+func (c *K8sOrchestrator) getPVCByVolumeName(ctx context.Context, volumeName string) *v1.PersistentVolumeClaim {
+	log := logger.GetLogger(ctx)
+	if c.pvcIndexer == nil || len(volumeName) <= pvcUIDLength {
+		return nil
+	}
+	uid := volumeName[len(volumeName)-pvcUIDLength:]
+	objs, err := c.pvcIndexer.ByIndex(k8s.PVCUIDIndex, uid)
+	if err != nil {
+		log.Errorf("failed to find the PVC of volume %q. Error: %+v", volumeName, err)
+		return nil
+	}
+	for _, obj := range objs {
+		if pvc, ok := obj.(*v1.PersistentVolumeClaim); ok {
+			return pvc
+		}
+	}
+	return nil
+}
+
+// RecordVolumeSnapshotEvent records a Warning event with the given reason and
+// fault type on the VolumeSnapshot with the given name and namespace.
+func (c *K8sOrchestrator) RecordVolumeSnapshotEvent(ctx context.Context, name string, namespace string,
+	reason string, faultType string, message string) {
+	log := logger.GetLogger(ctx)
+	if !c.isEventRecordingEnabled(ctx) || name == "" || namespace == "" {
+		return
+	}
+	volumeSnapshot, err := c.snapshotterClient.SnapshotV1().VolumeSnapshots(namespace).Get(ctx, name,
+		metav1.GetOptions{})
+	if err != nil {
+		log.Debugf("failed to get VolumeSnapshot %s/%s to record event %q. Error: %+v",
+			namespace, name, reason, err)
+		return
+	}
+	// VolumeSnapshot is not registered in the scheme used by the recorder, so
+	// the reference to it is built here.
+	k8s.RecordFaultEvent(c.eventRecorder, &v1.ObjectReference{
+		Kind:            common.VolumeSnapshotKind,
+		APIVersion:      common.VolumeSnapshotApiGroup + "/v1",
+		Namespace:       volumeSnapshot.Namespace,
+		Name:            volumeSnapshot.Name,
+		UID:             volumeSnapshot.UID,
+		ResourceVersion: volumeSnapshot.ResourceVersion,
+	}, reason, faultType, message)
+}
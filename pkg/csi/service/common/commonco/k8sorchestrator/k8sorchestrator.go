@@ -39,8 +39,10 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/informers"
 	clientset "k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	cnsoperatorv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
@@ -202,6 +204,10 @@ type K8sOrchestrator struct {
 	volumeIDToNameMap    *volumeIDToNameMap    // used when ListVolume FSS is enabled
 	k8sClient            clientset.Interface
 	snapshotterClient    snapshotterClientSet.Interface
+	eventRecorder        record.EventRecorder                    // used when CnsFailureEvents FSS is enabled
+	pvcLister            corelisters.PersistentVolumeClaimLister // used when CnsFailureEvents FSS is enabled
+	pvcIndexer           cache.Indexer                           // PVCs indexed by UID, used with pvcLister
+	pvIndexer            cache.Indexer                           // PVs indexed by volume handle, in the controller
 }
 
 // K8sGuestInitParams lists the set of parameters required to run the init for
@@ -286,6 +292,22 @@ func Newk8sOrchestrator(ctx context.Context, controllerClusterFlavor cnstypes.Cn
 				}
 			}
 
+			if serviceMode != "node" {
+				// The PV indexer is used to find the Kubernetes objects of the
+				// volumes referenced in the audit log.
+				pvIndexer, err := k8sOrchestratorInstance.informerManager.GetPVVolumeHandleIndexer()
+				if err != nil {
+					log.Warnf("Failed to index PVs by volume handle. Kubernetes objects will not be "+
+						"referenced in the audit log. Error: %v", err)
+				}
+				k8sOrchestratorInstance.pvIndexer = pvIndexer
+			}
+			if controllerClusterFlavor == cnstypes.CnsClusterFlavorVanilla && serviceMode != "node" {
+				// Events are recorded only while the CnsFailureEvents FSS is
+				// enabled, which is checked on each CNS failure.
+				initEventRecorder(ctx)
+			}
+
 			k8sOrchestratorInstance.informerManager.Listen()
 			atomic.StoreUint32(&k8sOrchestratorInstanceInitialized, 1)
 			log.Info("k8sOrchestratorInstance initialized")
@@ -930,6 +952,45 @@ func (c *K8sOrchestrator) GetAllK8sVolumes() []string {
 	return volumeIDs
 }
 
+// GetVolumeObjectReferences returns the references, as Kind/Name or
+// Kind/Namespace/Name, of the PV of the given volume and of the PVC bound to
+// it. It returns nil if the PV is not found.
+func (c *K8sOrchestrator) GetVolumeObjectReferences(ctx context.Context, volumeID string) []string {
+	pv := c.getPVByVolumeHandle(ctx, volumeID)
+	if pv == nil {
+		return nil
+	}
+	refs := []string{"PersistentVolume/" + pv.Name}
+	if pv.Spec.ClaimRef != nil {
+		refs = append(refs, "PersistentVolumeClaim/"+pv.Spec.ClaimRef.Namespace+"/"+pv.Spec.ClaimRef.Name)
+	}
+	return refs
+}
+
+// getPVByVolumeHandle returns the PV of the given volume from the PV indexer.
+// It returns nil if the PV is not found.
+func (c *K8sOrchestrator) getPVByVolumeHandle(ctx context.Context, volumeID string) *v1.PersistentVolume {
+	log := logger.GetLogger(ctx)
+	if c.pvIndexer == nil {
+		return nil
+	}
+	pvs, err := k8s.GetPVsByVolumeHandle(c.pvIndexer, csitypes.Name, volumeID)
+	if err != nil {
+		log.Errorf("failed to find the PV of volume %q. Error: %+v", volumeID, err)
+		return nil
+	}
+	if len(pvs) == 0 {
+		return nil
+	}
+	return pvs[0]
+}
+
+// AreInformersSynced returns true if the caches of the Kubernetes informers
+// used by the orchestrator are synced.
+func (c *K8sOrchestrator) AreInformersSynced(ctx context.Context) bool {
+	return c.informerManager.HasSynced()
+}
+
 // IsFSSEnabled utilises the cluster flavor to check their corresponding FSS
 // maps and returns if the feature state switch is enabled for the given feature
 // indicated by featureName.
@@ -24,8 +24,17 @@ import (
 	"testing"
 
 	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
 	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
 )
 
 var (
@@ -267,3 +276,163 @@ func TestGetNodesForVolumes(t *testing.T) {
 		t.Errorf("Expected node names %v but got %v", expectedNodeNames, nodeNames)
 	}
 }
+
+// TestGetvCentersOfNodeVMs tests that the distinct vCenters of node VMs spread across
+// vCenters are returned in order.
+func TestGetvCentersOfNodeVMs(t *testing.T) {
+	nodeVMs := []*cnsvsphere.VirtualMachine{
+		{VirtualCenterHost: "vc-2"},
+		{VirtualCenterHost: "vc-1"},
+		{VirtualCenterHost: "vc-2"},
+	}
+	vCenters := getvCentersOfNodeVMs(nodeVMs)
+	expectedvCenters := []string{"vc-1", "vc-2"}
+	if !reflect.DeepEqual(vCenters, expectedvCenters) {
+		t.Errorf("Expected vCenters %v but got %v", expectedvCenters, vCenters)
+	}
+	if vCenters := getvCentersOfNodeVMs(nil); len(vCenters) != 0 {
+		t.Errorf("Expected no vCenters but got %v", vCenters)
+	}
+}
+
+// TestGetVolumeObjectReferences tests that the PV of a volume and the PVC bound
+// to it are found with the PV indexer.
+func TestGetVolumeObjectReferences(t *testing.T) {
+	newPV := func(name string, driver string, volumeHandle string, claimRef *v1.ObjectReference) *v1.PersistentVolume {
+		return &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1.PersistentVolumeSpec{
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					CSI: &v1.CSIPersistentVolumeSource{Driver: driver, VolumeHandle: volumeHandle},
+				},
+				ClaimRef: claimRef,
+			},
+		}
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc,
+		cache.Indexers{k8s.PVVolumeHandleIndex: k8s.PVVolumeHandleIndexFunc})
+	for _, pv := range []*v1.PersistentVolume{
+		newPV("pv-1", csitypes.Name, "volume-1", &v1.ObjectReference{Namespace: "ns-1", Name: "pvc-1"}),
+		newPV("pv-2", csitypes.Name, "volume-2", nil),
+		newPV("pv-3", "other.csi.driver", "volume-3", nil),
+		{ObjectMeta: metav1.ObjectMeta{Name: "pv-4"}},
+	} {
+		if err := indexer.Add(pv); err != nil {
+			t.Fatalf("failed to add PV %s to the indexer. Err: %v", pv.Name, err)
+		}
+	}
+	k8sOrchestrator := K8sOrchestrator{pvIndexer: indexer}
+	tests := map[string][]string{
+		"volume-1": {"PersistentVolume/pv-1", "PersistentVolumeClaim/ns-1/pvc-1"},
+		"volume-2": {"PersistentVolume/pv-2"},
+		"volume-3": nil,
+		"volume-4": nil,
+	}
+	for volumeID, expectedRefs := range tests {
+		if refs := k8sOrchestrator.GetVolumeObjectReferences(ctx, volumeID); !reflect.DeepEqual(refs, expectedRefs) {
+			t.Errorf("Expected references %v of volume %s but got %v", expectedRefs, volumeID, refs)
+		}
+	}
+	if refs := (&K8sOrchestrator{}).GetVolumeObjectReferences(ctx, "volume-1"); refs != nil {
+		t.Errorf("Expected no references without PV indexer but got %v", refs)
+	}
+}
+
+func TestRecordVolumeEvent(t *testing.T) {
+	pvIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc,
+		cache.Indexers{k8s.PVVolumeHandleIndex: k8s.PVVolumeHandleIndexFunc})
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{Driver: csitypes.Name, VolumeHandle: "volume-1"},
+			},
+			ClaimRef: &v1.ObjectReference{Namespace: "ns-1", Name: "pvc-1"},
+		},
+	}
+	if err := pvIndexer.Add(pv); err != nil {
+		t.Fatalf("failed to add PV %s to the indexer. Err: %v", pv.Name, err)
+	}
+	pvcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	pvc := &v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "ns-1", Name: "pvc-1"}}
+	if err := pvcIndexer.Add(pvc); err != nil {
+		t.Fatalf("failed to add PVC %s to the indexer. Err: %v", pvc.Name, err)
+	}
+	recorder := record.NewFakeRecorder(10)
+	featureStates := map[string]string{common.CnsFailureEvents: "false"}
+	k8sOrchestrator := K8sOrchestrator{
+		clusterFlavor: cnstypes.CnsClusterFlavorVanilla,
+		internalFSS:   FSSConfigMapInfo{featureStates: featureStates},
+		eventRecorder: recorder,
+		pvcLister:     corelisters.NewPersistentVolumeClaimLister(pvcIndexer),
+		pvIndexer:     pvIndexer,
+	}
+
+	k8sOrchestrator.RecordVolumeEvent(ctx, "volume-1", common.DeleteVolumeFailedReason, "fault-1", "failed")
+	if len(recorder.Events) != 0 {
+		t.Fatalf("Expected no events while the FSS is disabled but got %d", len(recorder.Events))
+	}
+
+	// The FSS is read on each call, so enabling it takes effect without a
+	// restart.
+	featureStates[common.CnsFailureEvents] = "true"
+	k8sOrchestrator.RecordVolumeEvent(ctx, "volume-1", common.DeleteVolumeFailedReason, "fault-1", "failed")
+	k8sOrchestrator.RecordVolumeEvent(ctx, "volume-2", common.DeleteVolumeFailedReason, "fault-2", "failed")
+	expectedEvent := "Warning " + common.DeleteVolumeFailedReason + " failed (fault: fault-1)"
+	for _, object := range []string{"PV", "PVC"} {
+		select {
+		case event := <-recorder.Events:
+			if event != expectedEvent {
+				t.Errorf("Expected event %q on the %s but got %q", expectedEvent, object, event)
+			}
+		default:
+			t.Errorf("Expected an event on the %s", object)
+		}
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("Expected no events for a volume without PV but got %d", len(recorder.Events))
+	}
+}
+
+func TestRecordPVCEvent(t *testing.T) {
+	pvcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{k8s.PVCUIDIndex: k8s.PVCUIDIndexFunc})
+	pvc := &v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "ns-1", Name: "pvc-1",
+		UID: "6b1e9c3a-2f5d-4c8e-9a7b-0d3f1e2c4b5a"}}
+	if err := pvcIndexer.Add(pvc); err != nil {
+		t.Fatalf("failed to add PVC %s to the indexer. Err: %v", pvc.Name, err)
+	}
+	recorder := record.NewFakeRecorder(10)
+	k8sOrchestrator := K8sOrchestrator{
+		clusterFlavor: cnstypes.CnsClusterFlavorVanilla,
+		internalFSS:   FSSConfigMapInfo{featureStates: map[string]string{common.CnsFailureEvents: "true"}},
+		eventRecorder: recorder,
+		pvcLister:     corelisters.NewPersistentVolumeClaimLister(pvcIndexer),
+		pvcIndexer:    pvcIndexer,
+	}
+	expectedEvent := "Warning " + common.CreateVolumeFailedReason + " failed (fault: fault-1)"
+
+	// The PVC name and namespace are set only when the provisioner runs with
+	// --extra-create-metadata.
+	k8sOrchestrator.RecordPVCEvent(ctx, "pvc-1", "ns-1", "pvc-other", common.CreateVolumeFailedReason,
+		"fault-1", "failed")
+	// Otherwise the PVC is found by the UID in the volume name.
+	k8sOrchestrator.RecordPVCEvent(ctx, "", "", "pvc-"+string(pvc.UID), common.CreateVolumeFailedReason,
+		"fault-1", "failed")
+	for i := 0; i < 2; i++ {
+		select {
+		case event := <-recorder.Events:
+			if event != expectedEvent {
+				t.Errorf("Expected event %q on the PVC but got %q", expectedEvent, event)
+			}
+		default:
+			t.Errorf("Expected event %d on the PVC", i+1)
+		}
+	}
+
+	k8sOrchestrator.RecordPVCEvent(ctx, "", "", "pvc-0a1b2c3d-4e5f-6a7b-8c9d-0e1f2a3b4c5d",
+		common.CreateVolumeFailedReason, "fault-1", "failed")
+	k8sOrchestrator.RecordPVCEvent(ctx, "", "", "short", common.CreateVolumeFailedReason, "fault-1", "failed")
+	if len(recorder.Events) != 0 {
+		t.Errorf("Expected no events for volumes without PVC but got %d", len(recorder.Events))
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -1312,6 +1313,48 @@ func (volTopology *controllerVolumeTopology) GetTopologyInfoFromNodes(ctx contex
 	return accessibleTopology, nil
 }
 
+// GetvCentersInTopology retrieves the vCenter hosts of the node VMs which satisfy the
+// given topology requirement. The preferred topology requirement is used first, and the
+// requisite one if no nodes match it.
+func (volTopology *controllerVolumeTopology) GetvCentersInTopology(ctx context.Context,
+	topologyRequirement *csi.TopologyRequirement) ([]string, error) {
+	log := logger.GetLogger(ctx)
+	log.Debugf("Get vCenters with topologyRequirement: %+v", topologyRequirement)
+	for _, topologyArr := range [][]*csi.Topology{topologyRequirement.GetPreferred(),
+		topologyRequirement.GetRequisite()} {
+		var matchingNodeVMs []*cnsvsphere.VirtualMachine
+		for _, topology := range topologyArr {
+			nodeVMs, err := volTopology.getNodesMatchingTopologySegment(ctx, topology.GetSegments())
+			if err != nil {
+				return nil, logger.LogNewErrorf(log, "failed to find nodes in topology segment %+v. Error: %+v",
+					topology.GetSegments(), err)
+			}
+			matchingNodeVMs = append(matchingNodeVMs, nodeVMs...)
+		}
+		if len(matchingNodeVMs) != 0 {
+			vCenters := getvCentersOfNodeVMs(matchingNodeVMs)
+			log.Infof("vCenters of the nodes matching topology requirement %+v are %+v", topologyArr, vCenters)
+			return vCenters, nil
+		}
+	}
+	log.Warnf("No nodes in the cluster matched the topology requirement provided: %+v", topologyRequirement)
+	return nil, nil
+}
+
+// getvCentersOfNodeVMs returns the sorted list of distinct vCenter hosts of the given node VMs.
+func getvCentersOfNodeVMs(nodeVMs []*cnsvsphere.VirtualMachine) []string {
+	vCenterSet := make(map[string]struct{})
+	for _, nodeVM := range nodeVMs {
+		vCenterSet[nodeVM.VirtualCenterHost] = struct{}{}
+	}
+	vCenters := make([]string, 0, len(vCenterSet))
+	for vCenter := range vCenterSet {
+		vCenters = append(vCenters, vCenter)
+	}
+	sort.Strings(vCenters)
+	return vCenters
+}
+
 func verifyAllNodesInTopologyAccessibleToDatastore(ctx context.Context, nodeNames []string,
 	datastoreURL string, topologySegments []map[string]string) ([]map[string]string, error) {
 	log := logger.GetLogger(ctx)
@@ -1491,3 +1534,10 @@ func (volTopology *wcpControllerVolumeTopology) GetTopologyInfoFromNodes(ctx con
 	log.Infof("Topology of the provisioned volume detected as %+v", topologySegments)
 	return topologySegments, nil
 }
+
+// GetvCentersInTopology is not supported in WCP, as the supervisor cluster spans a single vCenter.
+func (volTopology *wcpControllerVolumeTopology) GetvCentersInTopology(ctx context.Context,
+	topologyRequirement *csi.TopologyRequirement) ([]string, error) {
+	log := logger.GetLogger(ctx)
+	return nil, logger.LogNewError(log, "GetvCentersInTopology is not supported in WCP")
+}
@@ -100,6 +100,9 @@ type ControllerTopologyService interface {
 	// GetTopologyInfoFromNodes retrieves the topology information of the nodes after the datastore has been
 	// selected for volume provisioning.
 	GetTopologyInfoFromNodes(ctx context.Context, retrieveTopologyInfoParams interface{}) ([]map[string]string, error)
+	// GetvCentersInTopology gets the list of vCenter hosts of the nodes which adhere to the
+	// topology requirement given in the CreateVolume request.
+	GetvCentersInTopology(ctx context.Context, topologyRequirement *csi.TopologyRequirement) ([]string, error)
 }
 
 // NodeTopologyService is an interface which exposes functionality related to
@@ -72,6 +72,46 @@ const (
 	// the given storage policy. For Example: HostLocal: "True".
 	AttributeHostLocal = "hostlocal"
 
+	// AttributeNetPermissionsConfigMap represents the name of the ConfigMap in
+	// the CSI namespace holding the net permissions for file volumes created
+	// using the StorageClass.
+	AttributeNetPermissionsConfigMap = "netpermissionsconfigmap"
+
+	// AttributeNetPermissionsSecret represents the name of the Secret in the
+	// CSI namespace holding the net permissions for file volumes created using
+	// the StorageClass.
+	AttributeNetPermissionsSecret = "netpermissionssecret"
+
+	// AttributePvcNamespace represents the namespace of the PVC, passed by the
+	// external-provisioner when --extra-create-metadata is set.
+	AttributePvcNamespace = "csi.storage.k8s.io/pvc/namespace"
+
+	// AttributePvcName represents the name of the PVC, passed by the
+	// external-provisioner when --extra-create-metadata is set.
+	AttributePvcName = "csi.storage.k8s.io/pvc/name"
+
+	// AttributePvName represents the name of the PV, passed by the
+	// external-provisioner when --extra-create-metadata is set.
+	AttributePvName = "csi.storage.k8s.io/pv/name"
+
+	// AttributeNetPermissionIPs is a PersistentVolume's attribute holding the
+	// JSON encoded list of client IPs the file share was exported to.
+	AttributeNetPermissionIPs = "netpermissionips"
+
+	// NetPermissionsDataKey is the key in the ConfigMap or Secret data which
+	// holds the net permissions in the vSphere CSI config format.
+	NetPermissionsDataKey = "netpermissions"
+
+	// NetPermissionsNamespaceAnnotationKey represents the Namespace annotation
+	// key holding the name of the ConfigMap in the CSI namespace with the net
+	// permissions for file volumes created in the namespace.
+	NetPermissionsNamespaceAnnotationKey = "csi.vsphere.vmware.com/netpermissions"
+
+	// AppliedNetPermissionIPsAnnotationKey represents the PersistentVolume
+	// annotation key holding the JSON encoded list of client IPs the file
+	// share is currently exported to.
+	AppliedNetPermissionIPsAnnotationKey = "csi.vsphere.vmware.com/applied-netpermission-ips"
+
 	// HostMoidAnnotationKey represents the Node annotation key that has the value
 	// of VC's ESX host moid of this node.
 	HostMoidAnnotationKey = "vmware-system-esxi-node-moid"
@@ -117,6 +157,18 @@ const (
 	// Nfsv4AccessPoint is the access point of file volume.
 	Nfsv4AccessPoint = "Nfsv4AccessPoint"
 
+	// Nfsv3AccessPointKey is the key for NFSv3 access point.
+	Nfsv3AccessPointKey = "NFSv3"
+
+	// Nfsv3AccessPoint is the NFSv3 access point of file volume.
+	Nfsv3AccessPoint = "Nfsv3AccessPoint"
+
+	// NfsVersion3 represents NFS version 3.
+	NfsVersion3 = "3"
+
+	// NfsVersion41 represents NFS version 4.1, used by default for file volumes.
+	NfsVersion41 = "4.1"
+
 	// MinSupportedVCenterMajor is the minimum, major version of vCenter
 	// on which CNS is supported.
 	MinSupportedVCenterMajor int = 6
@@ -253,10 +305,31 @@ const (
 	// VolHealthStatusInaccessible is volume health status for inaccessible volume.
 	VolHealthStatusInaccessible = "inaccessible"
 
+	// VolHealthStatusReducedAvailability is volume health status for an
+	// accessible volume which has lost some of its redundancy.
+	VolHealthStatusReducedAvailability = "reduced-availability"
+
+	// VolHealthStatusNonCompliant is volume health status for an accessible
+	// volume which does not comply with its storage policy.
+	VolHealthStatusNonCompliant = "non-compliant"
+
+	// VolHealthStatusResyncing is volume health status for an accessible volume
+	// whose data is being resynchronized to restore its redundancy.
+	VolHealthStatusResyncing = "resyncing"
+
+	// VolumeHealthConditionType is the type of the volume health condition on
+	// volume claim.
+	VolumeHealthConditionType = "VolumeHealth"
+
 	// AnnIgnoreInaccessiblePV is annotation key on volume claim to indicate
 	// if inaccessible PV can be fake attached.
 	AnnIgnoreInaccessiblePV = "pv.attach.kubernetes.io/ignore-if-inaccessible"
 
+	// AnnVolumeRelocation is annotation key on PV to indicate that the PV is
+	// being recreated to update its node affinity after its volume is
+	// relocated, so its deletion must not delete or untag the CNS volume.
+	AnnVolumeRelocation = "cns.vmware.com/volume-relocation"
+
 	// TriggerCsiFullSyncCRName is the instance name of TriggerCsiFullSync
 	// All other names will be rejected by TriggerCsiFullSync controller.
 	TriggerCsiFullSyncCRName = "csifullsync"
@@ -385,4 +458,51 @@ const (
 	// CSIInternalGeneratedClusterID enables support to generate unique cluster
 	// ID internally if user doesn't provide it in vSphere config secret.
 	CSIInternalGeneratedClusterID = "csi-internal-generated-cluster-id"
+	// VanillaRegisterVolume enables the CnsRegisterVolume workflow in vanilla
+	// clusters to import existing FCDs and VMDKs as PV/PVC pairs.
+	VanillaRegisterVolume = "vanilla-register-volume"
+	// FileVolumeScopedNetPermissions enables net permissions for file volumes
+	// to be specified per StorageClass and per Namespace in vanilla clusters.
+	FileVolumeScopedNetPermissions = "file-volume-scoped-net-permissions"
+	// CnsFailureEvents enables recording of Kubernetes events on PVCs, PVs and
+	// VolumeSnapshots when CNS operations on them fail.
+	CnsFailureEvents = "cns-failure-events"
+	// RichVolumeHealth enables the volume health states beyond accessible and
+	// inaccessible, i.e. reduced-availability, non-compliant and resyncing,
+	// along with the volume health condition on PVCs.
+	RichVolumeHealth = "rich-volume-health"
+	// StoragePolicyCompliance enables the periodic check of the compliance of
+	// volumes with their storage policies in vanilla clusters.
+	StoragePolicyCompliance = "storage-policy-compliance"
+	// VolumeRelocation enables the CnsVolumeRelocation workflow in vanilla
+	// clusters to relocate volumes of PVs to other datastores.
+	VolumeRelocation = "volume-relocation"
+)
+
+// Reasons of the Warning events recorded when CNS operations fail.
+const (
+	// CreateVolumeFailedReason is the reason of the event recorded on a PVC
+	// when creating its volume fails.
+	CreateVolumeFailedReason = "CreateVolumeFailed"
+	// DeleteVolumeFailedReason is the reason of the event recorded on a PV
+	// when deleting its volume fails.
+	DeleteVolumeFailedReason = "DeleteVolumeFailed"
+	// AttachVolumeFailedReason is the reason of the event recorded on a PV
+	// and its PVC when attaching the volume to a node fails.
+	AttachVolumeFailedReason = "AttachVolumeFailed"
+	// DetachVolumeFailedReason is the reason of the event recorded on a PV
+	// and its PVC when detaching the volume from a node fails.
+	DetachVolumeFailedReason = "DetachVolumeFailed"
+	// ExpandVolumeFailedReason is the reason of the event recorded on a PV
+	// and its PVC when expanding the volume fails.
+	ExpandVolumeFailedReason = "ExpandVolumeFailed"
+	// CreateSnapshotFailedReason is the reason of the event recorded on a
+	// VolumeSnapshot when creating the snapshot fails.
+	CreateSnapshotFailedReason = "CreateSnapshotFailed"
+	// RegisterVolumeFailedReason is the reason of the event recorded on a
+	// statically provisioned PV when registering its volume with CNS fails.
+	RegisterVolumeFailedReason = "RegisterVolumeFailed"
+	// UpdateVolumeMetadataFailedReason is the reason of the event recorded on
+	// a PV or PVC when updating its metadata in CNS fails.
+	UpdateVolumeMetadataFailedReason = "UpdateVolumeMetadataFailed"
 )
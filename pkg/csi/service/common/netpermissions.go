@@ -0,0 +1,254 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	vsanfstypes "github.com/vmware/govmomi/vsan/vsanfs/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+// NetPermissionsSource gets the objects holding the scoped net permissions
+// of file volumes.
+type NetPermissionsSource interface {
+	// GetConfigMap returns the ConfigMap with the given namespace and name.
+	GetConfigMap(ctx context.Context, namespace string, name string) (*v1.ConfigMap, error)
+	// GetSecret returns the Secret with the given namespace and name.
+	GetSecret(ctx context.Context, namespace string, name string) (*v1.Secret, error)
+	// GetNamespace returns the Namespace with the given name.
+	GetNamespace(ctx context.Context, name string) (*v1.Namespace, error)
+}
+
+// clientNetPermissionsSource gets the objects using the API server.
+type clientNetPermissionsSource struct {
+	k8sClient clientset.Interface
+}
+
+// NewClientNetPermissionsSource returns a NetPermissionsSource which gets the
+// objects from the API server using the given client.
+func NewClientNetPermissionsSource(k8sClient clientset.Interface) NetPermissionsSource {
+	return &clientNetPermissionsSource{k8sClient: k8sClient}
+}
+
+func (s *clientNetPermissionsSource) GetConfigMap(ctx context.Context, namespace string,
+	name string) (*v1.ConfigMap, error) {
+	return s.k8sClient.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+func (s *clientNetPermissionsSource) GetSecret(ctx context.Context, namespace string,
+	name string) (*v1.Secret, error) {
+	return s.k8sClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+func (s *clientNetPermissionsSource) GetNamespace(ctx context.Context, name string) (*v1.Namespace, error) {
+	return s.k8sClient.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+}
+
+// listerNetPermissionsSource gets the objects from the informer caches.
+type listerNetPermissionsSource struct {
+	configMapLister corelisters.ConfigMapLister
+	secretLister    corelisters.SecretLister
+	namespaceLister corelisters.NamespaceLister
+}
+
+// NewListerNetPermissionsSource returns a NetPermissionsSource which gets the
+// objects from the informer caches using the given listers.
+func NewListerNetPermissionsSource(configMapLister corelisters.ConfigMapLister,
+	secretLister corelisters.SecretLister, namespaceLister corelisters.NamespaceLister) NetPermissionsSource {
+	return &listerNetPermissionsSource{
+		configMapLister: configMapLister,
+		secretLister:    secretLister,
+		namespaceLister: namespaceLister,
+	}
+}
+
+func (s *listerNetPermissionsSource) GetConfigMap(ctx context.Context, namespace string,
+	name string) (*v1.ConfigMap, error) {
+	return s.configMapLister.ConfigMaps(namespace).Get(name)
+}
+
+func (s *listerNetPermissionsSource) GetSecret(ctx context.Context, namespace string,
+	name string) (*v1.Secret, error) {
+	return s.secretLister.Secrets(namespace).Get(name)
+}
+
+func (s *listerNetPermissionsSource) GetNamespace(ctx context.Context, name string) (*v1.Namespace, error) {
+	return s.namespaceLister.Get(name)
+}
+
+// GetScopedNetPermissions returns the net permissions for a file volume
+// created using the given StorageClass params. The scoped net permissions are
+// merged with the global ones, keyed by their Ips, the more specific scope
+// winning: the global net permissions are overridden by the ConfigMap
+// referenced by the StorageClass, then by the Secret referenced by the
+// StorageClass and finally by the ConfigMap referenced by the namespace of
+// the PVC.
+func GetScopedNetPermissions(ctx context.Context, source NetPermissionsSource,
+	globalNetPermissions map[string]*cnsconfig.NetPermissionConfig,
+	netPermissionsConfigMap string, netPermissionsSecret string,
+	namespace string) (map[string]*cnsconfig.NetPermissionConfig, error) {
+	log := logger.GetLogger(ctx)
+	csiNamespace := GetCSINamespace()
+	scopes := []map[string]*cnsconfig.NetPermissionConfig{globalNetPermissions}
+	if netPermissionsConfigMap != "" {
+		netPermissions, err := getNetPermissionsFromConfigMap(ctx, source, csiNamespace, netPermissionsConfigMap)
+		if err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, netPermissions)
+	}
+	if netPermissionsSecret != "" {
+		secret, err := source.GetSecret(ctx, csiNamespace, netPermissionsSecret)
+		if err != nil {
+			return nil, logger.LogNewErrorf(log, "failed to get Secret %s/%s. Error: %+v",
+				csiNamespace, netPermissionsSecret, err)
+		}
+		netPermissions, err := cnsconfig.ParseNetPermissions(ctx, string(secret.Data[NetPermissionsDataKey]))
+		if err != nil {
+			return nil, logger.LogNewErrorf(log, "failed to parse net permissions in Secret %s/%s. Error: %+v",
+				csiNamespace, netPermissionsSecret, err)
+		}
+		scopes = append(scopes, netPermissions)
+	}
+	if namespace != "" {
+		ns, err := source.GetNamespace(ctx, namespace)
+		if err != nil {
+			return nil, logger.LogNewErrorf(log, "failed to get Namespace %s. Error: %+v", namespace, err)
+		}
+		if configMapName := ns.Annotations[NetPermissionsNamespaceAnnotationKey]; configMapName != "" {
+			netPermissions, err := getNetPermissionsFromConfigMap(ctx, source, csiNamespace, configMapName)
+			if err != nil {
+				return nil, err
+			}
+			scopes = append(scopes, netPermissions)
+		}
+	}
+	return MergeNetPermissions(scopes...), nil
+}
+
+// getNetPermissionsFromConfigMap reads the net permissions from the given
+// ConfigMap.
+func getNetPermissionsFromConfigMap(ctx context.Context, source NetPermissionsSource,
+	namespace string, name string) (map[string]*cnsconfig.NetPermissionConfig, error) {
+	log := logger.GetLogger(ctx)
+	configMap, err := source.GetConfigMap(ctx, namespace, name)
+	if err != nil {
+		return nil, logger.LogNewErrorf(log, "failed to get ConfigMap %s/%s. Error: %+v", namespace, name, err)
+	}
+	netPermissions, err := cnsconfig.ParseNetPermissions(ctx, configMap.Data[NetPermissionsDataKey])
+	if err != nil {
+		return nil, logger.LogNewErrorf(log, "failed to parse net permissions in ConfigMap %s/%s. Error: %+v",
+			namespace, name, err)
+	}
+	return netPermissions, nil
+}
+
+// MergeNetPermissions merges the given net permissions in order. Entries are
+// keyed by their Ips, so an entry in a later map replaces the entry with the
+// same Ips from an earlier one.
+func MergeNetPermissions(
+	scopes ...map[string]*cnsconfig.NetPermissionConfig) map[string]*cnsconfig.NetPermissionConfig {
+	merged := make(map[string]*cnsconfig.NetPermissionConfig)
+	for _, netPermissions := range scopes {
+		for _, netPerm := range netPermissions {
+			merged[netPerm.Ips] = netPerm
+		}
+	}
+	return merged
+}
+
+// GetVsanFileShareNetPermissions converts the given net permissions to the
+// format required by CNS, sorted by Ips.
+func GetVsanFileShareNetPermissions(
+	netPermissions map[string]*cnsconfig.NetPermissionConfig) []vsanfstypes.VsanFileShareNetPermission {
+	netPerms := make([]vsanfstypes.VsanFileShareNetPermission, 0)
+	for _, netPerm := range netPermissions {
+		netPerms = append(netPerms, vsanfstypes.VsanFileShareNetPermission{
+			Ips:         netPerm.Ips,
+			Permissions: netPerm.Permissions,
+			AllowRoot:   !netPerm.RootSquash,
+		})
+	}
+	sort.Slice(netPerms, func(i, j int) bool {
+		return netPerms[i].Ips < netPerms[j].Ips
+	})
+	return netPerms
+}
+
+// GetNetPermissionIPs returns the JSON encoded sorted list of client IPs in
+// the given net permissions.
+func GetNetPermissionIPs(netPermissions map[string]*cnsconfig.NetPermissionConfig) string {
+	ips := make([]string, 0)
+	for _, netPerm := range GetVsanFileShareNetPermissions(netPermissions) {
+		ips = append(ips, netPerm.Ips)
+	}
+	data, _ := json.Marshal(ips)
+	return string(data)
+}
+
+// ParseNetPermissionIPs parses the JSON encoded list of client IPs returned
+// by GetNetPermissionIPs.
+func ParseNetPermissionIPs(data string) ([]string, error) {
+	var ips []string
+	if data == "" {
+		return ips, nil
+	}
+	err := json.Unmarshal([]byte(data), &ips)
+	return ips, err
+}
+
+// GetNetPermissionsACLSpec returns the CnsVolumeACLConfigureSpec which sets
+// the given net permissions on the file volume and removes the client IPs
+// in appliedIPs which are no longer present. The net permissions are expected
+// to be keyed by their Ips, as returned by MergeNetPermissions.
+func GetNetPermissionsACLSpec(volumeID string, netPermissions map[string]*cnsconfig.NetPermissionConfig,
+	appliedIPs []string) cnstypes.CnsVolumeACLConfigureSpec {
+	spec := cnstypes.CnsVolumeACLConfigureSpec{
+		VolumeId: cnstypes.CnsVolumeId{Id: volumeID},
+	}
+	var removed []vsanfstypes.VsanFileShareNetPermission
+	for _, ip := range appliedIPs {
+		if _, exists := netPermissions[ip]; !exists {
+			removed = append(removed, vsanfstypes.VsanFileShareNetPermission{
+				Ips:         ip,
+				Permissions: vsanfstypes.VsanFileShareAccessTypeNO_ACCESS,
+			})
+		}
+	}
+	if len(removed) > 0 {
+		spec.AccessControlSpecList = append(spec.AccessControlSpecList, cnstypes.CnsNFSAccessControlSpec{
+			Permission: removed,
+			Delete:     true,
+		})
+	}
+	if len(netPermissions) > 0 {
+		spec.AccessControlSpecList = append(spec.AccessControlSpecList, cnstypes.CnsNFSAccessControlSpec{
+			Permission: GetVsanFileShareNetPermissions(netPermissions),
+		})
+	}
+	return spec
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	vsanfstypes "github.com/vmware/govmomi/vsan/vsanfs/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+)
+
+func TestMergeNetPermissions(t *testing.T) {
+	global := map[string]*cnsconfig.NetPermissionConfig{
+		"#": {Ips: "*", Permissions: vsanfstypes.VsanFileShareAccessTypeREAD_WRITE},
+	}
+	storageClass := map[string]*cnsconfig.NetPermissionConfig{
+		"A": {Ips: "10.20.0.0/16", Permissions: vsanfstypes.VsanFileShareAccessTypeREAD_ONLY},
+	}
+	namespace := map[string]*cnsconfig.NetPermissionConfig{
+		"B": {Ips: "*", Permissions: vsanfstypes.VsanFileShareAccessTypeNO_ACCESS},
+		"C": {Ips: "10.20.30.0/24", Permissions: vsanfstypes.VsanFileShareAccessTypeREAD_WRITE, RootSquash: true},
+	}
+	merged := MergeNetPermissions(global, storageClass, namespace)
+	if len(merged) != 3 {
+		t.Fatalf("expected 3 net permissions, got %d: %+v", len(merged), merged)
+	}
+	if merged["*"].Permissions != vsanfstypes.VsanFileShareAccessTypeNO_ACCESS {
+		t.Errorf("expected namespace net permissions to override the global ones, got %+v", merged["*"])
+	}
+	if merged["10.20.0.0/16"].Permissions != vsanfstypes.VsanFileShareAccessTypeREAD_ONLY {
+		t.Errorf("unexpected net permissions for 10.20.0.0/16: %+v", merged["10.20.0.0/16"])
+	}
+	if ips := GetNetPermissionIPs(merged); ips != `["*","10.20.0.0/16","10.20.30.0/24"]` {
+		t.Errorf("unexpected net permission IPs %s", ips)
+	}
+}
+
+func TestGetNetPermissionsACLSpec(t *testing.T) {
+	netPermissions := MergeNetPermissions(map[string]*cnsconfig.NetPermissionConfig{
+		"A": {Ips: "10.20.30.0/24", Permissions: vsanfstypes.VsanFileShareAccessTypeREAD_ONLY, RootSquash: true},
+	})
+	appliedIPs, err := ParseNetPermissionIPs(`["*","10.20.30.0/24"]`)
+	if err != nil {
+		t.Fatalf("failed to parse net permission IPs: %v", err)
+	}
+	spec := GetNetPermissionsACLSpec("volume-1", netPermissions, appliedIPs)
+	if spec.VolumeId.Id != "volume-1" {
+		t.Errorf("unexpected volume ID %q", spec.VolumeId.Id)
+	}
+	if len(spec.AccessControlSpecList) != 2 {
+		t.Fatalf("expected 2 access control specs, got %+v", spec.AccessControlSpecList)
+	}
+	removed := spec.AccessControlSpecList[0]
+	if !removed.Delete || len(removed.Permission) != 1 || removed.Permission[0].Ips != "*" {
+		t.Errorf("expected client IPs \"*\" to be removed, got %+v", removed)
+	}
+	added := spec.AccessControlSpecList[1]
+	if added.Delete || len(added.Permission) != 1 || added.Permission[0].Ips != "10.20.30.0/24" ||
+		added.Permission[0].Permissions != vsanfstypes.VsanFileShareAccessTypeREAD_ONLY ||
+		added.Permission[0].AllowRoot {
+		t.Errorf("unexpected net permissions to be configured: %+v", added)
+	}
+
+	spec = GetNetPermissionsACLSpec("volume-1", netPermissions, []string{"10.20.30.0/24"})
+	if len(spec.AccessControlSpecList) != 1 || spec.AccessControlSpecList[0].Delete {
+		t.Errorf("expected no client IPs to be removed, got %+v", spec.AccessControlSpecList)
+	}
+}
+
+func TestGetScopedNetPermissions(t *testing.T) {
+	ctx := context.Background()
+	csiNamespace := GetCSINamespace()
+	k8sClient := fake.NewSimpleClientset(
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "sc-netpermissions", Namespace: csiNamespace},
+			Data: map[string]string{NetPermissionsDataKey: `[NetPermissions "A"]
+ips = "10.20.0.0/16"
+permissions = "READ_ONLY"`},
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "sc-netpermissions", Namespace: csiNamespace},
+			Data: map[string][]byte{NetPermissionsDataKey: []byte(`[NetPermissions "B"]
+ips = "10.20.0.0/16"
+permissions = "READ_WRITE"
+[NetPermissions "C"]
+ips = "10.30.0.0/16"
+permissions = "READ_ONLY"`)},
+		},
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "ns-netpermissions", Namespace: csiNamespace},
+			Data: map[string]string{NetPermissionsDataKey: `[NetPermissions "D"]
+ips = "*"
+permissions = "NO_ACCESS"
+[NetPermissions "E"]
+ips = "10.20.30.0/24"
+permissions = "READ_ONLY"`},
+		},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "scoped",
+			Annotations: map[string]string{NetPermissionsNamespaceAnnotationKey: "ns-netpermissions"}}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "unscoped"}},
+	)
+	source := NewClientNetPermissionsSource(k8sClient)
+	global := map[string]*cnsconfig.NetPermissionConfig{
+		"#": {Ips: "*", Permissions: vsanfstypes.VsanFileShareAccessTypeREAD_WRITE},
+	}
+	readOnly := vsanfstypes.VsanFileShareAccessTypeREAD_ONLY
+	readWrite := vsanfstypes.VsanFileShareAccessTypeREAD_WRITE
+	noAccess := vsanfstypes.VsanFileShareAccessTypeNO_ACCESS
+
+	tests := []struct {
+		name      string
+		configMap string
+		secret    string
+		namespace string
+		expected  map[string]vsanfstypes.VsanFileShareAccessType
+	}{
+		{name: "Global", namespace: "unscoped",
+			expected: map[string]vsanfstypes.VsanFileShareAccessType{"*": readWrite}},
+		{name: "StorageClassConfigMap", configMap: "sc-netpermissions", namespace: "unscoped",
+			expected: map[string]vsanfstypes.VsanFileShareAccessType{"*": readWrite, "10.20.0.0/16": readOnly}},
+		{name: "StorageClassConfigMapAndSecret", configMap: "sc-netpermissions", secret: "sc-netpermissions",
+			expected: map[string]vsanfstypes.VsanFileShareAccessType{"*": readWrite, "10.20.0.0/16": readWrite,
+				"10.30.0.0/16": readOnly}},
+		{name: "Namespace", configMap: "sc-netpermissions", secret: "sc-netpermissions", namespace: "scoped",
+			expected: map[string]vsanfstypes.VsanFileShareAccessType{"*": noAccess, "10.20.0.0/16": readWrite,
+				"10.30.0.0/16": readOnly, "10.20.30.0/24": readOnly}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			netPermissions, err := GetScopedNetPermissions(ctx, source, global, test.configMap, test.secret,
+				test.namespace)
+			if err != nil {
+				t.Fatalf("failed to get scoped net permissions: %v", err)
+			}
+			permissions := make(map[string]vsanfstypes.VsanFileShareAccessType)
+			for ips, netPerm := range netPermissions {
+				permissions[ips] = netPerm.Permissions
+			}
+			if !reflect.DeepEqual(permissions, test.expected) {
+				t.Errorf("expected net permissions %v, got %v", test.expected, permissions)
+			}
+		})
+	}
+
+	_, err := GetScopedNetPermissions(ctx, source, global, "missing", "", "")
+	if err == nil {
+		t.Errorf("expected an error for a missing ConfigMap")
+	}
+}
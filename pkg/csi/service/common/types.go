@@ -52,6 +52,10 @@ var (
 
 	// ErrNotFound represents not found error
 	ErrNotFound = errors.New("not found")
+
+	// ErrNoMatchingDatastores is wrapped by the errors returned when none of
+	// the datastores of a vCenter can be used to create a volume
+	ErrNoMatchingDatastores = errors.New("no matching datastores")
 )
 
 // Manager type comprises VirtualCenterConfig, CnsConfig, VolumeManager and VirtualCenterManager
@@ -87,6 +91,9 @@ type CreateVolumeSpec struct {
 	VolumeType              string
 	VsanDirectDatastoreURL  string // Datastore URL from vSan direct storage pool
 	ContentSourceSnapshotID string // SnapshotID from VolumeContentSource in CreateVolumeRequest
+	// NetPermissions overrides the net permissions from CnsConfig for file
+	// volumes, when set.
+	NetPermissions map[string]*config.NetPermissionConfig
 }
 
 // StorageClassParams represents the storage class parameterss
@@ -95,4 +102,10 @@ type StorageClassParams struct {
 	StoragePolicyName string
 	CSIMigration      string
 	Datastore         string
+	// NetPermissionsConfigMap and NetPermissionsSecret are the names of the
+	// ConfigMap and Secret in the CSI namespace holding net permissions.
+	NetPermissionsConfigMap string
+	NetPermissionsSecret    string
+	// PvcNamespace is the namespace of the PVC being provisioned.
+	PvcNamespace string
 }
@@ -235,6 +235,8 @@ func ParseStorageClassParams(ctx context.Context, params map[string]string,
 				scParams.StoragePolicyName = value
 			} else if param == AttributeFsType {
 				log.Warnf("param 'fstype' is deprecated, please use 'csi.storage.k8s.io/fstype' instead")
+			} else if isNetPermissionsParam(scParams, param, value) {
+				continue
 			} else {
 				return nil, fmt.Errorf("invalid param: %q and value: %q", param, value)
 			}
@@ -251,6 +253,8 @@ func ParseStorageClassParams(ctx context.Context, params map[string]string,
 				log.Warnf("param 'fstype' is deprecated, please use 'csi.storage.k8s.io/fstype' instead")
 			} else if param == CSIMigrationParams {
 				scParams.CSIMigration = value
+			} else if isNetPermissionsParam(scParams, param, value) {
+				continue
 			} else {
 				otherParams[param] = value
 			}
@@ -282,6 +286,116 @@ func ParseStorageClassParams(ctx context.Context, params map[string]string,
 	return scParams, nil
 }
 
+// isNetPermissionsParam checks if the given StorageClass param is used for
+// scoped net permissions and sets it on the StorageClassParams.
+func isNetPermissionsParam(scParams *StorageClassParams, param string, value string) bool {
+	switch param {
+	case AttributeNetPermissionsConfigMap:
+		scParams.NetPermissionsConfigMap = value
+	case AttributeNetPermissionsSecret:
+		scParams.NetPermissionsSecret = value
+	case AttributePvcNamespace:
+		scParams.PvcNamespace = value
+	case AttributePvcName, AttributePvName:
+	default:
+		return false
+	}
+	return true
+}
+
+// ValidateFileVolumeMountFlags validates the NFS mount options given in the
+// StorageClass or PV for a file volume and returns the NFS version requested
+// by them, NfsVersion41 by default. The NFS version is used to choose the
+// access point of the file share, version 3 selecting the NFSv3 one. Options
+// not listed below are passed to the mount as is.
+func ValidateFileVolumeMountFlags(mountFlags []string) (string, error) {
+	nfsVersion := ""
+	hasHard, hasSoft := false, false
+	for _, flag := range SplitMountFlags(mountFlags) {
+		key, value, hasValue := strings.Cut(flag, "=")
+		switch key {
+		case "vers", "nfsvers":
+			version := NfsVersion41
+			if value == NfsVersion3 || value == "3.0" {
+				version = NfsVersion3
+			} else if value != "4" && value != NfsVersion41 {
+				return "", fmt.Errorf("unsupported NFS version %q in mount option %q, "+
+					"only 3 and 4.1 are supported", value, flag)
+			}
+			if nfsVersion != "" && nfsVersion != version {
+				return "", fmt.Errorf("conflicting NFS versions in mount options %v", mountFlags)
+			}
+			nfsVersion = version
+		case "minorversion":
+			if value != "1" {
+				return "", fmt.Errorf("unsupported NFS minor version in mount option %q", flag)
+			}
+		case "rsize", "wsize":
+			size, err := strconv.Atoi(value)
+			if err != nil || size < 1024 || size > 1048576 || size%1024 != 0 {
+				return "", fmt.Errorf("invalid mount option %q, %s must be a multiple of 1024 "+
+					"between 1024 and 1048576", flag, key)
+			}
+		case "timeo", "retrans":
+			num, err := strconv.Atoi(value)
+			if err != nil || num < 1 {
+				return "", fmt.Errorf("invalid mount option %q, %s must be a positive integer", flag, key)
+			}
+		case "nconnect":
+			num, err := strconv.Atoi(value)
+			if err != nil || num < 1 || num > 16 {
+				return "", fmt.Errorf("invalid mount option %q, nconnect must be between 1 and 16", flag)
+			}
+		case "proto":
+			if value != "tcp" {
+				return "", fmt.Errorf("unsupported mount option %q, only tcp is supported", flag)
+			}
+		case "hard", "soft":
+			if hasValue {
+				return "", fmt.Errorf("invalid mount option %q", flag)
+			}
+			hasHard = hasHard || key == "hard"
+			hasSoft = hasSoft || key == "soft"
+		}
+	}
+	if hasHard && hasSoft {
+		return "", fmt.Errorf("conflicting mount options hard and soft in %v", mountFlags)
+	}
+	if nfsVersion == "" {
+		nfsVersion = NfsVersion41
+	}
+	for _, flag := range SplitMountFlags(mountFlags) {
+		if strings.HasPrefix(flag, "minorversion=") && nfsVersion == NfsVersion3 {
+			return "", fmt.Errorf("mount option %q is not supported with NFS version 3", flag)
+		}
+	}
+	return nfsVersion, nil
+}
+
+// GetNfsAccessPointKeys returns the key of the access point of the given NFS
+// version in the file share backing details and the key of the access point
+// in the publish context.
+func GetNfsAccessPointKeys(nfsVersion string) (string, string) {
+	if nfsVersion == NfsVersion3 {
+		return Nfsv3AccessPointKey, Nfsv3AccessPoint
+	}
+	return Nfsv4AccessPointKey, Nfsv4AccessPoint
+}
+
+// SplitMountFlags splits the mount flags which hold several comma separated
+// options and returns the individual options.
+func SplitMountFlags(mountFlags []string) []string {
+	var flags []string
+	for _, mountFlag := range mountFlags {
+		for _, flag := range strings.Split(mountFlag, ",") {
+			if flag = strings.TrimSpace(flag); flag != "" {
+				flags = append(flags, flag)
+			}
+		}
+	}
+	return flags
+}
+
 // GetConfigPath returns ConfigPath depending on the environment variable
 // specified and the cluster flavor set.
 func GetConfigPath(ctx context.Context) string {
@@ -306,6 +420,15 @@ func GetConfigPath(ctx context.Context) string {
 	return cfgPath
 }
 
+// ValidateConfigFile validates the config file of the driver, at the path
+// returned by GetConfigPath, and returns the path along with all the errors
+// found in it.
+func ValidateConfigFile(ctx context.Context) (string, []error) {
+	cfgPath := GetConfigPath(ctx)
+	guestCluster := cnstypes.CnsClusterFlavor(os.Getenv(csitypes.EnvClusterFlavor)) == cnstypes.CnsClusterFlavorGuest
+	return cfgPath, cnsconfig.ValidateConfigFile(ctx, cfgPath, guestCluster)
+}
+
 // GetConfig loads configuration from secret and returns config object.
 func GetConfig(ctx context.Context) (*cnsconfig.Config, error) {
 	var cfg *cnsconfig.Config
@@ -389,6 +512,31 @@ func ConvertVolumeHealthStatus(ctx context.Context, volID string, volHealthStatu
 	}
 }
 
+// ConvertDetailedVolumeHealthStatus converts the volume health status, along
+// with the storage policy compliance status and whether the volume is being
+// resynchronized, into the volume health states used when the
+// RichVolumeHealth FSS is enabled. An accessible volume which is being
+// resynchronized is reported as resyncing, one which has lost redundancy as
+// reduced-availability and one which does not comply with its storage policy
+// as non-compliant, in that order of precedence.
+func ConvertDetailedVolumeHealthStatus(ctx context.Context, volID string, volHealthStatus string,
+	complianceStatus string, resyncing bool) (string, error) {
+	status, err := ConvertVolumeHealthStatus(ctx, volID, volHealthStatus)
+	if err != nil || status != VolHealthStatusAccessible {
+		return status, err
+	}
+	if resyncing {
+		return VolHealthStatusResyncing, nil
+	}
+	if volHealthStatus == string(pbmtypes.PbmHealthStatusForEntityYellow) {
+		return VolHealthStatusReducedAvailability, nil
+	}
+	if complianceStatus == string(pbmtypes.PbmComplianceStatusNonCompliant) {
+		return VolHealthStatusNonCompliant, nil
+	}
+	return VolHealthStatusAccessible, nil
+}
+
 // ParseCSISnapshotID parses the SnapshotID from CSI RPC such as DeleteSnapshot, CreateVolume from snapshot
 // into a pair of CNS VolumeID and CNS SnapshotID.
 func ParseCSISnapshotID(csiSnapshotID string) (string, string, error) {
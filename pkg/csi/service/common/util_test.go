@@ -444,3 +444,61 @@ func TestParseCSISnapshotID(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateFileVolumeMountFlags(t *testing.T) {
+	tests := []struct {
+		mountFlags []string
+		nfsVersion string
+		valid      bool
+	}{
+		{nil, NfsVersion41, true},
+		{[]string{"rsize=1048576", "wsize=1048576", "soft", "timeo=600", "nconnect=4"}, NfsVersion41, true},
+		{[]string{"nfsvers=3,hard", "noatime"}, NfsVersion3, true},
+		{[]string{"vers=4.1", "minorversion=1"}, NfsVersion41, true},
+		{[]string{"vers=3.0", "proto=tcp"}, NfsVersion3, true},
+		{[]string{"vers=3", "minorversion=1"}, "", false},
+		{[]string{"vers=4.2"}, "", false},
+		{[]string{"vers=3", "nfsvers=4"}, "", false},
+		{[]string{"rsize=1000"}, "", false},
+		{[]string{"hard", "soft"}, "", false},
+		{[]string{"nconnect=32"}, "", false},
+		{[]string{"timeo=0"}, "", false},
+		{[]string{"proto=udp"}, "", false},
+	}
+	for _, test := range tests {
+		nfsVersion, err := ValidateFileVolumeMountFlags(test.mountFlags)
+		if test.valid && (err != nil || nfsVersion != test.nfsVersion) {
+			t.Errorf("mount flags %v: expected NFS version %q, got %q with error %v",
+				test.mountFlags, test.nfsVersion, nfsVersion, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("mount flags %v: expected validation to fail", test.mountFlags)
+		}
+	}
+}
+
+func TestConvertDetailedVolumeHealthStatus(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		healthStatus     string
+		complianceStatus string
+		resyncing        bool
+		expected         string
+	}{
+		{"green", "compliant", false, VolHealthStatusAccessible},
+		{"green", "nonCompliant", false, VolHealthStatusNonCompliant},
+		{"yellow", "nonCompliant", false, VolHealthStatusReducedAvailability},
+		{"yellow", "compliant", true, VolHealthStatusResyncing},
+		{"red", "nonCompliant", true, VolHealthStatusInaccessible},
+		{"unknown", "compliant", false, "unknown"},
+		{"", "", false, VolHealthStatusInaccessible},
+	}
+	for _, test := range tests {
+		status, err := ConvertDetailedVolumeHealthStatus(ctx, "vol-1", test.healthStatus,
+			test.complianceStatus, test.resyncing)
+		if err != nil || status != test.expected {
+			t.Errorf("health %q, compliance %q, resyncing %v: expected %q, got %q with error %v",
+				test.healthStatus, test.complianceStatus, test.resyncing, test.expected, status, err)
+		}
+	}
+}
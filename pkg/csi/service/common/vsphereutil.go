@@ -28,7 +28,6 @@ import (
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/mo"
 	vim25types "github.com/vmware/govmomi/vim25/types"
-	vsanfstypes "github.com/vmware/govmomi/vsan/vsanfs/types"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -177,7 +176,7 @@ func CreateBlockVolumeUtil(ctx context.Context, clusterFlavor cnstypes.CnsCluste
 		clusterID = manager.CnsConfig.Global.SupervisorID
 	}
 	containerCluster := vsphere.GetContainerCluster(clusterID,
-		manager.CnsConfig.VirtualCenter[vc.Config.Host].User, clusterFlavor,
+		vsphere.GetUsername(vc.Config.Host, manager.CnsConfig.VirtualCenter[vc.Config.Host].User), clusterFlavor,
 		manager.CnsConfig.Global.ClusterDistribution)
 	containerClusterArray = append(containerClusterArray, containerCluster)
 	createSpec := &cnstypes.CnsVolumeCreateSpec{
@@ -316,22 +315,19 @@ func CreateFileVolumeUtil(ctx context.Context, clusterFlavor cnstypes.CnsCluster
 		if !isFound {
 			// TODO: Need to figure out which fault need to be returned when datastoreURL is not specified in
 			// storage class. Currently, just return csi.fault.Internal.
-			return "", csifault.CSIInternalFault, logger.LogNewErrorf(log,
-				"CSI user doesn't have permission on the datastore: %s specified in storage class",
-				spec.ScParams.DatastoreURL)
+			return "", csifault.CSIInternalFault, fmt.Errorf("%w: %v", ErrNoMatchingDatastores,
+				logger.LogNewErrorf(log, "CSI user doesn't have permission on the datastore: %s specified in "+
+					"storage class", spec.ScParams.DatastoreURL))
 		}
 	}
 
-	// Retrieve net permissions from CnsConfig of manager and convert to required
-	// format.
-	netPerms := make([]vsanfstypes.VsanFileShareNetPermission, 0)
-	for _, netPerm := range manager.CnsConfig.NetPermissions {
-		netPerms = append(netPerms, vsanfstypes.VsanFileShareNetPermission{
-			Ips:         netPerm.Ips,
-			Permissions: netPerm.Permissions,
-			AllowRoot:   !netPerm.RootSquash,
-		})
+	// Retrieve net permissions from the spec, or else from CnsConfig of manager,
+	// and convert to required format.
+	netPermissions := manager.CnsConfig.NetPermissions
+	if spec.NetPermissions != nil {
+		netPermissions = spec.NetPermissions
 	}
+	netPerms := GetVsanFileShareNetPermissions(netPermissions)
 
 	clusterID := manager.CnsConfig.Global.ClusterID
 	if useSupervisorId {
@@ -339,7 +335,7 @@ func CreateFileVolumeUtil(ctx context.Context, clusterFlavor cnstypes.CnsCluster
 	}
 	var containerClusterArray []cnstypes.CnsContainerCluster
 	containerCluster := vsphere.GetContainerCluster(clusterID,
-		manager.CnsConfig.VirtualCenter[vc.Config.Host].User, clusterFlavor,
+		vsphere.GetUsername(vc.Config.Host, manager.CnsConfig.VirtualCenter[vc.Config.Host].User), clusterFlavor,
 		manager.CnsConfig.Global.ClusterDistribution)
 	containerClusterArray = append(containerClusterArray, containerCluster)
 	createSpec := &cnstypes.CnsVolumeCreateSpec{
@@ -446,14 +442,14 @@ func CreateFileVolumeUtilOld(ctx context.Context, clusterFlavor cnstypes.CnsClus
 			if len(datastores) == 0 {
 				// TODO: Need to figure out which fault need to be returned if no file service enabled vsan datatore is present.
 				// Currently, just return csi.fault.Internal.
-				return "", csifault.CSIInternalFault,
-					logger.LogNewError(log, "no file service enabled vsan datastore is present in the environment")
+				return "", csifault.CSIInternalFault, fmt.Errorf("%w: %v", ErrNoMatchingDatastores,
+					logger.LogNewError(log, "no file service enabled vsan datastore is present in the environment"))
 			}
 		} else {
 			// If DatastoreURL is not specified in StorageClass, get all datastores
 			// from TargetvSANFileShareDatastoreURLs in vcenter configuration.
 			for _, TargetvSANFileShareDatastoreURL := range manager.VcenterConfig.TargetvSANFileShareDatastoreURLs {
-				datastoreInfoObj, err := getDatastoreInfoObj(ctx, vc, TargetvSANFileShareDatastoreURL)
+				datastoreInfoObj, err := GetDatastoreInfoObj(ctx, vc, TargetvSANFileShareDatastoreURL)
 				if err != nil {
 					log.Errorf("failed to get datastore %s. Error: %+v", TargetvSANFileShareDatastoreURL, err)
 					// TODO: Need to figure out the fault extracted from getDatastore.
@@ -466,8 +462,8 @@ func CreateFileVolumeUtilOld(ctx context.Context, clusterFlavor cnstypes.CnsClus
 				datastores = append(datastores, datastoreInfoObj.Datastore.Reference())
 			}
 			if len(datastores) == 0 {
-				return "", csifault.CSIInternalFault,
-					logger.LogNewError(log, "no compatible datastore found")
+				return "", csifault.CSIInternalFault, fmt.Errorf("%w: %v", ErrNoMatchingDatastores,
+					logger.LogNewError(log, "no compatible datastore found"))
 			}
 		}
 
@@ -476,7 +472,7 @@ func CreateFileVolumeUtilOld(ctx context.Context, clusterFlavor cnstypes.CnsClus
 		// is empty. If true, create the file volume on the datastoreUrl set in
 		// storage class.
 		if len(manager.VcenterConfig.TargetvSANFileShareDatastoreURLs) == 0 {
-			datastoreInfoObj, err := getDatastoreInfoObj(ctx, vc, spec.ScParams.DatastoreURL)
+			datastoreInfoObj, err := GetDatastoreInfoObj(ctx, vc, spec.ScParams.DatastoreURL)
 			if err != nil {
 				log.Errorf("failed to get datastore %q. Error: %+v", spec.ScParams.DatastoreURL, err)
 				// TODO: Need to figure out the fault extracted from getDatastore.
@@ -484,8 +480,9 @@ func CreateFileVolumeUtilOld(ctx context.Context, clusterFlavor cnstypes.CnsClus
 				return "", csifault.CSIInternalFault, err
 			}
 			if filterSuspendedDatastores && vsphere.IsVolumeCreationSuspended(ctx, datastoreInfoObj) {
-				return "", csifault.CSIInternalFault, logger.LogNewErrorf(log,
-					"volume creation is suspended on Datastore URL %q", spec.ScParams.DatastoreURL)
+				return "", csifault.CSIInternalFault, fmt.Errorf("%w: %v", ErrNoMatchingDatastores,
+					logger.LogNewErrorf(log, "volume creation is suspended on Datastore URL %q",
+						spec.ScParams.DatastoreURL))
 			}
 			datastores = append(datastores, datastoreInfoObj.Datastore.Reference())
 		} else {
@@ -501,11 +498,11 @@ func CreateFileVolumeUtilOld(ctx context.Context, clusterFlavor cnstypes.CnsClus
 			if !found {
 				// TODO: Need to figure out which fault need to be returned when datastoreURL is not in the allowed list.
 				// Currently, return csi.fault.Internal.
-				return "", csifault.CSIInternalFault, logger.LogNewErrorf(log,
-					"Datastore URL %q specified in storage class is not in the allowed list %+v",
-					spec.ScParams.DatastoreURL, manager.VcenterConfig.TargetvSANFileShareDatastoreURLs)
+				return "", csifault.CSIInternalFault, fmt.Errorf("%w: %v", ErrNoMatchingDatastores,
+					logger.LogNewErrorf(log, "Datastore URL %q specified in storage class is not in the allowed list %+v",
+						spec.ScParams.DatastoreURL, manager.VcenterConfig.TargetvSANFileShareDatastoreURLs))
 			}
-			datastoreInfoObj, err := getDatastoreInfoObj(ctx, vc, spec.ScParams.DatastoreURL)
+			datastoreInfoObj, err := GetDatastoreInfoObj(ctx, vc, spec.ScParams.DatastoreURL)
 			if err != nil {
 				log.Errorf("failed to get datastore %q. Error: %+v", spec.ScParams.DatastoreURL, err)
 				// TODO: Need to figure out the fault extracted from getDatastore.
@@ -513,30 +510,28 @@ func CreateFileVolumeUtilOld(ctx context.Context, clusterFlavor cnstypes.CnsClus
 				return "", csifault.CSIInternalFault, err
 			}
 			if filterSuspendedDatastores && vsphere.IsVolumeCreationSuspended(ctx, datastoreInfoObj) {
-				return "", csifault.CSIInternalFault, logger.LogNewErrorf(log,
-					"volume creation is suspended on Datastore URL %q", spec.ScParams.DatastoreURL)
+				return "", csifault.CSIInternalFault, fmt.Errorf("%w: %v", ErrNoMatchingDatastores,
+					logger.LogNewErrorf(log, "volume creation is suspended on Datastore URL %q",
+						spec.ScParams.DatastoreURL))
 			}
 			datastores = append(datastores, datastoreInfoObj.Datastore.Reference())
 		}
 	}
 
-	// Retrieve net permissions from CnsConfig of manager and convert to required
-	// format.
-	netPerms := make([]vsanfstypes.VsanFileShareNetPermission, 0)
-	for _, netPerm := range manager.CnsConfig.NetPermissions {
-		netPerms = append(netPerms, vsanfstypes.VsanFileShareNetPermission{
-			Ips:         netPerm.Ips,
-			Permissions: netPerm.Permissions,
-			AllowRoot:   !netPerm.RootSquash,
-		})
+	// Retrieve net permissions from the spec, or else from CnsConfig of manager,
+	// and convert to required format.
+	netPermissions := manager.CnsConfig.NetPermissions
+	if spec.NetPermissions != nil {
+		netPermissions = spec.NetPermissions
 	}
+	netPerms := GetVsanFileShareNetPermissions(netPermissions)
 	clusterID := manager.CnsConfig.Global.ClusterID
 	if useSupervisorId {
 		clusterID = manager.CnsConfig.Global.SupervisorID
 	}
 	var containerClusterArray []cnstypes.CnsContainerCluster
 	containerCluster := vsphere.GetContainerCluster(clusterID,
-		manager.CnsConfig.VirtualCenter[vc.Config.Host].User, clusterFlavor,
+		vsphere.GetUsername(vc.Config.Host, manager.CnsConfig.VirtualCenter[vc.Config.Host].User), clusterFlavor,
 		manager.CnsConfig.Global.ClusterDistribution)
 	containerClusterArray = append(containerClusterArray, containerCluster)
 	createSpec := &cnstypes.CnsVolumeCreateSpec{
@@ -969,9 +964,9 @@ func getDatastoreMoRefs(datastores []*vsphere.DatastoreInfo) []vim25types.Manage
 	return datastoreMoRefs
 }
 
-// Helper function to get DatastoreInfo object for given datastoreURL in the given
-// virtual center.
-func getDatastoreInfoObj(ctx context.Context, vc *vsphere.VirtualCenter,
+// GetDatastoreInfoObj gets the DatastoreInfo object for given datastoreURL in
+// the given virtual center.
+func GetDatastoreInfoObj(ctx context.Context, vc *vsphere.VirtualCenter,
 	datastoreURL string) (*vsphere.DatastoreInfo, error) {
 	log := logger.GetLogger(ctx)
 	datacenters, err := vc.GetDatacenters(ctx)
@@ -989,8 +984,8 @@ func getDatastoreInfoObj(ctx context.Context, vc *vsphere.VirtualCenter,
 		}
 	}
 
-	return nil, logger.LogNewErrorf(log,
-		"Unable to find datastore for datastore URL %s in VC %+v", datastoreURL, vc)
+	return nil, fmt.Errorf("%w: %v", ErrNoMatchingDatastores, logger.LogNewErrorf(log,
+		"Unable to find datastore for datastore URL %s in VC %+v", datastoreURL, vc))
 }
 
 // isExpansionRequired verifies if the requested size to expand a volume is
@@ -1036,23 +1031,24 @@ func isExpansionRequired(ctx context.Context, volumeID string, requestedSize int
 //
 // The returned string is a combination of CNS VolumeID and CNS SnapshotID concatenated by the "+" sign.
 // The returned *time.Time denotes the creation time of snapshot from the storage system, i.e., CNS.
+// The returned fault type is set when the snapshot creation fails.
 func CreateSnapshotUtil(ctx context.Context, manager *Manager, volumeID string, snapshotName string) (string,
-	*time.Time, error) {
+	*time.Time, string, error) {
 	log := logger.GetLogger(ctx)
 
 	log.Debugf("vSphere CSI driver is creating snapshot with description, %q, on volume: %q", snapshotName, volumeID)
-	cnsSnapshotInfo, err := manager.VolumeManager.CreateSnapshot(ctx, volumeID, snapshotName)
+	cnsSnapshotInfo, faultType, err := manager.VolumeManager.CreateSnapshot(ctx, volumeID, snapshotName)
 	if err != nil {
 		log.Errorf("failed to create snapshot on volume %q with description %q with error %+v",
 			volumeID, snapshotName, err)
-		return "", nil, err
+		return "", nil, faultType, err
 	}
 	log.Debugf("Successfully created snapshot %q with description, %q, on volume: %q at timestamp %q",
 		cnsSnapshotInfo.SnapshotID, snapshotName, volumeID, cnsSnapshotInfo.SnapshotCreationTimestamp)
 
 	csiSnapshotID := volumeID + VSphereCSISnapshotIdDelimiter + cnsSnapshotInfo.SnapshotID
 
-	return csiSnapshotID, &cnsSnapshotInfo.SnapshotCreationTimestamp, nil
+	return csiSnapshotID, &cnsSnapshotInfo.SnapshotCreationTimestamp, "", nil
 }
 
 // DeleteSnapshotUtil is the helper function to delete CNS snapshot for given snapshotId
@@ -1119,7 +1115,7 @@ func GetNodeVMsWithAccessToDatastore(ctx context.Context, vc *vsphere.VirtualCen
 	}
 
 	// Get datastore object.
-	dsInfoObj, err := getDatastoreInfoObj(ctx, vc, dsURL)
+	dsInfoObj, err := GetDatastoreInfoObj(ctx, vc, dsURL)
 	if err != nil {
 		return nil, logger.LogNewErrorf(log, "failed to retrieve datastore object using datastore "+
 			"URL %q. Error: %+v", dsURL, err)
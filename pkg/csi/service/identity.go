@@ -20,6 +20,8 @@ import (
 	"context"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
 	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
 )
 
@@ -30,7 +32,14 @@ func (driver *vsphereCSIDriver) Probe(
 	ctx context.Context,
 	req *csi.ProbeRequest) (
 	*csi.ProbeResponse, error) {
-
+	ctx = logger.NewContextWithLogger(ctx)
+	// The controllers which can check their readiness, e.g. their vCenter
+	// connectivity, report not ready when the check fails.
+	if checker, ok := driver.cnscs.(csitypes.HealthChecker); ok {
+		if err := checker.CheckReadiness(ctx); err != nil {
+			return nil, err
+		}
+	}
 	return &csi.ProbeResponse{}, nil
 }
 
@@ -4,8 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
+	vim25types "github.com/vmware/govmomi/vim25/types"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc/codes"
@@ -24,21 +28,82 @@ const (
 	EnvLoggerLevel = "LOGGER_LEVEL"
 	// LogCtxIDKey holds the TraceId for log.
 	LogCtxIDKey = "TraceId"
+	// LogOpIDKey holds the vCenter operation ID for log.
+	LogOpIDKey = "OpId"
+	// opIDPrefix is the prefix of the vCenter operation IDs set by the driver.
+	opIDPrefix = "csi-"
 )
 
-var defaultLogLevel LogLevel
+var (
+	defaultLogLevel LogLevel
+	// atomicLevel is the minimum level logged by all the loggers. It can be
+	// changed at runtime.
+	atomicLevel = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	// startupLevel is the minimum level set at startup, which is restored
+	// when a runtime override is removed.
+	startupLevel = zapcore.InfoLevel
+)
 
 // loggerKey holds the context key used for loggers.
 type loggerKey struct{}
 
 // SetLoggerLevel helps set defaultLogLevel, using which newLogger func helps
-// create either development logger or production logger
+// create either development logger or production logger. DEVELOPMENT logs
+// from debug level. A zap level name (debug, info, warn, error) or number
+// (-1 to 2) creates a production logger logging from that level. Any other
+// value creates a production logger logging from info level.
 func SetLoggerLevel(logLevel LogLevel) {
 	defaultLogLevel = logLevel
-	if logLevel != ProductionLogLevel && logLevel != DevelopmentLogLevel {
+	level := zapcore.InfoLevel
+	if logLevel == DevelopmentLogLevel {
+		level = zapcore.DebugLevel
+	} else if logLevel != ProductionLogLevel {
 		defaultLogLevel = ProductionLogLevel
+		if parsedLevel, err := ParseLevel(string(logLevel)); err == nil {
+			level = parsedLevel
+		}
+	}
+	startupLevel = level
+	atomicLevel.SetLevel(level)
+	GetLoggerWithNoContext().Infof("Setting default log level to :%q, logging from %s level",
+		defaultLogLevel, level)
+}
+
+// ParseLevel returns the zap level with the given name or number.
+func ParseLevel(level string) (zapcore.Level, error) {
+	var zapLevel zapcore.Level
+	if number, err := strconv.Atoi(strings.TrimSpace(level)); err == nil {
+		if number < int(zapcore.DebugLevel) || number > int(zapcore.FatalLevel) {
+			return zapLevel, fmt.Errorf("invalid log level %q", level)
+		}
+		return zapcore.Level(number), nil
 	}
-	GetLoggerWithNoContext().Infof("Setting default log level to :%q", defaultLogLevel)
+	if err := zapLevel.UnmarshalText([]byte(strings.ToLower(strings.TrimSpace(level)))); err != nil {
+		return zapLevel, fmt.Errorf("invalid log level %q", level)
+	}
+	return zapLevel, nil
+}
+
+// SetLevel changes the minimum level logged by all the loggers at runtime.
+// An empty level restores the level set at startup.
+func SetLevel(level string) error {
+	zapLevel := startupLevel
+	if level != "" {
+		var err error
+		if zapLevel, err = ParseLevel(level); err != nil {
+			return err
+		}
+	}
+	if zapLevel != atomicLevel.Level() {
+		atomicLevel.SetLevel(zapLevel)
+		GetLoggerWithNoContext().Infof("Changed log level to %s", zapLevel)
+	}
+	return nil
+}
+
+// GetLevel returns the minimum level currently logged.
+func GetLevel() zapcore.Level {
+	return atomicLevel.Level()
 }
 
 // getLogger returns the logger associated with the given context.
@@ -57,10 +122,26 @@ func GetLogger(ctx context.Context) *zap.SugaredLogger {
 }
 
 // NewContextWithLogger returns a new child context with context UUID set
-// using key CtxId.
+// using key CtxId. If the context holds a span, the trace ID of the span is
+// used, so that the logs of a request can be found from its trace.
+// The context also holds a vCenter operation ID derived from the context
+// UUID, which govmomi sends with every vCenter call made with the context, so
+// that the vCenter logs of a request can be matched with the driver logs.
 func NewContextWithLogger(ctx context.Context) context.Context {
-	newCtx := withFields(ctx, zap.String(LogCtxIDKey, uuid.New().String()))
-	return newCtx
+	traceID := uuid.New().String()
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		traceID = spanContext.TraceID().String()
+	}
+	opID := opIDPrefix + traceID
+	newCtx := withFields(ctx, zap.String(LogCtxIDKey, traceID), zap.String(LogOpIDKey, opID))
+	return context.WithValue(newCtx, vim25types.ID{}, opID)
+}
+
+// GetOpID returns the vCenter operation ID held by the given context, or an
+// empty string if there is none.
+func GetOpID(ctx context.Context) string {
+	opID, _ := ctx.Value(vim25types.ID{}).(string)
+	return opID
 }
 
 // GetNewContextWithLogger creates a new context with context UUID and logger
@@ -80,9 +161,12 @@ func withFields(ctx context.Context, fields ...zapcore.Field) context.Context {
 func newLogger() *zap.Logger {
 	var logger *zap.Logger
 	if defaultLogLevel == DevelopmentLogLevel {
-		logger, _ = zap.NewDevelopment()
+		loggerConfig := zap.NewDevelopmentConfig()
+		loggerConfig.Level = atomicLevel
+		logger, _ = loggerConfig.Build()
 	} else {
 		loggerConfig := zap.NewProductionConfig()
+		loggerConfig.Level = atomicLevel
 		loggerConfig.EncoderConfig.TimeKey = "time"
 		loggerConfig.EncoderConfig.EncodeTime = zapcore.RFC3339NanoTimeEncoder
 		logger, _ = loggerConfig.Build()
@@ -17,8 +17,11 @@ limitations under the License.
 package logger
 
 import (
+	"context"
+	"strings"
 	"testing"
 
+	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc/codes"
 )
 
@@ -54,6 +57,46 @@ func TestLogNewErrorCodef(t *testing.T) {
 	}
 }
 
+func TestNewContextWithLoggerSetsOpID(t *testing.T) {
+	ctx := NewContextWithLogger(context.Background())
+	opID := GetOpID(ctx)
+	if !strings.HasPrefix(opID, opIDPrefix) {
+		t.Errorf("expected opID with prefix %q, got %q", opIDPrefix, opID)
+	}
+	if childOpID := GetOpID(NewContextWithLogger(ctx)); childOpID == opID {
+		t.Errorf("expected a new opID for a new logger context, got %q", childOpID)
+	}
+	if opID := GetOpID(context.Background()); opID != "" {
+		t.Errorf("expected no opID without logger context, got %q", opID)
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	SetLoggerLevel("warn")
+	defer SetLoggerLevel(ProductionLogLevel)
+	if defaultLogLevel != ProductionLogLevel || GetLevel() != zapcore.WarnLevel {
+		t.Fatalf("expected production logger at warn level, got %q at %s", defaultLogLevel, GetLevel())
+	}
+	for level, expected := range map[string]zapcore.Level{
+		"debug": zapcore.DebugLevel,
+		"ERROR": zapcore.ErrorLevel,
+		"0":     zapcore.InfoLevel,
+		"-1":    zapcore.DebugLevel,
+		"":      zapcore.WarnLevel,
+	} {
+		if err := SetLevel(level); err != nil {
+			t.Errorf("failed to set log level %q. Err: %v", level, err)
+		} else if GetLevel() != expected {
+			t.Errorf("expected level %s for %q, got %s", expected, level, GetLevel())
+		}
+	}
+	for _, level := range []string{"verbose", "9"} {
+		if err := SetLevel(level); err == nil {
+			t.Errorf("expected an error for invalid log level %q", level)
+		}
+	}
+}
+
 func BenchmarkLogNewError(b *testing.B) {
 	log := GetLoggerWithNoContext()
 	b.ResetTimer()
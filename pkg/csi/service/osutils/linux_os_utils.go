@@ -50,6 +50,10 @@ const (
 // defaultFileMountOptions are the mount flag options used by default while publishing a file volume.
 var defaultFileMountOptions = []string{"hard", "sec=sys", "vers=4", "minorversion=1"}
 
+// defaultNfsv3FileMountOptions are the mount flag options used by default while
+// publishing a file volume over NFSv3.
+var defaultNfsv3FileMountOptions = []string{"hard", "sec=sys", "vers=3"}
+
 // NewOsUtils creates OsUtils with a linux specific mounter
 func NewOsUtils(ctx context.Context) (*OsUtils, error) {
 	log := logger.GetLogger(ctx)
@@ -562,17 +566,28 @@ func (osUtils *OsUtils) PublishFileVol(
 		}
 	}
 
+	// Validate the mount options from the StorageClass or PV and get the NFS
+	// version requested by them.
+	nfsVersion, err := common.ValidateFileVolumeMountFlags(mntFlags)
+	if err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"invalid mount options for file volume: %v", err)
+	}
 	// Check for read-only flag on Pod pvc spec.
 	if params.Ro {
 		mntFlags = append(mntFlags, "ro")
 	}
-	// Add defaultFileMountOptions to the mntFlags.
-	mntFlags = append(mntFlags, defaultFileMountOptions...)
+	// Add default file mount options not overridden by the mount options.
+	mntFlags = getFileMountFlags(mntFlags, nfsVersion)
 	// Retrieve the file share access point from publish context.
-	mntSrc, ok := req.GetPublishContext()[common.Nfsv4AccessPoint]
+	_, accessPoint := common.GetNfsAccessPointKeys(nfsVersion)
+	if nfsVersion == common.NfsVersion3 && fsType == common.NfsV4FsType {
+		fsType = common.NfsFsType
+	}
+	mntSrc, ok := req.GetPublishContext()[accessPoint]
 	if !ok {
-		return nil, logger.LogNewErrorCode(log, codes.Internal,
-			"nfs v4 accesspoint not set in publish context")
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"nfs v%s accesspoint not set in publish context", nfsVersion)
 	}
 	// Directly mount the file share volume to the pod. No bind mount required.
 	log.Debugf("PublishFileVolume: Attempting to mount %q to %q with fstype %q and mountflags %v",
@@ -585,6 +600,38 @@ func (osUtils *OsUtils) PublishFileVol(
 	return &csi.NodePublishVolumeResponse{}, nil
 }
 
+// getFileMountFlags returns the given mount flags for a file volume, as is,
+// along with the default file mount options for the given NFS version which
+// are not overridden by them.
+func getFileMountFlags(mntFlags []string, nfsVersion string) []string {
+	defaultOptions := defaultFileMountOptions
+	if nfsVersion == common.NfsVersion3 {
+		defaultOptions = defaultNfsv3FileMountOptions
+	}
+	overridden := make(map[string]bool)
+	var fileMntFlags []string
+	for _, flag := range common.SplitMountFlags(mntFlags) {
+		key, _, _ := strings.Cut(flag, "=")
+		switch key {
+		case "vers", "nfsvers":
+			// The version given in the mount options holds the minor version.
+			overridden["vers"] = true
+			overridden["minorversion"] = true
+		case "soft":
+			overridden["hard"] = true
+		}
+		overridden[key] = true
+		fileMntFlags = append(fileMntFlags, flag)
+	}
+	for _, flag := range defaultOptions {
+		key, _, _ := strings.Cut(flag, "=")
+		if !overridden[key] {
+			fileMntFlags = append(fileMntFlags, flag)
+		}
+	}
+	return fileMntFlags
+}
+
 // GetDevice returns a Device struct with info about the given device, or
 // an error if it doesn't exist or is not a block device.
 func (osUtils *OsUtils) GetDevice(path string) (*Device, error) {
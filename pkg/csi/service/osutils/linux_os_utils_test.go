@@ -6,6 +6,7 @@ package osutils
 import (
 	"context"
 	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -52,3 +53,44 @@ func TestUnescape(t *testing.T) {
 		})
 	}
 }
+
+func TestGetFileMountFlags(t *testing.T) {
+	tests := []struct {
+		mntFlags   []string
+		nfsVersion string
+		out        []string
+	}{
+		{
+			mntFlags:   nil,
+			nfsVersion: "4.1",
+			out:        []string{"hard", "sec=sys", "vers=4", "minorversion=1"},
+		},
+		{
+			mntFlags:   []string{"soft,timeo=100", "nfsvers=3", "ro"},
+			nfsVersion: "3",
+			out:        []string{"soft", "timeo=100", "nfsvers=3", "ro", "sec=sys"},
+		},
+		{
+			mntFlags:   []string{"vers=4.1", "rsize=65536", "sec=krb5"},
+			nfsVersion: "4.1",
+			out:        []string{"vers=4.1", "rsize=65536", "sec=krb5", "hard"},
+		},
+		{
+			mntFlags:   []string{"vers=4.2", "proto=udp"},
+			nfsVersion: "4.1",
+			out:        []string{"vers=4.2", "proto=udp", "hard", "sec=sys"},
+		},
+		{
+			mntFlags:   []string{"minorversion=2"},
+			nfsVersion: "4.1",
+			out:        []string{"minorversion=2", "hard", "sec=sys", "vers=4"},
+		},
+	}
+
+	for i, test := range tests {
+		out := getFileMountFlags(test.mntFlags, test.nfsVersion)
+		if strings.Join(out, ",") != strings.Join(test.out, ",") {
+			t.Errorf("%d: expected mount flags %v, got %v", i, test.out, out)
+		}
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package service
 
 import (
+	"context"
 	"net"
 	"os"
 	"strings"
@@ -24,6 +25,10 @@ import (
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/tracing"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
 
 	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
@@ -113,7 +118,8 @@ func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer,
 		return logger.LogNewErrorf(log, "failed to listen: %v", err)
 	}
 
-	server := grpc.NewServer()
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(tracing.UnaryServerInterceptor(),
+		unavailableOnCircuitOpenInterceptor))
 	s.server = server
 
 	// Register the CSI services.
@@ -156,3 +162,15 @@ func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer,
 	}
 	return nil
 }
+
+// unavailableOnCircuitOpenInterceptor returns codes.Unavailable for the RPCs
+// which failed because the circuit breaker of a vCenter is open, so that the
+// callers back off until vCenter is reachable again.
+func unavailableOnCircuitOpenInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if cnsvsphere.IsCircuitOpenError(err) {
+		return resp, status.Error(codes.Unavailable, err.Error())
+	}
+	return resp, err
+}
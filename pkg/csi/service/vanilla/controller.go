@@ -18,6 +18,7 @@ package vanilla
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"path/filepath"
@@ -37,6 +38,7 @@ import (
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/migration"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/audit"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/node"
 	cnsvolume "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
 	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
@@ -51,6 +53,7 @@ import (
 	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/cnsvolumeinfo"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/cnsvolumeoperationrequest"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
 )
 
 // NodeManagerInterface provides functionality to manage (VM) nodes.
@@ -120,6 +123,12 @@ func (c *controller) Init(config *cnsconfig.Config, version string) error {
 		common.MultiVCenterCSITopology)
 	isAuthCheckFSSEnabled := commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.CSIAuthCheck)
 
+	// Read the vCenter credentials from the referenced Secrets before
+	// connecting to vCenter.
+	if err := k8s.WatchCredentialsSecrets(ctx, config, common.GetCSINamespace()); err != nil {
+		log.Errorf("failed to watch the vCenter credentials Secrets. err=%v", err)
+		return err
+	}
 	vcManager := cnsvsphere.GetVirtualCenterManager(ctx)
 	if !multivCenterCSITopologyEnabled {
 		// Get VirtualCenterManager instance and validate version.
@@ -254,9 +263,8 @@ func (c *controller) Init(config *cnsconfig.Config, version string) error {
 						"Error:%+v", vcconfig.Host, err)
 				}
 				if isvSANFileServicesSupported {
-					for _, authMgr := range authMgrs {
-						go common.ComputeFSEnabledClustersToDsMap(authMgr, config.Global.CSIAuthCheckIntervalInMin)
-					}
+					go common.ComputeFSEnabledClustersToDsMap(authMgrs[vcconfig.Host],
+						config.Global.CSIAuthCheckIntervalInMin)
 				}
 			}
 		}
@@ -356,6 +364,7 @@ func (c *controller) Init(config *cnsconfig.Config, version string) error {
 		return err
 	}
 
+	c.registerHealthHandlers()
 	// Go module to keep the metrics http server running all the time.
 	go func() {
 		prometheus.CsiInfo.WithLabelValues(version).Set(1)
@@ -381,6 +390,11 @@ func (c *controller) ReloadConfiguration() error {
 	if err != nil {
 		return logger.LogNewErrorf(log, "failed to read config. Error: %+v", err)
 	}
+	username := cnsvsphere.GetUsername(c.manager.VcenterConfig.Host, c.manager.VcenterConfig.Username)
+	// Watch the credentials Secrets referenced by the new config.
+	if err = k8s.WatchCredentialsSecrets(ctx, cfg, common.GetCSINamespace()); err != nil {
+		return logger.LogNewErrorf(log, "failed to watch the vCenter credentials Secrets. err=%v", err)
+	}
 	newVCConfig, err := cnsvsphere.GetVirtualCenterConfig(ctx, cfg)
 	if err != nil {
 		log.Errorf("failed to get VirtualCenterConfig. err=%v", err)
@@ -389,7 +403,7 @@ func (c *controller) ReloadConfiguration() error {
 	if newVCConfig != nil {
 		var vcenter *cnsvsphere.VirtualCenter
 		if c.manager.VcenterConfig.Host != newVCConfig.Host ||
-			c.manager.VcenterConfig.Username != newVCConfig.Username ||
+			username != cnsvsphere.GetUsername(newVCConfig.Host, newVCConfig.Username) ||
 			c.manager.VcenterConfig.Password != newVCConfig.Password {
 
 			// Verify if new configuration has valid credentials by connecting to
@@ -902,12 +916,20 @@ func (c *controller) createFileVolume(ctx context.Context, req *csi.CreateVolume
 	*csi.CreateVolumeResponse, string, error) {
 	log := logger.GetLogger(ctx)
 	// Error out if TopologyRequirement is provided during file volume provisioning
-	// as this is not supported yet.
-	if req.GetAccessibilityRequirements() != nil {
+	// as this is only supported to select the vCenter in multi vCenter deployments.
+	if req.GetAccessibilityRequirements() != nil && !multivCenterCSITopologyEnabled {
 		return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument,
 			"volume topology feature for file volumes is not supported.")
 	}
 
+	// Validate the mount options from the StorageClass.
+	for _, volCap := range req.GetVolumeCapabilities() {
+		if _, err := common.ValidateFileVolumeMountFlags(volCap.GetMount().GetMountFlags()); err != nil {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"invalid mount options for file volume. Error: %v", err)
+		}
+	}
+
 	// Volume Size - Default is 10 GiB.
 	volSizeBytes := int64(common.DefaultGbDiskSize * common.GbInBytes)
 	if req.GetCapacityRange() != nil && req.GetCapacityRange().RequiredBytes != 0 {
@@ -932,10 +954,34 @@ func (c *controller) createFileVolume(ctx context.Context, req *csi.CreateVolume
 		ScParams:   scParams,
 		VolumeType: common.FileVolumeType,
 	}
+	if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.FileVolumeScopedNetPermissions) {
+		k8sClient, err := k8s.NewClient(ctx)
+		if err != nil {
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to create kubernetes client. Error: %+v", err)
+		}
+		cnsConfig := c.manager.CnsConfig
+		if multivCenterCSITopologyEnabled {
+			cnsConfig = c.managers.CnsConfig
+		}
+		createVolumeSpec.NetPermissions, err = common.GetScopedNetPermissions(ctx,
+			common.NewClientNetPermissionsSource(k8sClient), cnsConfig.NetPermissions,
+			scParams.NetPermissionsConfigMap, scParams.NetPermissionsSecret, scParams.PvcNamespace)
+		if err != nil {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"failed to get net permissions for the volume. Error: %+v", err)
+		}
+	}
 	var volumeID string
 	var faultType string
 	filterSuspendedDatastores := commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.CnsMgrSuspendCreateVolume)
-	if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.CSIAuthCheck) {
+	if multivCenterCSITopologyEnabled {
+		volumeID, faultType, err = c.createFileVolumeOnMatchingVCenter(ctx, req, &createVolumeSpec,
+			filterSuspendedDatastores)
+		if err != nil {
+			return nil, faultType, err
+		}
+	} else if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.CSIAuthCheck) {
 		fsEnabledClusterToDsInfoMap := c.authMgr.GetFsEnabledClusterToDsMap(ctx)
 
 		var filteredDatastores []*cnsvsphere.DatastoreInfo
@@ -964,6 +1010,9 @@ func (c *controller) createFileVolume(ctx context.Context, req *csi.CreateVolume
 
 	attributes := make(map[string]string)
 	attributes[common.AttributeDiskType] = common.DiskTypeFileVolume
+	if createVolumeSpec.NetPermissions != nil {
+		attributes[common.AttributeNetPermissionIPs] = common.GetNetPermissionIPs(createVolumeSpec.NetPermissions)
+	}
 
 	resp := &csi.CreateVolumeResponse{
 		Volume: &csi.Volume{
@@ -975,6 +1024,91 @@ func (c *controller) createFileVolume(ctx context.Context, req *csi.CreateVolume
 	return resp, "", nil
 }
 
+// createFileVolumeOnMatchingVCenter creates the file volume on the first vCenter,
+// among the vCenters of the nodes matching the topology requirement of the request or
+// all the vCenters if there is none, which supports vSAN file services and has file
+// service enabled datastores. If the volume creation fails on a vCenter before
+// anything is created, i.e. it has no matching datastores or the CNS task could not
+// be started, the next matching vCenter is tried. In multi vCenter deployments, the
+// vCenter of the volume is persisted in a CnsVolumeInfo CR.
+func (c *controller) createFileVolumeOnMatchingVCenter(ctx context.Context, req *csi.CreateVolumeRequest,
+	createVolumeSpec *common.CreateVolumeSpec, filterSuspendedDatastores bool) (string, string, error) {
+	log := logger.GetLogger(ctx)
+	vCenterHosts, err := getvCentersForFileVolume(ctx, c, req)
+	if err != nil {
+		return "", csifault.CSIInternalFault, err
+	}
+	isAuthCheckFSSEnabled := commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.CSIAuthCheck)
+	// lastErr and lastFaultType hold the error of the last vCenter the volume
+	// could not be created on.
+	var lastErr error
+	var lastFaultType string
+	for _, vCenterHost := range vCenterHosts {
+		isvSANFileServicesSupported, err := c.managers.VcenterManager.IsvSANFileServicesSupported(ctx, vCenterHost)
+		if err != nil {
+			lastFaultType = csifault.CSIInternalFault
+			lastErr = logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to verify if vSAN file services is supported or not for vCenter: %q. Error:%+v",
+				vCenterHost, err)
+			continue
+		}
+		if !isvSANFileServicesSupported {
+			log.Infof("vSAN file services are not supported on vCenter: %q", vCenterHost)
+			continue
+		}
+		manager := &common.Manager{
+			VcenterConfig:  c.managers.VcenterConfigs[vCenterHost],
+			CnsConfig:      c.managers.CnsConfig,
+			VolumeManager:  c.managers.VolumeManagers[vCenterHost],
+			VcenterManager: c.managers.VcenterManager,
+		}
+		var volumeID, faultType string
+		if isAuthCheckFSSEnabled {
+			var filteredDatastores []*cnsvsphere.DatastoreInfo
+			if authMgr, ok := c.authMgrs[vCenterHost]; ok {
+				for _, datastores := range authMgr.GetFsEnabledClusterToDsMap(ctx) {
+					filteredDatastores = append(filteredDatastores, datastores...)
+				}
+			}
+			if !hasDatastoreURL(filteredDatastores, createVolumeSpec.ScParams.DatastoreURL) {
+				log.Infof("No file service enabled datastores found to create file volume on vCenter: %q",
+					vCenterHost)
+				continue
+			}
+			volumeID, faultType, err = common.CreateFileVolumeUtil(ctx, cnstypes.CnsClusterFlavorVanilla,
+				manager, createVolumeSpec, filteredDatastores, filterSuspendedDatastores, false)
+		} else {
+			volumeID, faultType, err = common.CreateFileVolumeUtilOld(ctx, cnstypes.CnsClusterFlavorVanilla,
+				manager, createVolumeSpec, filterSuspendedDatastores, false)
+		}
+		if err != nil {
+			lastFaultType = faultType
+			lastErr = logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to create volume on vCenter: %q. Error: %+v", vCenterHost, err)
+			if errors.Is(err, common.ErrNoMatchingDatastores) || errors.Is(err, cnsvolume.ErrTaskNotStarted) {
+				continue
+			}
+			// The volume may have been created on the vCenter, so the volume
+			// creation is retried on the same vCenter.
+			return "", lastFaultType, lastErr
+		}
+		if len(c.managers.VcenterConfigs) > 1 {
+			err = volumeInfoService.CreateVolumeInfo(ctx, volumeID, vCenterHost)
+			if err != nil {
+				return "", csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+					"failed to store volumeID %q for vCenter %q in CNSVolumeInfo CR. Error: %+v",
+					volumeID, vCenterHost, err)
+			}
+		}
+		return volumeID, "", nil
+	}
+	if lastErr != nil {
+		return "", lastFaultType, lastErr
+	}
+	return "", csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+		"no datastores found to create file volume on vCenters: %v", vCenterHosts)
+}
+
 // CreateVolume is creating CNS Volume using volume request specified in
 // CreateVolumeRequest.
 func (c *controller) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest) (
@@ -999,15 +1133,19 @@ func (c *controller) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequ
 		}
 		if common.IsFileVolumeRequest(ctx, volumeCapabilities) {
 			volumeType = prometheus.PrometheusFileVolumeType
-			isvSANFileServicesSupported, err := c.manager.VcenterManager.IsvSANFileServicesSupported(ctx,
-				c.manager.VcenterConfig.Host)
-			if err != nil {
-				return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
-					"failed to verify if vSAN file services is supported or not. Error:%+v", err)
-			}
-			if !isvSANFileServicesSupported {
-				return nil, csifault.CSIInternalFault, logger.LogNewErrorCode(log, codes.FailedPrecondition,
-					"fileshare volume creation is not supported on vSAN 67u3 release")
+			// In multi vCenter deployments, vSAN file services support is verified
+			// for each vCenter on which the volume can be created.
+			if !multivCenterCSITopologyEnabled {
+				isvSANFileServicesSupported, err := c.manager.VcenterManager.IsvSANFileServicesSupported(ctx,
+					c.manager.VcenterConfig.Host)
+				if err != nil {
+					return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+						"failed to verify if vSAN file services is supported or not. Error:%+v", err)
+				}
+				if !isvSANFileServicesSupported {
+					return nil, csifault.CSIInternalFault, logger.LogNewErrorCode(log, codes.FailedPrecondition,
+						"fileshare volume creation is not supported on vSAN 67u3 release")
+				}
 			}
 			return c.createFileVolume(ctx, req)
 		}
@@ -1022,6 +1160,9 @@ func (c *controller) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequ
 			prometheus.PrometheusCreateVolumeOpType, volumeType, faultType)
 		prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, prometheus.PrometheusCreateVolumeOpType,
 			prometheus.PrometheusFailStatus, faultType).Observe(time.Since(start).Seconds())
+		commonco.ContainerOrchestratorUtility.RecordPVCEvent(ctx, req.Parameters[common.AttributePvcName],
+			req.Parameters[common.AttributePvcNamespace], req.Name, common.CreateVolumeFailedReason, faultType,
+			err.Error())
 	} else {
 		log.Infof("Volume created successfully. Volume Handle: %q, PV Name: %q", resp.Volume.VolumeId, req.Name)
 		prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, prometheus.PrometheusCreateVolumeOpType,
@@ -1034,7 +1175,7 @@ func (c *controller) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequ
 func (c *controller) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest) (
 	*csi.DeleteVolumeResponse, error) {
 	start := time.Now()
-	ctx = logger.NewContextWithLogger(ctx)
+	ctx = audit.NewContext(logger.NewContextWithLogger(ctx))
 	log := logger.GetLogger(ctx)
 	volumeType := prometheus.PrometheusUnknownVolumeType
 	cnsVolumeType := common.UnknownVolumeType
@@ -1160,7 +1301,15 @@ func (c *controller) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequ
 		}
 		return &csi.DeleteVolumeResponse{}, "", nil
 	}
+	// The PV and PVC are looked up before the volume is deleted.
+	auditEvent := &audit.Event{
+		Operation:         audit.OperationDeleteVolume,
+		Component:         audit.ComponentController,
+		VolumeID:          req.VolumeId,
+		KubernetesObjects: commonco.ContainerOrchestratorUtility.GetVolumeObjectReferences(ctx, req.VolumeId),
+	}
 	resp, faultType, err := deleteVolumeInternal()
+	audit.Log(ctx, auditEvent, err)
 	log.Debugf("deleteVolumeInternal: returns fault %q for volume %q", faultType, req.VolumeId)
 	if err != nil {
 		log.Errorf("Operation failed, reporting failure status to Prometheus."+
@@ -1168,6 +1317,8 @@ func (c *controller) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequ
 			prometheus.PrometheusDeleteVolumeOpType, volumeType, faultType)
 		prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, prometheus.PrometheusDeleteVolumeOpType,
 			prometheus.PrometheusFailStatus, faultType).Observe(time.Since(start).Seconds())
+		commonco.ContainerOrchestratorUtility.RecordVolumeEvent(ctx, req.VolumeId,
+			common.DeleteVolumeFailedReason, faultType, err.Error())
 	} else {
 		log.Infof("Volume %q deleted successfully.", req.VolumeId)
 		prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, prometheus.PrometheusDeleteVolumeOpType,
@@ -1229,21 +1380,31 @@ func (c *controller) ControllerPublishVolume(ctx context.Context, req *csi.Contr
 					"volumeID %s not found in QueryVolume", req.VolumeId)
 			}
 
+			// Validate the mount options and choose the access point for the
+			// NFS version requested by them.
+			nfsVersion, err := common.ValidateFileVolumeMountFlags(
+				req.GetVolumeCapability().GetMount().GetMountFlags())
+			if err != nil {
+				return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+					"invalid mount options for volume: %q. Error: %v", req.VolumeId, err)
+			}
+			accessPointKey, accessPoint := common.GetNfsAccessPointKeys(nfsVersion)
+
 			vSANFileBackingDetails :=
 				queryResult.Volumes[0].BackingObjectDetails.(*cnstypes.CnsVsanFileShareBackingDetails)
 			publishInfo[common.AttributeDiskType] = common.DiskTypeFileVolume
-			nfsv4AccessPointFound := false
+			accessPointFound := false
 			for _, kv := range vSANFileBackingDetails.AccessPoints {
-				if kv.Key == common.Nfsv4AccessPointKey {
-					publishInfo[common.Nfsv4AccessPoint] = kv.Value
-					nfsv4AccessPointFound = true
+				if kv.Key == accessPointKey {
+					publishInfo[accessPoint] = kv.Value
+					accessPointFound = true
 					break
 				}
 			}
-			if !nfsv4AccessPointFound {
+			if !accessPointFound {
 				return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
-					"failed to get NFSv4 access point for volume: %q. Returned vSAN file backing details: %+v",
-					req.VolumeId, vSANFileBackingDetails)
+					"failed to get %s access point for volume: %q. Returned vSAN file backing details: %+v",
+					accessPointKey, req.VolumeId, vSANFileBackingDetails)
 			}
 		} else {
 			// Block Volume.
@@ -1325,6 +1486,8 @@ func (c *controller) ControllerPublishVolume(ctx context.Context, req *csi.Contr
 			prometheus.PrometheusAttachVolumeOpType, volumeType, faultType)
 		prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, prometheus.PrometheusAttachVolumeOpType,
 			prometheus.PrometheusFailStatus, faultType).Observe(time.Since(start).Seconds())
+		commonco.ContainerOrchestratorUtility.RecordVolumeEvent(ctx, req.VolumeId,
+			common.AttachVolumeFailedReason, faultType, err.Error())
 	} else {
 		log.Infof("Volume %q attached successfully to node %q.", req.VolumeId, req.NodeId)
 		prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, prometheus.PrometheusAttachVolumeOpType,
@@ -1338,7 +1501,7 @@ func (c *controller) ControllerPublishVolume(ctx context.Context, req *csi.Contr
 func (c *controller) ControllerUnpublishVolume(ctx context.Context, req *csi.ControllerUnpublishVolumeRequest) (
 	*csi.ControllerUnpublishVolumeResponse, error) {
 	start := time.Now()
-	ctx = logger.NewContextWithLogger(ctx)
+	ctx = audit.NewContext(logger.NewContextWithLogger(ctx))
 	log := logger.GetLogger(ctx)
 	volumeType := prometheus.PrometheusUnknownVolumeType
 
@@ -1464,6 +1627,13 @@ func (c *controller) ControllerUnpublishVolume(ctx context.Context, req *csi.Con
 		return &csi.ControllerUnpublishVolumeResponse{}, "", nil
 	}
 	resp, faultType, err := controllerUnpublishVolumeInternal()
+	audit.Log(ctx, &audit.Event{
+		Operation:         audit.OperationControllerUnpublish,
+		Component:         audit.ComponentController,
+		VolumeID:          req.VolumeId,
+		NodeID:            req.NodeId,
+		KubernetesObjects: commonco.ContainerOrchestratorUtility.GetVolumeObjectReferences(ctx, req.VolumeId),
+	}, err)
 	log.Debugf("controllerUnpublishVolumeInternal: returns fault %q for volume %q", faultType, req.VolumeId)
 	if err != nil {
 		log.Errorf("Operation failed, reporting failure status to Prometheus."+
@@ -1471,6 +1641,8 @@ func (c *controller) ControllerUnpublishVolume(ctx context.Context, req *csi.Con
 			prometheus.PrometheusDetachVolumeOpType, volumeType, faultType)
 		prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, prometheus.PrometheusDetachVolumeOpType,
 			prometheus.PrometheusFailStatus, faultType).Observe(time.Since(start).Seconds())
+		commonco.ContainerOrchestratorUtility.RecordVolumeEvent(ctx, req.VolumeId,
+			common.DetachVolumeFailedReason, faultType, err.Error())
 	} else {
 		log.Infof("Volume %q detached successfully from node %q.", req.VolumeId, req.NodeId)
 		prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, prometheus.PrometheusDetachVolumeOpType,
@@ -1484,7 +1656,7 @@ func (c *controller) ControllerUnpublishVolume(ctx context.Context, req *csi.Con
 func (c *controller) ControllerExpandVolume(ctx context.Context, req *csi.ControllerExpandVolumeRequest) (
 	*csi.ControllerExpandVolumeResponse, error) {
 	start := time.Now()
-	ctx = logger.NewContextWithLogger(ctx)
+	ctx = audit.NewContext(logger.NewContextWithLogger(ctx))
 	log := logger.GetLogger(ctx)
 	volumeType := prometheus.PrometheusUnknownVolumeType
 	controllerExpandVolumeInternal := func() (
@@ -1587,6 +1759,13 @@ func (c *controller) ControllerExpandVolume(ctx context.Context, req *csi.Contro
 	}
 
 	resp, faultType, err := controllerExpandVolumeInternal()
+	audit.Log(ctx, &audit.Event{
+		Operation:         audit.OperationExpandVolume,
+		Component:         audit.ComponentController,
+		VolumeID:          req.VolumeId,
+		CapacityInMb:      common.RoundUpSize(req.GetCapacityRange().GetRequiredBytes(), common.MbInBytes),
+		KubernetesObjects: commonco.ContainerOrchestratorUtility.GetVolumeObjectReferences(ctx, req.VolumeId),
+	}, err)
 	if err != nil {
 		log.Debugf("controllerExpandVolumeInternal: returns fault %q for volume %q", faultType, req.VolumeId)
 		log.Errorf("Operation failed, reporting failure status to Prometheus."+
@@ -1594,6 +1773,8 @@ func (c *controller) ControllerExpandVolume(ctx context.Context, req *csi.Contro
 			prometheus.PrometheusExpandVolumeOpType, volumeType, faultType)
 		prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, prometheus.PrometheusExpandVolumeOpType,
 			prometheus.PrometheusFailStatus, faultType).Observe(time.Since(start).Seconds())
+		commonco.ContainerOrchestratorUtility.RecordVolumeEvent(ctx, req.VolumeId,
+			common.ExpandVolumeFailedReason, faultType, err.Error())
 	} else {
 		log.Infof("Volume %q expanded successfully.", req.VolumeId)
 		prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, prometheus.PrometheusExpandVolumeOpType,
@@ -1920,17 +2101,17 @@ func (c *controller) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshot
 			"VC version does not support snapshot operations")
 	}
 	volumeType := prometheus.PrometheusUnknownVolumeType
-	createSnapshotInternal := func() (*csi.CreateSnapshotResponse, error) {
+	createSnapshotInternal := func() (*csi.CreateSnapshotResponse, string, error) {
 		// Validate CreateSnapshotRequest
 		if err := validateVanillaCreateSnapshotRequestRequest(ctx, req); err != nil {
-			return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.Internal,
 				"validation for CreateSnapshot Request: %+v has failed. Error: %v", *req, err)
 		}
 		volumeID := req.GetSourceVolumeId()
 
 		// Check if the source volume is migrated vSphere volume
 		if strings.Contains(volumeID, ".vmdk") {
-			return nil, logger.LogNewErrorCodef(log, codes.Unimplemented,
+			return nil, csifault.CSIUnimplementedFault, logger.LogNewErrorCodef(log, codes.Unimplemented,
 				"cannot snapshot migrated vSphere volume. :%q", volumeID)
 		}
 		volumeType = prometheus.PrometheusBlockVolumeType
@@ -1938,16 +2119,16 @@ func (c *controller) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshot
 		volumeIds := []cnstypes.CnsVolumeId{{Id: volumeID}}
 		cnsVolumeDetailsMap, err := utils.QueryVolumeDetailsUtil(ctx, c.manager.VolumeManager, volumeIds)
 		if err != nil {
-			return nil, err
+			return nil, csifault.CSIInternalFault, err
 		}
 		if _, ok := cnsVolumeDetailsMap[volumeID]; !ok {
-			return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			return nil, csifault.CSINotFoundFault, logger.LogNewErrorCodef(log, codes.Internal,
 				"cns query volume did not return the volume: %s", volumeID)
 		}
 		snapshotSizeInMB := cnsVolumeDetailsMap[volumeID].SizeInMB
 		datastoreUrl := cnsVolumeDetailsMap[volumeID].DatastoreUrl
 		if cnsVolumeDetailsMap[volumeID].VolumeType != common.BlockVolumeType {
-			return nil, logger.LogNewErrorCodef(log, codes.FailedPrecondition,
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.FailedPrecondition,
 				"queried volume doesn't have the expected volume type. Expected VolumeType: %v. "+
 					"Queried VolumeType: %v", volumeType, cnsVolumeDetailsMap[volumeID].VolumeType)
 		}
@@ -1982,12 +2163,12 @@ func (c *controller) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshot
 		snapshotList, _, err := common.QueryVolumeSnapshotsByVolumeID(ctx, c.manager.VolumeManager, volumeID,
 			common.QuerySnapshotLimit)
 		if err != nil {
-			return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
 				"failed to query snapshots of volume %s for the limit check. Error: %v", volumeID, err)
 		}
 
 		if len(snapshotList) >= maxSnapshotsPerBlockVolume {
-			return nil, logger.LogNewErrorCodef(log, codes.FailedPrecondition,
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.FailedPrecondition,
 				"the number of snapshots on the source volume %s reaches the configured maximum (%v)",
 				volumeID, c.manager.CnsConfig.Snapshot.GlobalMaxSnapshotsPerBlockVolume)
 		}
@@ -1996,9 +2177,10 @@ func (c *controller) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshot
 		// sign. That is, a string of "<UUID>+<UUID>". Because, all other CNS snapshot APIs still require both
 		// VolumeID and SnapshotID as the input, while corresponding snapshot APIs in upstream CSI require SnapshotID.
 		// So, we need to bridge the gap in vSphere CSI driver and return a combined SnapshotID to CSI Snapshotter.
-		snapshotID, snapshotCreateTimePtr, err := common.CreateSnapshotUtil(ctx, c.manager, volumeID, req.Name)
+		snapshotID, snapshotCreateTimePtr, faultType, err := common.CreateSnapshotUtil(ctx, c.manager, volumeID,
+			req.Name)
 		if err != nil {
-			return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			return nil, faultType, logger.LogNewErrorCodef(log, codes.Internal,
 				"failed to create snapshot on volume %q: %v", volumeID, err)
 		}
 		snapshotCreateTimeInProto := timestamppb.New(*snapshotCreateTimePtr)
@@ -2017,28 +2199,31 @@ func (c *controller) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshot
 			"on volume %s size %d Time proto %+v Timestamp %+v Response: %+v",
 			snapshotID, volumeID, snapshotSizeInMB*common.MbInBytes, snapshotCreateTimeInProto,
 			*snapshotCreateTimePtr, createSnapshotResponse)
-		return createSnapshotResponse, nil
+		return createSnapshotResponse, "", nil
 	}
 
 	start := time.Now()
-	resp, err := createSnapshotInternal()
+	resp, faultType, err := createSnapshotInternal()
 	if err != nil {
 		log.Errorf("Operation failed, reporting failure status to Prometheus."+
 			" Operation Type: %q, Volume Type: %q, Fault Type: %q",
-			prometheus.PrometheusCreateSnapshotOpType, volumeType, "NotComputed")
+			prometheus.PrometheusCreateSnapshotOpType, volumeType, faultType)
 		prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, prometheus.PrometheusCreateSnapshotOpType,
-			prometheus.PrometheusFailStatus, "NotComputed").Observe(time.Since(start).Seconds())
+			prometheus.PrometheusFailStatus, faultType).Observe(time.Since(start).Seconds())
+		commonco.ContainerOrchestratorUtility.RecordVolumeSnapshotEvent(ctx,
+			req.Parameters[common.VolumeSnapshotNameKey], req.Parameters[common.VolumeSnapshotNamespaceKey],
+			common.CreateSnapshotFailedReason, faultType, err.Error())
 	} else {
 		log.Infof("Snapshot for volume %q created successfully.", req.GetSourceVolumeId())
 		prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, prometheus.PrometheusCreateSnapshotOpType,
-			prometheus.PrometheusPassStatus, "").Observe(time.Since(start).Seconds())
+			prometheus.PrometheusPassStatus, faultType).Observe(time.Since(start).Seconds())
 	}
 	return resp, err
 }
 
 func (c *controller) DeleteSnapshot(ctx context.Context, req *csi.DeleteSnapshotRequest) (
 	*csi.DeleteSnapshotResponse, error) {
-	ctx = logger.NewContextWithLogger(ctx)
+	ctx = audit.NewContext(logger.NewContextWithLogger(ctx))
 	log := logger.GetLogger(ctx)
 	log.Infof("DeleteSnapshot: called with args %+v", *req)
 
@@ -2075,6 +2260,16 @@ func (c *controller) DeleteSnapshot(ctx context.Context, req *csi.DeleteSnapshot
 	volumeType := prometheus.PrometheusBlockVolumeType
 	start := time.Now()
 	resp, err := deleteSnapshotInternal()
+	auditEvent := &audit.Event{
+		Operation:  audit.OperationDeleteSnapshot,
+		Component:  audit.ComponentController,
+		SnapshotID: req.GetSnapshotId(),
+	}
+	if volumeID, _, parseErr := common.ParseCSISnapshotID(req.GetSnapshotId()); parseErr == nil {
+		auditEvent.VolumeID = volumeID
+		auditEvent.KubernetesObjects = commonco.ContainerOrchestratorUtility.GetVolumeObjectReferences(ctx, volumeID)
+	}
+	audit.Log(ctx, auditEvent, err)
 	if err != nil {
 		log.Errorf("Operation failed, reporting failure status to Prometheus."+
 			" Operation Type: %q, Volume Type: %q, Fault Type: %q",
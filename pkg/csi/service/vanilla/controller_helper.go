@@ -19,6 +19,7 @@ package vanilla
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -270,3 +271,44 @@ func getVCenterManagerForVCenter(ctx context.Context, controller *controller) vs
 	}
 	return vCenterManager
 }
+
+// getvCentersForFileVolume returns the sorted list of vCenters on which the file volume
+// requested can be created. If the request has a topology requirement, these are the
+// vCenters of the nodes matching it, otherwise all the vCenters.
+func getvCentersForFileVolume(ctx context.Context, controller *controller,
+	req *csi.CreateVolumeRequest) ([]string, error) {
+	log := logger.GetLogger(ctx)
+	if req.GetAccessibilityRequirements() == nil {
+		vCenterHosts := make([]string, 0, len(controller.managers.VcenterConfigs))
+		for vCenterHost := range controller.managers.VcenterConfigs {
+			vCenterHosts = append(vCenterHosts, vCenterHost)
+		}
+		sort.Strings(vCenterHosts)
+		return vCenterHosts, nil
+	}
+	vCenterHosts, err := controller.topologyMgr.GetvCentersInTopology(ctx, req.GetAccessibilityRequirements())
+	if err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to get vCenters for topology requirement: %+v. Error: %+v",
+			req.GetAccessibilityRequirements(), err)
+	}
+	if len(vCenterHosts) == 0 {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"no nodes found matching topology requirement: %+v", req.GetAccessibilityRequirements())
+	}
+	return vCenterHosts, nil
+}
+
+// hasDatastoreURL returns true if the given datastores include the datastore with the given
+// URL, or if there is any datastore when the URL is empty.
+func hasDatastoreURL(datastores []*vsphere.DatastoreInfo, datastoreURL string) bool {
+	if datastoreURL == "" {
+		return len(datastores) != 0
+	}
+	for _, datastore := range datastores {
+		if datastore.Info.Url == datastoreURL {
+			return true
+		}
+	}
+	return false
+}
@@ -22,8 +22,10 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"reflect"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/vmware/govmomi/cns"
@@ -54,6 +56,7 @@ import (
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/unittestcommon"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco"
+	commoncotypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco/types"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/cnsvolumeoperationrequest"
 	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
 )
@@ -2275,3 +2278,308 @@ func TestCreateSnapshotWithManagedObjectNotFound(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestGetHealthStatus(t *testing.T) {
+	healthy := vCenterHealth{Host: "vc1"}
+	unreachable := vCenterHealth{Host: "vc2", SessionError: "connection refused"}
+	tests := []struct {
+		vCenters        []vCenterHealth
+		informersSynced bool
+		expected        string
+	}{
+		{[]vCenterHealth{healthy}, true, healthStatusHealthy},
+		{[]vCenterHealth{healthy}, false, healthStatusUnhealthy},
+		{[]vCenterHealth{unreachable}, true, healthStatusUnhealthy},
+		{[]vCenterHealth{healthy, unreachable}, true, healthStatusDegraded},
+		{[]vCenterHealth{healthy, {Host: "vc3", CnsError: "timeout"}}, true, healthStatusDegraded},
+	}
+	for _, test := range tests {
+		if status := getHealthStatus(test.vCenters, test.informersSynced); status != test.expected {
+			t.Errorf("expected status %q for vCenters %+v and informers synced %v, got %q",
+				test.expected, test.vCenters, test.informersSynced, status)
+		}
+	}
+}
+
+func TestCheckReadiness(t *testing.T) {
+	savedReport := getLastHealthReport()
+	defer func() {
+		lastHealthReportLock.Lock()
+		lastHealthReport = savedReport
+		lastHealthReportLock.Unlock()
+	}()
+	c := &controller{}
+	for status, expectReady := range map[string]bool{
+		healthStatusHealthy:   true,
+		healthStatusDegraded:  true,
+		healthStatusUnhealthy: false,
+	} {
+		// The report is fresh, so that CheckReadiness does not refresh it.
+		lastHealthReportLock.Lock()
+		lastHealthReport = &healthReport{Status: status, time: time.Now()}
+		lastHealthReportLock.Unlock()
+		err := c.CheckReadiness(context.Background())
+		if expectReady && err != nil {
+			t.Errorf("expected the controller to be ready with status %q, got %v", status, err)
+		}
+		if !expectReady && err == nil {
+			t.Errorf("expected the controller not to be ready with status %q", status)
+		}
+	}
+}
+
+// fakeFileVolumeTopology returns the given vCenters for any topology requirement.
+type fakeFileVolumeTopology struct {
+	commoncotypes.ControllerTopologyService
+	vCenters []string
+}
+
+func (f *fakeFileVolumeTopology) GetvCentersInTopology(ctx context.Context,
+	topologyRequirement *csi.TopologyRequirement) ([]string, error) {
+	return f.vCenters, nil
+}
+
+// fakeFileVolumeVCenterManager returns the same VirtualCenter for all the vCenter
+// hosts, with vSAN file services supported on the given ones.
+type fakeFileVolumeVCenterManager struct {
+	cnsvsphere.VirtualCenterManager
+	vcenter               *cnsvsphere.VirtualCenter
+	fileServicesSupported map[string]bool
+}
+
+func (f *fakeFileVolumeVCenterManager) GetVirtualCenter(ctx context.Context,
+	host string) (*cnsvsphere.VirtualCenter, error) {
+	return f.vcenter, nil
+}
+
+func (f *fakeFileVolumeVCenterManager) IsvSANFileServicesSupported(ctx context.Context, host string) (bool, error) {
+	supported, ok := f.fileServicesSupported[host]
+	if !ok {
+		return false, fmt.Errorf("vCenter %q is not reachable", host)
+	}
+	return supported, nil
+}
+
+// fakeFileVolumeManager creates the file volume with the given ID, or fails
+// with the given fault, wrapping cnsvolume.ErrTaskNotStarted if taskNotStarted
+// is set.
+type fakeFileVolumeManager struct {
+	cnsvolume.Manager
+	volumeID       string
+	faultType      string
+	taskNotStarted bool
+	created        int
+}
+
+func (f *fakeFileVolumeManager) CreateVolume(ctx context.Context,
+	spec *cnstypes.CnsVolumeCreateSpec) (*cnsvolume.CnsVolumeInfo, string, error) {
+	if f.taskNotStarted {
+		return nil, f.faultType, fmt.Errorf("%w: failed to create volume %q", cnsvolume.ErrTaskNotStarted,
+			spec.Name)
+	}
+	if f.faultType != "" {
+		return nil, f.faultType, fmt.Errorf("failed to create volume %q", spec.Name)
+	}
+	f.created++
+	return &cnsvolume.CnsVolumeInfo{VolumeID: cnstypes.CnsVolumeId{Id: f.volumeID}}, "", nil
+}
+
+// fakeVolumeInfoService records the vCenter of the volumes in memory.
+type fakeVolumeInfoService struct {
+	vCenters map[string]string
+}
+
+func (f *fakeVolumeInfoService) GetvCenterForVolumeID(ctx context.Context, volumeID string) (string, error) {
+	vCenter, ok := f.vCenters[volumeID]
+	if !ok {
+		return "", fmt.Errorf("volume %q not found", volumeID)
+	}
+	return vCenter, nil
+}
+
+func (f *fakeVolumeInfoService) CreateVolumeInfo(ctx context.Context, volumeID string, vCenter string) error {
+	f.vCenters[volumeID] = vCenter
+	return nil
+}
+
+func (f *fakeVolumeInfoService) DeleteVolumeInfo(ctx context.Context, volumeID string) error {
+	delete(f.vCenters, volumeID)
+	return nil
+}
+
+// getFileVolumeControllerTest returns a multi vCenter controller for the given
+// vCenter hosts, all backed by the vcsim instance, with the given volume managers.
+func getFileVolumeControllerTest(t *testing.T, volumeManagers map[string]cnsvolume.Manager,
+	fileServicesSupported map[string]bool, topologyvCenters []string) *controller {
+	ct := getControllerTest(t)
+	vcenterconfig, err := cnsvsphere.GetVirtualCenterConfig(ctx, ct.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vcenterconfig.TargetvSANFileShareDatastoreURLs = []string{ct.controller.nodeMgr.(*FakeNodeManager).sharedDatastoreURL}
+	vcenterConfigs := make(map[string]*cnsvsphere.VirtualCenterConfig)
+	for vCenterHost := range volumeManagers {
+		vcenterConfigs[vCenterHost] = vcenterconfig
+	}
+	return &controller{
+		managers: &common.Managers{
+			VcenterConfigs: vcenterConfigs,
+			CnsConfig:      ct.config,
+			VolumeManagers: volumeManagers,
+			VcenterManager: &fakeFileVolumeVCenterManager{
+				vcenter:               ct.vcenter,
+				fileServicesSupported: fileServicesSupported,
+			},
+		},
+		topologyMgr: &fakeFileVolumeTopology{vCenters: topologyvCenters},
+	}
+}
+
+func TestGetvCentersForFileVolume(t *testing.T) {
+	c := getFileVolumeControllerTest(t, map[string]cnsvolume.Manager{
+		"vc-3": &fakeFileVolumeManager{}, "vc-1": &fakeFileVolumeManager{}, "vc-2": &fakeFileVolumeManager{},
+	}, nil, []string{"vc-2"})
+
+	vCenters, err := getvCentersForFileVolume(ctx, c, &csi.CreateVolumeRequest{})
+	if err != nil {
+		t.Fatalf("failed to get vCenters: %v", err)
+	}
+	if !reflect.DeepEqual(vCenters, []string{"vc-1", "vc-2", "vc-3"}) {
+		t.Errorf("expected all the vCenters sorted, got %v", vCenters)
+	}
+
+	req := &csi.CreateVolumeRequest{AccessibilityRequirements: &csi.TopologyRequirement{
+		Preferred: []*csi.Topology{{Segments: map[string]string{"topology.csi.vmware.com/k8s-zone": "zone-2"}}},
+	}}
+	vCenters, err = getvCentersForFileVolume(ctx, c, req)
+	if err != nil {
+		t.Fatalf("failed to get vCenters in topology: %v", err)
+	}
+	if !reflect.DeepEqual(vCenters, []string{"vc-2"}) {
+		t.Errorf("expected the vCenters in topology, got %v", vCenters)
+	}
+
+	c.topologyMgr = &fakeFileVolumeTopology{}
+	if _, err = getvCentersForFileVolume(ctx, c, req); err == nil {
+		t.Errorf("expected an error when no nodes match the topology requirement")
+	}
+}
+
+func TestHasDatastoreURL(t *testing.T) {
+	datastores := []*cnsvsphere.DatastoreInfo{
+		{Info: &types.DatastoreInfo{Url: "ds:///vmfs/volumes/vsan:1/"}},
+		{Info: &types.DatastoreInfo{Url: "ds:///vmfs/volumes/vsan:2/"}},
+	}
+	tests := []struct {
+		name         string
+		datastores   []*cnsvsphere.DatastoreInfo
+		datastoreURL string
+		expected     bool
+	}{
+		{name: "AnyDatastore", datastores: datastores, expected: true},
+		{name: "NoDatastores", expected: false},
+		{name: "MatchingURL", datastores: datastores, datastoreURL: "ds:///vmfs/volumes/vsan:2/", expected: true},
+		{name: "OtherURL", datastores: datastores, datastoreURL: "ds:///vmfs/volumes/vsan:3/", expected: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := hasDatastoreURL(test.datastores, test.datastoreURL); actual != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCreateFileVolumeOnMatchingVCenter(t *testing.T) {
+	savedVolumeInfoService := volumeInfoService
+	defer func() {
+		volumeInfoService = savedVolumeInfoService
+	}()
+	req := &csi.CreateVolumeRequest{Name: testVolumeName}
+
+	t.Run("SkipsvCentersWithoutFileServices", func(t *testing.T) {
+		vc1, vc2, vc3 := &fakeFileVolumeManager{volumeID: "volume-1"},
+			&fakeFileVolumeManager{volumeID: "volume-2"}, &fakeFileVolumeManager{volumeID: "volume-3"}
+		c := getFileVolumeControllerTest(t, map[string]cnsvolume.Manager{"vc-1": vc1, "vc-2": vc2, "vc-3": vc3},
+			map[string]bool{"vc-1": false, "vc-2": true, "vc-3": true}, nil)
+		infoService := &fakeVolumeInfoService{vCenters: make(map[string]string)}
+		volumeInfoService = infoService
+
+		volumeID, _, err := c.createFileVolumeOnMatchingVCenter(ctx, req,
+			&common.CreateVolumeSpec{Name: req.Name, ScParams: &common.StorageClassParams{},
+				VolumeType: common.FileVolumeType}, false)
+		if err != nil {
+			t.Fatalf("failed to create file volume: %v", err)
+		}
+		if volumeID != "volume-2" || vc1.created != 0 || vc2.created != 1 || vc3.created != 0 {
+			t.Errorf("expected the volume to be created on vc-2 only, got volume %q", volumeID)
+		}
+		if !reflect.DeepEqual(infoService.vCenters, map[string]string{"volume-2": "vc-2"}) {
+			t.Errorf("unexpected CnsVolumeInfo %v", infoService.vCenters)
+		}
+	})
+
+	t.Run("TriesNextvCenterOnFailure", func(t *testing.T) {
+		vc1, vc2, vc3 := &fakeFileVolumeManager{faultType: "csi.fault.vc1", taskNotStarted: true},
+			&fakeFileVolumeManager{volumeID: "volume-2"}, &fakeFileVolumeManager{volumeID: "volume-3"}
+		c := getFileVolumeControllerTest(t, map[string]cnsvolume.Manager{"vc-1": vc1, "vc-2": vc2, "vc-3": vc3},
+			map[string]bool{"vc-2": true, "vc-3": true}, []string{"vc-0", "vc-1", "vc-3"})
+		// vc-0 is not reachable and the volume creation fails on vc-1.
+		c.managers.VolumeManagers["vc-0"] = &fakeFileVolumeManager{}
+		c.managers.VcenterManager.(*fakeFileVolumeVCenterManager).fileServicesSupported["vc-1"] = true
+		infoService := &fakeVolumeInfoService{vCenters: make(map[string]string)}
+		volumeInfoService = infoService
+
+		topologyReq := &csi.CreateVolumeRequest{Name: testVolumeName,
+			AccessibilityRequirements: &csi.TopologyRequirement{}}
+		volumeID, _, err := c.createFileVolumeOnMatchingVCenter(ctx, topologyReq,
+			&common.CreateVolumeSpec{Name: req.Name, ScParams: &common.StorageClassParams{},
+				VolumeType: common.FileVolumeType}, false)
+		if err != nil {
+			t.Fatalf("failed to create file volume: %v", err)
+		}
+		if volumeID != "volume-3" || vc2.created != 0 || vc3.created != 1 {
+			t.Errorf("expected the volume to be created on vc-3, got volume %q", volumeID)
+		}
+		if !reflect.DeepEqual(infoService.vCenters, map[string]string{"volume-3": "vc-3"}) {
+			t.Errorf("unexpected CnsVolumeInfo %v", infoService.vCenters)
+		}
+	})
+
+	t.Run("RetriesSamevCenterAfterTaskStarted", func(t *testing.T) {
+		vc1, vc2 := &fakeFileVolumeManager{faultType: "csi.fault.vc1"}, &fakeFileVolumeManager{volumeID: "volume-2"}
+		c := getFileVolumeControllerTest(t, map[string]cnsvolume.Manager{"vc-1": vc1, "vc-2": vc2},
+			map[string]bool{"vc-1": true, "vc-2": true}, nil)
+		infoService := &fakeVolumeInfoService{vCenters: make(map[string]string)}
+		volumeInfoService = infoService
+
+		// The volume may have been created on vc-1, so vc-2 is not tried.
+		_, faultType, err := c.createFileVolumeOnMatchingVCenter(ctx, req,
+			&common.CreateVolumeSpec{Name: req.Name, ScParams: &common.StorageClassParams{},
+				VolumeType: common.FileVolumeType}, false)
+		if err == nil || faultType != "csi.fault.vc1" {
+			t.Errorf("expected the volume creation to fail with fault csi.fault.vc1, got %q: %v", faultType, err)
+		}
+		if vc2.created != 0 || len(infoService.vCenters) != 0 {
+			t.Errorf("expected the volume not to be created on vc-2, got CnsVolumeInfo %v", infoService.vCenters)
+		}
+	})
+
+	t.Run("ReturnsLastFailure", func(t *testing.T) {
+		vc1 := &fakeFileVolumeManager{faultType: "csi.fault.vc1"}
+		c := getFileVolumeControllerTest(t, map[string]cnsvolume.Manager{"vc-1": vc1},
+			map[string]bool{"vc-1": true}, nil)
+		infoService := &fakeVolumeInfoService{vCenters: make(map[string]string)}
+		volumeInfoService = infoService
+
+		_, faultType, err := c.createFileVolumeOnMatchingVCenter(ctx, req,
+			&common.CreateVolumeSpec{Name: req.Name, ScParams: &common.StorageClassParams{},
+				VolumeType: common.FileVolumeType}, false)
+		if err == nil || faultType != "csi.fault.vc1" {
+			t.Errorf("expected the volume creation to fail with fault csi.fault.vc1, got %q: %v", faultType, err)
+		}
+		if len(infoService.vCenters) != 0 {
+			t.Errorf("unexpected CnsVolumeInfo %v", infoService.vCenters)
+		}
+	})
+}
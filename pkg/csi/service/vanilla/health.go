@@ -0,0 +1,273 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vanilla
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	"google.golang.org/grpc/codes"
+
+	cnsvolume "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+const (
+	// healthStatusHealthy is reported when all the vCenters are reachable and
+	// the informers are synced.
+	healthStatusHealthy = "healthy"
+	// healthStatusDegraded is reported when some, but not all, of the vCenters
+	// of a multi vCenter deployment are not reachable.
+	healthStatusDegraded = "degraded"
+	// healthStatusUnhealthy is reported when no vCenter is reachable or the
+	// informers are not synced.
+	healthStatusUnhealthy = "unhealthy"
+	// healthStatusUnknown is reported by /healthz until the health of the
+	// controller is checked by /readyz.
+	healthStatusUnknown = "unknown"
+
+	// healthCheckTimeout is the timeout of the checks of a vCenter.
+	healthCheckTimeout = 30 * time.Second
+	// healthReportTTL is the duration for which a health report is reused, so
+	// that the readiness probes do not load vCenter.
+	healthReportTTL = 10 * time.Second
+)
+
+// vCenterHealth is the health of a vCenter in a health report.
+type vCenterHealth struct {
+	Host string `json:"host"`
+	// SessionError is set if the session to vCenter is not valid and could
+	// not be renewed.
+	SessionError string `json:"sessionError,omitempty"`
+	// CnsError is set if the CNS service of vCenter could not be queried.
+	CnsError string `json:"cnsError,omitempty"`
+}
+
+// healthy returns true if the vCenter session is valid and CNS reachable.
+func (h vCenterHealth) healthy() bool {
+	return h.SessionError == "" && h.CnsError == ""
+}
+
+// healthReport is the health of the controller served by /healthz and
+// /readyz.
+type healthReport struct {
+	Status          string          `json:"status"`
+	InformersSynced bool            `json:"informersSynced"`
+	VCenters        []vCenterHealth `json:"vCenters"`
+	time            time.Time
+}
+
+var (
+	// healthCheckLock serializes the health checks.
+	healthCheckLock sync.Mutex
+	// lastHealthReportLock protects lastHealthReport. It is not held during
+	// the health checks, so that /healthz never waits for vCenter.
+	lastHealthReportLock sync.Mutex
+	// lastHealthReport is the last health report, reused for healthReportTTL.
+	lastHealthReport *healthReport
+	// healthReportRefreshing is 1 while the health report is refreshed in the
+	// background for Probe.
+	healthReportRefreshing int32
+)
+
+// getHealthStatus returns the health status of the controller given the
+// health of the vCenters and the sync state of the informers.
+func getHealthStatus(vCenters []vCenterHealth, informersSynced bool) string {
+	healthyCount := 0
+	for _, vCenter := range vCenters {
+		if vCenter.healthy() {
+			healthyCount++
+		}
+	}
+	switch {
+	case !informersSynced || healthyCount == 0:
+		return healthStatusUnhealthy
+	case healthyCount < len(vCenters):
+		return healthStatusDegraded
+	default:
+		return healthStatusHealthy
+	}
+}
+
+// isReadyWhenDegraded returns true if the controller reports ready while
+// some of the vCenters of a multi vCenter deployment are not reachable.
+// It can be disabled by setting environment variable READY_WHEN_DEGRADED to
+// false.
+func isReadyWhenDegraded(ctx context.Context) bool {
+	log := logger.GetLogger(ctx)
+	readyWhenDegraded := true
+	if v := os.Getenv("READY_WHEN_DEGRADED"); v != "" {
+		if value, err := strconv.ParseBool(v); err == nil {
+			readyWhenDegraded = value
+		} else {
+			log.Warnf("Value set in env variable READY_WHEN_DEGRADED %s is invalid, "+
+				"will report ready when degraded", v)
+		}
+	}
+	return readyWhenDegraded
+}
+
+// getVolumeManagers returns the volume managers of the configured vCenters
+// by vCenter host.
+func (c *controller) getVolumeManagers() map[string]cnsvolume.Manager {
+	if multivCenterCSITopologyEnabled {
+		return c.managers.VolumeManagers
+	}
+	return map[string]cnsvolume.Manager{c.manager.VcenterConfig.Host: c.manager.VolumeManager}
+}
+
+// checkVCenterHealth checks that the session to the given vCenter is valid,
+// renewing it if needed, and that its CNS service can be queried.
+func (c *controller) checkVCenterHealth(ctx context.Context, host string,
+	volumeManager cnsvolume.Manager) vCenterHealth {
+	log := logger.GetLogger(ctx)
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	health := vCenterHealth{Host: host}
+	vCenter, err := getVCenterManagerForVCenter(ctx, c).GetVirtualCenter(ctx, host)
+	if err == nil {
+		err = vCenter.Connect(ctx)
+	}
+	if err != nil {
+		log.Errorf("health check: failed to connect to vCenter %q. Err: %v", host, err)
+		health.SessionError = err.Error()
+		return health
+	}
+	// Query a single volume to check that CNS is reachable.
+	_, err = volumeManager.QueryVolume(ctx, cnstypes.CnsQueryFilter{Cursor: &cnstypes.CnsCursor{Limit: 1}})
+	if err != nil {
+		log.Errorf("health check: failed to query CNS on vCenter %q. Err: %v", host, err)
+		health.CnsError = err.Error()
+	}
+	return health
+}
+
+// getLastHealthReport returns the last health report, or nil if the health
+// of the controller has not been checked yet.
+func getLastHealthReport() *healthReport {
+	lastHealthReportLock.Lock()
+	defer lastHealthReportLock.Unlock()
+	return lastHealthReport
+}
+
+// getHealthReport checks the health of the controller, or returns the last
+// health report if it is more recent than healthReportTTL.
+func (c *controller) getHealthReport(ctx context.Context) *healthReport {
+	healthCheckLock.Lock()
+	defer healthCheckLock.Unlock()
+	if report := getLastHealthReport(); report != nil && time.Since(report.time) < healthReportTTL {
+		return report
+	}
+	report := &healthReport{
+		InformersSynced: commonco.ContainerOrchestratorUtility.AreInformersSynced(ctx),
+		time:            time.Now(),
+	}
+	for host, volumeManager := range c.getVolumeManagers() {
+		report.VCenters = append(report.VCenters, c.checkVCenterHealth(ctx, host, volumeManager))
+	}
+	sort.Slice(report.VCenters, func(i, j int) bool {
+		return report.VCenters[i].Host < report.VCenters[j].Host
+	})
+	report.Status = getHealthStatus(report.VCenters, report.InformersSynced)
+	lastHealthReportLock.Lock()
+	lastHealthReport = report
+	lastHealthReportLock.Unlock()
+	return report
+}
+
+// refreshHealthReport refreshes the health report in the background if it is
+// older than healthReportTTL and no refresh is already running.
+func (c *controller) refreshHealthReport() {
+	if report := getLastHealthReport(); report != nil && time.Since(report.time) < healthReportTTL {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&healthReportRefreshing, 0, 1) {
+		return
+	}
+	go func() {
+		defer atomic.StoreInt32(&healthReportRefreshing, 0)
+		c.getHealthReport(logger.NewContextWithLogger(context.Background()))
+	}()
+}
+
+// CheckReadiness returns an error if the last health report of the controller
+// is unhealthy, i.e. no vCenter session is valid with CNS reachable or the
+// informers are not synced. It never waits for the health checks, so that
+// Probe returns within the timeout of the liveness probe: a stale report is
+// refreshed in the background and the controller is reported ready until the
+// first report is available. READY_WHEN_DEGRADED does not apply to Probe.
+func (c *controller) CheckReadiness(ctx context.Context) error {
+	log := logger.GetLogger(ctx)
+	c.refreshHealthReport()
+	report := getLastHealthReport()
+	if report == nil || report.Status != healthStatusUnhealthy {
+		return nil
+	}
+	return logger.LogNewErrorCodef(log, codes.FailedPrecondition,
+		"controller is unhealthy: informers synced: %t, vCenters: %+v", report.InformersSynced, report.VCenters)
+}
+
+// isReady returns true if the given health report is ready.
+func isReady(ctx context.Context, report *healthReport) bool {
+	return report.Status == healthStatusHealthy ||
+		(report.Status == healthStatusDegraded && isReadyWhenDegraded(ctx))
+}
+
+// writeHealthReport writes the given health report, with status code 503 if
+// ready is false.
+func writeHealthReport(ctx context.Context, w http.ResponseWriter, report *healthReport, ready bool) {
+	log := logger.GetLogger(ctx)
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Errorf("failed to write health report. Err: %v", err)
+	}
+}
+
+// registerHealthHandlers registers the /healthz and /readyz handlers on the
+// HTTP server exposing the Prometheus metrics. /healthz only reports that the
+// controller is alive, along with the last health report, and never checks
+// vCenter or the informers, so that it can be used as a liveness probe during
+// vCenter outages. /readyz, used by the readiness probe, checks the health of
+// the controller and fails when it is unhealthy, or degraded and
+// READY_WHEN_DEGRADED is false.
+func (c *controller) registerHealthHandlers() {
+	http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		ctx := logger.NewContextWithLogger(r.Context())
+		report := getLastHealthReport()
+		if report == nil {
+			report = &healthReport{Status: healthStatusUnknown}
+		}
+		writeHealthReport(ctx, w, report, true)
+	})
+	http.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		ctx := logger.NewContextWithLogger(r.Context())
+		report := c.getHealthReport(ctx)
+		writeHealthReport(ctx, w, report, isReady(ctx, report))
+	})
+}
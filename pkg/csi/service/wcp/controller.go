@@ -40,6 +40,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/util/wait"
 
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/audit"
 	cnsvolume "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
 	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
 	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
@@ -845,7 +846,7 @@ func (c *controller) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequ
 	*csi.DeleteVolumeResponse, error) {
 
 	start := time.Now()
-	ctx = logger.NewContextWithLogger(ctx)
+	ctx = audit.NewContext(logger.NewContextWithLogger(ctx))
 	log := logger.GetLogger(ctx)
 	volumeType := prometheus.PrometheusUnknownVolumeType
 	cnsVolumeType := common.UnknownVolumeType
@@ -905,7 +906,15 @@ func (c *controller) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequ
 		}
 		return &csi.DeleteVolumeResponse{}, "", nil
 	}
+	// The PV and PVC are looked up before the volume is deleted.
+	auditEvent := &audit.Event{
+		Operation:         audit.OperationDeleteVolume,
+		Component:         audit.ComponentController,
+		VolumeID:          req.VolumeId,
+		KubernetesObjects: commonco.ContainerOrchestratorUtility.GetVolumeObjectReferences(ctx, req.VolumeId),
+	}
 	resp, faultType, err := deleteVolumeInternal()
+	audit.Log(ctx, auditEvent, err)
 	log.Debugf("deleteVolumeInternal: returns fault %q for volume %q", faultType, req.VolumeId)
 
 	if err != nil {
@@ -1082,7 +1091,7 @@ func (c *controller) ControllerPublishVolume(ctx context.Context, req *csi.Contr
 func (c *controller) ControllerUnpublishVolume(ctx context.Context, req *csi.ControllerUnpublishVolumeRequest) (
 	*csi.ControllerUnpublishVolumeResponse, error) {
 	start := time.Now()
-	ctx = logger.NewContextWithLogger(ctx)
+	ctx = audit.NewContext(logger.NewContextWithLogger(ctx))
 	log := logger.GetLogger(ctx)
 	volumeType := prometheus.PrometheusUnknownVolumeType
 	controllerUnpublishVolumeInternal := func() (
@@ -1210,6 +1219,13 @@ func (c *controller) ControllerUnpublishVolume(ctx context.Context, req *csi.Con
 		return &csi.ControllerUnpublishVolumeResponse{}, "", nil
 	}
 	resp, faultType, err := controllerUnpublishVolumeInternal()
+	audit.Log(ctx, &audit.Event{
+		Operation:         audit.OperationControllerUnpublish,
+		Component:         audit.ComponentController,
+		VolumeID:          req.VolumeId,
+		NodeID:            req.NodeId,
+		KubernetesObjects: commonco.ContainerOrchestratorUtility.GetVolumeObjectReferences(ctx, req.VolumeId),
+	}, err)
 	log.Debugf("controllerUnpublishVolumeInternal: returns fault %q for volume %q", faultType, req.VolumeId)
 
 	if err != nil {
@@ -1482,7 +1498,7 @@ func (c *controller) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshot
 		// sign. That is, a string of "<UUID>+<UUID>". Because, all other CNS snapshot APIs still require both
 		// VolumeID and SnapshotID as the input, while corresponding snapshot APIs in upstream CSI require SnapshotID.
 		// So, we need to bridge the gap in vSphere CSI driver and return a combined SnapshotID to CSI Snapshotter.
-		snapshotID, snapshotCreateTimePtr, err := common.CreateSnapshotUtil(ctx, c.manager, volumeID, req.Name)
+		snapshotID, snapshotCreateTimePtr, _, err := common.CreateSnapshotUtil(ctx, c.manager, volumeID, req.Name)
 		if err != nil {
 			return nil, logger.LogNewErrorCodef(log, codes.Internal,
 				"failed to create snapshot on volume %q: %v", volumeID, err)
@@ -1535,7 +1551,7 @@ func (c *controller) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshot
 func (c *controller) DeleteSnapshot(ctx context.Context, req *csi.DeleteSnapshotRequest) (
 	*csi.DeleteSnapshotResponse, error) {
 
-	ctx = logger.NewContextWithLogger(ctx)
+	ctx = audit.NewContext(logger.NewContextWithLogger(ctx))
 	log := logger.GetLogger(ctx)
 	log.Infof("DeleteSnapshot: called with args %+v", *req)
 	volumeType := prometheus.PrometheusBlockVolumeType
@@ -1557,6 +1573,16 @@ func (c *controller) DeleteSnapshot(ctx context.Context, req *csi.DeleteSnapshot
 		return &csi.DeleteSnapshotResponse{}, nil
 	}
 	resp, err := deleteSnapshotInternal()
+	auditEvent := &audit.Event{
+		Operation:  audit.OperationDeleteSnapshot,
+		Component:  audit.ComponentController,
+		SnapshotID: req.GetSnapshotId(),
+	}
+	if volumeID, _, parseErr := common.ParseCSISnapshotID(req.GetSnapshotId()); parseErr == nil {
+		auditEvent.VolumeID = volumeID
+		auditEvent.KubernetesObjects = commonco.ContainerOrchestratorUtility.GetVolumeObjectReferences(ctx, volumeID)
+	}
+	audit.Log(ctx, auditEvent, err)
 	if err != nil {
 		prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, prometheus.PrometheusDeleteSnapshotOpType,
 			prometheus.PrometheusFailStatus, "NotComputed").Observe(time.Since(start).Seconds())
@@ -1622,7 +1648,7 @@ func (c *controller) ListSnapshots(ctx context.Context, req *csi.ListSnapshotsRe
 func (c *controller) ControllerExpandVolume(ctx context.Context, req *csi.ControllerExpandVolumeRequest) (
 	*csi.ControllerExpandVolumeResponse, error) {
 	start := time.Now()
-	ctx = logger.NewContextWithLogger(ctx)
+	ctx = audit.NewContext(logger.NewContextWithLogger(ctx))
 	log := logger.GetLogger(ctx)
 	volumeType := prometheus.PrometheusUnknownVolumeType
 	cnsVolumeType := common.UnknownVolumeType
@@ -1704,6 +1730,13 @@ func (c *controller) ControllerExpandVolume(ctx context.Context, req *csi.Contro
 		return resp, "", nil
 	}
 	resp, faultType, err := controllerExpandVolumeInternal()
+	audit.Log(ctx, &audit.Event{
+		Operation:         audit.OperationExpandVolume,
+		Component:         audit.ComponentController,
+		VolumeID:          req.VolumeId,
+		CapacityInMb:      common.RoundUpSize(req.GetCapacityRange().GetRequiredBytes(), common.MbInBytes),
+		KubernetesObjects: commonco.ContainerOrchestratorUtility.GetVolumeObjectReferences(ctx, req.VolumeId),
+	}, err)
 	log.Debugf("controllerExpandVolumeInternal: returns fault %q for volume %q", faultType, req.VolumeId)
 
 	if err != nil {
@@ -47,6 +47,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	cnsoperatorv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
 	cnsfileaccessconfigv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsfileaccessconfig/v1alpha1"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/audit"
 	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
 	csifault "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/fault"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
@@ -424,7 +425,7 @@ func (c *controller) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequ
 	*csi.DeleteVolumeResponse, error) {
 
 	start := time.Now()
-	ctx = logger.NewContextWithLogger(ctx)
+	ctx = audit.NewContext(logger.NewContextWithLogger(ctx))
 	log := logger.GetLogger(ctx)
 	volumeType := prometheus.PrometheusUnknownVolumeType
 
@@ -478,7 +479,15 @@ func (c *controller) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequ
 		log.Infof("DeleteVolume: Volume deleted successfully. VolumeID: %q", req.VolumeId)
 		return &csi.DeleteVolumeResponse{}, "", nil
 	}
+	// The PV and PVC are looked up before the volume is deleted.
+	auditEvent := &audit.Event{
+		Operation:         audit.OperationDeleteVolume,
+		Component:         audit.ComponentController,
+		VolumeID:          req.VolumeId,
+		KubernetesObjects: commonco.ContainerOrchestratorUtility.GetVolumeObjectReferences(ctx, req.VolumeId),
+	}
 	resp, faultType, err := deleteVolumeInternal()
+	audit.Log(ctx, auditEvent, err)
 	log.Debugf("deleteVolumeInternal: returns fault %q for volume %q", faultType, req.VolumeId)
 	if err != nil {
 		log.Errorf("Operation failed, reporting failure status to Prometheus."+
@@ -693,6 +702,14 @@ func controllerPublishForBlockVolume(ctx context.Context, req *csi.ControllerPub
 func controllerPublishForFileVolume(ctx context.Context, req *csi.ControllerPublishVolumeRequest, c *controller) (
 	*csi.ControllerPublishVolumeResponse, string, error) {
 	log := logger.GetLogger(ctx)
+	// Validate the mount options and choose the access point for the NFS
+	// version requested by them.
+	nfsVersion, err := common.ValidateFileVolumeMountFlags(req.GetVolumeCapability().GetMount().GetMountFlags())
+	if err != nil {
+		return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"invalid mount options for volume: %q. Error: %v", req.VolumeId, err)
+	}
+	accessPointKey, accessPoint := common.GetNfsAccessPointKeys(nfsVersion)
 	// Build the CnsFileAccessConfig instance name and namespace
 	cnsFileAccessConfigInstance := &cnsfileaccessconfigv1alpha1.CnsFileAccessConfig{}
 	cnsFileAccessConfigInstanceName := req.NodeId + "-" + req.VolumeId
@@ -745,8 +762,8 @@ func controllerPublishForFileVolume(ctx context.Context, req *csi.ControllerPubl
 	// Verify if the CnsFileAccessConfig instance has status with done set to true and error is empty
 	if cnsFileAccessConfigInstance.Status.Done && cnsFileAccessConfigInstance.Status.Error == "" {
 		for key, value := range cnsFileAccessConfigInstance.Status.AccessPoints {
-			if key == common.Nfsv4AccessPointKey {
-				publishInfo[common.Nfsv4AccessPoint] = value
+			if key == accessPointKey {
+				publishInfo[accessPoint] = value
 				break
 			}
 		}
@@ -810,14 +827,14 @@ func controllerPublishForFileVolume(ctx context.Context, req *csi.ControllerPubl
 			cnsfileaccessconfig.DeletionTimestamp == nil {
 			// Check if the updated instance has the AccessPoints
 			for key, value := range cnsfileaccessconfig.Status.AccessPoints {
-				if key == common.Nfsv4AccessPointKey {
+				if key == accessPointKey {
 					publishInfo[common.AttributeDiskType] = common.DiskTypeFileVolume
-					publishInfo[common.Nfsv4AccessPoint] = value
+					publishInfo[accessPoint] = value
 					break
 				}
 			}
-			if _, ok := publishInfo[common.Nfsv4AccessPoint]; ok {
-				log.Debugf("Found Nfsv4AccessPoint in publishInfo. publishInfo=%+v", publishInfo)
+			if _, ok := publishInfo[accessPoint]; ok {
+				log.Debugf("Found %s in publishInfo. publishInfo=%+v", accessPoint, publishInfo)
 				break
 			}
 		}
@@ -835,7 +852,7 @@ func controllerPublishForFileVolume(ctx context.Context, req *csi.ControllerPubl
 func (c *controller) ControllerUnpublishVolume(ctx context.Context, req *csi.ControllerUnpublishVolumeRequest) (
 	*csi.ControllerUnpublishVolumeResponse, error) {
 	start := time.Now()
-	ctx = logger.NewContextWithLogger(ctx)
+	ctx = audit.NewContext(logger.NewContextWithLogger(ctx))
 	log := logger.GetLogger(ctx)
 	volumeType := prometheus.PrometheusUnknownVolumeType
 
@@ -883,6 +900,13 @@ func (c *controller) ControllerUnpublishVolume(ctx context.Context, req *csi.Con
 		return controllerUnpublishForBlockVolume(ctx, req, c)
 	}
 	resp, faultType, err := controllerUnpublishVolumeInternal()
+	audit.Log(ctx, &audit.Event{
+		Operation:         audit.OperationControllerUnpublish,
+		Component:         audit.ComponentController,
+		VolumeID:          req.VolumeId,
+		NodeID:            req.NodeId,
+		KubernetesObjects: commonco.ContainerOrchestratorUtility.GetVolumeObjectReferences(ctx, req.VolumeId),
+	}, err)
 	log.Debugf("controllerUnpublishVolumeInternal: returns fault %q for volume %q", faultType, req.VolumeId)
 	if err != nil {
 		log.Errorf("Operation failed, reporting failure status to Prometheus."+
@@ -1109,7 +1133,7 @@ func controllerUnpublishForFileVolume(ctx context.Context, req *csi.ControllerUn
 func (c *controller) ControllerExpandVolume(ctx context.Context, req *csi.ControllerExpandVolumeRequest) (
 	*csi.ControllerExpandVolumeResponse, error) {
 	start := time.Now()
-	ctx = logger.NewContextWithLogger(ctx)
+	ctx = audit.NewContext(logger.NewContextWithLogger(ctx))
 	log := logger.GetLogger(ctx)
 	volumeType := prometheus.PrometheusUnknownVolumeType
 
@@ -1241,6 +1265,13 @@ func (c *controller) ControllerExpandVolume(ctx context.Context, req *csi.Contro
 		return resp, "", nil
 	}
 	resp, faultType, err := controllerExpandVolumeInternal()
+	audit.Log(ctx, &audit.Event{
+		Operation:         audit.OperationExpandVolume,
+		Component:         audit.ComponentController,
+		VolumeID:          req.VolumeId,
+		CapacityInMb:      common.RoundUpSize(req.GetCapacityRange().GetRequiredBytes(), common.MbInBytes),
+		KubernetesObjects: commonco.ContainerOrchestratorUtility.GetVolumeObjectReferences(ctx, req.VolumeId),
+	}, err)
 	log.Debugf("controllerExpandVolumeInternal: returns fault %q for volume %q", faultType, req.VolumeId)
 	if err != nil {
 		log.Errorf("Operation failed, reporting failure status to Prometheus."+
@@ -1433,7 +1464,7 @@ func (c *controller) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshot
 
 func (c *controller) DeleteSnapshot(ctx context.Context, req *csi.DeleteSnapshotRequest) (
 	*csi.DeleteSnapshotResponse, error) {
-	ctx = logger.NewContextWithLogger(ctx)
+	ctx = audit.NewContext(logger.NewContextWithLogger(ctx))
 	log := logger.GetLogger(ctx)
 	start := time.Now()
 	volumeType := prometheus.PrometheusBlockVolumeType
@@ -1479,6 +1510,12 @@ func (c *controller) DeleteSnapshot(ctx context.Context, req *csi.DeleteSnapshot
 		return &csi.DeleteSnapshotResponse{}, nil
 	}
 	resp, err := deleteSnapshotInternal()
+	// The snapshot ID is the name of the supervisor VolumeSnapshot.
+	audit.Log(ctx, &audit.Event{
+		Operation:  audit.OperationDeleteSnapshot,
+		Component:  audit.ComponentController,
+		SnapshotID: req.GetSnapshotId(),
+	}, err)
 	if err != nil {
 		prometheus.CsiControlOpsHistVec.WithLabelValues(volumeType, prometheus.PrometheusDeleteSnapshotOpType,
 			prometheus.PrometheusFailStatus, "NotComputed").Observe(time.Since(start).Seconds())
@@ -28,10 +28,16 @@ import (
 
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	clientset "k8s.io/client-go/kubernetes"
 	testclient "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	cnsoperatorv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
+	cnsfileaccessconfigv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsfileaccessconfig/v1alpha1"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/unittestcommon"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
@@ -416,3 +422,65 @@ func TestGenerateGuestClusterRequestedTopologyJSON(t *testing.T) {
 	t.Logf("volumeAccessibleTopologyJSON %v match with expectedVolumeAccessibleTopologyJSON: %v",
 		volumeAccessibleTopologyJSON, expectedVolumeAccessibleTopologyJSON)
 }
+
+// TestControllerPublishForFileVolumeAccessPoint tests that the access point of
+// the NFS version requested by the mount options is published.
+func TestControllerPublishForFileVolumeAccessPoint(t *testing.T) {
+	ctx := context.Background()
+	nodeID, volumeID := "test-vm", "test-supervisor-pvc"
+	s := runtime.NewScheme()
+	if err := cnsoperatorv1alpha1.AddToScheme(s); err != nil {
+		t.Fatalf("Failed to register CnsOperator types. Err: %v", err)
+	}
+	c := &controller{
+		supervisorNamespace: testNamespace,
+		cnsOperatorClient: fake.NewClientBuilder().WithScheme(s).WithObjects(
+			&cnsfileaccessconfigv1alpha1.CnsFileAccessConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeID + "-" + volumeID, Namespace: testNamespace},
+				Spec:       cnsfileaccessconfigv1alpha1.CnsFileAccessConfigSpec{VMName: nodeID, PvcName: volumeID},
+				Status: cnsfileaccessconfigv1alpha1.CnsFileAccessConfigStatus{
+					Done: true,
+					AccessPoints: map[string]string{
+						common.Nfsv4AccessPointKey: "10.0.0.1:/52d7e15c-d282-3be5-8c3d-8a4b1c5a6d7e",
+						common.Nfsv3AccessPointKey: "10.0.0.1:/vsanfs/pvc-12345",
+					},
+				},
+			}).Build(),
+	}
+	tests := []struct {
+		mountFlags  []string
+		accessPoint string
+		value       string
+	}{
+		{nil, common.Nfsv4AccessPoint, "10.0.0.1:/52d7e15c-d282-3be5-8c3d-8a4b1c5a6d7e"},
+		{[]string{"vers=4.1"}, common.Nfsv4AccessPoint, "10.0.0.1:/52d7e15c-d282-3be5-8c3d-8a4b1c5a6d7e"},
+		{[]string{"nfsvers=3,proto=tcp"}, common.Nfsv3AccessPoint, "10.0.0.1:/vsanfs/pvc-12345"},
+	}
+	newRequest := func(mountFlags []string) *csi.ControllerPublishVolumeRequest {
+		return &csi.ControllerPublishVolumeRequest{
+			NodeId:   nodeID,
+			VolumeId: volumeID,
+			VolumeCapability: &csi.VolumeCapability{
+				AccessType: &csi.VolumeCapability_Mount{
+					Mount: &csi.VolumeCapability_MountVolume{MountFlags: mountFlags},
+				},
+			},
+		}
+	}
+	for _, test := range tests {
+		resp, _, err := controllerPublishForFileVolume(ctx, newRequest(test.mountFlags), c)
+		if err != nil {
+			t.Fatalf("mount flags %v: ControllerPublishVolume failed. Err: %v", test.mountFlags, err)
+		}
+		if value := resp.PublishContext[test.accessPoint]; value != test.value {
+			t.Errorf("mount flags %v: expected %s %q in publish context %v", test.mountFlags,
+				test.accessPoint, test.value, resp.PublishContext)
+		}
+	}
+	for _, mountFlags := range [][]string{{"vers=4.2"}, {"proto=udp"}, {"hard", "soft"}} {
+		_, _, err := controllerPublishForFileVolume(ctx, newRequest(mountFlags), c)
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("mount flags %v: expected InvalidArgument, got %v", mountFlags, err)
+		}
+	}
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package types
 
 import (
+	"context"
+
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
 )
@@ -27,3 +29,11 @@ type CnsController interface {
 	csi.ControllerServer
 	Init(config *config.Config, version string) error
 }
+
+// HealthChecker is implemented by the CnsControllers which can check whether
+// they are ready to serve requests, e.g. whether vCenter is reachable.
+type HealthChecker interface {
+	// CheckReadiness returns an error if the controller is not ready. It must
+	// not block on vCenter, as it is called by Probe.
+	CheckReadiness(ctx context.Context) error
+}
@@ -55,6 +55,14 @@ type TriggerCsiFullSyncStatus struct {
 	// This timestamp can be either the successful or failed full sync end timestamp.
 	LastRunEndTimeStamp *metav1.Time `json:"lastRunEndTimeStamp,omitempty"`
 
+	// ShardCount indicates the number of shards the volumes are split into
+	// by full sync. Each periodic full sync processes one shard, while a full
+	// sync triggered by updating TriggerSyncID processes all the shards.
+	ShardCount int `json:"shardCount,omitempty"`
+
+	// NextShard indicates the shard to be processed by the next full sync.
+	NextShard int `json:"nextShard,omitempty"`
+
 	// The last error encountered during CSI full sync operation, if any.
 	// Previous error will be cleared when a new full sync is in progress.
 	Error string `json:"error,omitempty"`
@@ -403,6 +403,10 @@ func (or *operationRequestStore) cleanupStaleInstances(cleanupInterval int, isBl
 				trimmedName = strings.TrimPrefix(instance.Name, "delete-")
 			case strings.HasPrefix(instance.Name, "expand"):
 				trimmedName = strings.TrimPrefix(instance.Name, "expand-")
+			case strings.HasPrefix(instance.Name, "registervolume"):
+				// Instances for CnsRegisterVolume requests are retained as long as
+				// the registered volume is backing a PV.
+				trimmedName = instance.Status.VolumeID
 			case blockVolumeSnapshotEnabled && strings.HasPrefix(instance.Name, "snapshot"):
 				trimmedName = strings.TrimPrefix(instance.Name, "snapshot-")
 			case blockVolumeSnapshotEnabled && strings.HasPrefix(instance.Name, "deletesnapshot"):
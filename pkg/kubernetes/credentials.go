@@ -0,0 +1,189 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+const (
+	// CredentialsSecretUsernameKey is the key of the vCenter username in a
+	// credentials Secret.
+	CredentialsSecretUsernameKey = "username"
+	// CredentialsSecretPasswordKey is the key of the vCenter password in a
+	// credentials Secret.
+	CredentialsSecretPasswordKey = "password"
+
+	resyncPeriodCredentialsSecretInformer = 10 * time.Minute
+)
+
+// getCredentialsFromSecret returns the vCenter username and password held by
+// the given credentials Secret.
+func getCredentialsFromSecret(secret *v1.Secret) (string, string, error) {
+	username := string(secret.Data[CredentialsSecretUsernameKey])
+	password := string(secret.Data[CredentialsSecretPasswordKey])
+	if username == "" || password == "" {
+		return "", "", fmt.Errorf("secret %s/%s must have non-empty %q and %q keys", secret.Namespace,
+			secret.Name, CredentialsSecretUsernameKey, CredentialsSecretPasswordKey)
+	}
+	return username, password, nil
+}
+
+// credentialsSecretWatch is the watch of the credentials Secret of a vCenter.
+type credentialsSecretWatch struct {
+	namespace string
+	name      string
+	stop      context.CancelFunc
+}
+
+var (
+	credentialsSecretWatchesLock sync.Mutex
+	// credentialsSecretWatches holds the watches of the credentials Secrets by
+	// vCenter host.
+	credentialsSecretWatches = make(map[string]*credentialsSecretWatch)
+)
+
+// WatchCredentialsSecrets reads the credentials of the vCenters referencing a
+// credentials Secret in the given config, and watches these Secrets to rotate
+// the credentials when they are updated. The Secrets without a namespace are
+// looked up in the given default namespace. The credentials are kept when a
+// Secret is deleted. It is called again when the config is reloaded: the
+// Secrets which are already watched are kept, the newly referenced ones are
+// watched, and the ones no longer referenced are no longer watched, the
+// configured credentials of their vCenter being used again.
+func WatchCredentialsSecrets(ctx context.Context, cfg *config.Config, defaultNamespace string) error {
+	log := logger.GetLogger(ctx)
+	credentialsSecretWatchesLock.Lock()
+	defer credentialsSecretWatchesLock.Unlock()
+	for host, watch := range credentialsSecretWatches {
+		if vcConfig, ok := cfg.VirtualCenter[host]; ok && vcConfig.SecretName == watch.name &&
+			getCredentialsSecretNamespace(vcConfig, defaultNamespace) == watch.namespace {
+			continue
+		}
+		log.Infof("vCenter %q no longer references credentials Secret %s/%s", host, watch.namespace, watch.name)
+		watch.stop()
+		delete(credentialsSecretWatches, host)
+		cnsvsphere.UnsetCredentials(host)
+	}
+	var k8sClient clientset.Interface
+	for host, vcConfig := range cfg.VirtualCenter {
+		if vcConfig.SecretName == "" {
+			continue
+		}
+		if _, ok := credentialsSecretWatches[host]; ok {
+			continue
+		}
+		if k8sClient == nil {
+			var err error
+			k8sClient, err = NewClient(ctx)
+			if err != nil {
+				return logger.LogNewErrorf(log, "failed to create kubernetes client. Err: %v", err)
+			}
+		}
+		namespace := getCredentialsSecretNamespace(vcConfig, defaultNamespace)
+		// The informer must outlive the given context, which may be the one of
+		// a config reload.
+		watchCtx, stop := context.WithCancel(logger.NewContextWithLogger(context.Background()))
+		if err := watchCredentialsSecret(watchCtx, k8sClient, host, namespace, vcConfig.SecretName); err != nil {
+			stop()
+			return err
+		}
+		credentialsSecretWatches[host] = &credentialsSecretWatch{
+			namespace: namespace,
+			name:      vcConfig.SecretName,
+			stop:      stop,
+		}
+	}
+	return nil
+}
+
+// getCredentialsSecretNamespace returns the namespace of the credentials
+// Secret of the given vCenter config, or the given default namespace if none
+// is set.
+func getCredentialsSecretNamespace(vcConfig *config.VirtualCenterConfig, defaultNamespace string) string {
+	if vcConfig.SecretNamespace != "" {
+		return vcConfig.SecretNamespace
+	}
+	return defaultNamespace
+}
+
+// watchCredentialsSecret sets the credentials of the given vCenter from the
+// given Secret, and watches the Secret to set them again when it is updated.
+func watchCredentialsSecret(ctx context.Context, k8sClient clientset.Interface, host string,
+	namespace string, name string) error {
+	log := logger.GetLogger(ctx)
+	secret, err := k8sClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return logger.LogNewErrorf(log, "failed to get credentials Secret %s/%s of vCenter %q. Err: %v",
+			namespace, name, host, err)
+	}
+	username, password, err := getCredentialsFromSecret(secret)
+	if err != nil {
+		return logger.LogNewErrorf(log, "invalid credentials Secret of vCenter %q. Err: %v", host, err)
+	}
+	if err := cnsvsphere.SetCredentials(ctx, host, username, password); err != nil {
+		return err
+	}
+	setCredentials := func(obj interface{}) {
+		secret, ok := obj.(*v1.Secret)
+		if !ok || secret == nil {
+			return
+		}
+		username, password, err := getCredentialsFromSecret(secret)
+		if err != nil {
+			log.Errorf("Invalid credentials Secret of vCenter %q, keeping the current credentials. Err: %v",
+				host, err)
+			return
+		}
+		if err := cnsvsphere.SetCredentials(ctx, host, username, password); err != nil {
+			log.Errorf("Failed to rotate the credentials of vCenter %q. Err: %v", host, err)
+		}
+	}
+	informerFactory := informers.NewSharedInformerFactoryWithOptions(k8sClient,
+		resyncPeriodCredentialsSecretInformer, informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}))
+	informer := informerFactory.Core().V1().Secrets().Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: setCredentials,
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			setCredentials(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			log.Warnf("Credentials Secret %s/%s of vCenter %q was deleted, keeping the current credentials",
+				namespace, name, host)
+		},
+	})
+	informerFactory.Start(ctx.Done())
+	log.Infof("Watching Secret %s/%s for the credentials of vCenter %q", namespace, name, host)
+	return nil
+}
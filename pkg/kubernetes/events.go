@@ -0,0 +1,72 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// eventBurstSize is the number of events which can be recorded on an
+	// object before the events on it are rate limited.
+	eventBurstSize = 10
+	// eventQPS is the rate at which events can be recorded on an object once
+	// eventBurstSize is exhausted, i.e. one event every 5 minutes.
+	eventQPS = 1. / 300.
+	// eventMaxSimilarEvents is the number of events with the same reason on
+	// an object after which they are aggregated into a single event.
+	eventMaxSimilarEvents = 5
+	// eventMaxIntervalInSeconds is the interval over which similar events
+	// are aggregated.
+	eventMaxIntervalInSeconds = 600
+)
+
+// NewEventRecorder returns an EventRecorder which records events from the
+// given component to the API server. Similar events on an object are
+// aggregated and the events on each object are rate limited, so that
+// repeated failures, e.g. when vCenter is flapping, do not flood the API
+// server.
+func NewEventRecorder(k8sClient clientset.Interface, component string) record.EventRecorder {
+	eventBroadcaster := record.NewBroadcasterWithCorrelatorOptions(record.CorrelatorOptions{
+		BurstSize:            eventBurstSize,
+		QPS:                  eventQPS,
+		MaxEvents:            eventMaxSimilarEvents,
+		MaxIntervalInSeconds: eventMaxIntervalInSeconds,
+	})
+	eventBroadcaster.StartRecordingToSink(
+		&typedcorev1.EventSinkImpl{
+			Interface: k8sClient.CoreV1().Events(""),
+		},
+	)
+	return eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: component})
+}
+
+// RecordFaultEvent records a Warning event with the given reason on the given
+// object. The fault type is appended to the message, so that the event can be
+// correlated with the fault types reported in the metrics.
+func RecordFaultEvent(recorder record.EventRecorder, object runtime.Object, reason string,
+	faultType string, message string) {
+	if recorder == nil || object == nil {
+		return
+	}
+	recorder.Eventf(object, v1.EventTypeWarning, reason, "%s (fault: %s)", message, faultType)
+}
@@ -21,10 +21,12 @@ import (
 	"sync"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/informers"
 	v1 "k8s.io/client-go/informers/core/v1"
 	clientset "k8s.io/client-go/kubernetes"
 	corelisters "k8s.io/client-go/listers/core/v1"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/sample-controller/pkg/signals"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
@@ -40,6 +42,12 @@ const (
 	// as part of NewFilteredConfigMapInformer(). Since we do not anticipate
 	// frequent changes to the configmaps, the resync interval is set to 30 min.
 	resyncPeriodConfigMapInformer = 30 * time.Minute
+
+	// PVVolumeHandleIndex is the name of the index of the PVs by the CSI
+	// volume handle.
+	PVVolumeHandleIndex = "pvVolumeHandle"
+	// PVCUIDIndex is the name of the index of the PVCs by UID.
+	PVCUIDIndex = "pvcUID"
 )
 
 var (
@@ -47,6 +55,8 @@ var (
 	inClusterInformerInstanceLock                      = &sync.Mutex{}
 	supervisorInformerManagerInstance *InformerManager = nil
 	supervisorInformerInstanceLock                     = &sync.Mutex{}
+	pvIndexerLock                                      = &sync.Mutex{}
+	pvcIndexerLock                                     = &sync.Mutex{}
 )
 
 func noResyncPeriodFunc() time.Duration {
@@ -186,6 +196,26 @@ func (im *InformerManager) AddConfigMapListener(
 	go im.configMapInformer.Run(stopCh)
 }
 
+// AddSecretListener hooks up add, update, delete callbacks.
+func (im *InformerManager) AddSecretListener(
+	ctx context.Context, client clientset.Interface, namespace string,
+	add func(obj interface{}), update func(oldObj, newObj interface{}), remove func(obj interface{})) {
+	if im.secretInformer == nil {
+		im.secretInformer = v1.NewFilteredSecretInformer(client, namespace,
+			resyncPeriodConfigMapInformer, cache.Indexers{}, nil)
+	}
+
+	im.secretInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    add,
+		UpdateFunc: update,
+		DeleteFunc: remove,
+	})
+	stopCh := make(chan struct{})
+	// Since NewFilteredSecretInformer is not part of the informer factory,
+	// we need to invoke the Run() explicitly to start the shared informer.
+	go im.secretInformer.Run(stopCh)
+}
+
 // AddPodListener hooks up add, update, delete callbacks.
 func (im *InformerManager) AddPodListener(
 	add func(obj interface{}), update func(oldObj, newObj interface{}), remove func(obj interface{})) {
@@ -220,21 +250,137 @@ func (im *InformerManager) GetPVLister() corelisters.PersistentVolumeLister {
 	return im.informerFactory.Core().V1().PersistentVolumes().Lister()
 }
 
+// GetPVVolumeHandleIndexer returns the PV indexer of the calling informer
+// manager, with the PVs indexed by CSI volume handle under
+// PVVolumeHandleIndex. It must be called before the informers are started.
+func (im *InformerManager) GetPVVolumeHandleIndexer() (cache.Indexer, error) {
+	pvIndexerLock.Lock()
+	defer pvIndexerLock.Unlock()
+	informer := im.informerFactory.Core().V1().PersistentVolumes().Informer()
+	if _, ok := informer.GetIndexer().GetIndexers()[PVVolumeHandleIndex]; !ok {
+		err := informer.AddIndexers(cache.Indexers{PVVolumeHandleIndex: PVVolumeHandleIndexFunc})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if im.pvSynced == nil {
+		im.pvSynced = informer.HasSynced
+	}
+	return informer.GetIndexer(), nil
+}
+
+// PVVolumeHandleIndexFunc indexes the CSI PVs by volume handle, under
+// PVVolumeHandleIndex.
+func PVVolumeHandleIndexFunc(obj interface{}) ([]string, error) {
+	pv, ok := obj.(*corev1.PersistentVolume)
+	if !ok || pv.Spec.CSI == nil || pv.Spec.CSI.VolumeHandle == "" {
+		return nil, nil
+	}
+	return []string{pv.Spec.CSI.VolumeHandle}, nil
+}
+
+// GetPVsByVolumeHandle returns the PVs of the given indexer, returned by
+// GetPVVolumeHandleIndexer, with the given CSI driver and volume handle.
+func GetPVsByVolumeHandle(indexer cache.Indexer, driver string,
+	volumeHandle string) ([]*corev1.PersistentVolume, error) {
+	objs, err := indexer.ByIndex(PVVolumeHandleIndex, volumeHandle)
+	if err != nil {
+		return nil, err
+	}
+	var pvs []*corev1.PersistentVolume
+	for _, obj := range objs {
+		if pv, ok := obj.(*corev1.PersistentVolume); ok && pv.Spec.CSI.Driver == driver {
+			pvs = append(pvs, pv)
+		}
+	}
+	return pvs, nil
+}
+
 // GetPVCLister returns PVC Lister for the calling informer manager.
 func (im *InformerManager) GetPVCLister() corelisters.PersistentVolumeClaimLister {
 	return im.informerFactory.Core().V1().PersistentVolumeClaims().Lister()
 }
 
+// GetPVCUIDIndexer returns the PVC indexer of the calling informer manager,
+// with the PVCs indexed by UID under PVCUIDIndex. It must be called before the
+// informers are started.
+func (im *InformerManager) GetPVCUIDIndexer() (cache.Indexer, error) {
+	pvcIndexerLock.Lock()
+	defer pvcIndexerLock.Unlock()
+	informer := im.informerFactory.Core().V1().PersistentVolumeClaims().Informer()
+	if _, ok := informer.GetIndexer().GetIndexers()[PVCUIDIndex]; !ok {
+		err := informer.AddIndexers(cache.Indexers{PVCUIDIndex: PVCUIDIndexFunc})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if im.pvcSynced == nil {
+		im.pvcSynced = informer.HasSynced
+	}
+	return informer.GetIndexer(), nil
+}
+
+// PVCUIDIndexFunc indexes the PVCs by UID, under PVCUIDIndex.
+func PVCUIDIndexFunc(obj interface{}) ([]string, error) {
+	pvc, ok := obj.(*corev1.PersistentVolumeClaim)
+	if !ok || pvc.UID == "" {
+		return nil, nil
+	}
+	return []string{string(pvc.UID)}, nil
+}
+
 // GetConfigMapLister returns ConfigMap Lister for the calling informer manager.
 func (im *InformerManager) GetConfigMapLister() corelisters.ConfigMapLister {
 	return im.informerFactory.Core().V1().ConfigMaps().Lister()
 }
 
+// GetFilteredConfigMapLister returns the ConfigMap Lister of the namespace
+// filtered informer started by AddConfigMapListener, or nil if no listener
+// was added.
+func (im *InformerManager) GetFilteredConfigMapLister() corelisters.ConfigMapLister {
+	if im.configMapInformer == nil {
+		return nil
+	}
+	return corelisters.NewConfigMapLister(im.configMapInformer.GetIndexer())
+}
+
+// GetFilteredSecretLister returns the Secret Lister of the namespace filtered
+// informer started by AddSecretListener, or nil if no listener was added.
+func (im *InformerManager) GetFilteredSecretLister() corelisters.SecretLister {
+	if im.secretInformer == nil {
+		return nil
+	}
+	return corelisters.NewSecretLister(im.secretInformer.GetIndexer())
+}
+
+// GetNamespaceLister returns Namespace Lister for the calling informer manager.
+func (im *InformerManager) GetNamespaceLister() corelisters.NamespaceLister {
+	return im.informerFactory.Core().V1().Namespaces().Lister()
+}
+
+// GetStorageClassLister returns StorageClass Lister for the calling informer
+// manager.
+func (im *InformerManager) GetStorageClassLister() storagelisters.StorageClassLister {
+	return im.informerFactory.Storage().V1().StorageClasses().Lister()
+}
+
 // GetPodLister returns Pod Lister for the calling informer manager.
 func (im *InformerManager) GetPodLister() corelisters.PodLister {
 	return im.informerFactory.Core().V1().Pods().Lister()
 }
 
+// HasSynced returns true if the caches of all the informers with listeners
+// are synced. It does not wait for them to sync.
+func (im *InformerManager) HasSynced() bool {
+	for _, synced := range []cache.InformerSynced{im.pvSynced, im.pvcSynced, im.podSynced, im.configMapSynced,
+		im.namespaceSynced} {
+		if synced != nil && !synced() {
+			return false
+		}
+	}
+	return true
+}
+
 // Listen starts the Informers.
 func (im *InformerManager) Listen() (stopCh <-chan struct{}) {
 	go im.informerFactory.Start(im.stopCh)
@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"context"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+const (
+	// LogLevelConfigMapName is the name of the ConfigMap holding the log level
+	// of the driver components. The key of each entry is a component name, or
+	// "default" for the components without an entry, and the value is a zap
+	// level name or number.
+	LogLevelConfigMapName = "vsphere-csi-log-level"
+	// LogLevelDefaultKey is the key of the log level of the components which
+	// have no entry in the log level ConfigMap.
+	LogLevelDefaultKey = "default"
+
+	// Components whose log level can be set in the log level ConfigMap.
+	LogComponentController = "controller"
+	LogComponentNode       = "node"
+	LogComponentSyncer     = "syncer"
+	LogComponentWebhook    = "webhook"
+
+	resyncPeriodLogLevelConfigMapInformer = 10 * time.Minute
+)
+
+// getComponentLogLevel returns the log level of the given component set in
+// the given log level ConfigMap data, or an empty string if none is set.
+func getComponentLogLevel(data map[string]string, component string) string {
+	if level, ok := data[component]; ok && level != "" {
+		return level
+	}
+	return data[LogLevelDefaultKey]
+}
+
+// WatchLogLevelConfigMap watches the log level ConfigMap in the given
+// namespace and changes the log level of the given component when it is
+// updated. When the ConfigMap or the entry of the component is removed, the
+// log level set at startup is restored.
+func WatchLogLevelConfigMap(ctx context.Context, namespace string, component string) error {
+	log := logger.GetLogger(ctx)
+	k8sClient, err := NewClient(ctx)
+	if err != nil {
+		return logger.LogNewErrorf(log, "failed to create kubernetes client. Err: %v", err)
+	}
+	setLevel := func(obj interface{}) {
+		level := ""
+		if configMap, ok := obj.(*v1.ConfigMap); ok && configMap != nil {
+			level = getComponentLogLevel(configMap.Data, component)
+		}
+		if err := logger.SetLevel(level); err != nil {
+			log.Errorf("Failed to set log level of %s from ConfigMap %s/%s. Err: %v", component, namespace,
+				LogLevelConfigMapName, err)
+		}
+	}
+	informerFactory := informers.NewSharedInformerFactoryWithOptions(k8sClient,
+		resyncPeriodLogLevelConfigMapInformer, informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", LogLevelConfigMapName).String()
+		}))
+	informer := informerFactory.Core().V1().ConfigMaps().Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: setLevel,
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			setLevel(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			setLevel(nil)
+		},
+	})
+	informerFactory.Start(ctx.Done())
+	log.Infof("Watching ConfigMap %s/%s for the log level of %s", namespace, LogLevelConfigMapName, component)
+	return nil
+}
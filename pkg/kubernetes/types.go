@@ -57,10 +57,13 @@ type InformerManager struct {
 	nodeInformer cache.SharedInformer
 
 	// ConfigMap informer
-	configMapInformer cache.SharedInformer
+	configMapInformer cache.SharedIndexInformer
 	// Function to determine if configMapInformer has been synced
 	configMapSynced cache.InformerSynced
 
+	// Secret informer
+	secretInformer cache.SharedIndexInformer
+
 	// PV informer
 	pvInformer cache.SharedInformer
 	// Function to determine if pvInformer has been synced
@@ -0,0 +1,416 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnscrossvcentervolumerelocation
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	apis "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
+	relocationv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnscrossvcentervolumerelocation/v1alpha1"
+	volumes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
+	commonconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/cnsvolumeinfo"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
+	cnsoperatorutil "sigs.k8s.io/vsphere-csi-driver/v2/pkg/syncer/cnsoperator/util"
+)
+
+const defaultMaxWorkerThreadsForCrossVCenterVolumeRelocation = 2
+
+// backOffDuration is a map of cnscrossvcentervolumerelocation name's to the
+// time after which a request for this instance will be requeued.
+// Initialized to 1 second for new instances and for instances whose latest
+// reconcile operation succeeded.
+// If the reconcile fails, backoff is incremented exponentially.
+var (
+	backOffDuration         map[string]time.Duration
+	backOffDurationMapMutex = sync.Mutex{}
+)
+
+// Add creates a new CnsCrossVCenterVolumeRelocation Controller and adds it to
+// the Manager. The Manager will set fields on the Controller and Start it when
+// the Manager is Started.
+func Add(mgr manager.Manager, clusterFlavor cnstypes.CnsClusterFlavor,
+	configInfo *commonconfig.ConfigurationInfo, volumeManager volumes.Manager) error {
+	ctx, log := logger.GetNewContextWithLogger()
+	if clusterFlavor != cnstypes.CnsClusterFlavorVanilla {
+		log.Debug("Not initializing the CnsCrossVCenterVolumeRelocation Controller as its not a vanilla cluster")
+		return nil
+	}
+	if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.MultiVCenterCSITopology) {
+		log.Debugf("Not initializing the CnsCrossVCenterVolumeRelocation Controller as %q feature is disabled",
+			common.MultiVCenterCSITopology)
+		return nil
+	}
+	if len(configInfo.Cfg.VirtualCenter) < 2 {
+		log.Debug("Not initializing the CnsCrossVCenterVolumeRelocation Controller as its not a " +
+			"multi vCenter deployment")
+		return nil
+	}
+	volumeInfoService, err := cnsvolumeinfo.InitVolumeInfoService(ctx)
+	if err != nil {
+		log.Errorf("Failed to initialize volumeInfo service. Err: %v", err)
+		return err
+	}
+	// Initializes kubernetes client.
+	k8sclient, err := k8s.NewClient(ctx)
+	if err != nil {
+		log.Errorf("Creating Kubernetes client failed. Err: %v", err)
+		return err
+	}
+
+	// eventBroadcaster broadcasts events on cnscrossvcentervolumerelocation
+	// instances to the event sink.
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(
+		&typedcorev1.EventSinkImpl{
+			Interface: k8sclient.CoreV1().Events(""),
+		},
+	)
+	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: apis.GroupName})
+	return add(mgr, newReconciler(mgr, configInfo, k8sclient, volumeInfoService, recorder))
+}
+
+// newReconciler returns a new reconcile.Reconciler.
+func newReconciler(mgr manager.Manager, configInfo *commonconfig.ConfigurationInfo, k8sclient clientset.Interface,
+	volumeInfoService cnsvolumeinfo.VolumeInfoService, recorder record.EventRecorder) reconcile.Reconciler {
+	return &ReconcileCnsCrossVCenterVolumeRelocation{client: mgr.GetClient(), scheme: mgr.GetScheme(),
+		configInfo: configInfo, k8sclient: k8sclient, volumeInfoService: volumeInfoService, recorder: recorder}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler.
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	_, log := logger.GetNewContextWithLogger()
+
+	// Create a new controller.
+	c, err := controller.New("cnscrossvcentervolumerelocation-controller", mgr,
+		controller.Options{Reconciler: r,
+			MaxConcurrentReconciles: defaultMaxWorkerThreadsForCrossVCenterVolumeRelocation})
+	if err != nil {
+		log.Errorf("Failed to create new CnsCrossVCenterVolumeRelocation controller with error: %+v", err)
+		return err
+	}
+
+	backOffDuration = make(map[string]time.Duration)
+
+	// Watch for changes to primary resource CnsCrossVCenterVolumeRelocation.
+	err = c.Watch(&source.Kind{Type: &relocationv1alpha1.CnsCrossVCenterVolumeRelocation{}},
+		&handler.EnqueueRequestForObject{})
+	if err != nil {
+		log.Errorf("Failed to watch for changes to CnsCrossVCenterVolumeRelocation resource with error: %+v", err)
+		return err
+	}
+	return nil
+}
+
+// blank assignment to verify that ReconcileCnsCrossVCenterVolumeRelocation
+// implements reconcile.Reconciler.
+var _ reconcile.Reconciler = &ReconcileCnsCrossVCenterVolumeRelocation{}
+
+// ReconcileCnsCrossVCenterVolumeRelocation reconciles a
+// CnsCrossVCenterVolumeRelocation object.
+type ReconcileCnsCrossVCenterVolumeRelocation struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver.
+	client            client.Client
+	scheme            *runtime.Scheme
+	configInfo        *commonconfig.ConfigurationInfo
+	k8sclient         clientset.Interface
+	volumeInfoService cnsvolumeinfo.VolumeInfoService
+	recorder          record.EventRecorder
+}
+
+// Reconcile reads that state of the cluster for a
+// CnsCrossVCenterVolumeRelocation object and makes changes based on the state
+// read and what is in the CnsCrossVCenterVolumeRelocation.Spec.
+// The volume is relocated to the target datastore using the source vCenter,
+// unregistered from CNS on the source vCenter, registered with CNS on the
+// target vCenter and the PersistentVolume is updated with the node affinity
+// of the target vCenter. The phase of the relocation is persisted in the
+// status of the instance, so that retries resume from the failed phase.
+// Note:
+// The Controller will requeue the Request to be processed again if the
+// returned error is non-nil or Result.Requeue is true. Otherwise, upon
+// completion it will remove the work from the queue.
+func (r *ReconcileCnsCrossVCenterVolumeRelocation) Reconcile(ctx context.Context,
+	request reconcile.Request) (reconcile.Result, error) {
+	log := logger.GetLogger(ctx)
+	// Fetch the CnsCrossVCenterVolumeRelocation instance.
+	instance := &relocationv1alpha1.CnsCrossVCenterVolumeRelocation{}
+	err := r.client.Get(ctx, request.NamespacedName, instance)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Infof("CnsCrossVCenterVolumeRelocation resource not found. Ignoring since object must be deleted.")
+			return reconcile.Result{}, nil
+		}
+		log.Errorf("Error reading the CnsCrossVCenterVolumeRelocation with name: %q. Err: %+v",
+			request.Name, err)
+		// Error reading the object - return with err.
+		return reconcile.Result{}, err
+	}
+	// Initialize backOffDuration for the instance, if required.
+	backOffDurationMapMutex.Lock()
+	var timeout time.Duration
+	if _, exists := backOffDuration[instance.Name]; !exists {
+		backOffDuration[instance.Name] = time.Second
+	}
+	timeout = backOffDuration[instance.Name]
+	backOffDurationMapMutex.Unlock()
+
+	// If the volume is already relocated, remove the instance from the queue.
+	if instance.Status.Phase == relocationv1alpha1.RelocationPhaseCompleted {
+		backOffDurationMapMutex.Lock()
+		delete(backOffDuration, instance.Name)
+		backOffDurationMapMutex.Unlock()
+		return reconcile.Result{}, nil
+	}
+
+	log.Infof("Reconciling CnsCrossVCenterVolumeRelocation with instance: %q in phase: %q. timeout %q seconds",
+		instance.Name, instance.Status.Phase, timeout)
+	for instance.Status.Phase != relocationv1alpha1.RelocationPhaseCompleted {
+		var nextPhase relocationv1alpha1.CnsCrossVCenterVolumeRelocationPhase
+		switch instance.Status.Phase {
+		case relocationv1alpha1.RelocationPhasePending:
+			err = r.validateRelocation(ctx, instance)
+			nextPhase = relocationv1alpha1.RelocationPhaseRelocating
+		case relocationv1alpha1.RelocationPhaseRelocating:
+			err = r.relocateVolume(ctx, instance)
+			nextPhase = relocationv1alpha1.RelocationPhaseUnregistering
+		case relocationv1alpha1.RelocationPhaseUnregistering:
+			err = r.unregisterVolume(ctx, instance)
+			nextPhase = relocationv1alpha1.RelocationPhaseRegistering
+		case relocationv1alpha1.RelocationPhaseRegistering:
+			err = r.registerVolume(ctx, instance)
+			nextPhase = relocationv1alpha1.RelocationPhaseUpdatingPersistentVolume
+		case relocationv1alpha1.RelocationPhaseUpdatingPersistentVolume:
+			err = r.updatePersistentVolume(ctx, instance)
+			nextPhase = relocationv1alpha1.RelocationPhaseCompleted
+		default:
+			err = fmt.Errorf("unknown phase %q", instance.Status.Phase)
+		}
+		if err != nil {
+			setInstanceError(ctx, r, instance, err.Error())
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		instance.Status.Phase = nextPhase
+		instance.Status.Error = ""
+		err = updateCnsCrossVCenterVolumeRelocation(ctx, r.client, instance)
+		if err != nil {
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		log.Infof("CnsCrossVCenterVolumeRelocation: %q moved to phase: %q", instance.Name, nextPhase)
+	}
+
+	msg := fmt.Sprintf("Successfully relocated volume: %s of PersistentVolume: %s from vCenter: %s to vCenter: %s",
+		instance.Status.VolumeID, instance.Spec.PersistentVolumeName, instance.Status.SourceVCenter,
+		instance.Spec.TargetVCenter)
+	r.recorder.Event(instance, v1.EventTypeNormal, "CnsCrossVCenterVolumeRelocationSucceeded", msg)
+	backOffDurationMapMutex.Lock()
+	delete(backOffDuration, instance.Name)
+	backOffDurationMapMutex.Unlock()
+	log.Info(msg)
+	return reconcile.Result{}, nil
+}
+
+// validateRelocation validates the CnsCrossVCenterVolumeRelocation instance
+// and the PersistentVolume to be relocated, and records the volume ID and
+// the source vCenter of the volume in the status of the instance.
+func (r *ReconcileCnsCrossVCenterVolumeRelocation) validateRelocation(ctx context.Context,
+	instance *relocationv1alpha1.CnsCrossVCenterVolumeRelocation) error {
+	err := validateCnsCrossVCenterVolumeRelocationSpec(ctx, r.configInfo.Cfg, instance)
+	if err != nil {
+		return err
+	}
+	pv, err := r.k8sclient.CoreV1().PersistentVolumes().Get(ctx, instance.Spec.PersistentVolumeName,
+		metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get PersistentVolume %q. Error: %+v", instance.Spec.PersistentVolumeName, err)
+	}
+	err = cnsoperatorutil.ValidatePersistentVolumeForRelocation(pv)
+	if err != nil {
+		return err
+	}
+	attached, err := isPersistentVolumeAttached(ctx, r.k8sclient, pv.Name)
+	if err != nil {
+		return fmt.Errorf("failed to list VolumeAttachments. Error: %+v", err)
+	}
+	if attached {
+		return fmt.Errorf("PersistentVolume %q is attached to a node. Detach the volume before relocating it",
+			pv.Name)
+	}
+	volumeID := pv.Spec.CSI.VolumeHandle
+	sourceVCenter, err := r.volumeInfoService.GetvCenterForVolumeID(ctx, volumeID)
+	if err != nil {
+		return err
+	}
+	if sourceVCenter == instance.Spec.TargetVCenter {
+		return fmt.Errorf("volume %q is already registered on vCenter %q", volumeID, sourceVCenter)
+	}
+	err = validateTargetDatastore(ctx, r.configInfo.Cfg, []string{sourceVCenter, instance.Spec.TargetVCenter},
+		instance.Spec.TargetDatastoreURL)
+	if err != nil {
+		return err
+	}
+	instance.Status.VolumeID = volumeID
+	instance.Status.SourceVCenter = sourceVCenter
+	return nil
+}
+
+// relocateVolume relocates the volume to the target datastore using the
+// source vCenter.
+func (r *ReconcileCnsCrossVCenterVolumeRelocation) relocateVolume(ctx context.Context,
+	instance *relocationv1alpha1.CnsCrossVCenterVolumeRelocation) error {
+	vc, volumeManager, err := cnsoperatorutil.GetVirtualCenterAndVolumeManager(ctx, r.configInfo.Cfg,
+		instance.Status.SourceVCenter)
+	if err != nil {
+		return err
+	}
+	return relocateVolumeToDatastore(ctx, vc, volumeManager, instance.Status.VolumeID,
+		instance.Spec.TargetDatastoreURL)
+}
+
+// unregisterVolume unregisters the volume from CNS on the source vCenter,
+// without deleting the backing disk.
+func (r *ReconcileCnsCrossVCenterVolumeRelocation) unregisterVolume(ctx context.Context,
+	instance *relocationv1alpha1.CnsCrossVCenterVolumeRelocation) error {
+	_, volumeManager, err := cnsoperatorutil.GetVirtualCenterAndVolumeManager(ctx, r.configInfo.Cfg,
+		instance.Status.SourceVCenter)
+	if err != nil {
+		return err
+	}
+	_, err = volumeManager.DeleteVolume(ctx, instance.Status.VolumeID, false)
+	if err != nil {
+		return fmt.Errorf("failed to unregister volume %q from vCenter %q. Error: %+v",
+			instance.Status.VolumeID, instance.Status.SourceVCenter, err)
+	}
+	return nil
+}
+
+// registerVolume registers the volume with CNS on the target vCenter and
+// maps the volume to the target vCenter in its CnsVolumeInfo.
+func (r *ReconcileCnsCrossVCenterVolumeRelocation) registerVolume(ctx context.Context,
+	instance *relocationv1alpha1.CnsCrossVCenterVolumeRelocation) error {
+	vc, volumeManager, err := cnsoperatorutil.GetVirtualCenterAndVolumeManager(ctx, r.configInfo.Cfg,
+		instance.Spec.TargetVCenter)
+	if err != nil {
+		return err
+	}
+	err = registerVolumeOnVCenter(ctx, r.configInfo.Cfg, vc, volumeManager, instance.Status.VolumeID,
+		instance.Spec.PersistentVolumeName, instance.Spec.TargetStoragePolicyName)
+	if err != nil {
+		return err
+	}
+	return r.updateVolumeInfo(ctx, instance)
+}
+
+// updateVolumeInfo maps the volume to the target vCenter in its
+// CnsVolumeInfo. It is a no-op if the volume is already mapped to the target
+// vCenter.
+func (r *ReconcileCnsCrossVCenterVolumeRelocation) updateVolumeInfo(ctx context.Context,
+	instance *relocationv1alpha1.CnsCrossVCenterVolumeRelocation) error {
+	vCenter, err := r.volumeInfoService.GetvCenterForVolumeID(ctx, instance.Status.VolumeID)
+	if err == nil && vCenter == instance.Spec.TargetVCenter {
+		return nil
+	}
+	// CnsVolumeInfo cannot be updated, hence it is recreated.
+	err = r.volumeInfoService.DeleteVolumeInfo(ctx, instance.Status.VolumeID)
+	if err != nil {
+		return err
+	}
+	return r.volumeInfoService.CreateVolumeInfo(ctx, instance.Status.VolumeID, instance.Spec.TargetVCenter)
+}
+
+// updatePersistentVolume updates the node affinity of the PersistentVolume
+// to the nodes of the target vCenter which have access to the target
+// datastore.
+func (r *ReconcileCnsCrossVCenterVolumeRelocation) updatePersistentVolume(ctx context.Context,
+	instance *relocationv1alpha1.CnsCrossVCenterVolumeRelocation) error {
+	vc, _, err := cnsoperatorutil.GetVirtualCenterAndVolumeManager(ctx, r.configInfo.Cfg, instance.Spec.TargetVCenter)
+	if err != nil {
+		return err
+	}
+	nodeAffinity, err := cnsoperatorutil.GetVolumeNodeAffinityForDatastore(ctx, r.configInfo.Cfg, vc,
+		instance.Spec.TargetDatastoreURL)
+	if err != nil {
+		return err
+	}
+	return r.setPersistentVolumeNodeAffinity(ctx, instance, nodeAffinity)
+}
+
+// setPersistentVolumeNodeAffinity sets the given node affinity on the
+// PersistentVolume, saving the PersistentVolume in the status of the instance
+// if it needs to be recreated.
+func (r *ReconcileCnsCrossVCenterVolumeRelocation) setPersistentVolumeNodeAffinity(ctx context.Context,
+	instance *relocationv1alpha1.CnsCrossVCenterVolumeRelocation, nodeAffinity *v1.VolumeNodeAffinity) error {
+	return cnsoperatorutil.UpdatePersistentVolumeNodeAffinity(ctx, r.k8sclient, instance.Spec.PersistentVolumeName,
+		nodeAffinity, instance.Status.SavedPersistentVolume, func(savedPV string) error {
+			instance.Status.SavedPersistentVolume = savedPV
+			return updateCnsCrossVCenterVolumeRelocation(ctx, r.client, instance)
+		}, instance.Name)
+}
+
+// setInstanceError sets error and records an event on the
+// CnsCrossVCenterVolumeRelocation instance.
+func setInstanceError(ctx context.Context, r *ReconcileCnsCrossVCenterVolumeRelocation,
+	instance *relocationv1alpha1.CnsCrossVCenterVolumeRelocation, errMsg string) {
+	log := logger.GetLogger(ctx)
+	log.Error(errMsg)
+	instance.Status.Error = errMsg
+	err := updateCnsCrossVCenterVolumeRelocation(ctx, r.client, instance)
+	if err != nil {
+		log.Errorf("updateCnsCrossVCenterVolumeRelocation failed. err: %v", err)
+	}
+	// Double backOff duration.
+	backOffDurationMapMutex.Lock()
+	backOffDuration[instance.Name] = backOffDuration[instance.Name] * 2
+	r.recorder.Event(instance, v1.EventTypeWarning, "CnsCrossVCenterVolumeRelocationFailed", errMsg)
+	backOffDurationMapMutex.Unlock()
+}
+
+// updateCnsCrossVCenterVolumeRelocation updates the
+// CnsCrossVCenterVolumeRelocation instance in K8S.
+func updateCnsCrossVCenterVolumeRelocation(ctx context.Context, client client.Client,
+	instance *relocationv1alpha1.CnsCrossVCenterVolumeRelocation) error {
+	log := logger.GetLogger(ctx)
+	err := client.Update(ctx, instance)
+	if err != nil {
+		log.Errorf("Failed to update CnsCrossVCenterVolumeRelocation instance: %q. Error: %+v",
+			instance.Name, err)
+	}
+	return err
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnscrossvcentervolumerelocation
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	apis "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
+	relocationv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnscrossvcentervolumerelocation/v1alpha1"
+	cnsoperatorutil "sigs.k8s.io/vsphere-csi-driver/v2/pkg/syncer/cnsoperator/util"
+)
+
+// fakeVolumeInfoService records the vCenter of the volumes in memory.
+type fakeVolumeInfoService struct {
+	vCenters map[string]string
+	deleted  int
+}
+
+func (f *fakeVolumeInfoService) GetvCenterForVolumeID(ctx context.Context, volumeID string) (string, error) {
+	vCenter, ok := f.vCenters[volumeID]
+	if !ok {
+		return "", fmt.Errorf("volume %q not found", volumeID)
+	}
+	return vCenter, nil
+}
+
+func (f *fakeVolumeInfoService) CreateVolumeInfo(ctx context.Context, volumeID string, vCenter string) error {
+	if _, ok := f.vCenters[volumeID]; ok {
+		return fmt.Errorf("volume info for volume %q already exists", volumeID)
+	}
+	f.vCenters[volumeID] = vCenter
+	return nil
+}
+
+func (f *fakeVolumeInfoService) DeleteVolumeInfo(ctx context.Context, volumeID string) error {
+	delete(f.vCenters, volumeID)
+	f.deleted++
+	return nil
+}
+
+func newTestInstance() *relocationv1alpha1.CnsCrossVCenterVolumeRelocation {
+	return &relocationv1alpha1.CnsCrossVCenterVolumeRelocation{
+		ObjectMeta: metav1.ObjectMeta{Name: "relocation-1"},
+		Spec: relocationv1alpha1.CnsCrossVCenterVolumeRelocationSpec{
+			PersistentVolumeName: "pv-1",
+			TargetVCenter:        "vc-2",
+			TargetDatastoreURL:   "ds:///vmfs/volumes/shared-ds/",
+		},
+		Status: relocationv1alpha1.CnsCrossVCenterVolumeRelocationStatus{
+			Phase:         relocationv1alpha1.RelocationPhaseRegistering,
+			VolumeID:      "volume-1",
+			SourceVCenter: "vc-1",
+		},
+	}
+}
+
+func TestUpdateVolumeInfo(t *testing.T) {
+	ctx := context.Background()
+	volumeInfoService := &fakeVolumeInfoService{vCenters: map[string]string{"volume-1": "vc-1"}}
+	r := &ReconcileCnsCrossVCenterVolumeRelocation{volumeInfoService: volumeInfoService}
+	instance := newTestInstance()
+
+	if err := r.updateVolumeInfo(ctx, instance); err != nil {
+		t.Fatalf("failed to update volume info: %v", err)
+	}
+	if vCenter := volumeInfoService.vCenters["volume-1"]; vCenter != "vc-2" {
+		t.Errorf("expected volume to be mapped to vCenter %q, got %q", "vc-2", vCenter)
+	}
+
+	// Retrying after the volume info is recreated is a no-op.
+	if err := r.updateVolumeInfo(ctx, instance); err != nil {
+		t.Fatalf("failed to update volume info: %v", err)
+	}
+	if volumeInfoService.deleted != 1 || volumeInfoService.vCenters["volume-1"] != "vc-2" {
+		t.Errorf("expected volume info to be recreated once, got %d deletions and mapping %v",
+			volumeInfoService.deleted, volumeInfoService.vCenters)
+	}
+
+	// Volume info deleted by a failed attempt is recreated.
+	delete(volumeInfoService.vCenters, "volume-1")
+	if err := r.updateVolumeInfo(ctx, instance); err != nil {
+		t.Fatalf("failed to update volume info: %v", err)
+	}
+	if vCenter := volumeInfoService.vCenters["volume-1"]; vCenter != "vc-2" {
+		t.Errorf("expected volume to be mapped to vCenter %q, got %q", "vc-2", vCenter)
+	}
+}
+
+func TestSetPersistentVolumeNodeAffinity(t *testing.T) {
+	ctx := context.Background()
+	oldAffinity := cnsoperatorutil.GetPersistentVolumeNodeAffinity(
+		[]map[string]string{{"topology.csi.vmware.com/k8s-zone": "zone-a"}})
+	newAffinity := cnsoperatorutil.GetPersistentVolumeNodeAffinity(
+		[]map[string]string{{"topology.csi.vmware.com/k8s-zone": "zone-b"}})
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv-1", UID: "pv-1-uid"},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeReclaimPolicy: v1.PersistentVolumeReclaimDelete,
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{Driver: "csi.vsphere.vmware.com", VolumeHandle: "volume-1"},
+			},
+			ClaimRef:     &v1.ObjectReference{Namespace: "ns-1", Name: "pvc-1", UID: "pvc-1-uid"},
+			NodeAffinity: oldAffinity,
+		},
+	}
+	instance := newTestInstance()
+	instance.Status.Phase = relocationv1alpha1.RelocationPhaseUpdatingPersistentVolume
+
+	s := scheme.Scheme
+	s.AddKnownTypes(apis.SchemeGroupVersion, instance)
+	fakeClient := fake.NewClientBuilder().WithScheme(s).WithObjects(instance).Build()
+	k8sclient := k8sfake.NewSimpleClientset(pv)
+	r := &ReconcileCnsCrossVCenterVolumeRelocation{client: fakeClient, k8sclient: k8sclient}
+
+	if err := r.setPersistentVolumeNodeAffinity(ctx, instance, newAffinity); err != nil {
+		t.Fatalf("failed to set node affinity: %v", err)
+	}
+	savedInstance := &relocationv1alpha1.CnsCrossVCenterVolumeRelocation{}
+	err := fakeClient.Get(ctx, types.NamespacedName{Name: instance.Name}, savedInstance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if savedInstance.Status.SavedPersistentVolume == "" {
+		t.Error("expected the PersistentVolume to be saved in the status before it is recreated")
+	}
+	recreated, err := k8sclient.CoreV1().PersistentVolumes().Get(ctx, pv.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(recreated.Spec.NodeAffinity, newAffinity) {
+		t.Errorf("expected the PersistentVolume to have node affinity %+v, got %+v", newAffinity,
+			recreated.Spec.NodeAffinity)
+	}
+	if recreated.Spec.ClaimRef == nil || recreated.Spec.ClaimRef.UID != pv.Spec.ClaimRef.UID ||
+		recreated.Spec.PersistentVolumeReclaimPolicy != v1.PersistentVolumeReclaimDelete ||
+		recreated.Spec.CSI.VolumeHandle != "volume-1" {
+		t.Errorf("expected the recreated PersistentVolume to keep its claim, reclaim policy and volume, got %+v",
+			recreated.Spec)
+	}
+
+	// Retrying after the PersistentVolume is recreated is a no-op.
+	recreated.UID = "pv-1-recreated-uid"
+	if _, err = k8sclient.CoreV1().PersistentVolumes().Update(ctx, recreated, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = r.setPersistentVolumeNodeAffinity(ctx, instance, newAffinity); err != nil {
+		t.Fatalf("failed to set node affinity on retry: %v", err)
+	}
+	current, err := k8sclient.CoreV1().PersistentVolumes().Get(ctx, pv.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.UID != "pv-1-recreated-uid" {
+		t.Errorf("expected the recreated PersistentVolume not to be recreated again")
+	}
+}
+
+func TestSetPersistentVolumeNodeAffinityWithoutNodeAffinity(t *testing.T) {
+	ctx := context.Background()
+	newAffinity := cnsoperatorutil.GetPersistentVolumeNodeAffinity(
+		[]map[string]string{{"topology.csi.vmware.com/k8s-zone": "zone-b"}})
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv-1", UID: "pv-1-uid"},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{Driver: "csi.vsphere.vmware.com", VolumeHandle: "volume-1"},
+			},
+		},
+	}
+	instance := newTestInstance()
+	k8sclient := k8sfake.NewSimpleClientset(pv)
+	// The PersistentVolume is updated in place, so the instance is not saved.
+	r := &ReconcileCnsCrossVCenterVolumeRelocation{k8sclient: k8sclient}
+
+	if err := r.setPersistentVolumeNodeAffinity(ctx, instance, newAffinity); err != nil {
+		t.Fatalf("failed to set node affinity: %v", err)
+	}
+	current, err := k8sclient.CoreV1().PersistentVolumes().Get(ctx, pv.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.UID != pv.UID || !reflect.DeepEqual(current.Spec.NodeAffinity, newAffinity) {
+		t.Errorf("expected the node affinity to be set on the PersistentVolume, got %+v", current)
+	}
+	if instance.Status.SavedPersistentVolume != "" {
+		t.Errorf("expected the PersistentVolume not to be saved")
+	}
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnscrossvcentervolumerelocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	"github.com/vmware/govmomi/vim25/soap"
+	vim25types "github.com/vmware/govmomi/vim25/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	cnscrossvcentervolumerelocationv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnscrossvcentervolumerelocation/v1alpha1"
+	volumes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	commonconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	cnsoperatorutil "sigs.k8s.io/vsphere-csi-driver/v2/pkg/syncer/cnsoperator/util"
+)
+
+// validateCnsCrossVCenterVolumeRelocationSpec validates the input params of
+// CnsCrossVCenterVolumeRelocation instance.
+func validateCnsCrossVCenterVolumeRelocationSpec(ctx context.Context, cfg *commonconfig.Config,
+	instance *cnscrossvcentervolumerelocationv1alpha1.CnsCrossVCenterVolumeRelocation) error {
+	var msg string
+	spec := instance.Spec
+	if spec.PersistentVolumeName == "" {
+		msg = "PersistentVolumeName must be specified"
+	} else if spec.TargetVCenter == "" {
+		msg = "TargetVCenter must be specified"
+	} else if spec.TargetDatastoreURL == "" {
+		msg = "TargetDatastoreURL must be specified"
+	} else if _, ok := cfg.VirtualCenter[spec.TargetVCenter]; !ok {
+		msg = fmt.Sprintf("TargetVCenter %q is not present in the vSphere config secret", spec.TargetVCenter)
+	}
+	if msg != "" {
+		return logger.LogNewError(logger.GetLogger(ctx), msg)
+	}
+	return nil
+}
+
+// validateTargetDatastore verifies that the target datastore is accessible
+// from each of the given source and target vCenters. The volume is relocated
+// with the source vCenter and registered with the target vCenter, so only
+// datastores shared between them are supported. First class disks cannot be
+// relocated to a datastore of another vCenter, as their relocate spec has no
+// service locator for the target vCenter, unlike the one of virtual machines.
+func validateTargetDatastore(ctx context.Context, cfg *commonconfig.Config, vCenters []string,
+	datastoreURL string) error {
+	for _, host := range vCenters {
+		vc, _, err := cnsoperatorutil.GetVirtualCenterAndVolumeManager(ctx, cfg, host)
+		if err != nil {
+			return err
+		}
+		if _, err = common.GetDatastoreInfoObj(ctx, vc, datastoreURL); err != nil {
+			return fmt.Errorf("datastore %q is not accessible from vCenter %q. Only datastores shared between "+
+				"the source and target vCenters are supported. Error: %+v", datastoreURL, host, err)
+		}
+	}
+	return nil
+}
+
+// isPersistentVolumeAttached returns true if there is a VolumeAttachment for
+// the given PersistentVolume.
+func isPersistentVolumeAttached(ctx context.Context, k8sclient clientset.Interface, pvName string) (bool, error) {
+	volumeAttachments, err := k8sclient.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return false, err
+	}
+	for _, volumeAttachment := range volumeAttachments.Items {
+		if volumeAttachment.Spec.Source.PersistentVolumeName != nil &&
+			*volumeAttachment.Spec.Source.PersistentVolumeName == pvName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// relocateVolumeToDatastore relocates the given volume to the datastore with
+// the given URL, using the source vCenter. The datastore must be accessible
+// from the source vCenter. It is a no-op if the volume is already present on
+// the datastore.
+func relocateVolumeToDatastore(ctx context.Context, vc *vsphere.VirtualCenter, volumeManager volumes.Manager,
+	volumeID string, datastoreURL string) error {
+	log := logger.GetLogger(ctx)
+	querySelection := cnstypes.CnsQuerySelection{
+		Names: []string{string(cnstypes.QuerySelectionNameTypeDataStoreUrl)},
+	}
+	volume, err := common.QueryVolumeByID(ctx, volumeManager, volumeID, &querySelection)
+	if err != nil {
+		return logger.LogNewErrorf(log, "failed to query volume %q on vCenter %q. Error: %+v",
+			volumeID, vc.Config.Host, err)
+	}
+	if volume.DatastoreUrl == datastoreURL {
+		log.Infof("Volume %q is already present on datastore %q", volumeID, datastoreURL)
+		return nil
+	}
+	dsInfo, err := common.GetDatastoreInfoObj(ctx, vc, datastoreURL)
+	if err != nil {
+		return logger.LogNewErrorf(log, "datastore %q is not accessible from the source vCenter %q. "+
+			"Relocating volumes to datastores which are not shared between the vCenters is not supported",
+			datastoreURL, vc.Config.Host)
+	}
+	relocateSpec := cnstypes.NewCnsBlockVolumeRelocateSpec(volumeID, dsInfo.Reference())
+	task, err := volumeManager.RelocateVolume(ctx, relocateSpec)
+	if err != nil {
+		if soap.IsSoapFault(err) {
+			if _, ok := soap.ToSoapFault(err).VimFault().(vim25types.AlreadyExists); ok {
+				// Volume is already present on the target datastore.
+				return nil
+			}
+		}
+		return logger.LogNewErrorf(log, "failed to relocate volume %q to datastore %q. Error: %+v",
+			volumeID, datastoreURL, err)
+	}
+	taskInfo, err := task.WaitForResult(ctx)
+	if err != nil {
+		return logger.LogNewErrorf(log, "failed to relocate volume %q to datastore %q. Error: %+v",
+			volumeID, datastoreURL, err)
+	}
+	results := taskInfo.Result.(cnstypes.CnsVolumeOperationBatchResult)
+	for _, result := range results.VolumeResults {
+		if fault := result.GetCnsVolumeOperationResult().Fault; fault != nil {
+			return logger.LogNewErrorf(log, "failed to relocate volume %q to datastore %q. Fault: %+v",
+				volumeID, datastoreURL, fault)
+		}
+	}
+	log.Infof("Relocated volume %q to datastore %q", volumeID, datastoreURL)
+	return nil
+}
+
+// registerVolumeOnVCenter registers the given volume with CNS on the given
+// vCenter. It is a no-op if the volume is already registered.
+func registerVolumeOnVCenter(ctx context.Context, cfg *commonconfig.Config, vc *vsphere.VirtualCenter,
+	volumeManager volumes.Manager, volumeID string, pvName string, storagePolicyName string) error {
+	log := logger.GetLogger(ctx)
+	querySelection := cnstypes.CnsQuerySelection{
+		Names: []string{string(cnstypes.QuerySelectionNameTypeVolumeType)},
+	}
+	_, err := common.QueryVolumeByID(ctx, volumeManager, volumeID, &querySelection)
+	if err == nil {
+		log.Infof("Volume %q is already registered on vCenter %q", volumeID, vc.Config.Host)
+		return nil
+	}
+	if !errors.Is(err, common.ErrNotFound) {
+		return logger.LogNewErrorf(log, "failed to query volume %q on vCenter %q. Error: %+v",
+			volumeID, vc.Config.Host, err)
+	}
+	containerCluster := vsphere.GetContainerCluster(cfg.Global.ClusterID,
+		vsphere.GetUsername(vc.Config.Host, cfg.VirtualCenter[vc.Config.Host].User), cnstypes.CnsClusterFlavorVanilla,
+		cfg.Global.ClusterDistribution)
+	createSpec := &cnstypes.CnsVolumeCreateSpec{
+		Name:       pvName,
+		VolumeType: common.BlockVolumeType,
+		Metadata: cnstypes.CnsVolumeMetadata{
+			ContainerCluster:      containerCluster,
+			ContainerClusterArray: []cnstypes.CnsContainerCluster{containerCluster},
+		},
+		BackingObjectDetails: &cnstypes.CnsBlockBackingDetails{
+			BackingDiskId: volumeID,
+		},
+	}
+	if storagePolicyName != "" {
+		storagePolicyID, err := vc.GetStoragePolicyIDByName(ctx, storagePolicyName)
+		if err != nil {
+			return logger.LogNewErrorf(log, "failed to find storage policy %q on vCenter %q. Error: %+v",
+				storagePolicyName, vc.Config.Host, err)
+		}
+		createSpec.Profile = append(createSpec.Profile, &vim25types.VirtualMachineDefinedProfileSpec{
+			ProfileId: storagePolicyID,
+		})
+	}
+	volumeInfo, _, err := volumeManager.CreateVolume(ctx, createSpec)
+	if err != nil {
+		return logger.LogNewErrorf(log, "failed to register volume %q on vCenter %q. Error: %+v",
+			volumeID, vc.Config.Host, err)
+	}
+	if volumeInfo.VolumeID.Id != volumeID {
+		return logger.LogNewErrorf(log, "volume %q is registered with unexpected volume ID %q on vCenter %q",
+			volumeID, volumeInfo.VolumeID.Id, vc.Config.Host)
+	}
+	log.Infof("Registered volume %q on vCenter %q", volumeID, vc.Config.Host)
+	return nil
+}
@@ -57,6 +57,14 @@ import (
 
 const (
 	defaultMaxWorkerThreadsForFileAccessConfig = 10
+	// vmSelectorResyncInterval is the interval after which CnsFileAccessConfig
+	// instances with a VMSelector are reconciled again, to configure net
+	// permissions for the VMs added to or removed from the selection.
+	vmSelectorResyncInterval = 5 * time.Minute
+	// ipRangeClientPrefix is the prefix of the client name recorded in the
+	// CnsFileVolumeClient instance for the IP ranges of a CnsFileAccessConfig
+	// instance.
+	ipRangeClientPrefix = "cnsfileaccessconfig:"
 )
 
 // backOffDuration is a map of cnsfileaccessconfig name's to the time after
@@ -235,17 +243,6 @@ func (r *ReconcileCnsFileAccessConfig) Reconcile(ctx context.Context,
 	timeout = backOffDuration[instance.Name]
 	backOffDurationMapMutex.Unlock()
 
-	// Get the virtualmachine instance
-	vm, err := getVirtualMachine(ctx, r.vmOperatorClient, instance.Spec.VMName, instance.Namespace)
-	if err != nil {
-		msg := fmt.Sprintf("Failed to get virtualmachine instance for the VM with name: %q. Error: %+v",
-			instance.Spec.VMName, err)
-		log.Error(msg)
-		setInstanceError(ctx, r, instance, msg)
-		return reconcile.Result{RequeueAfter: timeout}, nil
-	}
-	log.Debugf("Found virtualMachine instance for VM: %q/%q: +%v", instance.Namespace, instance.Spec.VMName, vm)
-
 	if instance.DeletionTimestamp != nil {
 		volumeID, err := cnsoperatorutil.GetVolumeID(ctx, r.client, instance.Spec.PvcName, instance.Namespace)
 		if err != nil {
@@ -254,7 +251,15 @@ func (r *ReconcileCnsFileAccessConfig) Reconcile(ctx context.Context,
 			setInstanceError(ctx, r, instance, msg)
 			return reconcile.Result{RequeueAfter: timeout}, nil
 		}
-		err = r.configureNetPermissionsForFileVolume(ctx, volumeID, vm, instance, true)
+		configuredClients, err := r.getConfiguredClients(ctx, instance)
+		if err != nil {
+			msg := fmt.Sprintf("Failed to get the clients configured for CnsFileAccessConfig instance. Error: %+v",
+				err)
+			log.Error(msg)
+			setInstanceError(ctx, r, instance, msg)
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		err = r.configureNetPermissionsForFileVolume(ctx, volumeID, instance, configuredClients, nil)
 		if err != nil {
 			msg := fmt.Sprintf("Failed to configure CnsFileAccessConfig instance with error: %+v", err)
 			log.Error(msg)
@@ -278,7 +283,8 @@ func (r *ReconcileCnsFileAccessConfig) Reconcile(ctx context.Context,
 
 	// If the CnsFileAccessConfig instance is already successful,
 	// and not deleted by the user, remove the instance from the queue.
-	if instance.Status.Done {
+	// Instances selecting VMs by labels are reconciled periodically.
+	if instance.Status.Done && instance.Spec.VMSelector == nil {
 		// Cleanup instance entry from backOffDuration map.
 		backOffDurationMapMutex.Lock()
 		delete(backOffDuration, instance.Name)
@@ -305,8 +311,29 @@ func (r *ReconcileCnsFileAccessConfig) Reconcile(ctx context.Context,
 		}
 	}
 
-	vmOwnerRefExists := false
-	if len(instance.OwnerReferences) != 0 {
+	err = validateCnsFileAccessConfigSpec(instance)
+	if err != nil {
+		msg := fmt.Sprintf("Invalid CnsFileAccessConfig instance: %q on namespace: %q. Error: %+v",
+			instance.Name, instance.Namespace, err)
+		log.Error(msg)
+		setInstanceError(ctx, r, instance, msg)
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+
+	var vm *vmoperatortypes.VirtualMachine
+	vmOwnerRefExists := true
+	if instance.Spec.VMName != "" {
+		// Get the virtualmachine instance
+		vm, err = getVirtualMachine(ctx, r.vmOperatorClient, instance.Spec.VMName, instance.Namespace)
+		if err != nil {
+			msg := fmt.Sprintf("Failed to get virtualmachine instance for the VM with name: %q. Error: %+v",
+				instance.Spec.VMName, err)
+			log.Error(msg)
+			setInstanceError(ctx, r, instance, msg)
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		log.Debugf("Found virtualMachine instance for VM: %q/%q: +%v", instance.Namespace, instance.Spec.VMName, vm)
+		vmOwnerRefExists = false
 		for _, ownerRef := range instance.OwnerReferences {
 			if ownerRef.Kind == reflect.TypeOf(vmoperatortypes.VirtualMachine{}).Name() &&
 				ownerRef.Name == instance.Spec.VMName && ownerRef.UID == vm.UID {
@@ -328,66 +355,88 @@ func (r *ReconcileCnsFileAccessConfig) Reconcile(ctx context.Context,
 	}
 	log.Infof("Reconciling CnsFileAccessConfig with instance: %q from namespace: %q. timeout %q seconds",
 		instance.Name, instance.Namespace, timeout)
-	if !instance.Status.Done {
-		volumeID, err := cnsoperatorutil.GetVolumeID(ctx, r.client, instance.Spec.PvcName, instance.Namespace)
-		if err != nil {
-			msg := fmt.Sprintf("Failed to get volumeID from pvcName: %q. Error: %+v", instance.Spec.PvcName, err)
-			log.Error(msg)
-			setInstanceError(ctx, r, instance, msg)
-			return reconcile.Result{RequeueAfter: timeout}, nil
-		}
+	volumeID, err := cnsoperatorutil.GetVolumeID(ctx, r.client, instance.Spec.PvcName, instance.Namespace)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to get volumeID from pvcName: %q. Error: %+v", instance.Spec.PvcName, err)
+		log.Error(msg)
+		setInstanceError(ctx, r, instance, msg)
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
 
-		// Query volume.
-		log.Debugf("Querying volume: %s for CnsFileAccessConfig request with name: %q on namespace: %q",
-			volumeID, instance.Name, instance.Namespace)
-		querySelection := cnstypes.CnsQuerySelection{
-			Names: []string{
-				string(cnstypes.QuerySelectionNameTypeVolumeType),
-				string(cnstypes.QuerySelectionNameTypeBackingObjectDetails),
-			},
-		}
-		volume, err := common.QueryVolumeByID(ctx, r.volumeManager, volumeID, &querySelection)
-		if err != nil {
-			if err.Error() == common.ErrNotFound.Error() {
-				msg := fmt.Sprintf("CNS Volume: %s not found", volumeID)
-				log.Error(msg)
-				setInstanceError(ctx, r, instance, msg)
-				return reconcile.Result{RequeueAfter: timeout}, nil
-			}
-			msg := fmt.Sprintf("Failed to query CNS volume: %s with error: %+v", volumeID, err)
+	// Query volume.
+	log.Debugf("Querying volume: %s for CnsFileAccessConfig request with name: %q on namespace: %q",
+		volumeID, instance.Name, instance.Namespace)
+	querySelection := cnstypes.CnsQuerySelection{
+		Names: []string{
+			string(cnstypes.QuerySelectionNameTypeVolumeType),
+			string(cnstypes.QuerySelectionNameTypeBackingObjectDetails),
+		},
+	}
+	volume, err := common.QueryVolumeByID(ctx, r.volumeManager, volumeID, &querySelection)
+	if err != nil {
+		if err.Error() == common.ErrNotFound.Error() {
+			msg := fmt.Sprintf("CNS Volume: %s not found", volumeID)
 			log.Error(msg)
 			setInstanceError(ctx, r, instance, msg)
 			return reconcile.Result{RequeueAfter: timeout}, nil
 		}
+		msg := fmt.Sprintf("Failed to query CNS volume: %s with error: %+v", volumeID, err)
+		log.Error(msg)
+		setInstanceError(ctx, r, instance, msg)
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
 
-		if volume.VolumeType != string(cnstypes.CnsVolumeTypeFile) {
-			msg := fmt.Sprintf("CNS Volume: %s is not RWX volume", volumeID)
-			err = logger.LogNewError(log, msg)
-			setInstanceError(ctx, r, instance, msg)
-			return reconcile.Result{RequeueAfter: timeout}, err
-		}
-		vSANFileBackingDetails := volume.BackingObjectDetails.(*cnstypes.CnsVsanFileShareBackingDetails)
-		accessPoints := make(map[string]string)
-		for _, kv := range vSANFileBackingDetails.AccessPoints {
-			accessPoints[kv.Key] = kv.Value
-		}
-		if len(accessPoints) == 0 {
-			msg := fmt.Sprintf("No access points found for volume: %q", volumeID)
-			log.Error(msg)
-			setInstanceError(ctx, r, instance, msg)
-			return reconcile.Result{RequeueAfter: timeout}, nil
-		}
-		err = r.configureNetPermissionsForFileVolume(ctx, volumeID, vm, instance, false)
-		if err != nil {
-			msg := fmt.Sprintf("Failed to configure CnsFileAccessConfig instance with error: %+v", err)
-			log.Error(msg)
-			setInstanceError(ctx, r, instance, msg)
-			return reconcile.Result{RequeueAfter: timeout}, nil
-		}
+	if volume.VolumeType != string(cnstypes.CnsVolumeTypeFile) {
+		msg := fmt.Sprintf("CNS Volume: %s is not RWX volume", volumeID)
+		err = logger.LogNewError(log, msg)
+		setInstanceError(ctx, r, instance, msg)
+		return reconcile.Result{RequeueAfter: timeout}, err
+	}
+	vSANFileBackingDetails := volume.BackingObjectDetails.(*cnstypes.CnsVsanFileShareBackingDetails)
+	accessPoints := make(map[string]string)
+	for _, kv := range vSANFileBackingDetails.AccessPoints {
+		accessPoints[kv.Key] = kv.Value
+	}
+	if len(accessPoints) == 0 {
+		msg := fmt.Sprintf("No access points found for volume: %q", volumeID)
+		log.Error(msg)
+		setInstanceError(ctx, r, instance, msg)
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	configuredClients, err := r.getConfiguredClients(ctx, instance)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to get the clients configured for CnsFileAccessConfig instance. Error: %+v",
+			err)
+		log.Error(msg)
+		setInstanceError(ctx, r, instance, msg)
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	desiredClients, skippedVMs, err := r.getDesiredClients(ctx, instance, vm)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to get the clients to be configured for CnsFileAccessConfig instance. "+
+			"Error: %+v", err)
+		log.Error(msg)
+		setInstanceError(ctx, r, instance, msg)
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	err = r.configureNetPermissionsForFileVolume(ctx, volumeID, instance, configuredClients, desiredClients)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to configure CnsFileAccessConfig instance with error: %+v", err)
+		log.Error(msg)
+		setInstanceError(ctx, r, instance, msg)
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	if !instance.Status.Done || !reflect.DeepEqual(instance.Status.Clients, desiredClients) ||
+		!reflect.DeepEqual(instance.Status.AccessPoints, accessPoints) {
 		// Update the instance to indicate the volume registration is successful.
-		msg := fmt.Sprintf("Successfully configured access points of VM: %q on the volume: %q",
-			instance.Spec.VMName, instance.Spec.PvcName)
+		msg := fmt.Sprintf("Successfully configured access points of %d clients on the volume: %q",
+			len(desiredClients), instance.Spec.PvcName)
+		if instance.Spec.VMName != "" && instance.Spec.VMSelector == nil && len(instance.Spec.IPRanges) == 0 {
+			msg = fmt.Sprintf("Successfully configured access points of VM: %q on the volume: %q",
+				instance.Spec.VMName, instance.Spec.PvcName)
+		}
 		instance.Status.AccessPoints = accessPoints
+		instance.Status.Clients = desiredClients
 		err = setInstanceSuccess(ctx, r, instance, msg)
 		if err != nil {
 			msg := fmt.Sprintf("Failed to update CnsFileAccessConfig instance with error: %+v", err)
@@ -398,84 +447,185 @@ func (r *ReconcileCnsFileAccessConfig) Reconcile(ctx context.Context,
 		log.Info(msg)
 	}
 
+	if len(skippedVMs) != 0 {
+		// The skipped VMs are retried with backoff until their external
+		// facing IP addresses are found.
+		backOffDurationMapMutex.Lock()
+		backOffDuration[instance.Name] = timeout * 2
+		backOffDurationMapMutex.Unlock()
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
 	backOffDurationMapMutex.Lock()
 	delete(backOffDuration, instance.Name)
 	backOffDurationMapMutex.Unlock()
+	if instance.Spec.VMSelector != nil {
+		return reconcile.Result{RequeueAfter: vmSelectorResyncInterval}, nil
+	}
 	return reconcile.Result{}, nil
 }
 
-// configureNetPermissionsForFileVolume helps to add or remove net permissions
-// for a given file volume. The callers of this method can remove or add net
-// permissions by setting the parameter removePermission to true or false
-// respectively. Returns error if any operation fails.
-func (r *ReconcileCnsFileAccessConfig) configureNetPermissionsForFileVolume(ctx context.Context,
-	volumeID string, vm *vmoperatortypes.VirtualMachine, instance *cnsfileaccessconfigv1alpha1.CnsFileAccessConfig,
-	removePermission bool) error {
+// getDesiredClients returns the clients for which net permissions need to
+// be configured on the volume, as specified in the CnsFileAccessConfig
+// instance. vm is the VirtualMachine instance specified by VMName, if any.
+// VMs selected by the VMSelector without an external facing IP address are
+// skipped, recording an event for each of them, and their names returned.
+func (r *ReconcileCnsFileAccessConfig) getDesiredClients(ctx context.Context,
+	instance *cnsfileaccessconfigv1alpha1.CnsFileAccessConfig, vm *vmoperatortypes.VirtualMachine) (
+	[]cnsfileaccessconfigv1alpha1.CnsFileAccessConfigClientStatus, []string, error) {
 	log := logger.GetLogger(ctx)
-	tkgVMIP, err := r.getVMExternalIP(ctx, vm)
+	vms := make(map[string]*vmoperatortypes.VirtualMachine)
+	if vm != nil {
+		vms[vm.Name] = vm
+	}
+	if instance.Spec.VMSelector != nil {
+		selectedVMs, err := getVirtualMachinesForSelector(ctx, r.vmOperatorClient,
+			instance.Spec.VMSelector, instance.Namespace)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := range selectedVMs {
+			vms[selectedVMs[i].Name] = &selectedVMs[i]
+		}
+	}
+	clients, skippedVMs, err := getVMClients(instance, vms, func(vm *vmoperatortypes.VirtualMachine) (
+		string, error) {
+		return r.getVMExternalIP(ctx, vm)
+	})
 	if err != nil {
-		return logger.LogNewErrorf(log, "Failed to get external facing IP address for VM: %s/%s instance. Error: %+v",
-			vm.Namespace, vm.Name, err)
+		return nil, nil, err
 	}
+	for _, vmName := range skippedVMs {
+		msg := fmt.Sprintf("Skipped configuring access of VM: %s/%s without an external facing IP address",
+			instance.Namespace, vmName)
+		log.Warn(msg)
+		r.recorder.Event(instance, v1.EventTypeWarning, "CnsFileAccessConfigVMSkipped", msg)
+	}
+	clients = append(clients, getIPRangeClients(instance.Spec.IPRanges)...)
+	sortClients(clients)
+	return clients, skippedVMs, nil
+}
+
+// getConfiguredClients returns the clients for which net permissions were
+// configured on the volume by the CnsFileAccessConfig instance.
+func (r *ReconcileCnsFileAccessConfig) getConfiguredClients(ctx context.Context,
+	instance *cnsfileaccessconfigv1alpha1.CnsFileAccessConfig) (
+	[]cnsfileaccessconfigv1alpha1.CnsFileAccessConfigClientStatus, error) {
+	if len(instance.Status.Clients) != 0 || instance.Spec.VMName == "" {
+		return instance.Status.Clients, nil
+	}
+	// Instances configured before the configured clients were recorded in
+	// the status only grant read-write access to the VM specified by VMName.
+	// Net permissions may also have been configured by a previous attempt
+	// which failed to update the status, hence the VM is always considered.
+	vm, err := getVirtualMachine(ctx, r.vmOperatorClient, instance.Spec.VMName, instance.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	vmIP, err := r.getVMExternalIP(ctx, vm)
+	if err != nil {
+		return nil, err
+	}
+	return []cnsfileaccessconfigv1alpha1.CnsFileAccessConfigClientStatus{
+		{
+			IPs:        vmIP,
+			VMName:     vm.Name,
+			AccessType: cnsfileaccessconfigv1alpha1.CnsFileAccessTypeReadWrite,
+		},
+	}, nil
+}
+
+// configureNetPermissionsForFileVolume helps to add or remove net permissions
+// for a given file volume. Net permissions are removed for the clients in
+// configuredClients which are not present in desiredClients, and added for
+// the clients in desiredClients which are not present in configuredClients
+// or whose access has changed. Returns error if any operation fails.
+func (r *ReconcileCnsFileAccessConfig) configureNetPermissionsForFileVolume(ctx context.Context,
+	volumeID string, instance *cnsfileaccessconfigv1alpha1.CnsFileAccessConfig,
+	configuredClients []cnsfileaccessconfigv1alpha1.CnsFileAccessConfigClientStatus,
+	desiredClients []cnsfileaccessconfigv1alpha1.CnsFileAccessConfigClientStatus) error {
+	log := logger.GetLogger(ctx)
 	cnsFileVolumeClientInstance, err := cnsfilevolumeclient.GetFileVolumeClientInstance(ctx)
 	if err != nil {
 		return logger.LogNewErrorf(log, "Failed to get CNSFileVolumeClient instance. Error: %+v", err)
 	}
-	clientVms, err := cnsFileVolumeClientInstance.GetClientVMsFromIPList(ctx,
-		instance.Namespace+"/"+instance.Spec.PvcName, tkgVMIP)
-	if err != nil {
-		return logger.LogNewErrorf(log, "Failed to get the list of clients VMs for IP %q. Error: %+v", tkgVMIP, err)
+	fileVolumeName := instance.Namespace + "/" + instance.Spec.PvcName
+	configured := make(map[string]cnsfileaccessconfigv1alpha1.CnsFileAccessConfigClientStatus)
+	for _, fileClient := range configuredClients {
+		configured[fileClient.VMName+"/"+fileClient.IPs] = fileClient
+	}
+	desired := make(map[string]bool)
+	for _, fileClient := range desiredClients {
+		desired[fileClient.VMName+"/"+fileClient.IPs] = true
 	}
-	if !removePermission {
-		if len(clientVms) == 0 {
-			err = r.configureVolumeACLs(ctx, volumeID, tkgVMIP, false)
+	for _, fileClient := range configuredClients {
+		if desired[fileClient.VMName+"/"+fileClient.IPs] {
+			continue
+		}
+		clientName := getFileVolumeClientName(instance, fileClient)
+		clientVms, err := cnsFileVolumeClientInstance.GetClientVMsFromIPList(ctx, fileVolumeName, fileClient.IPs)
+		if err != nil {
+			return logger.LogNewErrorf(log, "Failed to get the list of clients VMs for IP %q. Error: %+v",
+				fileClient.IPs, err)
+		}
+		if len(clientVms) == 0 || (len(clientVms) == 1 && clientVms[0] == clientName) {
+			err = r.configureVolumeACLs(ctx, volumeID, fileClient, true)
 			if err != nil {
-				return logger.LogNewErrorf(log, "Failed to add net permissions for file volume %q. Error: %+v",
+				return logger.LogNewErrorf(log, "Failed to remove net permissions for file volume %q. Error: %+v",
 					volumeID, err)
 			}
 		}
-		err = cnsFileVolumeClientInstance.AddClientVMToIPList(ctx,
-			instance.Namespace+"/"+instance.Spec.PvcName, instance.Spec.VMName, tkgVMIP)
+		err = cnsFileVolumeClientInstance.RemoveClientVMFromIPList(ctx, fileVolumeName, clientName, fileClient.IPs)
 		if err != nil {
-			return logger.LogNewErrorf(log, "Failed to add VM %q with IP %q to IPList. Error: %+v",
-				vm.Name, tkgVMIP, err)
+			return logger.LogNewErrorf(log, "Failed to remove client %q with IP %q from IPList. Error: %+v",
+				clientName, fileClient.IPs, err)
 		}
-		log.Debugf("Successfully added VM IP %q to IPList for CnsFileAccessConfig request with name: %q on namespace: %q",
-			tkgVMIP, instance.Name, instance.Namespace)
-		return nil
+		log.Debugf("Successfully removed IP %q from IPList for CnsFileAccessConfig request with name: %q "+
+			"on namespace: %q", fileClient.IPs, instance.Name, instance.Namespace)
 	}
-	// RemovePermission is set to true.
-	if len(clientVms) == 1 && clientVms[0] == vm.Name {
-		err = r.configureVolumeACLs(ctx, volumeID, tkgVMIP, true)
+	for _, fileClient := range desiredClients {
+		clientName := getFileVolumeClientName(instance, fileClient)
+		clientVms, err := cnsFileVolumeClientInstance.GetClientVMsFromIPList(ctx, fileVolumeName, fileClient.IPs)
 		if err != nil {
-			return logger.LogNewErrorf(log, "Failed to remove net permissions for file volume %q. Error: %+v",
-				volumeID, err)
+			return logger.LogNewErrorf(log, "Failed to get the list of clients VMs for IP %q. Error: %+v",
+				fileClient.IPs, err)
 		}
+		previous, isConfigured := configured[fileClient.VMName+"/"+fileClient.IPs]
+		accessChanged := isConfigured && previous != fileClient
+		if len(clientVms) == 0 || accessChanged {
+			err = r.configureVolumeACLs(ctx, volumeID, fileClient, false)
+			if err != nil {
+				return logger.LogNewErrorf(log, "Failed to add net permissions for file volume %q. Error: %+v",
+					volumeID, err)
+			}
+		}
+		err = cnsFileVolumeClientInstance.AddClientVMToIPList(ctx, fileVolumeName, clientName, fileClient.IPs)
+		if err != nil {
+			return logger.LogNewErrorf(log, "Failed to add client %q with IP %q to IPList. Error: %+v",
+				clientName, fileClient.IPs, err)
+		}
+		log.Debugf("Successfully added IP %q to IPList for CnsFileAccessConfig request with name: %q on namespace: %q",
+			fileClient.IPs, instance.Name, instance.Namespace)
 	}
-	err = cnsFileVolumeClientInstance.RemoveClientVMFromIPList(ctx,
-		instance.Namespace+"/"+instance.Spec.PvcName, instance.Spec.VMName, tkgVMIP)
-	if err != nil {
-		return logger.LogNewErrorf(log, "Failed to remove VM %q with IP %q to IPList. Error: %+v", vm.Name, tkgVMIP, err)
-	}
-	log.Debugf("Successfully removed VM IP %q to IPList for CnsFileAccessConfig request with name: %q on namespace: %q",
-		tkgVMIP, instance.Name, instance.Namespace)
 	return nil
 }
 
 // configureVolumeACLs helps to prepare the CnsVolumeACLConfigureSpec
-// for a given TKG VM IP address and volumeID and invoke CNS API.
+// for a given client and volumeID and invoke CNS API.
 func (r *ReconcileCnsFileAccessConfig) configureVolumeACLs(ctx context.Context,
-	volumeID string, tkgVMIP string, delete bool) error {
+	volumeID string, fileClient cnsfileaccessconfigv1alpha1.CnsFileAccessConfigClientStatus, delete bool) error {
 	log := logger.GetLogger(ctx)
 	cnsVolumeID := cnstypes.CnsVolumeId{
 		Id: volumeID,
 	}
 	vSanFileShareNetPermissions := make([]vsanfstypes.VsanFileShareNetPermission, 0)
 	vsanFileShareAccessType := vsanfstypes.VsanFileShareAccessTypeREAD_WRITE
+	if fileClient.AccessType == cnsfileaccessconfigv1alpha1.CnsFileAccessTypeReadOnly {
+		vsanFileShareAccessType = vsanfstypes.VsanFileShareAccessTypeREAD_ONLY
+	}
 	vSanFileShareNetPermissions = append(vSanFileShareNetPermissions, vsanfstypes.VsanFileShareNetPermission{
-		Ips:         tkgVMIP,
+		Ips:         fileClient.IPs,
 		Permissions: vsanFileShareAccessType,
-		AllowRoot:   true,
+		AllowRoot:   !fileClient.RootSquash,
 	})
 
 	cnsNFSAccessControlSpecList := make([]cnstypes.CnsNFSAccessControlSpec, 0)
@@ -18,9 +18,12 @@ package cnsfileaccessconfig
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"os"
 	"reflect"
+	"sort"
 	"strconv"
 
 	vmoperatortypes "github.com/vmware-tanzu/vm-operator-api/api/v1alpha1"
@@ -50,6 +53,147 @@ func getVirtualMachine(ctx context.Context, vmOperatorClient client.Client,
 	return virtualMachine, nil
 }
 
+// getVirtualMachinesForSelector lists the virtual machine instances on a SV
+// namespace matching the given label selector.
+func getVirtualMachinesForSelector(ctx context.Context, vmOperatorClient client.Client,
+	selector *metav1.LabelSelector, namespace string) ([]vmoperatortypes.VirtualMachine, error) {
+	log := logger.GetLogger(ctx)
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, logger.LogNewErrorf(log, "Invalid VMSelector %+v. Error: %+v", selector, err)
+	}
+	virtualMachines := &vmoperatortypes.VirtualMachineList{}
+	err = vmOperatorClient.List(ctx, virtualMachines, client.InNamespace(namespace),
+		client.MatchingLabelsSelector{Selector: labelSelector})
+	if err != nil {
+		return nil, logger.LogNewErrorf(log, "Failed to list virtualmachine instances for VMSelector %q. Error: %+v",
+			labelSelector.String(), err)
+	}
+	return virtualMachines.Items, nil
+}
+
+// validateCnsFileAccessConfigSpec validates the input params of
+// CnsFileAccessConfig instance.
+func validateCnsFileAccessConfigSpec(instance *cnsfileaccessconfigv1alpha1.CnsFileAccessConfig) error {
+	spec := instance.Spec
+	if spec.VMName == "" && spec.VMSelector == nil && len(spec.IPRanges) == 0 {
+		return errors.New("one of VMName, VMSelector or IPRanges must be specified")
+	}
+	if spec.VMSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(spec.VMSelector); err != nil {
+			return fmt.Errorf("invalid VMSelector. Error: %+v", err)
+		}
+	}
+	if !isValidAccessType(spec.AccessType) {
+		return fmt.Errorf("invalid accessType %q", spec.AccessType)
+	}
+	for _, ipRange := range spec.IPRanges {
+		if _, _, err := net.ParseCIDR(ipRange.CIDR); err != nil && net.ParseIP(ipRange.CIDR) == nil {
+			return fmt.Errorf("invalid CIDR %q in IPRanges", ipRange.CIDR)
+		}
+		if !isValidAccessType(ipRange.AccessType) {
+			return fmt.Errorf("invalid accessType %q for CIDR %q", ipRange.AccessType, ipRange.CIDR)
+		}
+	}
+	return nil
+}
+
+// isValidAccessType returns true if accessType is empty or one of the
+// supported access types.
+func isValidAccessType(accessType cnsfileaccessconfigv1alpha1.CnsFileAccessType) bool {
+	return accessType == "" || accessType == cnsfileaccessconfigv1alpha1.CnsFileAccessTypeReadWrite ||
+		accessType == cnsfileaccessconfigv1alpha1.CnsFileAccessTypeReadOnly
+}
+
+// getAccessType returns the given access type, defaulting to ReadWrite.
+func getAccessType(accessType cnsfileaccessconfigv1alpha1.CnsFileAccessType,
+) cnsfileaccessconfigv1alpha1.CnsFileAccessType {
+	if accessType == "" {
+		return cnsfileaccessconfigv1alpha1.CnsFileAccessTypeReadWrite
+	}
+	return accessType
+}
+
+// getIPRangeClients returns the clients for the given IP ranges. If the same
+// range is specified more than once, the last entry is used.
+func getIPRangeClients(ipRanges []cnsfileaccessconfigv1alpha1.CnsFileAccessConfigIPRange,
+) []cnsfileaccessconfigv1alpha1.CnsFileAccessConfigClientStatus {
+	clients := make(map[string]cnsfileaccessconfigv1alpha1.CnsFileAccessConfigClientStatus)
+	for _, ipRange := range ipRanges {
+		clients[ipRange.CIDR] = cnsfileaccessconfigv1alpha1.CnsFileAccessConfigClientStatus{
+			IPs:        ipRange.CIDR,
+			AccessType: getAccessType(ipRange.AccessType),
+			RootSquash: ipRange.RootSquash,
+		}
+	}
+	var result []cnsfileaccessconfigv1alpha1.CnsFileAccessConfigClientStatus
+	for _, fileClient := range clients {
+		result = append(result, fileClient)
+	}
+	sortClients(result)
+	return result
+}
+
+// getVMClients returns the clients for the given VMs, using getVMIP to find
+// their external facing IP address. VMs selected by the VMSelector of the
+// instance, other than the one specified by VMName, are skipped if their IP
+// address cannot be found, keeping the clients configured for them, if any.
+// The names of the skipped VMs are returned.
+func getVMClients(instance *cnsfileaccessconfigv1alpha1.CnsFileAccessConfig,
+	vms map[string]*vmoperatortypes.VirtualMachine,
+	getVMIP func(vm *vmoperatortypes.VirtualMachine) (string, error)) (
+	[]cnsfileaccessconfigv1alpha1.CnsFileAccessConfigClientStatus, []string, error) {
+	vmAccessType := getAccessType(instance.Spec.AccessType)
+	var clients []cnsfileaccessconfigv1alpha1.CnsFileAccessConfigClientStatus
+	var skippedVMs []string
+	for _, clientVM := range vms {
+		vmIP, err := getVMIP(clientVM)
+		if err != nil {
+			if clientVM.Name == instance.Spec.VMName {
+				return nil, nil, fmt.Errorf("failed to get external facing IP address for VM: %s/%s instance. "+
+					"Error: %+v", clientVM.Namespace, clientVM.Name, err)
+			}
+			skippedVMs = append(skippedVMs, clientVM.Name)
+			for _, fileClient := range instance.Status.Clients {
+				if fileClient.VMName == clientVM.Name {
+					clients = append(clients, fileClient)
+				}
+			}
+			continue
+		}
+		clients = append(clients, cnsfileaccessconfigv1alpha1.CnsFileAccessConfigClientStatus{
+			IPs:        vmIP,
+			VMName:     clientVM.Name,
+			AccessType: vmAccessType,
+			RootSquash: instance.Spec.RootSquash,
+		})
+	}
+	sort.Strings(skippedVMs)
+	return clients, skippedVMs, nil
+}
+
+// sortClients sorts the clients by VM name and IPs, so that the clients
+// recorded in the status of a CnsFileAccessConfig instance are stable.
+func sortClients(clients []cnsfileaccessconfigv1alpha1.CnsFileAccessConfigClientStatus) {
+	sort.Slice(clients, func(i, j int) bool {
+		if clients[i].VMName != clients[j].VMName {
+			return clients[i].VMName < clients[j].VMName
+		}
+		return clients[i].IPs < clients[j].IPs
+	})
+}
+
+// getFileVolumeClientName returns the name with which the client is recorded
+// in the CnsFileVolumeClient instance of the volume. VMs are recorded with
+// their name, IP ranges with the name of the CnsFileAccessConfig instance.
+func getFileVolumeClientName(instance *cnsfileaccessconfigv1alpha1.CnsFileAccessConfig,
+	fileClient cnsfileaccessconfigv1alpha1.CnsFileAccessConfigClientStatus) string {
+	if fileClient.VMName != "" {
+		return fileClient.VMName
+	}
+	return ipRangeClientPrefix + instance.Name
+}
+
 // setInstanceOwnerRef sets ownerRef on CnsFileAccessConfig instance to VM
 // instance.
 func setInstanceOwnerRef(instance *cnsfileaccessconfigv1alpha1.CnsFileAccessConfig, vmName string,
@@ -0,0 +1,142 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsfileaccessconfig
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	vmoperatortypes "github.com/vmware-tanzu/vm-operator-api/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cnsfileaccessconfigv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsfileaccessconfig/v1alpha1"
+)
+
+func TestValidateCnsFileAccessConfigSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    cnsfileaccessconfigv1alpha1.CnsFileAccessConfigSpec
+		isValid bool
+	}{
+		{
+			name:    "no clients",
+			spec:    cnsfileaccessconfigv1alpha1.CnsFileAccessConfigSpec{PvcName: "pvc"},
+			isValid: false,
+		},
+		{
+			name:    "vm name",
+			spec:    cnsfileaccessconfigv1alpha1.CnsFileAccessConfigSpec{PvcName: "pvc", VMName: "vm"},
+			isValid: true,
+		},
+		{
+			name: "vm selector",
+			spec: cnsfileaccessconfigv1alpha1.CnsFileAccessConfigSpec{PvcName: "pvc",
+				VMSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "ci"}},
+				AccessType: cnsfileaccessconfigv1alpha1.CnsFileAccessTypeReadOnly},
+			isValid: true,
+		},
+		{
+			name: "invalid access type",
+			spec: cnsfileaccessconfigv1alpha1.CnsFileAccessConfigSpec{PvcName: "pvc", VMName: "vm",
+				AccessType: "WriteOnly"},
+			isValid: false,
+		},
+		{
+			name: "ip ranges",
+			spec: cnsfileaccessconfigv1alpha1.CnsFileAccessConfigSpec{PvcName: "pvc",
+				IPRanges: []cnsfileaccessconfigv1alpha1.CnsFileAccessConfigIPRange{
+					{CIDR: "10.20.30.0/24"}, {CIDR: "10.20.40.5", RootSquash: true}}},
+			isValid: true,
+		},
+		{
+			name: "invalid cidr",
+			spec: cnsfileaccessconfigv1alpha1.CnsFileAccessConfigSpec{PvcName: "pvc",
+				IPRanges: []cnsfileaccessconfigv1alpha1.CnsFileAccessConfigIPRange{{CIDR: "10.20.30.0/33"}}},
+			isValid: false,
+		},
+	}
+	for _, test := range tests {
+		instance := &cnsfileaccessconfigv1alpha1.CnsFileAccessConfig{Spec: test.spec}
+		err := validateCnsFileAccessConfigSpec(instance)
+		if (err == nil) != test.isValid {
+			t.Errorf("test %q: expected valid: %t, got error: %v", test.name, test.isValid, err)
+		}
+	}
+}
+
+func TestGetIPRangeClients(t *testing.T) {
+	ipRanges := []cnsfileaccessconfigv1alpha1.CnsFileAccessConfigIPRange{
+		{CIDR: "10.20.40.0/24", AccessType: cnsfileaccessconfigv1alpha1.CnsFileAccessTypeReadOnly},
+		{CIDR: "10.20.30.0/24", RootSquash: true},
+	}
+	expected := []cnsfileaccessconfigv1alpha1.CnsFileAccessConfigClientStatus{
+		{IPs: "10.20.30.0/24", AccessType: cnsfileaccessconfigv1alpha1.CnsFileAccessTypeReadWrite, RootSquash: true},
+		{IPs: "10.20.40.0/24", AccessType: cnsfileaccessconfigv1alpha1.CnsFileAccessTypeReadOnly},
+	}
+	if clients := getIPRangeClients(ipRanges); !reflect.DeepEqual(clients, expected) {
+		t.Errorf("unexpected clients. expected: %+v, got: %+v", expected, clients)
+	}
+}
+
+func TestGetVMClients(t *testing.T) {
+	vmIPs := map[string]string{"vm-1": "10.20.30.1", "vm-2": "10.20.30.2"}
+	getVMIP := func(vm *vmoperatortypes.VirtualMachine) (string, error) {
+		if ip, ok := vmIPs[vm.Name]; ok {
+			return ip, nil
+		}
+		return "", fmt.Errorf("no IP address for VM %q", vm.Name)
+	}
+	vms := make(map[string]*vmoperatortypes.VirtualMachine)
+	for _, name := range []string{"vm-1", "vm-2", "vm-3", "vm-4"} {
+		vms[name] = &vmoperatortypes.VirtualMachine{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns-1"}}
+	}
+	readWrite := cnsfileaccessconfigv1alpha1.CnsFileAccessTypeReadWrite
+	instance := &cnsfileaccessconfigv1alpha1.CnsFileAccessConfig{
+		Spec: cnsfileaccessconfigv1alpha1.CnsFileAccessConfigSpec{VMName: "vm-1",
+			VMSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+		Status: cnsfileaccessconfigv1alpha1.CnsFileAccessConfigStatus{
+			Clients: []cnsfileaccessconfigv1alpha1.CnsFileAccessConfigClientStatus{
+				{VMName: "vm-3", IPs: "10.20.30.3", AccessType: readWrite},
+			},
+		},
+	}
+
+	// VMs without an IP address are skipped, keeping their configured clients.
+	clients, skippedVMs, err := getVMClients(instance, vms, getVMIP)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sortClients(clients)
+	expected := []cnsfileaccessconfigv1alpha1.CnsFileAccessConfigClientStatus{
+		{VMName: "vm-1", IPs: "10.20.30.1", AccessType: readWrite},
+		{VMName: "vm-2", IPs: "10.20.30.2", AccessType: readWrite},
+		{VMName: "vm-3", IPs: "10.20.30.3", AccessType: readWrite},
+	}
+	if !reflect.DeepEqual(clients, expected) {
+		t.Errorf("unexpected clients. expected: %+v, got: %+v", expected, clients)
+	}
+	if !reflect.DeepEqual(skippedVMs, []string{"vm-3", "vm-4"}) {
+		t.Errorf("unexpected skipped VMs %v", skippedVMs)
+	}
+
+	// The VM specified by VMName is not skipped.
+	delete(vmIPs, "vm-1")
+	if _, _, err = getVMClients(instance, vms, getVMIP); err == nil {
+		t.Errorf("expected an error for the VM specified by VMName without an IP address")
+	}
+}
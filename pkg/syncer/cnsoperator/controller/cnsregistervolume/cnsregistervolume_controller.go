@@ -30,6 +30,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	apitypes "k8s.io/apimachinery/pkg/types"
+	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/record"
@@ -47,12 +48,19 @@ import (
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/cnsvolumeoperationrequest"
 	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
+	cnsoperatortypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/syncer/cnsoperator/types"
+	cnsoperatorutil "sigs.k8s.io/vsphere-csi-driver/v2/pkg/syncer/cnsoperator/util"
 )
 
 const (
 	defaultMaxWorkerThreadsForRegisterVolume = 40
 	staticPvNamePrefix                       = "static-pv-"
+	// registerVolumeOperationPrefix is the prefix of the names of the
+	// CnsVolumeOperationRequest instances persisted for CnsRegisterVolume
+	// instances in vanilla clusters.
+	registerVolumeOperationPrefix = "registervolume-"
 )
 
 // backOffDuration is a map of cnsregistervolume name's to the time after which
@@ -71,7 +79,13 @@ var (
 func Add(mgr manager.Manager, clusterFlavor cnstypes.CnsClusterFlavor,
 	configInfo *commonconfig.ConfigurationInfo, volumeManager volumes.Manager) error {
 	ctx, log := logger.GetNewContextWithLogger()
-	if clusterFlavor != cnstypes.CnsClusterFlavorWorkload {
+	if clusterFlavor == cnstypes.CnsClusterFlavorVanilla {
+		if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.VanillaRegisterVolume) {
+			log.Debugf("Not initializing the CnsRegisterVolume Controller as %q feature is disabled",
+				common.VanillaRegisterVolume)
+			return nil
+		}
+	} else if clusterFlavor != cnstypes.CnsClusterFlavorWorkload {
 		log.Debug("Not initializing the CnsRegisterVolume Controller as its a non-WCP CSI deployment")
 		return nil
 	}
@@ -88,6 +102,22 @@ func Add(mgr manager.Manager, clusterFlavor cnstypes.CnsClusterFlavor,
 			}
 		}
 	}
+	var operationStore cnsvolumeoperationrequest.VolumeOperationRequest
+	if clusterFlavor == cnstypes.CnsClusterFlavorVanilla || clusterFlavor == cnstypes.CnsClusterFlavorWorkload {
+		// The volumes registered with CNS are persisted using the
+		// VolumeOperationRequest interface, so that retries do not
+		// register the same disk again.
+		var err error
+		operationStore, err = cnsvolumeoperationrequest.InitVolumeOperationRequestInterface(ctx,
+			configInfo.Cfg.Global.CnsVolumeOperationRequestCleanupIntervalInMin,
+			func() bool {
+				return commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.BlockVolumeSnapshot)
+			})
+		if err != nil {
+			log.Errorf("failed to initialize VolumeOperationRequestInterface with error: %v", err)
+			return err
+		}
+	}
 	// Initializes kubernetes client.
 	k8sclient, err := k8s.NewClient(ctx)
 	if err != nil {
@@ -104,14 +134,17 @@ func Add(mgr manager.Manager, clusterFlavor cnstypes.CnsClusterFlavor,
 		},
 	)
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: apis.GroupName})
-	return add(mgr, newReconciler(mgr, configInfo, volumeManager, recorder))
+	return add(mgr, newReconciler(mgr, clusterFlavor, configInfo, volumeManager, operationStore, recorder))
 }
 
 // newReconciler returns a new reconcile.Reconciler.
-func newReconciler(mgr manager.Manager, configInfo *commonconfig.ConfigurationInfo,
-	volumeManager volumes.Manager, recorder record.EventRecorder) reconcile.Reconciler {
+func newReconciler(mgr manager.Manager, clusterFlavor cnstypes.CnsClusterFlavor,
+	configInfo *commonconfig.ConfigurationInfo, volumeManager volumes.Manager,
+	operationStore cnsvolumeoperationrequest.VolumeOperationRequest,
+	recorder record.EventRecorder) reconcile.Reconciler {
 	return &ReconcileCnsRegisterVolume{client: mgr.GetClient(), scheme: mgr.GetScheme(),
-		configInfo: configInfo, volumeManager: volumeManager, recorder: recorder}
+		clusterFlavor: clusterFlavor, configInfo: configInfo, volumeManager: volumeManager,
+		operationStore: operationStore, recorder: recorder}
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler.
@@ -148,9 +181,13 @@ type ReconcileCnsRegisterVolume struct {
 	// that reads objects from the cache and writes to the apiserver.
 	client        client.Client
 	scheme        *runtime.Scheme
+	clusterFlavor cnstypes.CnsClusterFlavor
 	configInfo    *commonconfig.ConfigurationInfo
 	volumeManager volumes.Manager
-	recorder      record.EventRecorder
+	// operationStore persists the volumes registered for CnsRegisterVolume
+	// instances. Set only in vanilla and supervisor clusters.
+	operationStore cnsvolumeoperationrequest.VolumeOperationRequest
+	recorder       record.EventRecorder
 }
 
 // Reconcile reads that state of the cluster for a CnsRegisterVolume object
@@ -213,6 +250,9 @@ func (r *ReconcileCnsRegisterVolume) Reconcile(ctx context.Context,
 		setInstanceError(ctx, r, instance, msg)
 		return reconcile.Result{RequeueAfter: timeout}, nil
 	}
+	if r.clusterFlavor == cnstypes.CnsClusterFlavorVanilla {
+		return r.reconcileVanilla(ctx, instance, timeout)
+	}
 
 	vc, err := cnsvsphere.GetVirtualCenterInstance(ctx, r.configInfo, false)
 	if err != nil {
@@ -221,27 +261,36 @@ func (r *ReconcileCnsRegisterVolume) Reconcile(ctx context.Context,
 		setInstanceError(ctx, r, instance, "Unable to connect to VC for volume registration")
 		return reconcile.Result{RequeueAfter: timeout}, nil
 	}
-	var (
-		volumeID string
-		pvName   string
-	)
-	// Create Volume for the input CnsRegisterVolume instance.
-	createSpec := constructCreateSpecForInstance(r, instance, vc.Config.Host, isTKGSHAEnabled)
-	log.Infof("Creating CNS volume: %+v for CnsRegisterVolume request with name: %q on namespace: %q",
-		instance, instance.Name, instance.Namespace)
-	log.Debugf("CNS Volume create spec is: %+v", createSpec)
-	volInfo, _, err := r.volumeManager.CreateVolume(ctx, createSpec)
+	// Check if the disk was registered with CNS by a previous attempt.
+	operationName := registerVolumeOperationPrefix + string(instance.UID)
+	volumeID, err := r.getRegisteredVolumeID(ctx, operationName)
 	if err != nil {
-		msg := "failed to create CNS volume"
-		log.Errorf(msg)
-		setInstanceError(ctx, r, instance, msg)
+		setInstanceError(ctx, r, instance, "Failed to get the volume registration details")
 		return reconcile.Result{RequeueAfter: timeout}, nil
 	}
+	if volumeID != "" {
+		log.Infof("Volume: %s is already registered with CNS for CnsRegisterVolume request with name: %q "+
+			"on namespace: %q", volumeID, instance.Name, instance.Namespace)
+	} else {
+		// Create Volume for the input CnsRegisterVolume instance.
+		createSpec := constructCreateSpecForInstance(r, instance, vc.Config.Host, isTKGSHAEnabled)
+		log.Infof("Creating CNS volume: %+v for CnsRegisterVolume request with name: %q on namespace: %q",
+			instance, instance.Name, instance.Namespace)
+		log.Debugf("CNS Volume create spec is: %+v", createSpec)
+		volInfo, _, err := r.volumeManager.CreateVolume(ctx, createSpec)
+		if err != nil {
+			msg := "failed to create CNS volume"
+			log.Errorf(msg)
+			setInstanceError(ctx, r, instance, msg)
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		volumeID = volInfo.VolumeID.Id
+		log.Infof("Created CNS volume with volumeID: %s", volumeID)
+		r.storeRegisterVolumeOperation(ctx, operationName, volumeID, vc.Config.Host,
+			cnsvolumeoperationrequest.TaskInvocationStatusInProgress)
+	}
 
-	volumeID = volInfo.VolumeID.Id
-	log.Infof("Created CNS volume with volumeID: %s", volumeID)
-
-	pvName = staticPvNamePrefix + volumeID
+	pvName := staticPvNamePrefix + volumeID
 	// Query volume
 	log.Infof("Querying volume: %s for CnsRegisterVolume request with name: %q on namespace: %q",
 		volumeID, instance.Name, instance.Namespace)
@@ -277,6 +326,8 @@ func (r *ReconcileCnsRegisterVolume) Reconcile(ctx context.Context,
 		_, err = common.DeleteVolumeUtil(ctx, r.volumeManager, volumeID, false)
 		if err != nil {
 			log.Errorf("Failed to untag CNS volume: %s with error: %+v", volumeID, err)
+		} else {
+			r.deleteRegisterVolumeOperation(ctx, instance)
 		}
 		return reconcile.Result{RequeueAfter: timeout}, nil
 	}
@@ -288,6 +339,8 @@ func (r *ReconcileCnsRegisterVolume) Reconcile(ctx context.Context,
 		_, err = common.DeleteVolumeUtil(ctx, r.volumeManager, volumeID, false)
 		if err != nil {
 			log.Errorf("Failed to untag CNS volume: %s with error: %+v", volumeID, err)
+		} else {
+			r.deleteRegisterVolumeOperation(ctx, instance)
 		}
 		return reconcile.Result{RequeueAfter: timeout}, nil
 	}
@@ -318,6 +371,228 @@ func (r *ReconcileCnsRegisterVolume) Reconcile(ctx context.Context,
 	if accessMode == "" && instance.Spec.DiskURLPath != "" {
 		accessMode = v1.ReadWriteOnce
 	}
+	pvc, err := r.createBoundPVAndPVC(ctx, k8sclient, instance, volumeID, pvName, capacityInMb,
+		accessMode, storageClassName, nil)
+	if err != nil {
+		setInstanceError(ctx, r, instance, err.Error())
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	r.storeRegisterVolumeOperation(ctx, operationName, volumeID, vc.Config.Host,
+		cnsvolumeoperationrequest.TaskInvocationStatusSuccess)
+
+	// Update the instance to indicate the volume registration is successful.
+	msg := fmt.Sprintf("Successfully registered the volume on namespace: %s", instance.Namespace)
+	err = setInstanceSuccess(ctx, r, instance, instance.Spec.PvcName, pvc.UID, msg)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to update CnsRegistered instance with error: %+v", err)
+		log.Error(msg)
+		setInstanceError(ctx, r, instance, msg)
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	backOffDurationMapMutex.Lock()
+	delete(backOffDuration, instance.Name)
+	backOffDurationMapMutex.Unlock()
+	log.Info(msg)
+	return reconcile.Result{}, nil
+}
+
+// reconcileVanilla registers the volume specified in the CnsRegisterVolume
+// instance with CNS in a vanilla cluster, and creates a PV/PVC pair bound to
+// it using the StorageClass specified in the instance.
+func (r *ReconcileCnsRegisterVolume) reconcileVanilla(ctx context.Context,
+	instance *cnsregistervolumev1alpha1.CnsRegisterVolume, timeout time.Duration) (reconcile.Result, error) {
+	log := logger.GetLogger(ctx)
+	err := validateVanillaCnsRegisterVolumeSpec(ctx, instance)
+	if err != nil {
+		log.Error(err.Error())
+		setInstanceError(ctx, r, instance, err.Error())
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	k8sclient, err := k8s.NewClient(ctx)
+	if err != nil {
+		log.Errorf("Failed to initialize K8S client when registering the CnsRegisterVolume "+
+			"instance: %s on namespace: %s. Error: %+v", instance.Name, instance.Namespace, err)
+		setInstanceError(ctx, r, instance, "Failed to init K8S client for volume registration")
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	storageClass, err := k8sclient.StorageV1().StorageClasses().Get(ctx,
+		instance.Spec.StorageClassName, metav1.GetOptions{})
+	if err != nil {
+		msg := fmt.Sprintf("Failed to get StorageClass: %s with error: %+v", instance.Spec.StorageClassName, err)
+		log.Error(msg)
+		setInstanceError(ctx, r, instance, msg)
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	if storageClass.Provisioner != cnsoperatortypes.VSphereCSIDriverName {
+		msg := fmt.Sprintf("StorageClass: %s is not provisioned by %s", storageClass.Name,
+			cnsoperatortypes.VSphereCSIDriverName)
+		log.Error(msg)
+		setInstanceError(ctx, r, instance, msg)
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+
+	vc, err := cnsvsphere.GetVirtualCenterInstance(ctx, r.configInfo, false)
+	if err != nil {
+		log.Errorf("Failed to get virtual center instance with error: %+v", err)
+		setInstanceError(ctx, r, instance, "Unable to connect to VC for volume registration")
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	storagePolicyID, err := getStoragePolicyIDFromStorageClass(ctx, vc, storageClass)
+	if err != nil {
+		setInstanceError(ctx, r, instance, err.Error())
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	// Check if the disk was registered with CNS by a previous attempt.
+	operationName := registerVolumeOperationPrefix + string(instance.UID)
+	volumeID, err := r.getRegisteredVolumeID(ctx, operationName)
+	if err != nil {
+		setInstanceError(ctx, r, instance, "Failed to get the volume registration details")
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	if volumeID != "" {
+		log.Infof("Volume: %s is already registered with CNS for CnsRegisterVolume request with name: %q "+
+			"on namespace: %q", volumeID, instance.Name, instance.Namespace)
+	} else {
+		// Register the disk with CNS.
+		createSpec, err := constructVanillaCreateSpecForInstance(ctx, r, instance, vc, storagePolicyID)
+		if err != nil {
+			setInstanceError(ctx, r, instance, err.Error())
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		log.Infof("Creating CNS volume: %+v for CnsRegisterVolume request with name: %q on namespace: %q",
+			instance, instance.Name, instance.Namespace)
+		log.Debugf("CNS Volume create spec is: %+v", createSpec)
+		volInfo, _, err := r.volumeManager.CreateVolume(ctx, createSpec)
+		if err != nil {
+			log.Errorf("Failed to create CNS volume with error: %+v", err)
+			setInstanceError(ctx, r, instance, "failed to create CNS volume")
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		volumeID = volInfo.VolumeID.Id
+		log.Infof("Created CNS volume with volumeID: %s", volumeID)
+		r.storeRegisterVolumeOperation(ctx, operationName, volumeID, vc.Config.Host,
+			cnsvolumeoperationrequest.TaskInvocationStatusInProgress)
+	}
+	pvName := staticPvNamePrefix + volumeID
+
+	querySelection := cnstypes.CnsQuerySelection{
+		Names: []string{
+			string(cnstypes.QuerySelectionNameTypeVolumeType),
+			string(cnstypes.QuerySelectionNameTypeDataStoreUrl),
+			string(cnstypes.QuerySelectionNameTypeBackingObjectDetails),
+		},
+	}
+	volume, err := common.QueryVolumeByID(ctx, r.volumeManager, volumeID, &querySelection)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to query CNS volume: %s with error: %+v", volumeID, err)
+		log.Error(msg)
+		setInstanceError(ctx, r, instance, msg)
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+
+	// Compute the node affinity of the PV from the nodes having access to the
+	// datastore of the volume.
+	nodeAffinity, err := cnsoperatorutil.GetVolumeNodeAffinityForDatastore(ctx, r.configInfo.Cfg, vc,
+		volume.DatastoreUrl)
+	if err != nil {
+		log.Errorf("Volume: %s present on datastore: %s is not accessible to the nodes in the cluster. Error: %+v",
+			volumeID, volume.DatastoreUrl, err)
+		setInstanceError(ctx, r, instance, "Volume in the spec is not accessible to the nodes in the cluster")
+		// Untag the CNS volume which was created previously.
+		_, err = common.DeleteVolumeUtil(ctx, r.volumeManager, volumeID, false)
+		if err != nil {
+			log.Errorf("Failed to untag CNS volume: %s with error: %+v", volumeID, err)
+		} else {
+			r.deleteRegisterVolumeOperation(ctx, instance)
+		}
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+
+	capacityInMb := volume.BackingObjectDetails.GetCnsBackingObjectDetails().CapacityInMb
+	accessMode := instance.Spec.AccessMode
+	if accessMode == "" {
+		accessMode = v1.ReadWriteOnce
+	}
+	pvc, err := r.createBoundPVAndPVC(ctx, k8sclient, instance, volumeID, pvName, capacityInMb,
+		accessMode, storageClass.Name, nodeAffinity)
+	if err != nil {
+		setInstanceError(ctx, r, instance, err.Error())
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	r.storeRegisterVolumeOperation(ctx, operationName, volumeID, vc.Config.Host,
+		cnsvolumeoperationrequest.TaskInvocationStatusSuccess)
+
+	msg := fmt.Sprintf("Successfully registered the volume on namespace: %s", instance.Namespace)
+	err = setInstanceSuccess(ctx, r, instance, instance.Spec.PvcName, pvc.UID, msg)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to update CnsRegistered instance with error: %+v", err)
+		log.Error(msg)
+		setInstanceError(ctx, r, instance, msg)
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	backOffDurationMapMutex.Lock()
+	delete(backOffDuration, instance.Name)
+	backOffDurationMapMutex.Unlock()
+	log.Info(msg)
+	return reconcile.Result{}, nil
+}
+
+// getRegisteredVolumeID returns the ID of the volume registered with CNS by a
+// previous attempt for the CnsRegisterVolume instance, persisted under the
+// given operation name, or an empty string if none is persisted.
+func (r *ReconcileCnsRegisterVolume) getRegisteredVolumeID(ctx context.Context,
+	operationName string) (string, error) {
+	log := logger.GetLogger(ctx)
+	operationDetails, err := r.operationStore.GetRequestDetails(ctx, operationName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		log.Errorf("Failed to get CnsVolumeOperationRequest: %q with error: %+v", operationName, err)
+		return "", err
+	}
+	return operationDetails.VolumeID, nil
+}
+
+// storeRegisterVolumeOperation persists the volume registered with CNS for a
+// CnsRegisterVolume instance. Failure to persist the details is only logged,
+// as it affects only the idempotency of retries.
+func (r *ReconcileCnsRegisterVolume) storeRegisterVolumeOperation(ctx context.Context,
+	operationName string, volumeID string, vCenterServer string, taskStatus string) {
+	log := logger.GetLogger(ctx)
+	operationDetails := cnsvolumeoperationrequest.CreateVolumeOperationRequestDetails(operationName,
+		volumeID, "", 0, metav1.Now(), "", vCenterServer, "", taskStatus, "")
+	err := r.operationStore.StoreRequestDetails(ctx, operationDetails)
+	if err != nil {
+		log.Warnf("Failed to store details of CnsVolumeOperationRequest: %q with error: %+v",
+			operationName, err)
+	}
+}
+
+// deleteRegisterVolumeOperation deletes the persisted details of the volume
+// registered for the CnsRegisterVolume instance, once the volume is untagged.
+func (r *ReconcileCnsRegisterVolume) deleteRegisterVolumeOperation(ctx context.Context,
+	instance *cnsregistervolumev1alpha1.CnsRegisterVolume) {
+	if r.operationStore == nil {
+		return
+	}
+	log := logger.GetLogger(ctx)
+	operationName := registerVolumeOperationPrefix + string(instance.UID)
+	err := r.operationStore.DeleteRequestDetails(ctx, operationName)
+	if err != nil {
+		log.Errorf("Failed to delete CnsVolumeOperationRequest: %q with error: %+v", operationName, err)
+	}
+}
+
+// createBoundPVAndPVC creates the PV for the registered volume, if not already
+// present, and the PVC specified in the CnsRegisterVolume instance, and waits
+// for the PVC to be bound to the PV. The message of the returned error is
+// meant to be set on the CnsRegisterVolume instance.
+func (r *ReconcileCnsRegisterVolume) createBoundPVAndPVC(ctx context.Context, k8sclient clientset.Interface,
+	instance *cnsregistervolumev1alpha1.CnsRegisterVolume, volumeID string, pvName string, capacityInMb int64,
+	accessMode v1.PersistentVolumeAccessMode, storageClassName string,
+	nodeAffinity *v1.VolumeNodeAffinity) (*v1.PersistentVolumeClaim, error) {
+	log := logger.GetLogger(ctx)
 	pv, err := k8sclient.CoreV1().PersistentVolumes().Get(ctx, pvName, metav1.GetOptions{})
 	if err != nil {
 		if apierrors.IsNotFound(err) {
@@ -330,29 +605,23 @@ func (r *ReconcileCnsRegisterVolume) Reconcile(ctx context.Context,
 				Name:       instance.Spec.PvcName,
 			}
 			pvSpec := getPersistentVolumeSpec(pvName, volumeID, capacityInMb,
-				accessMode, storageClassName, claimRef)
+				accessMode, storageClassName, claimRef, nodeAffinity)
 			log.Debugf("PV spec is: %+v", pvSpec)
 			pv, err = k8sclient.CoreV1().PersistentVolumes().Create(ctx, pvSpec, metav1.CreateOptions{})
 			if err != nil {
 				log.Errorf("Failed to create PV with spec: %+v. Error: %+v", pvSpec, err)
-				setInstanceError(ctx, r, instance,
-					fmt.Sprintf("Failed to create PV: %s for volume with err: %+v", pvName, err))
-				return reconcile.Result{RequeueAfter: timeout}, nil
+				return nil, fmt.Errorf("Failed to create PV: %s for volume with err: %+v", pvName, err)
 			}
 			log.Infof("PV: %s is created successfully", pvName)
 		} else {
-			msg := fmt.Sprintf("Failed to get PV: %s with error: %+v", pvName, err)
-			log.Error(msg)
-			setInstanceError(ctx, r, instance, msg)
-			return reconcile.Result{RequeueAfter: timeout}, nil
+			return nil, logger.LogNewErrorf(log, "Failed to get PV: %s with error: %+v", pvName, err)
 		}
 	}
 	// If PV is already bound to a different PVC at this point, then its a
 	// duplicate request.
 	if pv.Status.Phase == v1.VolumeBound && pv.Spec.ClaimRef.Name != instance.Spec.PvcName {
 		log.Errorf("Duplicate Request. There already exists a PV: %s which is bound", pvName)
-		setInstanceError(ctx, r, instance, "Duplicate Request")
-		return reconcile.Result{RequeueAfter: timeout}, nil
+		return nil, errors.New("Duplicate Request")
 	}
 	// Create PVC mapping to above created PV.
 	log.Infof("Now creating pvc: %s", instance.Spec.PvcName)
@@ -367,10 +636,8 @@ func (r *ReconcileCnsRegisterVolume) Reconcile(ctx context.Context,
 			pvc, err = k8sclient.CoreV1().PersistentVolumeClaims(instance.Namespace).Get(ctx,
 				instance.Spec.PvcName, metav1.GetOptions{})
 			if err != nil {
-				msg := fmt.Sprintf("Failed to get PVC: %s on namespace: %s", instance.Spec.PvcName, instance.Namespace)
-				log.Errorf(msg)
-				setInstanceError(ctx, r, instance, msg)
-				return reconcile.Result{RequeueAfter: timeout}, nil
+				return nil, logger.LogNewErrorf(log, "Failed to get PVC: %s on namespace: %s",
+					instance.Spec.PvcName, instance.Namespace)
 			}
 			if pvc.Status.Phase == v1.ClaimBound && pvc.Spec.VolumeName != pvName {
 				// This is handle cases where PVC with this name already exists and
@@ -380,53 +647,35 @@ func (r *ReconcileCnsRegisterVolume) Reconcile(ctx context.Context,
 				msg := fmt.Sprintf("Another PVC: %s already exists in namespace: %s which is Bound to a different PV",
 					instance.Spec.PvcName, instance.Namespace)
 				log.Errorf(msg)
-				setInstanceError(ctx, r, instance, msg)
 				// Untag the CNS volume which was created previously.
 				_, err = common.DeleteVolumeUtil(ctx, r.volumeManager, volumeID, false)
 				if err != nil {
 					log.Errorf("Failed to untag CNS volume: %s with error: %+v", volumeID, err)
 				} else {
+					r.deleteRegisterVolumeOperation(ctx, instance)
 					// Delete PV created above.
 					err = k8sclient.CoreV1().PersistentVolumes().Delete(ctx, pvName, *metav1.NewDeleteOptions(0))
 					if err != nil {
 						log.Errorf("Failed to delete PV: %s with error: %+v", pvName, err)
 					}
 				}
-				return reconcile.Result{RequeueAfter: timeout}, nil
+				return nil, errors.New(msg)
 			}
 		} else {
 			log.Errorf("Failed to create PVC with spec: %+v. Error: %+v", pvcSpec, err)
-			setInstanceError(ctx, r, instance,
-				fmt.Sprintf("Failed to create PVC: %s for volume with err: %+v", instance.Spec.PvcName, err))
-			return reconcile.Result{RequeueAfter: timeout}, nil
+			return nil, fmt.Errorf("Failed to create PVC: %s for volume with err: %+v", instance.Spec.PvcName, err)
 		}
 	} else {
 		log.Infof("PVC: %s is created successfully", instance.Spec.PvcName)
 	}
 	// Watch for PVC to be bound.
 	isBound, err := isPVCBound(ctx, k8sclient, pvc, time.Duration(1*time.Minute))
-	if isBound {
-		log.Infof("PVC: %s is bound", instance.Spec.PvcName)
-	} else {
+	if !isBound {
 		log.Errorf("PVC: %s is not bound. Error: %+v", instance.Spec.PvcName, err)
-		setInstanceError(ctx, r, instance, fmt.Sprintf("PVC: %s is not bound", instance.Spec.PvcName))
-		return reconcile.Result{RequeueAfter: timeout}, nil
+		return nil, fmt.Errorf("PVC: %s is not bound", instance.Spec.PvcName)
 	}
-
-	// Update the instance to indicate the volume registration is successful.
-	msg := fmt.Sprintf("Successfully registered the volume on namespace: %s", instance.Namespace)
-	err = setInstanceSuccess(ctx, r, instance, instance.Spec.PvcName, pvc.UID, msg)
-	if err != nil {
-		msg := fmt.Sprintf("Failed to update CnsRegistered instance with error: %+v", err)
-		log.Error(msg)
-		setInstanceError(ctx, r, instance, msg)
-		return reconcile.Result{RequeueAfter: timeout}, nil
-	}
-	backOffDurationMapMutex.Lock()
-	delete(backOffDuration, instance.Name)
-	backOffDurationMapMutex.Unlock()
-	log.Info(msg)
-	return reconcile.Result{}, nil
+	log.Infof("PVC: %s is bound", instance.Spec.PvcName)
+	return pvc, nil
 }
 
 // validateCnsRegisterVolumeSpec validates the input params of
@@ -445,6 +694,22 @@ func validateCnsRegisterVolumeSpec(ctx context.Context, instance *cnsregistervol
 	return nil
 }
 
+// validateVanillaCnsRegisterVolumeSpec validates the input params of
+// CnsRegisterVolume instance which are specific to vanilla clusters.
+func validateVanillaCnsRegisterVolumeSpec(ctx context.Context,
+	instance *cnsregistervolumev1alpha1.CnsRegisterVolume) error {
+	var msg string
+	if instance.Spec.StorageClassName == "" {
+		msg = "StorageClassName must be specified to register a volume in a vanilla cluster"
+	} else if instance.Spec.VolumeID == "" && instance.Spec.DiskURLPath == "" {
+		msg = "Either VolumeID or DiskURLPath must be specified"
+	}
+	if msg != "" {
+		return errors.New(msg)
+	}
+	return nil
+}
+
 // isBlockVolumeRegisterRequest verifies if block volume register is requested
 // via CnsRegisterVolume instance.
 func isBlockVolumeRegisterRequest(ctx context.Context, instance *cnsregistervolumev1alpha1.CnsRegisterVolume) bool {
@@ -26,7 +26,9 @@ import (
 
 	"github.com/google/uuid"
 	cnstypes "github.com/vmware/govmomi/cns/types"
+	vim25types "github.com/vmware/govmomi/vim25/types"
 	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
@@ -81,7 +83,7 @@ func constructCreateSpecForInstance(r *ReconcileCnsRegisterVolume,
 		clusterIDForVolumeMetadata = r.configInfo.Cfg.Global.ClusterID
 	}
 	containerCluster := vsphere.GetContainerCluster(clusterIDForVolumeMetadata,
-		r.configInfo.Cfg.VirtualCenter[host].User,
+		vsphere.GetUsername(host, r.configInfo.Cfg.VirtualCenter[host].User),
 		cnstypes.CnsClusterFlavorWorkload, r.configInfo.Cfg.Global.ClusterDistribution)
 	createSpec := &cnstypes.CnsVolumeCreateSpec{
 		Name:       volumeName,
@@ -107,6 +109,92 @@ func constructCreateSpecForInstance(r *ReconcileCnsRegisterVolume,
 	return createSpec
 }
 
+// constructVanillaCreateSpecForInstance creates the CNS CreateVolume spec to
+// register the volume in the CnsRegisterVolume instance in a vanilla cluster.
+// If DiskURLPath is specified and the vCenter supports VSLM APIs, the disk is
+// first registered as an FCD using the VSLM endpoint.
+func constructVanillaCreateSpecForInstance(ctx context.Context, r *ReconcileCnsRegisterVolume,
+	instance *cnsregistervolumev1alpha1.CnsRegisterVolume, vc *vsphere.VirtualCenter,
+	storagePolicyID string) (*cnstypes.CnsVolumeCreateSpec, error) {
+	log := logger.GetLogger(ctx)
+	var volumeName string
+	if instance.Spec.VolumeID != "" {
+		volumeName = staticPvNamePrefix + instance.Spec.VolumeID
+	} else {
+		id, _ := uuid.NewUUID()
+		volumeName = staticPvNamePrefix + id.String()
+	}
+	var containerClusterArray []cnstypes.CnsContainerCluster
+	containerCluster := vsphere.GetContainerCluster(r.configInfo.Cfg.Global.ClusterID,
+		vsphere.GetUsername(vc.Config.Host, r.configInfo.Cfg.VirtualCenter[vc.Config.Host].User),
+		cnstypes.CnsClusterFlavorVanilla,
+		r.configInfo.Cfg.Global.ClusterDistribution)
+	containerClusterArray = append(containerClusterArray, containerCluster)
+	createSpec := &cnstypes.CnsVolumeCreateSpec{
+		Name:       volumeName,
+		VolumeType: common.BlockVolumeType,
+		Metadata: cnstypes.CnsVolumeMetadata{
+			ContainerCluster:      containerCluster,
+			ContainerClusterArray: containerClusterArray,
+		},
+	}
+	if storagePolicyID != "" {
+		createSpec.Profile = append(createSpec.Profile, &vim25types.VirtualMachineDefinedProfileSpec{
+			ProfileId: storagePolicyID,
+		})
+	}
+	if instance.Spec.VolumeID != "" {
+		createSpec.BackingObjectDetails = &cnstypes.CnsBlockBackingDetails{
+			BackingDiskId: instance.Spec.VolumeID,
+		}
+		return createSpec, nil
+	}
+	useVslmAPIs, err := common.UseVslmAPIs(ctx, vc.Client.ServiceContent.About)
+	if err != nil {
+		return nil, logger.LogNewErrorf(log, "Error while determining the correct APIs to use for "+
+			"vSphere version %q. Error: %+v", vc.Client.ServiceContent.About.ApiVersion, err)
+	}
+	if !useVslmAPIs {
+		createSpec.BackingObjectDetails = &cnstypes.CnsBlockBackingDetails{
+			BackingDiskUrlPath: instance.Spec.DiskURLPath,
+		}
+		return createSpec, nil
+	}
+	backingDiskID, err := r.volumeManager.RegisterDisk(ctx, instance.Spec.DiskURLPath, volumeName)
+	if err != nil {
+		return nil, logger.LogNewErrorf(log, "Failed to register disk: %s as FCD. Error: %+v",
+			instance.Spec.DiskURLPath, err)
+	}
+	log.Infof("Registered disk: %s as FCD with ID: %s", instance.Spec.DiskURLPath, backingDiskID)
+	createSpec.BackingObjectDetails = &cnstypes.CnsBlockBackingDetails{
+		BackingDiskId: backingDiskID,
+	}
+	return createSpec, nil
+}
+
+// getStoragePolicyIDFromStorageClass returns the storage policy ID specified
+// in the parameters of the given StorageClass, either directly or by the
+// storage policy name. An empty string is returned if the StorageClass does
+// not specify a storage policy.
+func getStoragePolicyIDFromStorageClass(ctx context.Context, vc *vsphere.VirtualCenter,
+	sc *storagev1.StorageClass) (string, error) {
+	log := logger.GetLogger(ctx)
+	for paramName, val := range sc.Parameters {
+		switch strings.ToLower(paramName) {
+		case common.AttributeStoragePolicyID:
+			return val, nil
+		case common.AttributeStoragePolicyName:
+			storagePolicyID, err := vc.GetStoragePolicyIDByName(ctx, val)
+			if err != nil {
+				return "", logger.LogNewErrorf(log, "Failed to find storage policy: %s specified in "+
+					"StorageClass: %s. Error: %+v", val, sc.Name, err)
+			}
+			return storagePolicyID, nil
+		}
+	}
+	return "", nil
+}
+
 // getK8sStorageClassName gets the storage class name in K8S mapping the vsphere
 // storagepolicy id. The policy must also be assigned to the passed namespace.
 func getK8sStorageClassName(ctx context.Context, k8sClient clientset.Interface,
@@ -161,7 +249,8 @@ func getK8sStorageClassName(ctx context.Context, k8sClient clientset.Interface,
 
 // getPersistentVolumeSpec to create PV volume spec for the given input params.
 func getPersistentVolumeSpec(volumeName string, volumeID string, capacity int64,
-	accessMode v1.PersistentVolumeAccessMode, scName string, claimRef *v1.ObjectReference) *v1.PersistentVolume {
+	accessMode v1.PersistentVolumeAccessMode, scName string, claimRef *v1.ObjectReference,
+	nodeAffinity *v1.VolumeNodeAffinity) *v1.PersistentVolume {
 	capacityInMb := strconv.FormatInt(capacity, 10) + "Mi"
 	pv := &v1.PersistentVolume{
 		TypeMeta: metav1.TypeMeta{},
@@ -186,6 +275,7 @@ func getPersistentVolumeSpec(volumeName string, volumeID string, capacity int64,
 			},
 			ClaimRef:         claimRef,
 			StorageClassName: scName,
+			NodeAffinity:     nodeAffinity,
 		},
 		Status: v1.PersistentVolumeStatus{},
 	}
@@ -0,0 +1,57 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsregistervolume
+
+import (
+	"context"
+	"testing"
+
+	cnsregistervolumev1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsregistervolume/v1alpha1"
+)
+
+func TestValidateVanillaCnsRegisterVolumeSpec(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		spec    cnsregistervolumev1alpha1.CnsRegisterVolumeSpec
+		isValid bool
+	}{
+		{
+			name:    "missing storage class",
+			spec:    cnsregistervolumev1alpha1.CnsRegisterVolumeSpec{PvcName: "pvc", VolumeID: "vol"},
+			isValid: false,
+		},
+		{
+			name:    "missing volume",
+			spec:    cnsregistervolumev1alpha1.CnsRegisterVolumeSpec{PvcName: "pvc", StorageClassName: "sc"},
+			isValid: false,
+		},
+		{
+			name: "valid disk url path",
+			spec: cnsregistervolumev1alpha1.CnsRegisterVolumeSpec{PvcName: "pvc", StorageClassName: "sc",
+				DiskURLPath: "https://vc/folder/vm/vm_1.vmdk?dcPath=dc&dsName=ds"},
+			isValid: true,
+		},
+	}
+	for _, test := range tests {
+		instance := &cnsregistervolumev1alpha1.CnsRegisterVolume{Spec: test.spec}
+		err := validateVanillaCnsRegisterVolumeSpec(ctx, instance)
+		if (err == nil) != test.isValid {
+			t.Errorf("test %q: expected valid: %t, got error: %v", test.name, test.isValid, err)
+		}
+	}
+}
@@ -0,0 +1,402 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsregistervolumebatch
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	apis "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
+	cnsregistervolumev1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsregistervolume/v1alpha1"
+	cnsregistervolumebatchv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsregistervolumebatch/v1alpha1"
+	volumes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
+	commonconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
+)
+
+const (
+	defaultMaxWorkerThreadsForRegisterVolumeBatch = 4
+	defaultMaxConcurrentRegistrations             = 10
+	// batchProgressCheckInterval is the interval after which an incomplete
+	// CnsRegisterVolumeBatch instance is reconciled again, in addition to the
+	// reconciles triggered by changes to its CnsRegisterVolume instances.
+	batchProgressCheckInterval = time.Minute
+	// batchNameLabel is the label set on the CnsRegisterVolume instances
+	// created for a CnsRegisterVolumeBatch instance. The value of the label
+	// is the name of the CnsRegisterVolumeBatch instance.
+	batchNameLabel = "cns.vmware.com/cnsregistervolumebatch"
+)
+
+// backOffDuration is a map of cnsregistervolumebatch name's to the time after
+// which a request for this instance will be requeued.
+// Initialized to 1 second for new instances and for instances whose latest
+// reconcile operation succeeded.
+// If the reconcile fails, backoff is incremented exponentially.
+var (
+	backOffDuration         map[string]time.Duration
+	backOffDurationMapMutex = sync.Mutex{}
+)
+
+// Add creates a new CnsRegisterVolumeBatch Controller and adds it to the
+// Manager. The Manager will set fields on the Controller and Start it when the
+// Manager is Started.
+func Add(mgr manager.Manager, clusterFlavor cnstypes.CnsClusterFlavor,
+	configInfo *commonconfig.ConfigurationInfo, volumeManager volumes.Manager) error {
+	ctx, log := logger.GetNewContextWithLogger()
+	if clusterFlavor == cnstypes.CnsClusterFlavorVanilla {
+		if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.VanillaRegisterVolume) {
+			log.Debugf("Not initializing the CnsRegisterVolumeBatch Controller as %q feature is disabled",
+				common.VanillaRegisterVolume)
+			return nil
+		}
+	} else if clusterFlavor == cnstypes.CnsClusterFlavorWorkload {
+		if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.TKGsHA) {
+			clusterComputeResourceMoIds, err := common.GetClusterComputeResourceMoIds(ctx)
+			if err != nil {
+				log.Errorf("failed to get clusterComputeResourceMoIds. err: %v", err)
+				return err
+			}
+			if len(clusterComputeResourceMoIds) > 1 {
+				log.Infof("Not initializing the CnsRegisterVolumeBatch Controller as stretched supervisor is detected.")
+				return nil
+			}
+		}
+	} else {
+		log.Debug("Not initializing the CnsRegisterVolumeBatch Controller as its a guest cluster")
+		return nil
+	}
+	// Initializes kubernetes client.
+	k8sclient, err := k8s.NewClient(ctx)
+	if err != nil {
+		log.Errorf("Creating Kubernetes client failed. Err: %v", err)
+		return err
+	}
+
+	// eventBroadcaster broadcasts events on cnsregistervolumebatch instances to
+	// the event sink.
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(
+		&typedcorev1.EventSinkImpl{
+			Interface: k8sclient.CoreV1().Events(""),
+		},
+	)
+	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: apis.GroupName})
+	return add(mgr, newReconciler(mgr, clusterFlavor, configInfo, recorder))
+}
+
+// newReconciler returns a new reconcile.Reconciler.
+func newReconciler(mgr manager.Manager, clusterFlavor cnstypes.CnsClusterFlavor,
+	configInfo *commonconfig.ConfigurationInfo, recorder record.EventRecorder) reconcile.Reconciler {
+	return &ReconcileCnsRegisterVolumeBatch{client: mgr.GetClient(), scheme: mgr.GetScheme(),
+		clusterFlavor: clusterFlavor, configInfo: configInfo, recorder: recorder}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler.
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	_, log := logger.GetNewContextWithLogger()
+
+	// Create a new controller.
+	c, err := controller.New("cnsregistervolumebatch-controller", mgr,
+		controller.Options{Reconciler: r, MaxConcurrentReconciles: defaultMaxWorkerThreadsForRegisterVolumeBatch})
+	if err != nil {
+		log.Errorf("Failed to create new CnsRegisterVolumeBatch controller with error: %+v", err)
+		return err
+	}
+
+	backOffDuration = make(map[string]time.Duration)
+
+	// Watch for changes to primary resource CnsRegisterVolumeBatch.
+	err = c.Watch(&source.Kind{Type: &cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatch{}},
+		&handler.EnqueueRequestForObject{})
+	if err != nil {
+		log.Errorf("Failed to watch for changes to CnsRegisterVolumeBatch resource with error: %+v", err)
+		return err
+	}
+	// Watch for changes to the CnsRegisterVolume instances created for a
+	// CnsRegisterVolumeBatch instance. The ownerReferences of CnsRegisterVolume
+	// instances are replaced by the PVC on successful registration, hence the
+	// batch is identified using the label.
+	err = c.Watch(&source.Kind{Type: &cnsregistervolumev1alpha1.CnsRegisterVolume{}},
+		handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+			batchName, ok := obj.GetLabels()[batchNameLabel]
+			if !ok {
+				return nil
+			}
+			return []reconcile.Request{{NamespacedName: apitypes.NamespacedName{
+				Namespace: obj.GetNamespace(), Name: batchName}}}
+		}))
+	if err != nil {
+		log.Errorf("Failed to watch for changes to CnsRegisterVolume resource with error: %+v", err)
+		return err
+	}
+	return nil
+}
+
+// blank assignment to verify that ReconcileCnsRegisterVolumeBatch implements
+// reconcile.Reconciler.
+var _ reconcile.Reconciler = &ReconcileCnsRegisterVolumeBatch{}
+
+// ReconcileCnsRegisterVolumeBatch reconciles a CnsRegisterVolumeBatch object.
+type ReconcileCnsRegisterVolumeBatch struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver.
+	client        client.Client
+	scheme        *runtime.Scheme
+	clusterFlavor cnstypes.CnsClusterFlavor
+	configInfo    *commonconfig.ConfigurationInfo
+	recorder      record.EventRecorder
+}
+
+// Reconcile reads that state of the cluster for a CnsRegisterVolumeBatch
+// object and makes changes based on the state read and what is in the
+// CnsRegisterVolumeBatch.Spec.
+// Each disk in the batch is registered using a CnsRegisterVolume instance.
+// The list of disks is computed once and persisted in the status of the
+// instance, so that retries operate on the same set of disks.
+// Note:
+// The Controller will requeue the Request to be processed again if the
+// returned error is non-nil or Result.Requeue is true. Otherwise, upon
+// completion it will remove the work from the queue.
+func (r *ReconcileCnsRegisterVolumeBatch) Reconcile(ctx context.Context,
+	request reconcile.Request) (reconcile.Result, error) {
+	log := logger.GetLogger(ctx)
+	// Fetch the CnsRegisterVolumeBatch instance.
+	instance := &cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatch{}
+	err := r.client.Get(ctx, request.NamespacedName, instance)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Infof("CnsRegisterVolumeBatch resource not found. Ignoring since object must be deleted.")
+			return reconcile.Result{}, nil
+		}
+		log.Errorf("Error reading the CnsRegisterVolumeBatch with name: %q on namespace: %q. Err: %+v",
+			request.Name, request.Namespace, err)
+		// Error reading the object - return with err.
+		return reconcile.Result{}, err
+	}
+	// Initialize backOffDuration for the instance, if required.
+	backOffDurationMapMutex.Lock()
+	var timeout time.Duration
+	if _, exists := backOffDuration[instance.Name]; !exists {
+		backOffDuration[instance.Name] = time.Second
+	}
+	timeout = backOffDuration[instance.Name]
+	backOffDurationMapMutex.Unlock()
+
+	// If all the disks in the CnsRegisterVolumeBatch instance are registered,
+	// remove the instance from the queue.
+	if instance.Status.Completed {
+		backOffDurationMapMutex.Lock()
+		delete(backOffDuration, instance.Name)
+		backOffDurationMapMutex.Unlock()
+		return reconcile.Result{}, nil
+	}
+
+	log.Infof("Reconciling CnsRegisterVolumeBatch with instance: %q from namespace: %q. timeout %q seconds",
+		instance.Name, request.Namespace, timeout)
+	if len(instance.Status.Volumes) == 0 {
+		err = validateCnsRegisterVolumeBatchSpec(ctx, r.clusterFlavor, instance)
+		if err != nil {
+			setInstanceError(ctx, r, instance, err.Error())
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		volumeStatuses, err := getBatchVolumes(ctx, r.configInfo, instance)
+		if err != nil {
+			setInstanceError(ctx, r, instance, err.Error())
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		if len(volumeStatuses) == 0 {
+			setInstanceError(ctx, r, instance, "No disks found to register")
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		// Persist the list of disks before creating any CnsRegisterVolume
+		// instances for them.
+		instance.Status.Volumes = volumeStatuses
+		instance.Status.Error = ""
+		err = updateCnsRegisterVolumeBatch(ctx, r.client, instance)
+		if err != nil {
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		log.Infof("Found %d disks to register for CnsRegisterVolumeBatch: %q on namespace: %q",
+			len(volumeStatuses), instance.Name, instance.Namespace)
+	}
+
+	maxConcurrentRegistrations := instance.Spec.MaxConcurrentRegistrations
+	if maxConcurrentRegistrations <= 0 {
+		maxConcurrentRegistrations = defaultMaxConcurrentRegistrations
+	}
+	newStatus := instance.Status.DeepCopy()
+	var pending []int
+	inFlight := 0
+	for index := range newStatus.Volumes {
+		volumeStatus := &newStatus.Volumes[index]
+		if volumeStatus.Registered {
+			continue
+		}
+		registerVolume := &cnsregistervolumev1alpha1.CnsRegisterVolume{}
+		err = r.client.Get(ctx, apitypes.NamespacedName{Namespace: instance.Namespace,
+			Name: volumeStatus.CnsRegisterVolumeName}, registerVolume)
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				log.Errorf("Failed to get CnsRegisterVolume: %q on namespace: %q. Error: %+v",
+					volumeStatus.CnsRegisterVolumeName, instance.Namespace, err)
+				return reconcile.Result{RequeueAfter: timeout}, nil
+			}
+			pending = append(pending, index)
+			continue
+		}
+		if !isCnsRegisterVolumeForBatchVolume(registerVolume, instance.Name, *volumeStatus) {
+			volumeStatus.Error = fmt.Sprintf("CnsRegisterVolume: %q on namespace: %q exists and does not "+
+				"register this disk", registerVolume.Name, instance.Namespace)
+			continue
+		}
+		volumeStatus.Registered = registerVolume.Status.Registered
+		volumeStatus.Error = registerVolume.Status.Error
+		if !volumeStatus.Registered {
+			// The CnsRegisterVolume instance is retried on errors, so the disk
+			// is in flight until it is registered.
+			inFlight++
+		}
+	}
+	// Create CnsRegisterVolume instances for the pending disks, without
+	// exceeding the maximum number of concurrent registrations.
+	for _, index := range pending {
+		if inFlight >= maxConcurrentRegistrations {
+			break
+		}
+		registerVolume := getCnsRegisterVolumeForBatchVolume(instance, newStatus.Volumes[index])
+		err = r.client.Create(ctx, registerVolume)
+		if err != nil && !apierrors.IsAlreadyExists(err) {
+			msg := fmt.Sprintf("Failed to create CnsRegisterVolume: %q. Error: %+v", registerVolume.Name, err)
+			log.Error(msg)
+			newStatus.Volumes[index].Error = msg
+			continue
+		}
+		log.Infof("Created CnsRegisterVolume: %q on namespace: %q for CnsRegisterVolumeBatch: %q",
+			registerVolume.Name, instance.Namespace, instance.Name)
+		inFlight++
+	}
+
+	newStatus.RegisteredCount, newStatus.FailedCount = 0, 0
+	for _, volumeStatus := range newStatus.Volumes {
+		if volumeStatus.Registered {
+			newStatus.RegisteredCount++
+		} else if volumeStatus.Error != "" {
+			newStatus.FailedCount++
+		}
+	}
+	newStatus.Completed = newStatus.RegisteredCount == len(newStatus.Volumes)
+	newStatus.Error = ""
+	if !reflect.DeepEqual(instance.Status, *newStatus) {
+		instance.Status = *newStatus
+		err = updateCnsRegisterVolumeBatch(ctx, r.client, instance)
+		if err != nil {
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+	}
+	backOffDurationMapMutex.Lock()
+	backOffDuration[instance.Name] = time.Second
+	backOffDurationMapMutex.Unlock()
+	if !newStatus.Completed {
+		log.Infof("CnsRegisterVolumeBatch: %q on namespace: %q registered %d of %d disks, %d failed",
+			instance.Name, instance.Namespace, newStatus.RegisteredCount, len(newStatus.Volumes),
+			newStatus.FailedCount)
+		return reconcile.Result{RequeueAfter: batchProgressCheckInterval}, nil
+	}
+	msg := fmt.Sprintf("Successfully registered %d volumes on namespace: %s",
+		newStatus.RegisteredCount, instance.Namespace)
+	r.recorder.Event(instance, v1.EventTypeNormal, "CnsRegisterVolumeBatchSucceeded", msg)
+	backOffDurationMapMutex.Lock()
+	delete(backOffDuration, instance.Name)
+	backOffDurationMapMutex.Unlock()
+	log.Info(msg)
+	return reconcile.Result{}, nil
+}
+
+// getCnsRegisterVolumeForBatchVolume returns the CnsRegisterVolume instance
+// to be created to register the given disk of the CnsRegisterVolumeBatch.
+func getCnsRegisterVolumeForBatchVolume(instance *cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatch,
+	volumeStatus cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatchVolumeStatus,
+) *cnsregistervolumev1alpha1.CnsRegisterVolume {
+	return &cnsregistervolumev1alpha1.CnsRegisterVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      volumeStatus.CnsRegisterVolumeName,
+			Namespace: instance.Namespace,
+			Labels: map[string]string{
+				batchNameLabel: instance.Name,
+			},
+		},
+		Spec: cnsregistervolumev1alpha1.CnsRegisterVolumeSpec{
+			PvcName:          volumeStatus.PvcName,
+			VolumeID:         volumeStatus.VolumeID,
+			DiskURLPath:      volumeStatus.DiskURLPath,
+			AccessMode:       instance.Spec.AccessMode,
+			StorageClassName: instance.Spec.StorageClassName,
+		},
+	}
+}
+
+// setInstanceError sets error and records an event on the
+// CnsRegisterVolumeBatch instance.
+func setInstanceError(ctx context.Context, r *ReconcileCnsRegisterVolumeBatch,
+	instance *cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatch, errMsg string) {
+	log := logger.GetLogger(ctx)
+	log.Error(errMsg)
+	instance.Status.Error = errMsg
+	err := updateCnsRegisterVolumeBatch(ctx, r.client, instance)
+	if err != nil {
+		log.Errorf("updateCnsRegisterVolumeBatch failed. err: %v", err)
+	}
+	// Double backOff duration.
+	backOffDurationMapMutex.Lock()
+	backOffDuration[instance.Name] = backOffDuration[instance.Name] * 2
+	r.recorder.Event(instance, v1.EventTypeWarning, "CnsRegisterVolumeBatchFailed", errMsg)
+	backOffDurationMapMutex.Unlock()
+}
+
+// updateCnsRegisterVolumeBatch updates the CnsRegisterVolumeBatch instance in
+// K8S.
+func updateCnsRegisterVolumeBatch(ctx context.Context, client client.Client,
+	instance *cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatch) error {
+	log := logger.GetLogger(ctx)
+	err := client.Update(ctx, instance)
+	if err != nil {
+		log.Errorf("Failed to update CnsRegisterVolumeBatch instance: %q on namespace: %q. Error: %+v",
+			instance.Name, instance.Namespace, err)
+	}
+	return err
+}
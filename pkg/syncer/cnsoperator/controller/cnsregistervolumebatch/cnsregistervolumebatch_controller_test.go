@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsregistervolumebatch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	apis "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
+	cnsregistervolumev1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsregistervolume/v1alpha1"
+	cnsregistervolumebatchv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsregistervolumebatch/v1alpha1"
+)
+
+func TestReconcileCountsFailedRegistrationsAsInFlight(t *testing.T) {
+	ctx := context.Background()
+	backOffDuration = make(map[string]time.Duration)
+	instance := &cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatch{
+		ObjectMeta: metav1.ObjectMeta{Name: "import", Namespace: "ns-1"},
+		Spec:       cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatchSpec{MaxConcurrentRegistrations: 1},
+		Status: cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatchStatus{
+			Volumes: []cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatchVolumeStatus{
+				{VolumeID: "volume-1", PvcName: "pvc-1", CnsRegisterVolumeName: "import-1"},
+				{VolumeID: "volume-2", PvcName: "pvc-2", CnsRegisterVolumeName: "import-2"},
+			},
+		},
+	}
+	// The registration of the first disk failed and is being retried.
+	failedRegisterVolume := getCnsRegisterVolumeForBatchVolume(instance, instance.Status.Volumes[0])
+	failedRegisterVolume.Status.Error = "failed to register volume"
+
+	s := scheme.Scheme
+	s.AddKnownTypes(apis.SchemeGroupVersion, instance, failedRegisterVolume,
+		&cnsregistervolumev1alpha1.CnsRegisterVolumeList{})
+	fakeClient := fake.NewClientBuilder().WithScheme(s).WithObjects(instance, failedRegisterVolume).Build()
+	r := &ReconcileCnsRegisterVolumeBatch{client: fakeClient, scheme: s, recorder: record.NewFakeRecorder(10)}
+	request := reconcile.Request{NamespacedName: apitypes.NamespacedName{Name: instance.Name,
+		Namespace: instance.Namespace}}
+
+	if _, err := r.Reconcile(ctx, request); err != nil {
+		t.Fatalf("failed to reconcile: %v", err)
+	}
+	registerVolumes := &cnsregistervolumev1alpha1.CnsRegisterVolumeList{}
+	if err := fakeClient.List(ctx, registerVolumes, client.InNamespace(instance.Namespace)); err != nil {
+		t.Fatal(err)
+	}
+	if len(registerVolumes.Items) != 1 {
+		t.Errorf("expected no CnsRegisterVolume to be created while a failed registration is retried, got %d",
+			len(registerVolumes.Items))
+	}
+
+	// The second disk is registered once the first one is.
+	failedRegisterVolume.Status = cnsregistervolumev1alpha1.CnsRegisterVolumeStatus{Registered: true}
+	if err := fakeClient.Update(ctx, failedRegisterVolume); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, request); err != nil {
+		t.Fatalf("failed to reconcile: %v", err)
+	}
+	if err := fakeClient.List(ctx, registerVolumes, client.InNamespace(instance.Namespace)); err != nil {
+		t.Fatal(err)
+	}
+	if len(registerVolumes.Items) != 2 {
+		t.Errorf("expected a CnsRegisterVolume to be created for the second disk, got %d",
+			len(registerVolumes.Items))
+	}
+}
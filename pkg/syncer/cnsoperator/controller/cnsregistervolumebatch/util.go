@@ -0,0 +1,254 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsregistervolumebatch
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	"github.com/vmware/govmomi/find"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	cnsregistervolumev1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsregistervolume/v1alpha1"
+	cnsregistervolumebatchv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsregistervolumebatch/v1alpha1"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	commonconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+const (
+	defaultPvcNameTemplate = "{{.BatchName}}-{{.Index}}"
+	// maxBatchNameLength is the maximum length of the name of a
+	// CnsRegisterVolumeBatch instance, as the name is used as a label value
+	// on the CnsRegisterVolume instances created for it.
+	maxBatchNameLength = validation.LabelValueMaxLength
+)
+
+var invalidDiskNameChars = regexp.MustCompile("[^a-z0-9-]+")
+
+// pvcNameTemplateParams holds the values which can be referred to in
+// CnsRegisterVolumeBatchSpec.PvcNameTemplate.
+type pvcNameTemplateParams struct {
+	BatchName string
+	Index     int
+	DiskName  string
+}
+
+// validateCnsRegisterVolumeBatchSpec validates the input params of
+// CnsRegisterVolumeBatch instance.
+func validateCnsRegisterVolumeBatchSpec(ctx context.Context, clusterFlavor cnstypes.CnsClusterFlavor,
+	instance *cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatch) error {
+	var msg string
+	spec := instance.Spec
+	if len(instance.Name) > maxBatchNameLength {
+		msg = fmt.Sprintf("CnsRegisterVolumeBatch name cannot be longer than %d characters", maxBatchNameLength)
+	} else if spec.DatastoreFolderPath != "" && len(spec.Disks) != 0 {
+		msg = "DatastoreFolderPath and Disks cannot be specified together"
+	} else if spec.DatastoreFolderPath == "" && len(spec.Disks) == 0 {
+		msg = "Either DatastoreFolderPath or Disks must be specified"
+	} else if clusterFlavor == cnstypes.CnsClusterFlavorVanilla && spec.StorageClassName == "" {
+		msg = "StorageClassName must be specified to register volumes in a vanilla cluster"
+	}
+	if msg == "" {
+		for index, disk := range spec.Disks {
+			if disk.VolumeID != "" && disk.DiskURLPath != "" {
+				msg = fmt.Sprintf("VolumeID and DiskURLPath cannot be specified together for disk %d", index)
+			} else if disk.VolumeID == "" && disk.DiskURLPath == "" {
+				msg = fmt.Sprintf("Either VolumeID or DiskURLPath must be specified for disk %d", index)
+			}
+			if msg != "" {
+				break
+			}
+		}
+	}
+	if msg != "" {
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// getBatchVolumes returns the initial registration status of every disk to be
+// registered for the CnsRegisterVolumeBatch instance, including the names of
+// the PVC and the CnsRegisterVolume instance to be created for each disk.
+func getBatchVolumes(ctx context.Context, configInfo *commonconfig.ConfigurationInfo,
+	instance *cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatch) (
+	[]cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatchVolumeStatus, error) {
+	disks := instance.Spec.Disks
+	if instance.Spec.DatastoreFolderPath != "" {
+		diskURLPaths, err := getDiskURLPathsInFolder(ctx, configInfo, instance.Spec.DatastoreFolderPath)
+		if err != nil {
+			return nil, err
+		}
+		disks = nil
+		for _, diskURLPath := range diskURLPaths {
+			disks = append(disks, cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatchDisk{DiskURLPath: diskURLPath})
+		}
+	}
+	return getBatchVolumeStatuses(instance.Name, instance.Spec.PvcNameTemplate, disks)
+}
+
+// getBatchVolumeStatuses generates the PVC name and the CnsRegisterVolume
+// name for each of the given disks.
+func getBatchVolumeStatuses(batchName string, pvcNameTemplate string,
+	disks []cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatchDisk) (
+	[]cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatchVolumeStatus, error) {
+	if pvcNameTemplate == "" {
+		pvcNameTemplate = defaultPvcNameTemplate
+	}
+	tmpl, err := template.New("pvcName").Option("missingkey=error").Parse(pvcNameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PvcNameTemplate %q. Error: %+v", pvcNameTemplate, err)
+	}
+	var volumeStatuses []cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatchVolumeStatus
+	pvcNames := make(map[string]bool)
+	registerVolumeNames := make(map[string]bool)
+	for index, disk := range disks {
+		pvcName := disk.PvcName
+		if pvcName == "" {
+			var buf bytes.Buffer
+			err = tmpl.Execute(&buf, pvcNameTemplateParams{
+				BatchName: batchName,
+				Index:     index,
+				DiskName:  getDiskName(disk),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate PVC name for disk %d. Error: %+v", index, err)
+			}
+			pvcName = buf.String()
+		}
+		if errs := validation.IsDNS1123Subdomain(pvcName); len(errs) != 0 {
+			return nil, fmt.Errorf("invalid PVC name %q for disk %d: %s", pvcName, index, strings.Join(errs, ", "))
+		}
+		if pvcNames[pvcName] {
+			return nil, fmt.Errorf("duplicate PVC name %q for disk %d", pvcName, index)
+		}
+		pvcNames[pvcName] = true
+		registerVolumeName := getCnsRegisterVolumeName(batchName, disk)
+		if registerVolumeNames[registerVolumeName] {
+			return nil, fmt.Errorf("disk %d is specified more than once", index)
+		}
+		registerVolumeNames[registerVolumeName] = true
+		volumeStatuses = append(volumeStatuses, cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatchVolumeStatus{
+			VolumeID:              disk.VolumeID,
+			DiskURLPath:           disk.DiskURLPath,
+			PvcName:               pvcName,
+			CnsRegisterVolumeName: registerVolumeName,
+		})
+	}
+	return volumeStatuses, nil
+}
+
+// getCnsRegisterVolumeName returns a name for the CnsRegisterVolume instance
+// registering the given disk, which is stable across reconciles. The name
+// holds the SHA-256 hash of the disk, so that distinct disks get distinct
+// names.
+func getCnsRegisterVolumeName(batchName string, disk cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatchDisk) string {
+	diskID := disk.DiskURLPath
+	if disk.VolumeID != "" {
+		diskID = disk.VolumeID
+	}
+	return fmt.Sprintf("%s-%x", batchName, sha256.Sum256([]byte(diskID)))
+}
+
+// isCnsRegisterVolumeForBatchVolume returns true if the given CnsRegisterVolume
+// instance was created by the CnsRegisterVolumeBatch with the given name to
+// register the disk of the given volume status.
+func isCnsRegisterVolumeForBatchVolume(registerVolume *cnsregistervolumev1alpha1.CnsRegisterVolume,
+	batchName string, volumeStatus cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatchVolumeStatus) bool {
+	return registerVolume.Labels[batchNameLabel] == batchName &&
+		registerVolume.Spec.VolumeID == volumeStatus.VolumeID &&
+		registerVolume.Spec.DiskURLPath == volumeStatus.DiskURLPath
+}
+
+// getDiskName returns a DNS-safe name for the disk, derived from the name of
+// the disk file without the ".vmdk" extension, or from the VolumeID.
+func getDiskName(disk cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatchDisk) string {
+	name := disk.VolumeID
+	if disk.DiskURLPath != "" {
+		name = disk.DiskURLPath
+		if u, err := url.Parse(disk.DiskURLPath); err == nil {
+			name = u.Path
+		}
+		name = strings.TrimSuffix(path.Base(name), ".vmdk")
+	}
+	name = invalidDiskNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	return strings.Trim(name, "-")
+}
+
+// getDiskURLPathsInFolder returns the URL paths of all the virtual disks
+// present in the datastore folder identified by datastoreFolderPath.
+func getDiskURLPathsInFolder(ctx context.Context, configInfo *commonconfig.ConfigurationInfo,
+	datastoreFolderPath string) ([]string, error) {
+	log := logger.GetLogger(ctx)
+	folderURL, err := url.Parse(datastoreFolderPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DatastoreFolderPath %q. Error: %+v", datastoreFolderPath, err)
+	}
+	folderPath := strings.TrimPrefix(folderURL.Path, "/folder/")
+	dcPath := folderURL.Query().Get("dcPath")
+	dsName := folderURL.Query().Get("dsName")
+	if !strings.HasPrefix(folderURL.Path, "/folder/") || dcPath == "" || dsName == "" {
+		return nil, fmt.Errorf("invalid DatastoreFolderPath %q", datastoreFolderPath)
+	}
+	vc, err := vsphere.GetVirtualCenterInstance(ctx, configInfo, false)
+	if err != nil {
+		log.Errorf("Failed to get virtual center instance with error: %+v", err)
+		return nil, err
+	}
+	err = vc.Connect(ctx)
+	if err != nil {
+		log.Errorf("Failed to connect to VC with error: %+v", err)
+		return nil, err
+	}
+	finder := find.NewFinder(vc.Client.Client, false)
+	dcObj, err := finder.Datacenter(ctx, dcPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find datacenter %q. Error: %+v", dcPath, err)
+	}
+	finder.SetDatacenter(dcObj)
+	dsObj, err := finder.Datastore(ctx, dsName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find datastore %q in datacenter %q. Error: %+v", dsName, dcPath, err)
+	}
+	ds := &vsphere.Datastore{
+		Datastore:  dsObj,
+		Datacenter: &vsphere.Datacenter{Datacenter: dcObj, VirtualCenterHost: vc.Config.Host},
+	}
+	diskPaths, err := ds.GetVirtualDiskPaths(ctx, folderPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list virtual disks in DatastoreFolderPath %q. Error: %+v",
+			datastoreFolderPath, err)
+	}
+	sort.Strings(diskPaths)
+	var diskURLPaths []string
+	for _, diskPath := range diskPaths {
+		diskURL := *folderURL
+		diskURL.Path = path.Join(folderURL.Path, diskPath)
+		diskURLPaths = append(diskURLPaths, diskURL.String())
+	}
+	return diskURLPaths, nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsregistervolumebatch
+
+import (
+	"context"
+	"testing"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cnsregistervolumev1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsregistervolume/v1alpha1"
+	cnsregistervolumebatchv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsregistervolumebatch/v1alpha1"
+)
+
+func TestGetBatchVolumeStatuses(t *testing.T) {
+	disks := []cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatchDisk{
+		{DiskURLPath: "https://vc/folder/legacy/App_Data_1.vmdk?dcPath=dc&dsName=ds"},
+		{VolumeID: "7e3a3f9c-5cb4-4d2c-9b9d-0f1f2e3a4b5c", PvcName: "explicit-pvc"},
+	}
+	statuses, err := getBatchVolumeStatuses("import", "{{.BatchName}}-{{.DiskName}}", disks)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("expected 2 volume statuses, got %d", len(statuses))
+	}
+	if statuses[0].PvcName != "import-app-data-1" {
+		t.Errorf("unexpected generated PVC name %q", statuses[0].PvcName)
+	}
+	if statuses[1].PvcName != "explicit-pvc" {
+		t.Errorf("unexpected PVC name %q", statuses[1].PvcName)
+	}
+	if statuses[0].CnsRegisterVolumeName == statuses[1].CnsRegisterVolumeName {
+		t.Errorf("expected unique CnsRegisterVolume names, got %q", statuses[0].CnsRegisterVolumeName)
+	}
+	// Names must be stable across reconciles.
+	again, err := getBatchVolumeStatuses("import", "{{.BatchName}}-{{.DiskName}}", disks)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again[0].CnsRegisterVolumeName != statuses[0].CnsRegisterVolumeName {
+		t.Errorf("CnsRegisterVolume name is not stable: %q vs %q",
+			again[0].CnsRegisterVolumeName, statuses[0].CnsRegisterVolumeName)
+	}
+
+	// The default template generates names from the index of the disk.
+	statuses, err = getBatchVolumeStatuses("import", "", disks[:1])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if statuses[0].PvcName != "import-0" {
+		t.Errorf("unexpected generated PVC name %q", statuses[0].PvcName)
+	}
+
+	// Duplicate PVC names are rejected.
+	if _, err = getBatchVolumeStatuses("import", "{{.BatchName}}", disks[:1]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	duplicates := []cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatchDisk{
+		{VolumeID: "vol-1"}, {VolumeID: "vol-2"},
+	}
+	if _, err = getBatchVolumeStatuses("import", "{{.BatchName}}", duplicates); err == nil {
+		t.Errorf("expected error for duplicate PVC names")
+	}
+	// Invalid PVC names are rejected.
+	if _, err = getBatchVolumeStatuses("import", "{{.BatchName}}_{{.Index}}", duplicates); err == nil {
+		t.Errorf("expected error for invalid PVC names")
+	}
+}
+
+func TestGetCnsRegisterVolumeName(t *testing.T) {
+	// The FNV-32a hashes of these volume IDs collide.
+	disk1 := cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatchDisk{VolumeID: "costarring"}
+	disk2 := cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatchDisk{VolumeID: "liquid"}
+	if getCnsRegisterVolumeName("import", disk1) == getCnsRegisterVolumeName("import", disk2) {
+		t.Errorf("expected distinct CnsRegisterVolume names for distinct disks")
+	}
+
+	volumeStatus := cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatchVolumeStatus{VolumeID: "liquid"}
+	registerVolume := &cnsregistervolumev1alpha1.CnsRegisterVolume{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{batchNameLabel: "import"}},
+		Spec:       cnsregistervolumev1alpha1.CnsRegisterVolumeSpec{VolumeID: "liquid"},
+	}
+	if !isCnsRegisterVolumeForBatchVolume(registerVolume, "import", volumeStatus) {
+		t.Errorf("expected CnsRegisterVolume to register the disk of the batch")
+	}
+	if isCnsRegisterVolumeForBatchVolume(registerVolume, "other", volumeStatus) {
+		t.Errorf("expected CnsRegisterVolume not to be created by another batch")
+	}
+	registerVolume.Spec.VolumeID = "costarring"
+	if isCnsRegisterVolumeForBatchVolume(registerVolume, "import", volumeStatus) {
+		t.Errorf("expected CnsRegisterVolume not to register the disk of the batch")
+	}
+}
+
+func TestValidateCnsRegisterVolumeBatchSpecAccessMode(t *testing.T) {
+	instance := &cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatch{
+		ObjectMeta: metav1.ObjectMeta{Name: "import"},
+		Spec: cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatchSpec{
+			Disks:      []cnsregistervolumebatchv1alpha1.CnsRegisterVolumeBatchDisk{{VolumeID: "vol-1"}},
+			AccessMode: v1.ReadWriteMany,
+		},
+	}
+	if err := validateCnsRegisterVolumeBatchSpec(context.Background(), cnstypes.CnsClusterFlavorWorkload,
+		instance); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
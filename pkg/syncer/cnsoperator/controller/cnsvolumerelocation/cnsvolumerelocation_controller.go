@@ -0,0 +1,516 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsvolumerelocation
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	"github.com/vmware/govmomi/vim25/soap"
+	vim25types "github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	apis "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
+	cnsvolumerelocationv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsvolumerelocation/v1alpha1"
+	volumes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	commonconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/cnsvolumeinfo"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
+	cnsoperatorutil "sigs.k8s.io/vsphere-csi-driver/v2/pkg/syncer/cnsoperator/util"
+)
+
+const (
+	defaultMaxWorkerThreadsForVolumeRelocation = 4
+	// relocationProgressCheckInterval is the interval after which the
+	// progress of the CNS task relocating a volume is checked.
+	relocationProgressCheckInterval = 10 * time.Second
+)
+
+// backOffDuration is a map of cnsvolumerelocation name's to the time after
+// which a request for this instance will be requeued.
+// Initialized to 1 second for new instances and for instances whose latest
+// reconcile operation succeeded.
+// If the reconcile fails, backoff is incremented exponentially.
+var (
+	backOffDuration         map[string]time.Duration
+	backOffDurationMapMutex = sync.Mutex{}
+)
+
+// Add creates a new CnsVolumeRelocation Controller and adds it to the Manager.
+// The Manager will set fields on the Controller and Start it when the Manager
+// is Started.
+func Add(mgr manager.Manager, clusterFlavor cnstypes.CnsClusterFlavor,
+	configInfo *commonconfig.ConfigurationInfo, volumeManager volumes.Manager) error {
+	ctx, log := logger.GetNewContextWithLogger()
+	if clusterFlavor != cnstypes.CnsClusterFlavorVanilla {
+		log.Debug("Not initializing the CnsVolumeRelocation Controller as its not a vanilla cluster")
+		return nil
+	}
+	if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.VolumeRelocation) {
+		log.Debugf("Not initializing the CnsVolumeRelocation Controller as %q feature is disabled",
+			common.VolumeRelocation)
+		return nil
+	}
+	var volumeInfoService cnsvolumeinfo.VolumeInfoService
+	if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.MultiVCenterCSITopology) &&
+		len(configInfo.Cfg.VirtualCenter) > 1 {
+		var err error
+		volumeInfoService, err = cnsvolumeinfo.InitVolumeInfoService(ctx)
+		if err != nil {
+			log.Errorf("Failed to initialize volumeInfo service. Err: %v", err)
+			return err
+		}
+	}
+	// Initializes kubernetes client.
+	k8sclient, err := k8s.NewClient(ctx)
+	if err != nil {
+		log.Errorf("Creating Kubernetes client failed. Err: %v", err)
+		return err
+	}
+
+	// eventBroadcaster broadcasts events on cnsvolumerelocation instances to
+	// the event sink.
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(
+		&typedcorev1.EventSinkImpl{
+			Interface: k8sclient.CoreV1().Events(""),
+		},
+	)
+	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: apis.GroupName})
+	return add(mgr, newReconciler(mgr, configInfo, volumeManager, k8sclient, volumeInfoService, recorder))
+}
+
+// newReconciler returns a new reconcile.Reconciler.
+func newReconciler(mgr manager.Manager, configInfo *commonconfig.ConfigurationInfo, volumeManager volumes.Manager,
+	k8sclient clientset.Interface, volumeInfoService cnsvolumeinfo.VolumeInfoService,
+	recorder record.EventRecorder) reconcile.Reconciler {
+	return &ReconcileCnsVolumeRelocation{client: mgr.GetClient(), scheme: mgr.GetScheme(),
+		configInfo: configInfo, volumeManager: volumeManager, k8sclient: k8sclient,
+		volumeInfoService: volumeInfoService, recorder: recorder}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler.
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	_, log := logger.GetNewContextWithLogger()
+
+	// Create a new controller.
+	c, err := controller.New("cnsvolumerelocation-controller", mgr,
+		controller.Options{Reconciler: r, MaxConcurrentReconciles: defaultMaxWorkerThreadsForVolumeRelocation})
+	if err != nil {
+		log.Errorf("Failed to create new CnsVolumeRelocation controller with error: %+v", err)
+		return err
+	}
+
+	backOffDuration = make(map[string]time.Duration)
+
+	// Watch for changes to primary resource CnsVolumeRelocation.
+	err = c.Watch(&source.Kind{Type: &cnsvolumerelocationv1alpha1.CnsVolumeRelocation{}},
+		&handler.EnqueueRequestForObject{})
+	if err != nil {
+		log.Errorf("Failed to watch for changes to CnsVolumeRelocation resource with error: %+v", err)
+		return err
+	}
+	return nil
+}
+
+// blank assignment to verify that ReconcileCnsVolumeRelocation implements
+// reconcile.Reconciler.
+var _ reconcile.Reconciler = &ReconcileCnsVolumeRelocation{}
+
+// ReconcileCnsVolumeRelocation reconciles a CnsVolumeRelocation object.
+type ReconcileCnsVolumeRelocation struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver.
+	client        client.Client
+	scheme        *runtime.Scheme
+	configInfo    *commonconfig.ConfigurationInfo
+	volumeManager volumes.Manager
+	k8sclient     clientset.Interface
+	// volumeInfoService is set only in multi vCenter deployments.
+	volumeInfoService cnsvolumeinfo.VolumeInfoService
+	recorder          record.EventRecorder
+}
+
+// Reconcile reads that state of the cluster for a CnsVolumeRelocation object
+// and makes changes based on the state read and what is in the
+// CnsVolumeRelocation.Spec.
+// The volume is relocated to the target datastore using CNS and the node
+// affinity of the PersistentVolume is updated to the nodes which have access
+// to the target datastore. The phase of the relocation and the progress of
+// the CNS task are persisted in the status of the instance, so that retries
+// resume from the failed phase.
+// Note:
+// The Controller will requeue the Request to be processed again if the
+// returned error is non-nil or Result.Requeue is true. Otherwise, upon
+// completion it will remove the work from the queue.
+func (r *ReconcileCnsVolumeRelocation) Reconcile(ctx context.Context,
+	request reconcile.Request) (reconcile.Result, error) {
+	log := logger.GetLogger(ctx)
+	// Fetch the CnsVolumeRelocation instance.
+	instance := &cnsvolumerelocationv1alpha1.CnsVolumeRelocation{}
+	err := r.client.Get(ctx, request.NamespacedName, instance)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Infof("CnsVolumeRelocation resource not found. Ignoring since object must be deleted.")
+			return reconcile.Result{}, nil
+		}
+		log.Errorf("Error reading the CnsVolumeRelocation with name: %q. Err: %+v", request.Name, err)
+		// Error reading the object - return with err.
+		return reconcile.Result{}, err
+	}
+	// Initialize backOffDuration for the instance, if required.
+	backOffDurationMapMutex.Lock()
+	var timeout time.Duration
+	if _, exists := backOffDuration[instance.Name]; !exists {
+		backOffDuration[instance.Name] = time.Second
+	}
+	timeout = backOffDuration[instance.Name]
+	backOffDurationMapMutex.Unlock()
+
+	// If the volume is already relocated, remove the instance from the queue.
+	if instance.Status.Phase == cnsvolumerelocationv1alpha1.RelocationPhaseCompleted {
+		backOffDurationMapMutex.Lock()
+		delete(backOffDuration, instance.Name)
+		backOffDurationMapMutex.Unlock()
+		return reconcile.Result{}, nil
+	}
+
+	log.Infof("Reconciling CnsVolumeRelocation with instance: %q in phase: %q. timeout %q seconds",
+		instance.Name, instance.Status.Phase, timeout)
+	for instance.Status.Phase != cnsvolumerelocationv1alpha1.RelocationPhaseCompleted {
+		var nextPhase cnsvolumerelocationv1alpha1.CnsVolumeRelocationPhase
+		done := true
+		switch instance.Status.Phase {
+		case cnsvolumerelocationv1alpha1.RelocationPhasePending:
+			err = r.validateRelocation(ctx, instance)
+			nextPhase = cnsvolumerelocationv1alpha1.RelocationPhaseRelocating
+		case cnsvolumerelocationv1alpha1.RelocationPhaseRelocating:
+			done, err = r.relocateVolume(ctx, instance)
+			nextPhase = cnsvolumerelocationv1alpha1.RelocationPhaseUpdatingPersistentVolume
+		case cnsvolumerelocationv1alpha1.RelocationPhaseUpdatingPersistentVolume:
+			err = r.updatePersistentVolume(ctx, instance)
+			nextPhase = cnsvolumerelocationv1alpha1.RelocationPhaseCompleted
+		default:
+			err = fmt.Errorf("unknown phase %q", instance.Status.Phase)
+		}
+		if err != nil {
+			setInstanceError(ctx, r, instance, err.Error())
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		instance.Status.Error = ""
+		if !done {
+			// Persist the progress of the relocation and check it again
+			// later.
+			err = updateCnsVolumeRelocation(ctx, r.client, instance)
+			if err != nil {
+				return reconcile.Result{RequeueAfter: timeout}, nil
+			}
+			backOffDurationMapMutex.Lock()
+			backOffDuration[instance.Name] = time.Second
+			backOffDurationMapMutex.Unlock()
+			log.Infof("CnsVolumeRelocation: %q is relocating volume: %q. Progress: %d%%",
+				instance.Name, instance.Status.VolumeID, instance.Status.Progress)
+			return reconcile.Result{RequeueAfter: relocationProgressCheckInterval}, nil
+		}
+		instance.Status.Phase = nextPhase
+		err = updateCnsVolumeRelocation(ctx, r.client, instance)
+		if err != nil {
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		log.Infof("CnsVolumeRelocation: %q moved to phase: %q", instance.Name, nextPhase)
+	}
+
+	msg := fmt.Sprintf("Successfully relocated volume: %s of PersistentVolume: %s from datastore: %s to "+
+		"datastore: %s", instance.Status.VolumeID, instance.Spec.PersistentVolumeName,
+		instance.Status.SourceDatastoreURL, instance.Spec.TargetDatastoreURL)
+	r.recorder.Event(instance, v1.EventTypeNormal, "CnsVolumeRelocationSucceeded", msg)
+	backOffDurationMapMutex.Lock()
+	delete(backOffDuration, instance.Name)
+	backOffDurationMapMutex.Unlock()
+	log.Info(msg)
+	return reconcile.Result{}, nil
+}
+
+// validateRelocation validates the CnsVolumeRelocation instance, the
+// PersistentVolume to be relocated and the accessibility of the target
+// datastore from the nodes the volume is attached to. The volume ID, the
+// vCenter and the current datastore of the volume are recorded in the status
+// of the instance.
+func (r *ReconcileCnsVolumeRelocation) validateRelocation(ctx context.Context,
+	instance *cnsvolumerelocationv1alpha1.CnsVolumeRelocation) error {
+	err := validateCnsVolumeRelocationSpec(ctx, instance)
+	if err != nil {
+		return err
+	}
+	pv, err := r.k8sclient.CoreV1().PersistentVolumes().Get(ctx, instance.Spec.PersistentVolumeName,
+		metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get PersistentVolume %q. Error: %+v", instance.Spec.PersistentVolumeName, err)
+	}
+	err = cnsoperatorutil.ValidatePersistentVolumeForRelocation(pv)
+	if err != nil {
+		return err
+	}
+	volumeID := pv.Spec.CSI.VolumeHandle
+	vCenter, err := r.getVCenterForVolume(ctx, volumeID)
+	if err != nil {
+		return err
+	}
+	vc, volumeManager, err := r.getVirtualCenterAndVolumeManager(ctx, vCenter)
+	if err != nil {
+		return err
+	}
+	querySelection := cnstypes.CnsQuerySelection{
+		Names: []string{string(cnstypes.QuerySelectionNameTypeDataStoreUrl)},
+	}
+	volume, err := common.QueryVolumeByID(ctx, volumeManager, volumeID, &querySelection)
+	if err != nil {
+		return fmt.Errorf("failed to query volume %q. Error: %+v", volumeID, err)
+	}
+	if volume.DatastoreUrl == instance.Spec.TargetDatastoreURL {
+		return fmt.Errorf("volume %q is already present on datastore %q", volumeID, volume.DatastoreUrl)
+	}
+	_, err = common.GetDatastoreInfoObj(ctx, vc, instance.Spec.TargetDatastoreURL)
+	if err != nil {
+		return fmt.Errorf("datastore %q is not found on vCenter %q", instance.Spec.TargetDatastoreURL, vCenter)
+	}
+	if instance.Spec.TargetStoragePolicyName != "" {
+		_, err = vc.GetStoragePolicyIDByName(ctx, instance.Spec.TargetStoragePolicyName)
+		if err != nil {
+			return fmt.Errorf("failed to find storage policy %q. Error: %+v",
+				instance.Spec.TargetStoragePolicyName, err)
+		}
+	}
+	nodeNames, err := r.getAttachedNodeNames(ctx, pv.Name)
+	if err != nil {
+		return err
+	}
+	err = validateDatastoreAccessibleFromNodes(ctx, vc, instance.Spec.TargetDatastoreURL, nodeNames)
+	if err != nil {
+		return err
+	}
+	nodeAffinity, err := cnsoperatorutil.GetVolumeNodeAffinityForDatastore(ctx, r.configInfo.Cfg, vc,
+		instance.Spec.TargetDatastoreURL)
+	if err != nil {
+		return err
+	}
+	if !isNodeAffinityChangeAllowed(pv, nodeAffinity, len(nodeNames) != 0) {
+		return fmt.Errorf("relocating the volume of PersistentVolume %q changes its node affinity, "+
+			"which requires the volume to be detached from the nodes %v", pv.Name, nodeNames)
+	}
+	instance.Status.VolumeID = volumeID
+	instance.Status.VCenter = vCenter
+	instance.Status.SourceDatastoreURL = volume.DatastoreUrl
+	return nil
+}
+
+// relocateVolume relocates the volume to the target datastore. The CNS
+// relocate task is started and its progress is recorded in the status of the
+// instance on subsequent calls. It returns true once the volume is relocated.
+func (r *ReconcileCnsVolumeRelocation) relocateVolume(ctx context.Context,
+	instance *cnsvolumerelocationv1alpha1.CnsVolumeRelocation) (bool, error) {
+	log := logger.GetLogger(ctx)
+	vc, volumeManager, err := r.getVirtualCenterAndVolumeManager(ctx, instance.Status.VCenter)
+	if err != nil {
+		return false, err
+	}
+	if instance.Status.TaskID != "" {
+		taskInfo, err := getRelocateTaskInfo(ctx, vc, instance.Status.TaskID)
+		if err != nil {
+			// The task may be purged from vCenter. Check the volume and
+			// relocate it again, if required.
+			log.Warnf("Failed to get info of relocate task %q. Error: %+v", instance.Status.TaskID, err)
+			instance.Status.TaskID = ""
+		} else if taskInfo.State == vim25types.TaskInfoStateQueued ||
+			taskInfo.State == vim25types.TaskInfoStateRunning {
+			instance.Status.Progress = taskInfo.Progress
+			return false, nil
+		} else {
+			instance.Status.TaskID = ""
+			err = getRelocateTaskError(ctx, taskInfo)
+			if err != nil {
+				return false, err
+			}
+			instance.Status.Progress = 100
+			return true, nil
+		}
+	}
+
+	querySelection := cnstypes.CnsQuerySelection{
+		Names: []string{string(cnstypes.QuerySelectionNameTypeDataStoreUrl)},
+	}
+	volume, err := common.QueryVolumeByID(ctx, volumeManager, instance.Status.VolumeID, &querySelection)
+	if err != nil {
+		return false, fmt.Errorf("failed to query volume %q. Error: %+v", instance.Status.VolumeID, err)
+	}
+	if volume.DatastoreUrl == instance.Spec.TargetDatastoreURL {
+		instance.Status.Progress = 100
+		return true, nil
+	}
+	dsInfo, err := common.GetDatastoreInfoObj(ctx, vc, instance.Spec.TargetDatastoreURL)
+	if err != nil {
+		return false, err
+	}
+	var storagePolicyID string
+	if instance.Spec.TargetStoragePolicyName != "" {
+		storagePolicyID, err = vc.GetStoragePolicyIDByName(ctx, instance.Spec.TargetStoragePolicyName)
+		if err != nil {
+			return false, fmt.Errorf("failed to find storage policy %q. Error: %+v",
+				instance.Spec.TargetStoragePolicyName, err)
+		}
+	}
+	task, err := volumeManager.RelocateVolume(ctx,
+		getRelocateSpec(instance.Status.VolumeID, dsInfo.Reference(), storagePolicyID))
+	if err != nil {
+		if soap.IsSoapFault(err) {
+			if _, ok := soap.ToSoapFault(err).VimFault().(vim25types.AlreadyExists); ok {
+				// Volume is already present on the target datastore.
+				instance.Status.Progress = 100
+				return true, nil
+			}
+		}
+		return false, fmt.Errorf("failed to relocate volume %q to datastore %q. Error: %+v",
+			instance.Status.VolumeID, instance.Spec.TargetDatastoreURL, err)
+	}
+	log.Infof("Started relocate task %q for volume %q to datastore %q", task.Reference().Value,
+		instance.Status.VolumeID, instance.Spec.TargetDatastoreURL)
+	instance.Status.TaskID = task.Reference().Value
+	instance.Status.Progress = 0
+	return false, nil
+}
+
+// updatePersistentVolume updates the node affinity of the PersistentVolume
+// to the nodes which have access to the target datastore.
+func (r *ReconcileCnsVolumeRelocation) updatePersistentVolume(ctx context.Context,
+	instance *cnsvolumerelocationv1alpha1.CnsVolumeRelocation) error {
+	vc, _, err := r.getVirtualCenterAndVolumeManager(ctx, instance.Status.VCenter)
+	if err != nil {
+		return err
+	}
+	nodeAffinity, err := cnsoperatorutil.GetVolumeNodeAffinityForDatastore(ctx, r.configInfo.Cfg, vc,
+		instance.Spec.TargetDatastoreURL)
+	if err != nil {
+		return err
+	}
+	pvName := instance.Spec.PersistentVolumeName
+	if instance.Status.SavedPersistentVolume == "" {
+		// The volume may be attached to a node after the validation.
+		pv, err := r.k8sclient.CoreV1().PersistentVolumes().Get(ctx, pvName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get PersistentVolume %q. Error: %+v", pvName, err)
+		}
+		nodeNames, err := r.getAttachedNodeNames(ctx, pvName)
+		if err != nil {
+			return err
+		}
+		if !isNodeAffinityChangeAllowed(pv, nodeAffinity, len(nodeNames) != 0) {
+			return fmt.Errorf("volume of PersistentVolume %q is relocated, but updating its node affinity "+
+				"requires the volume to be detached from the nodes %v", pvName, nodeNames)
+		}
+	}
+	return cnsoperatorutil.UpdatePersistentVolumeNodeAffinity(ctx, r.k8sclient, pvName, nodeAffinity,
+		instance.Status.SavedPersistentVolume, func(savedPV string) error {
+			instance.Status.SavedPersistentVolume = savedPV
+			return updateCnsVolumeRelocation(ctx, r.client, instance)
+		}, instance.Name)
+}
+
+// getAttachedNodeNames returns the names of the nodes the given
+// PersistentVolume is attached to.
+func (r *ReconcileCnsVolumeRelocation) getAttachedNodeNames(ctx context.Context, pvName string) ([]string, error) {
+	volumeAttachments, err := r.k8sclient.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VolumeAttachments. Error: %+v", err)
+	}
+	return getAttachedNodeNames(volumeAttachments.Items, pvName), nil
+}
+
+// getVCenterForVolume returns the vCenter host the given volume is registered
+// on.
+func (r *ReconcileCnsVolumeRelocation) getVCenterForVolume(ctx context.Context, volumeID string) (string, error) {
+	if r.volumeInfoService != nil {
+		return r.volumeInfoService.GetvCenterForVolumeID(ctx, volumeID)
+	}
+	vc, err := cnsvsphere.GetVirtualCenterInstance(ctx, r.configInfo, false)
+	if err != nil {
+		return "", err
+	}
+	return vc.Config.Host, nil
+}
+
+// getVirtualCenterAndVolumeManager returns the VirtualCenter instance and the
+// volume manager for the given vCenter host.
+func (r *ReconcileCnsVolumeRelocation) getVirtualCenterAndVolumeManager(ctx context.Context,
+	host string) (*cnsvsphere.VirtualCenter, volumes.Manager, error) {
+	if r.volumeInfoService != nil {
+		return cnsoperatorutil.GetVirtualCenterAndVolumeManager(ctx, r.configInfo.Cfg, host)
+	}
+	vc, err := cnsvsphere.GetVirtualCenterInstance(ctx, r.configInfo, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	return vc, r.volumeManager, nil
+}
+
+// setInstanceError sets error and records an event on the CnsVolumeRelocation
+// instance.
+func setInstanceError(ctx context.Context, r *ReconcileCnsVolumeRelocation,
+	instance *cnsvolumerelocationv1alpha1.CnsVolumeRelocation, errMsg string) {
+	log := logger.GetLogger(ctx)
+	log.Error(errMsg)
+	instance.Status.Error = errMsg
+	err := updateCnsVolumeRelocation(ctx, r.client, instance)
+	if err != nil {
+		log.Errorf("updateCnsVolumeRelocation failed. err: %v", err)
+	}
+	// Double backOff duration.
+	backOffDurationMapMutex.Lock()
+	backOffDuration[instance.Name] = backOffDuration[instance.Name] * 2
+	r.recorder.Event(instance, v1.EventTypeWarning, "CnsVolumeRelocationFailed", errMsg)
+	backOffDurationMapMutex.Unlock()
+}
+
+// updateCnsVolumeRelocation updates the CnsVolumeRelocation instance in K8S.
+func updateCnsVolumeRelocation(ctx context.Context, client client.Client,
+	instance *cnsvolumerelocationv1alpha1.CnsVolumeRelocation) error {
+	log := logger.GetLogger(ctx)
+	err := client.Update(ctx, instance)
+	if err != nil {
+		log.Errorf("Failed to update CnsVolumeRelocation instance: %q. Error: %+v", instance.Name, err)
+	}
+	return err
+}
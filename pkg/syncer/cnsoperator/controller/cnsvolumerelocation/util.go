@@ -0,0 +1,157 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsvolumerelocation
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/vmware/govmomi/cns"
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	vim25types "github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+
+	cnsvolumerelocationv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsvolumerelocation/v1alpha1"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/node"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+// validateCnsVolumeRelocationSpec validates the input params of
+// CnsVolumeRelocation instance.
+func validateCnsVolumeRelocationSpec(ctx context.Context,
+	instance *cnsvolumerelocationv1alpha1.CnsVolumeRelocation) error {
+	var msg string
+	spec := instance.Spec
+	if spec.PersistentVolumeName == "" {
+		msg = "PersistentVolumeName must be specified"
+	} else if spec.TargetDatastoreURL == "" {
+		msg = "TargetDatastoreURL must be specified"
+	}
+	if msg != "" {
+		return logger.LogNewError(logger.GetLogger(ctx), msg)
+	}
+	return nil
+}
+
+// getAttachedNodeNames returns the names of the nodes the given
+// PersistentVolume is attached to, according to the given VolumeAttachments.
+func getAttachedNodeNames(volumeAttachments []storagev1.VolumeAttachment, pvName string) []string {
+	var nodeNames []string
+	for _, volumeAttachment := range volumeAttachments {
+		if volumeAttachment.Spec.Source.PersistentVolumeName != nil &&
+			*volumeAttachment.Spec.Source.PersistentVolumeName == pvName {
+			nodeNames = append(nodeNames, volumeAttachment.Spec.NodeName)
+		}
+	}
+	return nodeNames
+}
+
+// validateDatastoreAccessibleFromNodes verifies that the datastore with the
+// given URL is accessible from all the given nodes.
+func validateDatastoreAccessibleFromNodes(ctx context.Context, vc *vsphere.VirtualCenter, datastoreURL string,
+	nodeNames []string) error {
+	if len(nodeNames) == 0 {
+		return nil
+	}
+	nodeMgr := node.GetManager(ctx)
+	var nodeVMs []*vsphere.VirtualMachine
+	for _, nodeName := range nodeNames {
+		nodeVM, err := nodeMgr.GetNodeByName(ctx, nodeName)
+		if err != nil {
+			return fmt.Errorf("failed to get node VM of node %q. Error: %+v", nodeName, err)
+		}
+		nodeVMs = append(nodeVMs, nodeVM)
+	}
+	accessibleNodes, err := common.GetNodeVMsWithAccessToDatastore(ctx, vc, datastoreURL, nodeVMs)
+	if err != nil {
+		return err
+	}
+	if len(accessibleNodes) != len(nodeVMs) {
+		return fmt.Errorf("datastore %q is not accessible from all the nodes %v the volume is attached to",
+			datastoreURL, nodeNames)
+	}
+	return nil
+}
+
+// isNodeAffinityChangeAllowed returns true if the node affinity of the given
+// PersistentVolume can be changed to the given node affinity. Changing the
+// node affinity of a PersistentVolume requires it to be recreated, which is
+// not allowed while the volume is attached to a node.
+func isNodeAffinityChangeAllowed(pv *v1.PersistentVolume, nodeAffinity *v1.VolumeNodeAffinity,
+	attached bool) bool {
+	if !attached || pv.Spec.NodeAffinity == nil {
+		return true
+	}
+	return reflect.DeepEqual(pv.Spec.NodeAffinity, nodeAffinity)
+}
+
+// getRelocateSpec returns the CNS relocate spec to relocate the given volume
+// to the given datastore, with the given storage policy, if any.
+func getRelocateSpec(volumeID string, datastore vim25types.ManagedObjectReference,
+	storagePolicyID string) cnstypes.CnsBlockVolumeRelocateSpec {
+	if storagePolicyID == "" {
+		return cnstypes.NewCnsBlockVolumeRelocateSpec(volumeID, datastore)
+	}
+	return cnstypes.NewCnsBlockVolumeRelocateSpec(volumeID, datastore,
+		&vim25types.VirtualMachineDefinedProfileSpec{ProfileId: storagePolicyID})
+}
+
+// getRelocateTaskInfo returns the current info of the CNS task with the given
+// ID, without waiting for the task to complete.
+func getRelocateTaskInfo(ctx context.Context, vc *vsphere.VirtualCenter,
+	taskID string) (*vim25types.TaskInfo, error) {
+	task := object.NewTask(vc.Client.Client, vim25types.ManagedObjectReference{
+		Type:  "Task",
+		Value: taskID,
+	})
+	var taskMo mo.Task
+	err := task.Properties(ctx, task.Reference(), []string{"info"}, &taskMo)
+	if err != nil {
+		return nil, err
+	}
+	return &taskMo.Info, nil
+}
+
+// getRelocateTaskError returns the error of the given completed CNS relocate
+// task, if any.
+func getRelocateTaskError(ctx context.Context, taskInfo *vim25types.TaskInfo) error {
+	if taskInfo.State == vim25types.TaskInfoStateError {
+		if taskInfo.Error != nil {
+			return fmt.Errorf("relocate task %q failed. Error: %s", taskInfo.Task.Value,
+				taskInfo.Error.LocalizedMessage)
+		}
+		return fmt.Errorf("relocate task %q failed", taskInfo.Task.Value)
+	}
+	taskResult, err := cns.GetTaskResult(ctx, taskInfo)
+	if err != nil {
+		return fmt.Errorf("failed to get result of relocate task %q. Error: %+v", taskInfo.Task.Value, err)
+	}
+	if fault := taskResult.GetCnsVolumeOperationResult().Fault; fault != nil {
+		if _, ok := fault.Fault.(*vim25types.AlreadyExists); ok {
+			// Volume is already present on the target datastore.
+			return nil
+		}
+		return fmt.Errorf("relocate task %q failed. Fault: %s", taskInfo.Task.Value, fault.LocalizedMessage)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsvolumerelocation
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+)
+
+func TestGetAttachedNodeNames(t *testing.T) {
+	pvName := "pv-1"
+	otherPVName := "pv-2"
+	volumeAttachments := []storagev1.VolumeAttachment{
+		{Spec: storagev1.VolumeAttachmentSpec{NodeName: "node-1",
+			Source: storagev1.VolumeAttachmentSource{PersistentVolumeName: &pvName}}},
+		{Spec: storagev1.VolumeAttachmentSpec{NodeName: "node-2",
+			Source: storagev1.VolumeAttachmentSource{PersistentVolumeName: &otherPVName}}},
+		{Spec: storagev1.VolumeAttachmentSpec{NodeName: "node-3"}},
+	}
+	nodeNames := getAttachedNodeNames(volumeAttachments, pvName)
+	if !reflect.DeepEqual(nodeNames, []string{"node-1"}) {
+		t.Errorf("unexpected attached nodes: %v", nodeNames)
+	}
+	if nodeNames = getAttachedNodeNames(volumeAttachments, "pv-3"); len(nodeNames) != 0 {
+		t.Errorf("unexpected attached nodes: %v", nodeNames)
+	}
+}
+
+func TestIsNodeAffinityChangeAllowed(t *testing.T) {
+	getNodeAffinity := func(zone string) *v1.VolumeNodeAffinity {
+		return &v1.VolumeNodeAffinity{
+			Required: &v1.NodeSelector{
+				NodeSelectorTerms: []v1.NodeSelectorTerm{{
+					MatchExpressions: []v1.NodeSelectorRequirement{{
+						Key:      v1.LabelTopologyZone,
+						Operator: v1.NodeSelectorOpIn,
+						Values:   []string{zone},
+					}},
+				}},
+			},
+		}
+	}
+	pv := &v1.PersistentVolume{Spec: v1.PersistentVolumeSpec{NodeAffinity: getNodeAffinity("zone-a")}}
+	if !isNodeAffinityChangeAllowed(pv, getNodeAffinity("zone-b"), false) {
+		t.Errorf("expected node affinity change to be allowed for detached volume")
+	}
+	if isNodeAffinityChangeAllowed(pv, getNodeAffinity("zone-b"), true) {
+		t.Errorf("expected node affinity change to be rejected for attached volume")
+	}
+	if !isNodeAffinityChangeAllowed(pv, getNodeAffinity("zone-a"), true) {
+		t.Errorf("expected unchanged node affinity to be allowed for attached volume")
+	}
+	pv.Spec.NodeAffinity = nil
+	if !isNodeAffinityChangeAllowed(pv, getNodeAffinity("zone-b"), true) {
+		t.Errorf("expected node affinity to be allowed to be set for attached volume")
+	}
+}
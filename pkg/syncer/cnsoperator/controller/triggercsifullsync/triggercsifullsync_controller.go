@@ -245,12 +245,23 @@ func (r *ReconcileTriggerCsiFullSync) Reconcile(ctx context.Context,
 	if r.clusterFlavor == cnstypes.CnsClusterFlavorGuest {
 		fullSyncErr = syncer.PvcsiFullSync(ctx, syncer.MetadataSyncer)
 	} else {
-		fullSyncErr = syncer.CsiFullSync(ctx, syncer.MetadataSyncer)
+		// Resume from the shard checkpointed by the previous full sync and
+		// process all the shards, as each full sync processes one shard.
+		_, shardCount := syncer.GetFullSyncShard(ctx)
+		if shardCount == instance.Status.ShardCount {
+			syncer.SetFullSyncNextShard(instance.Status.NextShard)
+		}
+		for i := 0; i < shardCount && fullSyncErr == nil; i++ {
+			fullSyncErr = syncer.CsiFullSync(ctx, syncer.MetadataSyncer)
+		}
 	}
 	err = r.client.Get(ctx, request.NamespacedName, instance)
 	if err != nil {
 		return reconcile.Result{}, nil
 	}
+	if r.clusterFlavor != cnstypes.CnsClusterFlavorGuest {
+		instance.Status.NextShard, instance.Status.ShardCount = syncer.GetFullSyncShard(ctx)
+	}
 	if fullSyncErr != nil {
 		msg := fmt.Sprintf("Full sync failed for triggerSyncID: %d with error: %+v", triggerSyncID, fullSyncErr)
 		log.Error(msg)
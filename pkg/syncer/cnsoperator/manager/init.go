@@ -27,6 +27,7 @@ import (
 	cnstypes "github.com/vmware/govmomi/cns/types"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
@@ -135,6 +136,14 @@ func InitCnsOperator(ctx context.Context, clusterFlavor cnstypes.CnsClusterFlavo
 				log.Errorf("Failed to create %q CRD. Err: %+v", cnsoperatorv1alpha1.CnsRegisterVolumePlural, err)
 				return err
 			}
+			// Create CnsRegisterVolumeBatch CRD from manifest.
+			err = k8s.CreateCustomResourceDefinitionFromManifest(ctx,
+				cnsoperatorconfig.EmbedCnsRegisterVolumeBatchCRFile,
+				cnsoperatorconfig.EmbedCnsRegisterVolumeBatchCRFileName)
+			if err != nil {
+				log.Errorf("Failed to create %q CRD. Err: %+v", cnsoperatorv1alpha1.CnsRegisterVolumeBatchPlural, err)
+				return err
+			}
 		}
 
 		if !stretchedSupervisor {
@@ -161,24 +170,10 @@ func InitCnsOperator(ctx context.Context, clusterFlavor cnstypes.CnsClusterFlavo
 
 		if !stretchedSupervisor {
 			// Clean up routine to cleanup successful CnsRegisterVolume instances.
-			err = watcher(ctx, cnsOperator)
+			err = startCnsRegisterVolumeCleanup(ctx, cnsOperator, restConfig)
 			if err != nil {
-				log.Error("Failed to watch on config file for changes to CnsRegisterVolumesCleanupIntervalInMin. Error: %+v",
-					err)
 				return err
 			}
-			go func() {
-				for {
-					ctx, log = logger.GetNewContextWithLogger()
-					log.Infof("Triggering CnsRegisterVolume cleanup routine")
-					cleanUpCnsRegisterVolumeInstances(ctx, restConfig,
-						cnsOperator.configInfo.Cfg.Global.CnsRegisterVolumesCleanupIntervalInMin)
-					log.Infof("Completed CnsRegisterVolume cleanup")
-					for i := 1; i <= cnsOperator.configInfo.Cfg.Global.CnsRegisterVolumesCleanupIntervalInMin; i++ {
-						time.Sleep(time.Duration(1 * time.Minute))
-					}
-				}
-			}()
 		}
 	} else if clusterFlavor == cnstypes.CnsClusterFlavorVanilla {
 		// Create CSINodeTopology CRD.
@@ -188,6 +183,49 @@ func InitCnsOperator(ctx context.Context, clusterFlavor cnstypes.CnsClusterFlavo
 			log.Errorf("Failed to create %q CRD. Error: %+v", csinodetopology.CRDSingular, err)
 			return err
 		}
+		if cnsOperator.coCommonInterface.IsFSSEnabled(ctx, common.VanillaRegisterVolume) {
+			// Create CnsRegisterVolume CRD from manifest.
+			err = k8s.CreateCustomResourceDefinitionFromManifest(ctx, cnsoperatorconfig.EmbedCnsRegisterVolumeCRFile,
+				cnsoperatorconfig.EmbedCnsRegisterVolumeCRFileName)
+			if err != nil {
+				log.Errorf("Failed to create %q CRD. Err: %+v", cnsoperatorv1alpha1.CnsRegisterVolumePlural, err)
+				return err
+			}
+			// Create CnsRegisterVolumeBatch CRD from manifest.
+			err = k8s.CreateCustomResourceDefinitionFromManifest(ctx,
+				cnsoperatorconfig.EmbedCnsRegisterVolumeBatchCRFile,
+				cnsoperatorconfig.EmbedCnsRegisterVolumeBatchCRFileName)
+			if err != nil {
+				log.Errorf("Failed to create %q CRD. Err: %+v", cnsoperatorv1alpha1.CnsRegisterVolumeBatchPlural, err)
+				return err
+			}
+			// Clean up routine to cleanup successful CnsRegisterVolume instances.
+			err = startCnsRegisterVolumeCleanup(ctx, cnsOperator, restConfig)
+			if err != nil {
+				return err
+			}
+		}
+		if cnsOperator.coCommonInterface.IsFSSEnabled(ctx, common.MultiVCenterCSITopology) &&
+			len(cnsOperator.configInfo.Cfg.VirtualCenter) > 1 {
+			// Create CnsCrossVCenterVolumeRelocation CRD from manifest.
+			err = k8s.CreateCustomResourceDefinitionFromManifest(ctx,
+				cnsoperatorconfig.EmbedCnsCrossVCenterVolumeRelocationCRFile,
+				cnsoperatorconfig.EmbedCnsCrossVCenterVolumeRelocationCRFileName)
+			if err != nil {
+				log.Errorf("Failed to create %q CRD. Err: %+v",
+					cnsoperatorv1alpha1.CnsCrossVCenterVolumeRelocationPlural, err)
+				return err
+			}
+		}
+		if cnsOperator.coCommonInterface.IsFSSEnabled(ctx, common.VolumeRelocation) {
+			// Create CnsVolumeRelocation CRD from manifest.
+			err = k8s.CreateCustomResourceDefinitionFromManifest(ctx, cnsoperatorconfig.EmbedCnsVolumeRelocationCRFile,
+				cnsoperatorconfig.EmbedCnsVolumeRelocationCRFileName)
+			if err != nil {
+				log.Errorf("Failed to create %q CRD. Err: %+v", cnsoperatorv1alpha1.CnsVolumeRelocationPlural, err)
+				return err
+			}
+		}
 	} else if clusterFlavor == cnstypes.CnsClusterFlavorGuest {
 		if cnsOperator.coCommonInterface.IsFSSEnabled(ctx, common.TKGsHA) {
 			// Create CSINodeTopology CRD.
@@ -319,6 +357,33 @@ func InitCommonModules(ctx context.Context, clusterFlavor cnstypes.CnsClusterFla
 	return nil
 }
 
+// startCnsRegisterVolumeCleanup starts the routine which periodically cleans up
+// successful CnsRegisterVolume instances. The cleanup interval is reloaded on
+// changes to the vsphere.conf file.
+func startCnsRegisterVolumeCleanup(ctx context.Context, cnsOperator *cnsOperator,
+	restConfig *rest.Config) error {
+	log := logger.GetLogger(ctx)
+	err := watcher(ctx, cnsOperator)
+	if err != nil {
+		log.Errorf("Failed to watch on config file for changes to CnsRegisterVolumesCleanupIntervalInMin. Error: %+v",
+			err)
+		return err
+	}
+	go func() {
+		for {
+			ctx, log := logger.GetNewContextWithLogger()
+			log.Infof("Triggering CnsRegisterVolume cleanup routine")
+			cleanUpCnsRegisterVolumeInstances(ctx, restConfig,
+				cnsOperator.configInfo.Cfg.Global.CnsRegisterVolumesCleanupIntervalInMin)
+			log.Infof("Completed CnsRegisterVolume cleanup")
+			for i := 1; i <= cnsOperator.configInfo.Cfg.Global.CnsRegisterVolumesCleanupIntervalInMin; i++ {
+				time.Sleep(time.Duration(1 * time.Minute))
+			}
+		}
+	}()
+	return nil
+}
+
 // watcher watches on the vsphere.conf file mounted as secret within the syncer
 // container.
 func watcher(ctx context.Context, cnsOperator *cnsOperator) error {
@@ -18,16 +18,29 @@ package util
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"sort"
 
 	vmoperatortypes "github.com/vmware-tanzu/vm-operator-api/api/v1alpha1"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	apitypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
+	clientset "k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/node"
+	volumes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco"
+	commoncotypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco/types"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
 	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
 
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
@@ -57,6 +70,9 @@ const (
 	NSXTNetworkProvider = "NSXT_CONTAINER_PLUGIN"
 	// VDSNetworkProvider holds the network provider name for VDS based setups.
 	VDSNetworkProvider = "VSPHERE_NETWORK"
+	// pvProtectionFinalizer is the finalizer preventing the deletion of a
+	// PersistentVolume bound to a PersistentVolumeClaim.
+	pvProtectionFinalizer = "kubernetes.io/pv-protection"
 )
 
 // GetVolumeID gets the volume ID from the PV that is bound to PVC by pvcName.
@@ -188,3 +204,279 @@ func GetNetworkProvider(ctx context.Context) (string, error) {
 	return "", fmt.Errorf("could not find network provider field in configmap %q in namespace %q",
 		wcpNetworkConfigMap, kubeSystemNamespace)
 }
+
+// GetVolumeNodeAffinityForDatastore returns the node affinity for a volume
+// present on the given datastore of the given vCenter in a vanilla cluster.
+// In a topology aware cluster, the node affinity is computed from the topology
+// labels of the nodes which have access to the datastore. Otherwise, the
+// datastore must be accessible to all the nodes of the vCenter and no node
+// affinity is returned.
+func GetVolumeNodeAffinityForDatastore(ctx context.Context, cfg *config.Config, vc *vsphere.VirtualCenter,
+	datastoreURL string) (*v1.VolumeNodeAffinity, error) {
+	log := logger.GetLogger(ctx)
+	nodeMgr := node.GetManager(ctx)
+	nodeVMs, err := nodeMgr.GetAllNodes(ctx)
+	if err != nil {
+		return nil, logger.LogNewErrorf(log, "failed to get node VMs in the cluster. Error: %+v", err)
+	}
+	// Only the node VMs of the given vCenter can access its datastores.
+	var allNodeVMs []*vsphere.VirtualMachine
+	for _, nodeVM := range nodeVMs {
+		if nodeVM.VirtualCenterHost == vc.Config.Host {
+			allNodeVMs = append(allNodeVMs, nodeVM)
+		}
+	}
+	accessibleNodes, err := common.GetNodeVMsWithAccessToDatastore(ctx, vc, datastoreURL, allNodeVMs)
+	if err != nil {
+		return nil, err
+	}
+	if len(accessibleNodes) == 0 {
+		return nil, logger.LogNewErrorf(log, "datastore %q is not accessible to any node in the cluster",
+			datastoreURL)
+	}
+	if cfg.Labels.TopologyCategories == "" && (cfg.Labels.Zone == "" || cfg.Labels.Region == "") {
+		if len(accessibleNodes) != len(allNodeVMs) {
+			return nil, logger.LogNewErrorf(log, "datastore %q is not accessible to all nodes in the cluster",
+				datastoreURL)
+		}
+		return nil, nil
+	}
+	var accessibleNodeNames []string
+	for _, vmref := range accessibleNodes {
+		vmUUID, err := vsphere.GetUUIDFromVMReference(ctx, vc, vmref.Reference())
+		if err != nil {
+			return nil, err
+		}
+		nodeName, err := nodeMgr.GetNodeNameByUUID(ctx, vmUUID)
+		if err != nil {
+			return nil, err
+		}
+		accessibleNodeNames = append(accessibleNodeNames, nodeName)
+	}
+	topologyMgr, err := commonco.ContainerOrchestratorUtility.InitTopologyServiceInController(ctx)
+	if err != nil {
+		return nil, logger.LogNewErrorf(log, "failed to initialize topology service. Error: %+v", err)
+	}
+	topologySegments, err := topologyMgr.GetTopologyInfoFromNodes(ctx,
+		commoncotypes.VanillaRetrieveTopologyInfoParams{
+			NodeNames:    accessibleNodeNames,
+			DatastoreURL: datastoreURL,
+		})
+	if err != nil {
+		return nil, err
+	}
+	return GetPersistentVolumeNodeAffinity(topologySegments), nil
+}
+
+// GetPersistentVolumeNodeAffinity converts the given topology segments into
+// PV node affinity. Each segment is translated into a node selector term, so
+// the PV is accessible from nodes matching any of the segments.
+func GetPersistentVolumeNodeAffinity(topologySegments []map[string]string) *v1.VolumeNodeAffinity {
+	var terms []v1.NodeSelectorTerm
+	for _, segment := range topologySegments {
+		if len(segment) == 0 {
+			continue
+		}
+		keys := make([]string, 0, len(segment))
+		for key := range segment {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var expressions []v1.NodeSelectorRequirement
+		for _, key := range keys {
+			expressions = append(expressions, v1.NodeSelectorRequirement{
+				Key:      key,
+				Operator: v1.NodeSelectorOpIn,
+				Values:   []string{segment[key]},
+			})
+		}
+		terms = append(terms, v1.NodeSelectorTerm{MatchExpressions: expressions})
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+	return &v1.VolumeNodeAffinity{
+		Required: &v1.NodeSelector{NodeSelectorTerms: terms},
+	}
+}
+
+// UpdatePersistentVolumeNodeAffinity updates the node affinity of the given
+// PersistentVolume. As the node affinity of a PersistentVolume is immutable
+// once set, the PersistentVolume is deleted without deleting the volume and
+// recreated with the new node affinity, bound to the same
+// PersistentVolumeClaim. The PersistentVolumeClaim is reported as Lost until
+// the PersistentVolume is recreated.
+// The JSON encoded PersistentVolume is persisted using savePV before it is
+// deleted and must be passed as savedPV on retries, so that the
+// PersistentVolume can be recreated if the deletion already took place.
+// owner is set as the value of the AnnVolumeRelocation annotation.
+func UpdatePersistentVolumeNodeAffinity(ctx context.Context, k8sclient clientset.Interface, pvName string,
+	nodeAffinity *v1.VolumeNodeAffinity, savedPV string, savePV func(string) error, owner string) error {
+	log := logger.GetLogger(ctx)
+	pvClient := k8sclient.CoreV1().PersistentVolumes()
+	if savedPV == "" {
+		pv, err := pvClient.Get(ctx, pvName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get PersistentVolume %q. Error: %+v", pvName, err)
+		}
+		if reflect.DeepEqual(pv.Spec.NodeAffinity, nodeAffinity) {
+			return nil
+		}
+		if pv.Spec.NodeAffinity == nil {
+			// Node affinity can be set on a PersistentVolume without one.
+			pv.Spec.NodeAffinity = nodeAffinity
+			_, err = pvClient.Update(ctx, pv, metav1.UpdateOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to update node affinity of PersistentVolume %q. Error: %+v", pvName, err)
+			}
+			return nil
+		}
+		encodedPV, err := json.Marshal(pv)
+		if err != nil {
+			return fmt.Errorf("failed to encode PersistentVolume %q. Error: %+v", pvName, err)
+		}
+		savedPV = string(encodedPV)
+		err = savePV(savedPV)
+		if err != nil {
+			return err
+		}
+	}
+
+	oldPV := &v1.PersistentVolume{}
+	err := json.Unmarshal([]byte(savedPV), oldPV)
+	if err != nil {
+		return fmt.Errorf("failed to decode saved PersistentVolume %q. Error: %+v", pvName, err)
+	}
+	pv, err := pvClient.Get(ctx, pvName, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get PersistentVolume %q. Error: %+v", pvName, err)
+	}
+	if err == nil && pv.UID != oldPV.UID {
+		// PersistentVolume is already recreated.
+		return nil
+	}
+	if err == nil {
+		if _, ok := pv.Annotations[common.AnnVolumeRelocation]; !ok ||
+			pv.Spec.PersistentVolumeReclaimPolicy != v1.PersistentVolumeReclaimRetain {
+			// Mark the PersistentVolume, so that neither its deletion nor the
+			// reclaim policy deletes the volume.
+			if pv.Annotations == nil {
+				pv.Annotations = make(map[string]string)
+			}
+			pv.Annotations[common.AnnVolumeRelocation] = owner
+			pv.Spec.PersistentVolumeReclaimPolicy = v1.PersistentVolumeReclaimRetain
+			pv, err = pvClient.Update(ctx, pv, metav1.UpdateOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to update PersistentVolume %q. Error: %+v", pvName, err)
+			}
+		}
+		if pv.DeletionTimestamp == nil {
+			err = pvClient.Delete(ctx, pvName, metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete PersistentVolume %q. Error: %+v", pvName, err)
+			}
+			pv, err = pvClient.Get(ctx, pvName, metav1.GetOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return fmt.Errorf("failed to get PersistentVolume %q. Error: %+v", pvName, err)
+			}
+		}
+		if err == nil && removePersistentVolumeProtectionFinalizer(pv) {
+			// The PersistentVolume protection finalizer is removed so that the
+			// PersistentVolume is deleted while it is still bound to the
+			// PersistentVolumeClaim. Other finalizers are left to their owners.
+			_, err = pvClient.Update(ctx, pv, metav1.UpdateOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return fmt.Errorf("failed to remove finalizer %q of PersistentVolume %q. Error: %+v",
+					pvProtectionFinalizer, pvName, err)
+			}
+		}
+		log.Infof("Deleted PersistentVolume %q to update its node affinity", pvName)
+	}
+	_, err = pvClient.Create(ctx, getRecreatedPersistentVolume(oldPV, nodeAffinity), metav1.CreateOptions{})
+	if err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("PersistentVolume %q is not deleted yet", pvName)
+		}
+		return fmt.Errorf("failed to recreate PersistentVolume %q. Error: %+v", pvName, err)
+	}
+	log.Infof("Recreated PersistentVolume %q with node affinity: %+v", pvName, nodeAffinity)
+	return nil
+}
+
+// removePersistentVolumeProtectionFinalizer removes the PersistentVolume
+// protection finalizer from the given PersistentVolume. It returns false if
+// the PersistentVolume does not have the finalizer.
+func removePersistentVolumeProtectionFinalizer(pv *v1.PersistentVolume) bool {
+	for i, finalizer := range pv.Finalizers {
+		if finalizer == pvProtectionFinalizer {
+			pv.Finalizers = append(pv.Finalizers[:i], pv.Finalizers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// getRecreatedPersistentVolume returns the PersistentVolume to be created in
+// place of the given saved PersistentVolume, with the given node affinity.
+// The PersistentVolume is bound to the same PersistentVolumeClaim.
+func getRecreatedPersistentVolume(savedPV *v1.PersistentVolume,
+	nodeAffinity *v1.VolumeNodeAffinity) *v1.PersistentVolume {
+	savedPV = savedPV.DeepCopy()
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        savedPV.Name,
+			Labels:      savedPV.Labels,
+			Annotations: savedPV.Annotations,
+			Finalizers:  savedPV.Finalizers,
+		},
+		Spec: savedPV.Spec,
+	}
+	delete(pv.Annotations, common.AnnVolumeRelocation)
+	pv.Spec.NodeAffinity = nodeAffinity
+	if pv.Spec.ClaimRef != nil {
+		pv.Spec.ClaimRef.ResourceVersion = ""
+	}
+	return pv
+}
+
+// GetVirtualCenterAndVolumeManager returns the VirtualCenter instance and the
+// volume manager for the given vCenter host.
+func GetVirtualCenterAndVolumeManager(ctx context.Context, cfg *config.Config,
+	host string) (*vsphere.VirtualCenter, volumes.Manager, error) {
+	log := logger.GetLogger(ctx)
+	vcconfigs, err := vsphere.GetVirtualCenterConfigs(ctx, cfg)
+	if err != nil {
+		return nil, nil, logger.LogNewErrorf(log, "failed to get VirtualCenterConfigs. Error: %+v", err)
+	}
+	for _, vcconfig := range vcconfigs {
+		if vcconfig.Host != host {
+			continue
+		}
+		vc, err := vsphere.GetVirtualCenterInstanceForVCenterConfig(ctx, vcconfig, false)
+		if err != nil {
+			return nil, nil, logger.LogNewErrorf(log, "failed to get vCenterInstance for vCenter Host: %q. "+
+				"Error: %+v", host, err)
+		}
+		return vc, volumes.GetManager(ctx, vc, nil, false, true, len(vcconfigs) > 1), nil
+	}
+	return nil, nil, logger.LogNewErrorf(log, "vCenter %q is not present in the vSphere config secret", host)
+}
+
+// ValidatePersistentVolumeForRelocation verifies that the given
+// PersistentVolume is a block volume provisioned by the vSphere CSI driver,
+// whose volume can be relocated.
+func ValidatePersistentVolumeForRelocation(pv *v1.PersistentVolume) error {
+	if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != csitypes.Name {
+		return fmt.Errorf("PersistentVolume %q is not a vSphere CSI volume", pv.Name)
+	}
+	for _, accessMode := range pv.Spec.AccessModes {
+		if accessMode == v1.ReadWriteMany || accessMode == v1.ReadOnlyMany {
+			return fmt.Errorf("PersistentVolume %q with access mode %q cannot be relocated. "+
+				"Only block volumes can be relocated", pv.Name, accessMode)
+		}
+	}
+	if pv.DeletionTimestamp != nil {
+		return fmt.Errorf("PersistentVolume %q is being deleted", pv.Name)
+	}
+	return nil
+}
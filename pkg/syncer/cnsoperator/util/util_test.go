@@ -0,0 +1,244 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+func TestGetPersistentVolumeNodeAffinity(t *testing.T) {
+	if affinity := GetPersistentVolumeNodeAffinity(nil); affinity != nil {
+		t.Errorf("expected nil node affinity for empty topology, got %+v", affinity)
+	}
+	segments := []map[string]string{
+		{"topology.csi.vmware.com/k8s-zone": "zone-a", "topology.csi.vmware.com/k8s-region": "region-1"},
+		{"topology.csi.vmware.com/k8s-zone": "zone-b", "topology.csi.vmware.com/k8s-region": "region-1"},
+	}
+	expected := &v1.VolumeNodeAffinity{
+		Required: &v1.NodeSelector{
+			NodeSelectorTerms: []v1.NodeSelectorTerm{
+				{
+					MatchExpressions: []v1.NodeSelectorRequirement{
+						{Key: "topology.csi.vmware.com/k8s-region", Operator: v1.NodeSelectorOpIn,
+							Values: []string{"region-1"}},
+						{Key: "topology.csi.vmware.com/k8s-zone", Operator: v1.NodeSelectorOpIn,
+							Values: []string{"zone-a"}},
+					},
+				},
+				{
+					MatchExpressions: []v1.NodeSelectorRequirement{
+						{Key: "topology.csi.vmware.com/k8s-region", Operator: v1.NodeSelectorOpIn,
+							Values: []string{"region-1"}},
+						{Key: "topology.csi.vmware.com/k8s-zone", Operator: v1.NodeSelectorOpIn,
+							Values: []string{"zone-b"}},
+					},
+				},
+			},
+		},
+	}
+	if affinity := GetPersistentVolumeNodeAffinity(segments); !reflect.DeepEqual(affinity, expected) {
+		t.Errorf("unexpected node affinity. expected: %+v, got: %+v", expected, affinity)
+	}
+}
+
+func TestGetRecreatedPersistentVolume(t *testing.T) {
+	savedPV := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "pv-1",
+			UID:             "uid-1",
+			ResourceVersion: "10",
+			Annotations:     map[string]string{common.AnnVolumeRelocation: "relocation-1", "key": "value"},
+			Finalizers:      []string{"kubernetes.io/pv-protection"},
+		},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeReclaimPolicy: v1.PersistentVolumeReclaimDelete,
+			ClaimRef:                      &v1.ObjectReference{Name: "pvc-1", UID: "pvc-uid", ResourceVersion: "5"},
+		},
+		Status: v1.PersistentVolumeStatus{Phase: v1.VolumeBound},
+	}
+	nodeAffinity := &v1.VolumeNodeAffinity{Required: &v1.NodeSelector{}}
+	pv := getRecreatedPersistentVolume(savedPV, nodeAffinity)
+	if pv.UID != "" || pv.ResourceVersion != "" || pv.Status.Phase != "" {
+		t.Errorf("expected server populated fields to be cleared, got %+v", pv)
+	}
+	if _, ok := pv.Annotations[common.AnnVolumeRelocation]; ok {
+		t.Errorf("expected relocation annotation to be removed")
+	}
+	if _, ok := savedPV.Annotations[common.AnnVolumeRelocation]; !ok {
+		t.Errorf("saved PersistentVolume must not be modified")
+	}
+	if pv.Annotations["key"] != "value" || len(pv.Finalizers) != 1 {
+		t.Errorf("expected annotations and finalizers to be retained, got %+v", pv.ObjectMeta)
+	}
+	if pv.Spec.NodeAffinity != nodeAffinity {
+		t.Errorf("expected node affinity to be updated")
+	}
+	if pv.Spec.ClaimRef.UID != "pvc-uid" || pv.Spec.ClaimRef.ResourceVersion != "" {
+		t.Errorf("unexpected claimRef %+v", pv.Spec.ClaimRef)
+	}
+	if pv.Spec.PersistentVolumeReclaimPolicy != v1.PersistentVolumeReclaimDelete {
+		t.Errorf("expected original reclaim policy, got %q", pv.Spec.PersistentVolumeReclaimPolicy)
+	}
+}
+
+func TestValidatePersistentVolumeForRelocation(t *testing.T) {
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+		Spec: v1.PersistentVolumeSpec{
+			AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{Driver: csitypes.Name, VolumeHandle: "vol-1"},
+			},
+		},
+	}
+	if err := ValidatePersistentVolumeForRelocation(pv); err != nil {
+		t.Errorf("unexpected error for block volume: %v", err)
+	}
+	fileVolume := pv.DeepCopy()
+	fileVolume.Spec.AccessModes = []v1.PersistentVolumeAccessMode{v1.ReadWriteMany}
+	if err := ValidatePersistentVolumeForRelocation(fileVolume); err == nil {
+		t.Errorf("expected error for file volume")
+	}
+	otherDriver := pv.DeepCopy()
+	otherDriver.Spec.CSI.Driver = "other.csi.driver"
+	if err := ValidatePersistentVolumeForRelocation(otherDriver); err == nil {
+		t.Errorf("expected error for volume of another driver")
+	}
+}
+
+// newPersistentVolumeFinalizingClientset returns a fake clientset whose
+// PersistentVolumes are only deleted once they have no finalizers, as the
+// fake object tracker deletes them right away.
+func newPersistentVolumeFinalizingClientset(objects ...runtime.Object) *fake.Clientset {
+	k8sclient := fake.NewSimpleClientset(objects...)
+	pvResource := v1.SchemeGroupVersion.WithResource("persistentvolumes")
+	k8sclient.PrependReactor("delete", "persistentvolumes",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			name := action.(k8stesting.DeleteAction).GetName()
+			obj, err := k8sclient.Tracker().Get(pvResource, "", name)
+			if err != nil {
+				return true, nil, err
+			}
+			pv := obj.(*v1.PersistentVolume)
+			if len(pv.Finalizers) == 0 {
+				return true, nil, k8sclient.Tracker().Delete(pvResource, "", name)
+			}
+			now := metav1.Now()
+			pv.DeletionTimestamp = &now
+			return true, nil, k8sclient.Tracker().Update(pvResource, pv, "")
+		})
+	k8sclient.PrependReactor("update", "persistentvolumes",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			pv := action.(k8stesting.UpdateAction).GetObject().(*v1.PersistentVolume)
+			if pv.DeletionTimestamp == nil || len(pv.Finalizers) != 0 {
+				return false, nil, nil
+			}
+			return true, pv, k8sclient.Tracker().Delete(pvResource, "", pv.Name)
+		})
+	return k8sclient
+}
+
+func TestUpdatePersistentVolumeNodeAffinity(t *testing.T) {
+	ctx := context.Background()
+	oldAffinity := GetPersistentVolumeNodeAffinity([]map[string]string{{"topology.csi.vmware.com/k8s-zone": "zone-a"}})
+	newAffinity := GetPersistentVolumeNodeAffinity([]map[string]string{{"topology.csi.vmware.com/k8s-zone": "zone-b"}})
+	const otherFinalizer = "example.com/backup-protection"
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "pv-1",
+			UID:        "pv-1-uid",
+			Finalizers: []string{pvProtectionFinalizer, otherFinalizer},
+		},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeReclaimPolicy: v1.PersistentVolumeReclaimDelete,
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{Driver: csitypes.Name, VolumeHandle: "volume-1"},
+			},
+			ClaimRef:     &v1.ObjectReference{Namespace: "ns-1", Name: "pvc-1", UID: "pvc-1-uid"},
+			NodeAffinity: oldAffinity,
+		},
+	}
+	k8sclient := newPersistentVolumeFinalizingClientset(pv)
+	var savedPV string
+	savePV := func(encodedPV string) error {
+		savedPV = encodedPV
+		return nil
+	}
+
+	// The PersistentVolume is not recreated while another finalizer remains.
+	err := UpdatePersistentVolumeNodeAffinity(ctx, k8sclient, pv.Name, newAffinity, savedPV, savePV, "relocation-1")
+	if err == nil {
+		t.Fatal("expected an error while the PersistentVolume is not deleted")
+	}
+	if savedPV == "" {
+		t.Fatal("expected the PersistentVolume to be saved before it is deleted")
+	}
+	current, err := k8sclient.CoreV1().PersistentVolumes().Get(ctx, pv.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(current.Finalizers, []string{otherFinalizer}) {
+		t.Errorf("expected only the %q finalizer to be removed, got %v", pvProtectionFinalizer, current.Finalizers)
+	}
+	if current.Annotations[common.AnnVolumeRelocation] != "relocation-1" ||
+		current.Spec.PersistentVolumeReclaimPolicy != v1.PersistentVolumeReclaimRetain {
+		t.Errorf("expected the PersistentVolume to be protected from volume deletion, got %+v", current)
+	}
+
+	// The PersistentVolume is recreated once its owner removes its finalizer.
+	current.Finalizers = nil
+	if _, err = k8sclient.CoreV1().PersistentVolumes().Update(ctx, current, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	err = UpdatePersistentVolumeNodeAffinity(ctx, k8sclient, pv.Name, newAffinity, savedPV, savePV, "relocation-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recreated, err := k8sclient.CoreV1().PersistentVolumes().Get(ctx, pv.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(recreated.Spec.NodeAffinity, newAffinity) {
+		t.Errorf("expected the recreated PersistentVolume to have node affinity %+v, got %+v", newAffinity,
+			recreated.Spec.NodeAffinity)
+	}
+	if recreated.Spec.ClaimRef == nil || recreated.Spec.ClaimRef.UID != pv.Spec.ClaimRef.UID ||
+		recreated.Spec.PersistentVolumeReclaimPolicy != v1.PersistentVolumeReclaimDelete {
+		t.Errorf("expected the recreated PersistentVolume to be bound with its reclaim policy, got %+v",
+			recreated.Spec)
+	}
+	if _, ok := recreated.Annotations[common.AnnVolumeRelocation]; ok {
+		t.Errorf("expected the recreated PersistentVolume not to have the %q annotation", common.AnnVolumeRelocation)
+	}
+
+	// Retrying after the PersistentVolume is recreated is a no-op.
+	err = UpdatePersistentVolumeNodeAffinity(ctx, k8sclient, pv.Name, newAffinity, savedPV, savePV, "relocation-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
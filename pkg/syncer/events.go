@@ -0,0 +1,71 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	volumes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
+)
+
+// recordFaultEvent records a Warning event with the given reason on the given
+// PV or PVC when a CNS operation on its volume fails, if the CnsFailureEvents
+// FSS is enabled. If faultType is empty, it is extracted from err.
+func recordFaultEvent(ctx context.Context, metadataSyncer *metadataSyncInformer, object runtime.Object,
+	reason string, faultType string, err error) {
+	if metadataSyncer.eventRecorder == nil || err == nil ||
+		!metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.CnsFailureEvents) {
+		return
+	}
+	if faultType == "" {
+		faultType = volumes.ExtractFaultTypeFromErr(ctx, err)
+	}
+	k8s.RecordFaultEvent(metadataSyncer.eventRecorder, object, reason, faultType, err.Error())
+}
+
+// recordPVFaultEvent records a Warning event with the given reason on the
+// given PV and on the PVC bound to it when a CNS operation on its volume
+// fails, if the CnsFailureEvents FSS is enabled.
+func recordPVFaultEvent(ctx context.Context, metadataSyncer *metadataSyncInformer, pv *v1.PersistentVolume,
+	reason string, faultType string, err error) {
+	log := logger.GetLogger(ctx)
+	if metadataSyncer.eventRecorder == nil || err == nil ||
+		!metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.CnsFailureEvents) {
+		return
+	}
+	if faultType == "" {
+		faultType = volumes.ExtractFaultTypeFromErr(ctx, err)
+	}
+	recordFaultEvent(ctx, metadataSyncer, pv, reason, faultType, err)
+	if pv.Spec.ClaimRef == nil || metadataSyncer.pvcLister == nil {
+		return
+	}
+	pvc, pvcErr := metadataSyncer.pvcLister.PersistentVolumeClaims(pv.Spec.ClaimRef.Namespace).
+		Get(pv.Spec.ClaimRef.Name)
+	if pvcErr != nil {
+		log.Debugf("failed to get PVC %s/%s to record event %q. Error: %+v",
+			pv.Spec.ClaimRef.Namespace, pv.Spec.ClaimRef.Name, reason, pvcErr)
+		return
+	}
+	recordFaultEvent(ctx, metadataSyncer, pvc, reason, faultType, err)
+}
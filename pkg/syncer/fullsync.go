@@ -18,6 +18,7 @@ package syncer
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
@@ -25,23 +26,36 @@ import (
 	"github.com/vmware/govmomi/cns"
 	cnstypes "github.com/vmware/govmomi/cns/types"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+
+	cnsoperatorv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
+	cnscrossvcentervolumerelocationv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnscrossvcentervolumerelocation/v1alpha1"
+	cnsvolumerelocationv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsvolumerelocation/v1alpha1"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/migration"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/audit"
 	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/tracing"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
 )
 
 // CsiFullSync reconciles volume metadata on a vanilla k8s cluster with volume
 // metadata on CNS.
 func CsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer) error {
+	ctx, span := tracing.StartSpan(ctx, "Syncer.FullSync")
 	log := logger.GetLogger(ctx)
 	log.Infof("FullSync: start")
 	fullSyncStartTime := time.Now()
 	var migrationFeatureStateForFullSync bool
 	var err error
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 	// Fetch CSI migration feature state, before performing full sync operations.
 	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla {
 		migrationFeatureStateForFullSync = metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.CSIMigration)
@@ -95,15 +109,116 @@ func CsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer) erro
 			k8sPVMap[volumeHandle] = ""
 		}
 	}
+	// Volumes being relocated are unregistered from CNS and their PVs are
+	// recreated during the relocation. They are left to the relocation
+	// controllers until the relocation completes.
+	relocatingVolumes, err := getRelocatingVolumes(ctx, metadataSyncer)
+	if err != nil {
+		log.Errorf("FullSync: Failed to get the volumes being relocated. Err: %v", err)
+		return err
+	}
+	for volumeID := range relocatingVolumes {
+		k8sPVMap[volumeID] = ""
+	}
+	k8sPVs = filterRelocatingPVs(ctx, k8sPVs, relocatingVolumes)
+	// Process only the PVs in the current shard, which have changed since they
+	// were last found in sync.
+	shard, shardCount := GetFullSyncShard(ctx)
+	fullPass := shard == 0
+	shardPVs := getFullSyncShardPVs(k8sPVs, shard, shardCount, getFullSyncShardBy(ctx))
+	if shardCount > 1 {
+		log.Infof("FullSync: processing shard %d of %d with %d PVs", shard, shardCount, len(shardPVs))
+	}
 	// pvToPVCMap maps pv name to corresponding PVC.
 	// pvcToPodMap maps pvc to the mounted Pod.
-	pvToPVCMap, pvcToPodMap, err := buildPVCMapPodMap(ctx, k8sPVs, metadataSyncer)
+	pvToPVCMap, pvcToPodMap, err := buildPVCMapPodMap(ctx, shardPVs, metadataSyncer)
 	if err != nil {
 		log.Errorf("FullSync: Failed to build PVCMap and PodMap. Err: %v", err)
 		return err
 	}
+	skipUnchangedPVInterval := getFullSyncSkipUnchangedPVInterval(ctx)
+	shardPVs = getFullSyncChangedPVs(ctx, shardPVs, pvToPVCMap, pvcToPodMap, skipUnchangedPVInterval)
 	log.Debugf("FullSync: pvToPVCMap %v", pvToPVCMap)
 	log.Debugf("FullSync: pvcToPodMap %v", pvcToPodMap)
+	// Volumes which no longer exist in kubernetes are looked for only once in a
+	// cycle of shards, as it requires querying all the volumes in CNS.
+	var cnsVolumes []cnstypes.CnsVolume
+	if fullPass {
+		var queryAllResult *cnstypes.CnsQueryResult
+		queryAllResult, err = fullSyncQueryAllVolumes(ctx, metadataSyncer)
+		if err != nil {
+			return err
+		}
+		cnsVolumes = queryAllResult.Volumes
+	}
+
+	volumeToCnsEntityMetadataMap, volumeToK8sEntityMetadataMap, volumeClusterDistributionMap, err :=
+		fullSyncConstructVolumeMaps(ctx, shardPVs, cnsVolumes, pvToPVCMap,
+			pvcToPodMap, metadataSyncer, migrationFeatureStateForFullSync)
+	if err != nil {
+		log.Errorf("FullSync: fullSyncGetEntityMetadata failed with err %+v", err)
+		return err
+	}
+	log.Debugf("FullSync: pvToCnsEntityMetadataMap %+v \n pvToK8sEntityMetadataMap: %+v \n",
+		spew.Sdump(volumeToCnsEntityMetadataMap), spew.Sdump(volumeToK8sEntityMetadataMap))
+	log.Debugf("FullSync: volumes where clusterDistribution is set: %+v", volumeClusterDistributionMap)
+
+	vcenter, err := cnsvsphere.GetVirtualCenterInstance(ctx, metadataSyncer.configInfo, false)
+	if err != nil {
+		log.Errorf("FullSync: failed to get vcenter with error %+v", err)
+		return err
+	}
+	containerCluster := cnsvsphere.GetContainerCluster(clusterIDforVolumeMetadata,
+		cnsvsphere.GetUsername(metadataSyncer.host,
+			metadataSyncer.configInfo.Cfg.VirtualCenter[metadataSyncer.host].User), metadataSyncer.clusterFlavor,
+		metadataSyncer.configInfo.Cfg.Global.ClusterDistribution)
+	createSpecArray, updateSpecArray := fullSyncGetVolumeSpecs(ctx, vcenter.Client.Version, shardPVs,
+		volumeToCnsEntityMetadataMap, volumeToK8sEntityMetadataMap, volumeClusterDistributionMap,
+		containerCluster, migrationFeatureStateForFullSync)
+	var volToBeDeleted []cnstypes.CnsVolumeId
+	if fullPass {
+		volToBeDeleted, err = getVolumesToBeDeleted(ctx, cnsVolumes, k8sPVMap, metadataSyncer,
+			migrationFeatureStateForFullSync)
+		if err != nil {
+			log.Errorf("FullSync: failed to get list of volumes to be deleted with err %+v", err)
+			return err
+		}
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(3)
+	// Perform operations.
+	go fullSyncCreateVolumes(ctx, createSpecArray, metadataSyncer, &wg, migrationFeatureStateForFullSync)
+	go fullSyncUpdateVolumes(ctx, updateSpecArray, metadataSyncer, &wg)
+	go fullSyncDeleteVolumes(ctx, volToBeDeleted, metadataSyncer, &wg, migrationFeatureStateForFullSync)
+	wg.Wait()
+
+	if fullPass && metadataSyncer.volumeSnapshotContentLister != nil {
+		// Errors are logged and do not fail full sync.
+		orphanSnapshots, err := fullSyncReconcileSnapshots(ctx, metadataSyncer, cnsVolumes)
+		if err == nil {
+			fullSyncCollectOrphanSnapshots(ctx, metadataSyncer, orphanSnapshots)
+		}
+		fullSyncVolumeSnapshotMetadata(ctx, metadataSyncer)
+	}
+
+	cleanupCnsMaps(k8sPVMap)
+	if skipUnchangedPVInterval > 0 {
+		markFullSyncPVsInSync(k8sPVs, shardPVs, pvToPVCMap, pvcToPodMap, createSpecArray, updateSpecArray)
+	}
+	SetFullSyncNextShard((shard + 1) % shardCount)
+	log.Debugf("FullSync: cnsDeletionMap at end of cycle: %v", cnsDeletionMap)
+	log.Debugf("FullSync: cnsCreationMap at end of cycle: %v", cnsCreationMap)
+	log.Infof("FullSync: end")
+	return nil
+}
+
+// fullSyncQueryAllVolumes queries all the volumes in CNS for the cluster. In
+// Supervisor clusters with TKGs HA enabled, the volume metadata is moved from
+// the cluster ID to the Supervisor ID before querying.
+func fullSyncQueryAllVolumes(ctx context.Context,
+	metadataSyncer *metadataSyncInformer) (*cnstypes.CnsQueryResult, error) {
+	log := logger.GetLogger(ctx)
 	// Call CNS QueryAll to get container volumes by cluster ID.
 	queryFilter := cnstypes.CnsQueryFilter{
 		ContainerClusterIds: []string{
@@ -113,7 +228,7 @@ func CsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer) erro
 	queryAllResult, err := metadataSyncer.volumeManager.QueryAllVolume(ctx, queryFilter, cnstypes.CnsQuerySelection{})
 	if err != nil {
 		log.Errorf("FullSync: QueryVolume failed with err=%+v", err.Error())
-		return err
+		return nil, err
 	}
 
 	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorWorkload &&
@@ -203,52 +318,10 @@ func CsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer) erro
 		queryAllResult, err = metadataSyncer.volumeManager.QueryAllVolume(ctx, queryFilter, cnstypes.CnsQuerySelection{})
 		if err != nil {
 			log.Errorf("FullSync: QueryVolume failed with err=%+v", err.Error())
-			return err
+			return nil, err
 		}
 	}
-
-	volumeToCnsEntityMetadataMap, volumeToK8sEntityMetadataMap, volumeClusterDistributionMap, err :=
-		fullSyncConstructVolumeMaps(ctx, k8sPVs, queryAllResult.Volumes, pvToPVCMap,
-			pvcToPodMap, metadataSyncer, migrationFeatureStateForFullSync)
-	if err != nil {
-		log.Errorf("FullSync: fullSyncGetEntityMetadata failed with err %+v", err)
-		return err
-	}
-	log.Debugf("FullSync: pvToCnsEntityMetadataMap %+v \n pvToK8sEntityMetadataMap: %+v \n",
-		spew.Sdump(volumeToCnsEntityMetadataMap), spew.Sdump(volumeToK8sEntityMetadataMap))
-	log.Debugf("FullSync: volumes where clusterDistribution is set: %+v", volumeClusterDistributionMap)
-
-	vcenter, err := cnsvsphere.GetVirtualCenterInstance(ctx, metadataSyncer.configInfo, false)
-	if err != nil {
-		log.Errorf("FullSync: failed to get vcenter with error %+v", err)
-		return err
-	}
-	containerCluster := cnsvsphere.GetContainerCluster(clusterIDforVolumeMetadata,
-		metadataSyncer.configInfo.Cfg.VirtualCenter[metadataSyncer.host].User, metadataSyncer.clusterFlavor,
-		metadataSyncer.configInfo.Cfg.Global.ClusterDistribution)
-	createSpecArray, updateSpecArray := fullSyncGetVolumeSpecs(ctx, vcenter.Client.Version, k8sPVs,
-		volumeToCnsEntityMetadataMap, volumeToK8sEntityMetadataMap, volumeClusterDistributionMap,
-		containerCluster, migrationFeatureStateForFullSync)
-	volToBeDeleted, err := getVolumesToBeDeleted(ctx, queryAllResult.Volumes, k8sPVMap, metadataSyncer,
-		migrationFeatureStateForFullSync)
-	if err != nil {
-		log.Errorf("FullSync: failed to get list of volumes to be deleted with err %+v", err)
-		return err
-	}
-
-	wg := sync.WaitGroup{}
-	wg.Add(3)
-	// Perform operations.
-	go fullSyncCreateVolumes(ctx, createSpecArray, metadataSyncer, &wg, migrationFeatureStateForFullSync)
-	go fullSyncUpdateVolumes(ctx, updateSpecArray, metadataSyncer, &wg)
-	go fullSyncDeleteVolumes(ctx, volToBeDeleted, metadataSyncer, &wg, migrationFeatureStateForFullSync)
-	wg.Wait()
-
-	cleanupCnsMaps(k8sPVMap)
-	log.Debugf("FullSync: cnsDeletionMap at end of cycle: %v", cnsDeletionMap)
-	log.Debugf("FullSync: cnsCreationMap at end of cycle: %v", cnsCreationMap)
-	log.Infof("FullSync: end")
-	return nil
+	return queryAllResult, nil
 }
 
 // fullSyncCreateVolumes creates volumes with given array of createSpec.
@@ -303,9 +376,13 @@ func fullSyncCreateVolumes(ctx context.Context, createSpecArray []cnstypes.CnsVo
 		if _, existsInK8s := currentK8sPVMap[volumeID]; existsInK8s {
 			log.Debugf("FullSync: Calling CreateVolume for volume id: %q with createSpec %+v",
 				volumeID, spew.Sdump(createSpec))
-			_, _, err := metadataSyncer.volumeManager.CreateVolume(ctx, &createSpec)
+			_, faultType, err := metadataSyncer.volumeManager.CreateVolume(ctx, &createSpec)
 			if err != nil {
 				log.Warnf("FullSync: Failed to create volume with the spec: %+v. Err: %+v", spew.Sdump(createSpec), err)
+				if pv, pvErr := metadataSyncer.pvLister.Get(createSpec.Name); pvErr == nil {
+					recordPVFaultEvent(ctx, metadataSyncer, pv, common.RegisterVolumeFailedReason, faultType,
+						fmt.Errorf("failed to register volume %q with CNS. Error: %v", volumeID, err))
+				}
 				continue
 			}
 		} else {
@@ -387,7 +464,14 @@ func fullSyncDeleteVolumes(ctx context.Context, volumeIDDeleteArray []cnstypes.C
 			if !inUsebyOtherK8SCluster {
 				log.Infof("FullSync: fullSyncDeleteVolumes: Calling DeleteVolume for volume %v with delete disk %v",
 					volume.VolumeId.Id, deleteDisk)
-				_, err := metadataSyncer.volumeManager.DeleteVolume(ctx, volume.VolumeId.Id, deleteDisk)
+				auditCtx := audit.NewContext(ctx)
+				_, err := metadataSyncer.volumeManager.DeleteVolume(auditCtx, volume.VolumeId.Id, deleteDisk)
+				audit.Log(auditCtx, &audit.Event{
+					Operation:         audit.OperationFullSyncDeleteVolume,
+					Component:         audit.ComponentSyncer,
+					VolumeID:          volume.VolumeId.Id,
+					KubernetesObjects: getKubernetesObjectReferences(volume.Metadata.EntityMetadata),
+				}, err)
 				if err != nil {
 					log.Warnf("FullSync: fullSyncDeleteVolumes: Failed to delete volume %s with error %+v",
 						volume.VolumeId.Id, err)
@@ -481,6 +565,9 @@ func fullSyncConstructVolumeMaps(ctx context.Context, pvList []*v1.PersistentVol
 	for _, vol := range cnsVolumeList {
 		cnsVolumeMap[vol.VolumeId.Id] = true
 	}
+	// When the list of volumes in CNS is not known, query all the volumes in
+	// pvList and treat the ones not found as missing in CNS.
+	queryAllPVs := cnsVolumeList == nil
 	var err error
 	var queryVolumeIds []cnstypes.CnsVolumeId
 	for _, pv := range pvList {
@@ -507,7 +594,7 @@ func fullSyncConstructVolumeMaps(ctx context.Context, pvList []*v1.PersistentVol
 		} else {
 			volumeToK8sEntityMetadataMap[volumeHandle] = k8sMetadata
 		}
-		if cnsVolumeMap[volumeHandle] {
+		if queryAllPVs || cnsVolumeMap[volumeHandle] {
 			// PV exist in both K8S and CNS cache, add to queryVolumeIds list to
 			// check if metadata has been changed or not.
 			queryVolumeIds = append(queryVolumeIds, cnstypes.CnsVolumeId{Id: volumeHandle})
@@ -888,3 +975,67 @@ func cleanupCnsMaps(k8sPVs map[string]string) {
 		}
 	}
 }
+
+// getRelocatingVolumes returns the IDs of the volumes of the
+// CnsCrossVCenterVolumeRelocation and CnsVolumeRelocation instances whose
+// relocation is in progress. No volumes are returned if the CRDs are not
+// installed.
+func getRelocatingVolumes(ctx context.Context, metadataSyncer *metadataSyncInformer) (map[string]bool, error) {
+	if metadataSyncer.clusterFlavor != cnstypes.CnsClusterFlavorVanilla {
+		return nil, nil
+	}
+	if metadataSyncer.cnsOperatorClient == nil {
+		restConfig, err := config.GetConfig()
+		if err != nil {
+			return nil, err
+		}
+		metadataSyncer.cnsOperatorClient, err = k8s.NewClientForGroup(ctx, restConfig, cnsoperatorv1alpha1.GroupName)
+		if err != nil {
+			return nil, err
+		}
+	}
+	relocatingVolumes := make(map[string]bool)
+	crossVCenterRelocations := &cnscrossvcentervolumerelocationv1alpha1.CnsCrossVCenterVolumeRelocationList{}
+	err := metadataSyncer.cnsOperatorClient.List(ctx, crossVCenterRelocations)
+	if err != nil && !meta.IsNoMatchError(err) {
+		return nil, err
+	}
+	for _, relocation := range crossVCenterRelocations.Items {
+		if relocation.Status.VolumeID != "" &&
+			relocation.Status.Phase != cnscrossvcentervolumerelocationv1alpha1.RelocationPhaseCompleted {
+			relocatingVolumes[relocation.Status.VolumeID] = true
+		}
+	}
+	relocations := &cnsvolumerelocationv1alpha1.CnsVolumeRelocationList{}
+	err = metadataSyncer.cnsOperatorClient.List(ctx, relocations)
+	if err != nil && !meta.IsNoMatchError(err) {
+		return nil, err
+	}
+	for _, relocation := range relocations.Items {
+		if relocation.Status.VolumeID != "" &&
+			relocation.Status.Phase != cnsvolumerelocationv1alpha1.RelocationPhaseCompleted {
+			relocatingVolumes[relocation.Status.VolumeID] = true
+		}
+	}
+	return relocatingVolumes, nil
+}
+
+// filterRelocatingPVs returns the given PVs, except the ones whose volume is
+// being relocated.
+func filterRelocatingPVs(ctx context.Context, pvs []*v1.PersistentVolume,
+	relocatingVolumes map[string]bool) []*v1.PersistentVolume {
+	log := logger.GetLogger(ctx)
+	if len(relocatingVolumes) == 0 {
+		return pvs
+	}
+	filteredPVs := make([]*v1.PersistentVolume, 0, len(pvs))
+	for _, pv := range pvs {
+		if pv.Spec.CSI != nil && relocatingVolumes[pv.Spec.CSI.VolumeHandle] {
+			log.Infof("FullSync: skipping PV %q as its volume %q is being relocated", pv.Name,
+				pv.Spec.CSI.VolumeHandle)
+			continue
+		}
+		filteredPVs = append(filteredPVs, pv)
+	}
+	return filteredPVs
+}
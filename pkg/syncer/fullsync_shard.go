@@ -0,0 +1,254 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+const (
+	// fullSyncShardByVolumeID shards the volumes by the hash of their volume
+	// ID or volume path.
+	fullSyncShardByVolumeID = "volumeid"
+	// fullSyncShardByNamespace shards the volumes by the hash of the namespace
+	// of their PVC.
+	fullSyncShardByNamespace = "namespace"
+)
+
+// fullSyncPVState is the state of a PV when it was last found in sync with
+// CNS by full sync.
+type fullSyncPVState struct {
+	// resourceVersion is the resource version of the PV, its PVC and the pods
+	// using it.
+	resourceVersion string
+	// syncedTime is the time at which the PV was found in sync.
+	syncedTime time.Time
+}
+
+var (
+	// fullSyncShardLock protects fullSyncNextShard and fullSyncSyncedPVs.
+	fullSyncShardLock = &sync.Mutex{}
+	// fullSyncNextShard is the shard to be processed by the next full sync.
+	fullSyncNextShard int
+	// fullSyncSyncedPVs maps the PV name to its state when it was last found
+	// in sync with CNS.
+	fullSyncSyncedPVs = make(map[string]fullSyncPVState)
+)
+
+// getFullSyncShardCount returns the number of shards the volumes are split
+// into by full sync. If environment variable FULL_SYNC_SHARD_COUNT is set and
+// valid, return the value read from environment variable. Otherwise, use 1,
+// which processes all the volumes in every full sync.
+func getFullSyncShardCount(ctx context.Context) int {
+	log := logger.GetLogger(ctx)
+	shardCount := 1
+	if v := os.Getenv("FULL_SYNC_SHARD_COUNT"); v != "" {
+		if value, err := strconv.Atoi(v); err == nil && value > 0 {
+			shardCount = value
+		} else {
+			log.Warnf("FullSync: shard count set in env variable FULL_SYNC_SHARD_COUNT %s "+
+				"is invalid, will process all the volumes in every full sync", v)
+		}
+	}
+	return shardCount
+}
+
+// getFullSyncShardBy returns how volumes are split into shards by full sync.
+// If environment variable FULL_SYNC_SHARD_BY is set to "namespace", volumes
+// are sharded by the namespace of their PVC. Otherwise, they are sharded by
+// volume ID.
+func getFullSyncShardBy(ctx context.Context) string {
+	log := logger.GetLogger(ctx)
+	shardBy := fullSyncShardByVolumeID
+	if v := os.Getenv("FULL_SYNC_SHARD_BY"); v != "" {
+		v = strings.ToLower(v)
+		if v == fullSyncShardByNamespace || v == fullSyncShardByVolumeID {
+			shardBy = v
+		} else {
+			log.Warnf("FullSync: shard key set in env variable FULL_SYNC_SHARD_BY %s "+
+				"is invalid, will shard volumes by %s", v, fullSyncShardByVolumeID)
+		}
+	}
+	return shardBy
+}
+
+// getFullSyncSkipUnchangedPVInterval returns the interval for which full sync
+// skips the PVs which were found in sync and have not changed since. If
+// environment variable FULL_SYNC_SKIP_UNCHANGED_PV_MINUTES is set and valid,
+// return the value read from environment variable. Otherwise, use 0, which
+// disables skipping PVs.
+func getFullSyncSkipUnchangedPVInterval(ctx context.Context) time.Duration {
+	log := logger.GetLogger(ctx)
+	var interval time.Duration
+	if v := os.Getenv("FULL_SYNC_SKIP_UNCHANGED_PV_MINUTES"); v != "" {
+		if value, err := strconv.Atoi(v); err == nil && value >= 0 {
+			interval = time.Duration(value) * time.Minute
+		} else {
+			log.Warnf("FullSync: interval set in env variable FULL_SYNC_SKIP_UNCHANGED_PV_MINUTES %s "+
+				"is invalid, will not skip unchanged PVs", v)
+		}
+	}
+	return interval
+}
+
+// GetFullSyncShard returns the shard to be processed by the next full sync
+// and the number of shards.
+func GetFullSyncShard(ctx context.Context) (int, int) {
+	shardCount := getFullSyncShardCount(ctx)
+	fullSyncShardLock.Lock()
+	defer fullSyncShardLock.Unlock()
+	return fullSyncNextShard % shardCount, shardCount
+}
+
+// SetFullSyncNextShard sets the shard to be processed by the next full sync.
+// It is used to resume full sync from the checkpoint in the TriggerCsiFullSync
+// status.
+func SetFullSyncNextShard(shard int) {
+	fullSyncShardLock.Lock()
+	defer fullSyncShardLock.Unlock()
+	if shard >= 0 {
+		fullSyncNextShard = shard
+	}
+}
+
+// getFullSyncShardForPV returns the shard the given PV belongs to.
+func getFullSyncShardForPV(pv *v1.PersistentVolume, shardBy string, shardCount int) int {
+	if shardCount <= 1 {
+		return 0
+	}
+	var key string
+	if shardBy == fullSyncShardByNamespace {
+		if pv.Spec.ClaimRef != nil {
+			key = pv.Spec.ClaimRef.Namespace
+		}
+	} else if pv.Spec.CSI != nil {
+		key = pv.Spec.CSI.VolumeHandle
+	} else if pv.Spec.VsphereVolume != nil {
+		key = pv.Spec.VsphereVolume.VolumePath
+	}
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(key))
+	return int(hash.Sum32() % uint32(shardCount))
+}
+
+// getFullSyncPVResourceVersion returns the resource version of the given PV,
+// its PVC and the pods using the PVC, whose metadata is also synced to CNS.
+func getFullSyncPVResourceVersion(pv *v1.PersistentVolume, pvToPVCMap pvcMap, pvcToPodMap podMap) string {
+	resourceVersion := pv.ResourceVersion
+	if pvc, ok := pvToPVCMap[pv.Name]; ok {
+		resourceVersion += "/" + pvc.ResourceVersion
+		var podVersions []string
+		for _, pod := range pvcToPodMap[pvc.Namespace+"/"+pvc.Name] {
+			podVersions = append(podVersions, pod.Name+":"+pod.ResourceVersion)
+		}
+		sort.Strings(podVersions)
+		resourceVersion += "/" + strings.Join(podVersions, ",")
+	}
+	return resourceVersion
+}
+
+// getFullSyncShardPVs returns the PVs which belong to the given shard.
+func getFullSyncShardPVs(pvs []*v1.PersistentVolume, shard int, shardCount int,
+	shardBy string) []*v1.PersistentVolume {
+	if shardCount <= 1 {
+		return pvs
+	}
+	var shardPVs []*v1.PersistentVolume
+	for _, pv := range pvs {
+		if getFullSyncShardForPV(pv, shardBy, shardCount) == shard {
+			shardPVs = append(shardPVs, pv)
+		}
+	}
+	return shardPVs
+}
+
+// getFullSyncChangedPVs returns the PVs which were not found in sync with CNS
+// within the given interval, or have changed since.
+func getFullSyncChangedPVs(ctx context.Context, pvs []*v1.PersistentVolume, pvToPVCMap pvcMap,
+	pvcToPodMap podMap, interval time.Duration) []*v1.PersistentVolume {
+	log := logger.GetLogger(ctx)
+	if interval == 0 {
+		return pvs
+	}
+	fullSyncShardLock.Lock()
+	defer fullSyncShardLock.Unlock()
+	var changedPVs []*v1.PersistentVolume
+	for _, pv := range pvs {
+		state, ok := fullSyncSyncedPVs[pv.Name]
+		if ok && state.resourceVersion == getFullSyncPVResourceVersion(pv, pvToPVCMap, pvcToPodMap) &&
+			time.Since(state.syncedTime) < interval {
+			continue
+		}
+		changedPVs = append(changedPVs, pv)
+	}
+	log.Infof("FullSync: skipping %d unchanged PVs", len(pvs)-len(changedPVs))
+	return changedPVs
+}
+
+// markFullSyncPVsInSync records the given PVs, which have no pending create
+// or update operations, as being in sync with CNS. PVs no longer present are
+// removed from the records.
+func markFullSyncPVsInSync(allPVs []*v1.PersistentVolume, pvs []*v1.PersistentVolume, pvToPVCMap pvcMap,
+	pvcToPodMap podMap, createSpecArray []cnstypes.CnsVolumeCreateSpec,
+	updateSpecArray []cnstypes.CnsVolumeMetadataUpdateSpec) {
+	pending := make(map[string]bool)
+	for _, createSpec := range createSpecArray {
+		pending[createSpec.Name] = true
+	}
+	updatedVolumes := make(map[string]bool)
+	for _, updateSpec := range updateSpecArray {
+		updatedVolumes[updateSpec.VolumeId.Id] = true
+	}
+	fullSyncShardLock.Lock()
+	defer fullSyncShardLock.Unlock()
+	existingPVs := make(map[string]bool)
+	for _, pv := range allPVs {
+		existingPVs[pv.Name] = true
+	}
+	for pvName := range fullSyncSyncedPVs {
+		if !existingPVs[pvName] {
+			delete(fullSyncSyncedPVs, pvName)
+		}
+	}
+	now := time.Now()
+	for _, pv := range pvs {
+		if pending[pv.Name] || pv.Spec.CSI == nil || updatedVolumes[pv.Spec.CSI.VolumeHandle] {
+			delete(fullSyncSyncedPVs, pv.Name)
+			continue
+		}
+		if _, ok := cnsCreationMap[pv.Spec.CSI.VolumeHandle]; ok {
+			delete(fullSyncSyncedPVs, pv.Name)
+			continue
+		}
+		fullSyncSyncedPVs[pv.Name] = fullSyncPVState{
+			resourceVersion: getFullSyncPVResourceVersion(pv, pvToPVCMap, pvcToPodMap),
+			syncedTime:      now,
+		}
+	}
+}
@@ -49,6 +49,7 @@ import (
 	volumes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
 	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
 	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	csifault "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/fault"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/utils"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco"
@@ -392,6 +393,11 @@ func InitMetadataSyncer(ctx context.Context, clusterFlavor cnstypes.CnsClusterFl
 		}
 	}
 
+	metadataSyncer.eventRecorder = k8s.NewEventRecorder(k8sClient, csitypes.Name)
+	if interval := getMetadataUpdateBatchInterval(ctx); interval > 0 {
+		metadataSyncer.metadataUpdateQueue = newMetadataUpdateQueue(interval, getMetadataUpdateBatchSize(ctx))
+		go metadataSyncer.metadataUpdateQueue.run(ctx, metadataSyncer)
+	}
 	// Set up kubernetes resource listeners for metadata syncer.
 	metadataSyncer.k8sInformerManager = k8s.NewInformer(ctx, k8sClient, true)
 	metadataSyncer.k8sInformerManager.AddPVCListener(
@@ -420,6 +426,10 @@ func InitMetadataSyncer(ctx context.Context, clusterFlavor cnstypes.CnsClusterFl
 		func(obj interface{}) { // Delete.
 			podDeleted(obj, metadataSyncer)
 		})
+	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.FileVolumeScopedNetPermissions) {
+		initNetPermissionsListeners(ctx, k8sClient, metadataSyncer)
+	}
 	metadataSyncer.pvLister = metadataSyncer.k8sInformerManager.GetPVLister()
 	metadataSyncer.pvcLister = metadataSyncer.k8sInformerManager.GetPVCLister()
 	metadataSyncer.podLister = metadataSyncer.k8sInformerManager.GetPodLister()
@@ -428,6 +438,13 @@ func InitMetadataSyncer(ctx context.Context, clusterFlavor cnstypes.CnsClusterFl
 		return logger.LogNewError(log, "Failed to sync informer caches")
 	}
 	log.Infof("Initialized metadata syncer")
+	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.BlockVolumeSnapshot) {
+		if err = initVolumeSnapshotInformers(ctx, metadataSyncer); err != nil {
+			log.Warnf("Snapshots will not be reconciled by full sync and VolumeSnapshot metadata will not be "+
+				"pushed to CNS. Err: %v", err)
+		}
+	}
 
 	fullSyncTicker := time.NewTicker(time.Duration(getFullSyncIntervalInMin(ctx)) * time.Minute)
 	defer fullSyncTicker.Stop()
@@ -479,6 +496,13 @@ func InitMetadataSyncer(ctx context.Context, clusterFlavor cnstypes.CnsClusterFl
 		log.Infof("%q feature flag is not enabled. Using the traditional way to directly invoke full sync",
 			common.TriggerCsiFullSync)
 
+		// The shard processed by full sync is checkpointed in the
+		// TriggerCsiFullSync status, if the instance exists, so that full sync
+		// resumes from it when the syncer restarts.
+		var cnsOperatorClient client.Client
+		if metadataSyncer.clusterFlavor != cnstypes.CnsClusterFlavorGuest {
+			cnsOperatorClient = resumeFullSyncShard(ctx)
+		}
 		go func() {
 			for ; true; <-fullSyncTicker.C {
 				log.Infof("fullSync is triggered")
@@ -492,6 +516,7 @@ func InitMetadataSyncer(ctx context.Context, clusterFlavor cnstypes.CnsClusterFl
 					if err != nil {
 						log.Infof("CSI full sync failed with error: %+v", err)
 					}
+					checkpointFullSyncShard(ctx, cnsOperatorClient)
 				} else {
 					//  TODO: Multi-VC : Temporary disabled full sync for development
 					if !isMultiVCenterFssEnabled {
@@ -499,6 +524,7 @@ func InitMetadataSyncer(ctx context.Context, clusterFlavor cnstypes.CnsClusterFl
 						if err != nil {
 							log.Infof("CSI full sync failed with error: %+v", err)
 						}
+						checkpointFullSyncShard(ctx, cnsOperatorClient)
 					}
 				}
 			}
@@ -531,6 +557,27 @@ func InitMetadataSyncer(ctx context.Context, clusterFlavor cnstypes.CnsClusterFl
 		}
 	}
 
+	// Trigger storage policy compliance check on vanilla cluster. The feature
+	// state is read on each tick, so that the check can be enabled or disabled
+	// without restarting the syncer.
+	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla {
+		storagePolicyComplianceTicker := time.NewTicker(time.Duration(
+			getStoragePolicyComplianceIntervalInMin(ctx)) * time.Minute)
+		defer storagePolicyComplianceTicker.Stop()
+		remediation := newStoragePolicyRemediation()
+		go func() {
+			for ; true; <-storagePolicyComplianceTicker.C {
+				ctx, log := logger.GetNewContextWithLogger()
+				if !metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.StoragePolicyCompliance) {
+					log.Debugf("storage policy compliance check is disabled")
+					continue
+				}
+				log.Info("storage policy compliance check is triggered")
+				csiCheckStoragePolicyCompliance(ctx, k8sClient, metadataSyncer, remediation)
+			}
+		}()
+	}
+
 	volumeHealthTicker := time.NewTicker(time.Duration(getVolumeHealthIntervalInMin(ctx)) * time.Minute)
 	defer volumeHealthTicker.Stop()
 
@@ -814,6 +861,60 @@ func getTriggerCsiFullSyncInstance(ctx context.Context,
 	return triggerCsiFullSyncInstance, nil
 }
 
+// resumeFullSyncShard sets the shard to be processed by the next full sync
+// from the checkpoint in the TriggerCsiFullSync status, if the instance exists
+// and was checkpointed with the same shard count. It returns the client used
+// to checkpoint the shard, or nil if the instance cannot be read.
+func resumeFullSyncShard(ctx context.Context) client.Client {
+	log := logger.GetLogger(ctx)
+	restConfig, err := config.GetConfig()
+	if err != nil {
+		log.Warnf("failed to get Kubernetes config, full sync will not be checkpointed. Err: %+v", err)
+		return nil
+	}
+	cnsOperatorClient, err := k8s.NewClientForGroup(ctx, restConfig, cnsoperatorv1alpha1.GroupName)
+	if err != nil {
+		log.Warnf("failed to create CnsOperator client, full sync will not be checkpointed. Err: %+v", err)
+		return nil
+	}
+	instance, err := getTriggerCsiFullSyncInstance(ctx, cnsOperatorClient)
+	if err != nil {
+		log.Infof("TriggerCsiFullSync instance %q is not found, full sync will not be checkpointed. Err: %v",
+			common.TriggerCsiFullSyncCRName, err)
+		return nil
+	}
+	if _, shardCount := GetFullSyncShard(ctx); shardCount == instance.Status.ShardCount {
+		SetFullSyncNextShard(instance.Status.NextShard)
+		log.Infof("FullSync: resuming from shard %d of %d", instance.Status.NextShard, shardCount)
+	}
+	return cnsOperatorClient
+}
+
+// checkpointFullSyncShard writes the shard to be processed by the next full
+// sync to the TriggerCsiFullSync status. It is a no-op if the given client
+// is nil.
+func checkpointFullSyncShard(ctx context.Context, cnsOperatorClient client.Client) {
+	log := logger.GetLogger(ctx)
+	if cnsOperatorClient == nil {
+		return
+	}
+	instance, err := getTriggerCsiFullSyncInstance(ctx, cnsOperatorClient)
+	if err != nil {
+		log.Warnf("failed to get TriggerCsiFullSync instance %q to checkpoint full sync. Err: %v",
+			common.TriggerCsiFullSyncCRName, err)
+		return
+	}
+	nextShard, shardCount := GetFullSyncShard(ctx)
+	if instance.Status.NextShard == nextShard && instance.Status.ShardCount == shardCount {
+		return
+	}
+	instance.Status.NextShard, instance.Status.ShardCount = nextShard, shardCount
+	if err = updateTriggerCsiFullSyncInstance(ctx, cnsOperatorClient, instance); err != nil {
+		log.Warnf("failed to checkpoint full sync in TriggerCsiFullSync instance %q. Err: %v",
+			common.TriggerCsiFullSyncCRName, err)
+	}
+}
+
 // updateTriggerCsiFullSyncInstance updates the full sync instance with
 // name "csifullsync".
 func updateTriggerCsiFullSyncInstance(ctx context.Context,
@@ -869,6 +970,14 @@ func ReloadConfiguration(metadataSyncer *metadataSyncInformer, reconnectToVCFrom
 			return logger.LogNewErrorf(log, "failed to create supervisorClient. Error: %+v", err)
 		}
 	} else {
+		username := cnsvsphere.GetUsername(metadataSyncer.host,
+			metadataSyncer.configInfo.Cfg.VirtualCenter[metadataSyncer.host].User)
+		if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla {
+			// Watch the credentials Secrets referenced by the new config.
+			if err = k8s.WatchCredentialsSecrets(ctx, cfg, common.GetCSINamespace()); err != nil {
+				return logger.LogNewErrorf(log, "failed to watch the vCenter credentials Secrets. Err: %v", err)
+			}
+		}
 		newVCConfig, err := cnsvsphere.GetVirtualCenterConfig(ctx, cfg)
 		if err != nil {
 			return logger.LogNewErrorf(log, "failed to get VirtualCenterConfig. err=%v", err)
@@ -876,7 +985,7 @@ func ReloadConfiguration(metadataSyncer *metadataSyncInformer, reconnectToVCFrom
 		if newVCConfig != nil {
 			var vcenter *cnsvsphere.VirtualCenter
 			if metadataSyncer.host != newVCConfig.Host ||
-				metadataSyncer.configInfo.Cfg.VirtualCenter[metadataSyncer.host].User != newVCConfig.Username ||
+				username != cnsvsphere.GetUsername(newVCConfig.Host, newVCConfig.Username) ||
 				metadataSyncer.configInfo.Cfg.VirtualCenter[metadataSyncer.host].Password != newVCConfig.Password ||
 				reconnectToVCFromNewConfig {
 				// Verify if new configuration has valid credentials by connecting
@@ -1364,6 +1473,9 @@ func csiPVCUpdated(ctx context.Context, pvc *v1.PersistentVolumeClaim,
 		if err != nil {
 			log.Errorf("PVC Updated: Failed to get VolumeID from volumeMigrationService for migration VolumeSpec: %v "+
 				"with error %+v", migrationVolumeSpec, err)
+			recordFaultEvent(ctx, metadataSyncer, pvc, common.UpdateVolumeMetadataFailedReason, "",
+				fmt.Errorf("failed to get volume ID for volume path %q. Error: %v",
+					pv.Spec.VsphereVolume.VolumePath, err))
 			return
 		}
 	} else {
@@ -1400,6 +1512,8 @@ func csiPVCUpdated(ctx context.Context, pvc *v1.PersistentVolumeClaim,
 		if err != nil {
 			log.Errorf("PVCUpdated: Error occurred while polling to check if volume is marked as container volume. "+
 				"err: %+v", err)
+			recordFaultEvent(ctx, metadataSyncer, pvc, common.UpdateVolumeMetadataFailedReason, "",
+				fmt.Errorf("failed to check if volume %q is registered with CNS. Error: %v", volumeHandle, err))
 			return
 		}
 
@@ -1407,6 +1521,8 @@ func csiPVCUpdated(ctx context.Context, pvc *v1.PersistentVolumeClaim,
 			// volumeFound will be false when wait poll times out.
 			log.Errorf("PVCUpdated: volume: %q is not marked as the container volume. Skipping PVC entity metadata update",
 				volumeHandle)
+			recordFaultEvent(ctx, metadataSyncer, pvc, common.UpdateVolumeMetadataFailedReason,
+				csifault.CSINotFoundFault, fmt.Errorf("volume %q is not registered with CNS", volumeHandle))
 			return
 		}
 	}
@@ -1427,7 +1543,7 @@ func csiPVCUpdated(ctx context.Context, pvc *v1.PersistentVolumeClaim,
 
 	metadataList = append(metadataList, cnstypes.BaseCnsEntityMetadata(pvcMetadata))
 	containerCluster := cnsvsphere.GetContainerCluster(clusterIDforVolumeMetadata,
-		vcHostObj.User, metadataSyncer.clusterFlavor,
+		cnsvsphere.GetUsername(vcHost, vcHostObj.User), metadataSyncer.clusterFlavor,
 		metadataSyncer.configInfo.Cfg.Global.ClusterDistribution)
 
 	updateSpec := &cnstypes.CnsVolumeMetadataUpdateSpec{
@@ -1441,9 +1557,16 @@ func csiPVCUpdated(ctx context.Context, pvc *v1.PersistentVolumeClaim,
 		},
 	}
 
+	if metadataSyncer.metadataUpdateQueue != nil {
+		log.Debugf("PVCUpdated: Queueing metadata update with updateSpec: %+v", spew.Sdump(updateSpec))
+		metadataSyncer.metadataUpdateQueue.add(cnsVolumeMgr, updateSpec, pvc)
+		return
+	}
 	log.Debugf("PVCUpdated: Calling UpdateVolumeMetadata with updateSpec: %+v", spew.Sdump(updateSpec))
 	if err := cnsVolumeMgr.UpdateVolumeMetadata(ctx, updateSpec); err != nil {
 		log.Errorf("PVCUpdated: UpdateVolumeMetadata failed with err %v", err)
+		recordFaultEvent(ctx, metadataSyncer, pvc, common.UpdateVolumeMetadataFailedReason, "",
+			fmt.Errorf("failed to update metadata of volume %q in CNS. Error: %v", volumeHandle, err))
 	}
 }
 
@@ -1452,6 +1575,10 @@ func csiPVCUpdated(ctx context.Context, pvc *v1.PersistentVolumeClaim,
 func csiPVCDeleted(ctx context.Context, pvc *v1.PersistentVolumeClaim,
 	pv *v1.PersistentVolume, metadataSyncer *metadataSyncInformer) {
 	log := logger.GetLogger(ctx)
+	if metadataSyncer.metadataUpdateQueue != nil && pv.Spec.CSI != nil {
+		metadataSyncer.metadataUpdateQueue.removeEntity(pv.Spec.CSI.VolumeHandle,
+			string(cnstypes.CnsKubernetesEntityTypePVC), pvc.Name, pvc.Namespace)
+	}
 	// Volume will be deleted by controller when reclaim policy is delete.
 	if pv.Spec.PersistentVolumeReclaimPolicy == v1.PersistentVolumeReclaimDelete {
 		log.Debugf("PVCDeleted: Reclaim policy is delete")
@@ -1500,7 +1627,8 @@ func csiPVCDeleted(ctx context.Context, pvc *v1.PersistentVolumeClaim,
 	}
 
 	containerCluster := cnsvsphere.GetContainerCluster(clusterIDforVolumeMetadata,
-		vcHostObj.User, metadataSyncer.clusterFlavor, metadataSyncer.configInfo.Cfg.Global.ClusterDistribution)
+		cnsvsphere.GetUsername(vcHost, vcHostObj.User), metadataSyncer.clusterFlavor,
+		metadataSyncer.configInfo.Cfg.Global.ClusterDistribution)
 	updateSpec := &cnstypes.CnsVolumeMetadataUpdateSpec{
 		VolumeId: cnstypes.CnsVolumeId{
 			Id: volumeHandle,
@@ -1532,7 +1660,8 @@ func csiPVUpdated(ctx context.Context, newPv *v1.PersistentVolume, oldPv *v1.Per
 	var volumeHandle string
 	var err error
 	containerCluster := cnsvsphere.GetContainerCluster(clusterIDforVolumeMetadata,
-		metadataSyncer.configInfo.Cfg.VirtualCenter[metadataSyncer.host].User, metadataSyncer.clusterFlavor,
+		cnsvsphere.GetUsername(metadataSyncer.host,
+			metadataSyncer.configInfo.Cfg.VirtualCenter[metadataSyncer.host].User), metadataSyncer.clusterFlavor,
 		metadataSyncer.configInfo.Cfg.Global.ClusterDistribution)
 	if metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.CSIMigration) && newPv.Spec.VsphereVolume != nil {
 		// In case if feature state switch is enabled after syncer is deployed,
@@ -1547,6 +1676,9 @@ func csiPVUpdated(ctx context.Context, newPv *v1.PersistentVolume, oldPv *v1.Per
 		if err != nil {
 			log.Errorf("PVUpdated: Failed to get VolumeID from volumeMigrationService for volumePath: %s with error %+v",
 				newPv.Spec.VsphereVolume.VolumePath, err)
+			recordFaultEvent(ctx, metadataSyncer, newPv, common.UpdateVolumeMetadataFailedReason, "",
+				fmt.Errorf("failed to get volume ID for volume path %q. Error: %v",
+					newPv.Spec.VsphereVolume.VolumePath, err))
 			return
 		}
 	} else {
@@ -1586,6 +1718,8 @@ func csiPVUpdated(ctx context.Context, newPv *v1.PersistentVolume, oldPv *v1.Per
 		queryResult, err := metadataSyncer.volumeManager.QueryAllVolume(ctx, queryFilter, cnstypes.CnsQuerySelection{})
 		if err != nil {
 			log.Errorf("PVUpdated: QueryVolume failed for volume %q with err=%+v", oldPv.Spec.CSI.VolumeHandle, err.Error())
+			recordFaultEvent(ctx, metadataSyncer, newPv, common.RegisterVolumeFailedReason, "",
+				fmt.Errorf("failed to query volume %q in CNS. Error: %v", oldPv.Spec.CSI.VolumeHandle, err))
 			return
 		}
 		if len(queryResult.Volumes) == 0 {
@@ -1616,9 +1750,11 @@ func csiPVUpdated(ctx context.Context, newPv *v1.PersistentVolume, oldPv *v1.Per
 			}
 			log.Debugf("PVUpdated: vSphere CSI Driver is creating volume %q with create spec %+v",
 				oldPv.Name, spew.Sdump(createSpec))
-			_, _, err := metadataSyncer.volumeManager.CreateVolume(ctx, createSpec)
+			_, faultType, err := metadataSyncer.volumeManager.CreateVolume(ctx, createSpec)
 			if err != nil {
 				log.Errorf("PVUpdated: Failed to create disk %s with error %+v", oldPv.Name, err)
+				recordPVFaultEvent(ctx, metadataSyncer, newPv, common.RegisterVolumeFailedReason, faultType,
+					fmt.Errorf("failed to register volume %q with CNS. Error: %v", oldPv.Spec.CSI.VolumeHandle, err))
 			} else {
 				log.Infof("PVUpdated: vSphere CSI Driver has successfully marked volume: %q as the container volume.",
 					oldPv.Spec.CSI.VolumeHandle)
@@ -1649,10 +1785,18 @@ func csiPVUpdated(ctx context.Context, newPv *v1.PersistentVolume, oldPv *v1.Per
 		},
 	}
 
+	if metadataSyncer.metadataUpdateQueue != nil {
+		log.Debugf("PVUpdated: Queueing metadata update for volume %q with updateSpec: %+v",
+			updateSpec.VolumeId.Id, spew.Sdump(updateSpec))
+		metadataSyncer.metadataUpdateQueue.add(metadataSyncer.volumeManager, updateSpec, newPv)
+		return
+	}
 	log.Debugf("PVUpdated: Calling UpdateVolumeMetadata for volume %q with updateSpec: %+v",
 		updateSpec.VolumeId.Id, spew.Sdump(updateSpec))
 	if err := metadataSyncer.volumeManager.UpdateVolumeMetadata(ctx, updateSpec); err != nil {
 		log.Errorf("PVUpdated: UpdateVolumeMetadata failed with err %v", err)
+		recordFaultEvent(ctx, metadataSyncer, newPv, common.UpdateVolumeMetadataFailedReason, "",
+			fmt.Errorf("failed to update metadata of volume %q in CNS. Error: %v", volumeHandle, err))
 		return
 	}
 	log.Debugf("PVUpdated: UpdateVolumeMetadata succeed for the volume %q with updateSpec: %+v",
@@ -1663,6 +1807,16 @@ func csiPVUpdated(ctx context.Context, newPv *v1.PersistentVolume, oldPv *v1.Per
 // Vanills k8s and supervisor cluster.
 func csiPVDeleted(ctx context.Context, pv *v1.PersistentVolume, metadataSyncer *metadataSyncInformer) {
 	log := logger.GetLogger(ctx)
+	if metadataSyncer.metadataUpdateQueue != nil && pv.Spec.CSI != nil {
+		metadataSyncer.metadataUpdateQueue.remove(pv.Spec.CSI.VolumeHandle)
+	}
+	if _, ok := pv.Annotations[common.AnnVolumeRelocation]; ok {
+		// PV is recreated by a volume relocation controller. The CNS volume
+		// is still in use and must not be deleted.
+		log.Infof("PVDeleted: PV %q is being recreated after volume relocation. Skipping deletion of "+
+			"the CNS volume", pv.Name)
+		return
+	}
 	if pv.Spec.ClaimRef != nil && pv.Status.Phase == v1.VolumeReleased &&
 		pv.Spec.PersistentVolumeReclaimPolicy == v1.PersistentVolumeReclaimDelete {
 		log.Debugf("PVDeleted: Volume deletion will be handled by Controller")
@@ -1705,7 +1859,8 @@ func csiPVDeleted(ctx context.Context, pv *v1.PersistentVolume, metadataSyncer *
 		metadataList = append(metadataList, cnstypes.BaseCnsEntityMetadata(pvMetadata))
 
 		containerCluster := cnsvsphere.GetContainerCluster(clusterIDforVolumeMetadata,
-			vcHostObj.User, metadataSyncer.clusterFlavor, metadataSyncer.configInfo.Cfg.Global.ClusterDistribution)
+			cnsvsphere.GetUsername(vcHost, vcHostObj.User), metadataSyncer.clusterFlavor,
+			metadataSyncer.configInfo.Cfg.Global.ClusterDistribution)
 		updateSpec := &cnstypes.CnsVolumeMetadataUpdateSpec{
 			VolumeId: cnstypes.CnsVolumeId{
 				Id: pv.Spec.CSI.VolumeHandle,
@@ -1912,7 +2067,7 @@ func csiUpdatePod(ctx context.Context, pod *v1.Pod, metadataSyncer *metadataSync
 		}
 
 		containerCluster := cnsvsphere.GetContainerCluster(clusterIDforVolumeMetadata,
-			vcHostObj.User, metadataSyncer.clusterFlavor,
+			cnsvsphere.GetUsername(vcHost, vcHostObj.User), metadataSyncer.clusterFlavor,
 			metadataSyncer.configInfo.Cfg.Global.ClusterDistribution)
 		updateSpec := &cnstypes.CnsVolumeMetadataUpdateSpec{
 			VolumeId: cnstypes.CnsVolumeId{
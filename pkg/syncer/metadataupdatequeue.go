@@ -0,0 +1,292 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	volumes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+const (
+	// defaultMetadataUpdateBatchSize is the default maximum number of volumes
+	// whose metadata is updated by a single CNS task.
+	defaultMetadataUpdateBatchSize = 100
+	// metadataUpdateMaxDelayFactor bounds, as a multiple of the batch interval,
+	// how long successive changes can postpone the metadata update of a volume.
+	metadataUpdateMaxDelayFactor = 5
+)
+
+// pendingMetadataUpdate is a metadata update of a volume waiting in the
+// metadata update queue.
+type pendingMetadataUpdate struct {
+	// volumeManager is the volume manager of the vCenter the volume is on.
+	volumeManager volumes.Manager
+	// spec is the update spec, with all the changes queued for the volume
+	// merged into it.
+	spec cnstypes.CnsVolumeMetadataUpdateSpec
+	// object is the PV or PVC on which an event is recorded if the update
+	// fails.
+	object runtime.Object
+	// queuedTime is the time at which the first change was queued.
+	queuedTime time.Time
+	// lastQueuedTime is the time at which the latest change was queued.
+	lastQueuedTime time.Time
+}
+
+// metadataUpdateQueue coalesces the metadata updates of PVs and PVCs per
+// volume and submits them to CNS in batches, once no more changes have been
+// queued for a volume for the batch interval.
+type metadataUpdateQueue struct {
+	lock *sync.Mutex
+	// pending maps the volume ID to its pending metadata update.
+	pending map[string]*pendingMetadataUpdate
+	// interval is the time for which a volume must have no new changes before
+	// its metadata is updated.
+	interval time.Duration
+	// batchSize is the maximum number of volumes updated by a single CNS task.
+	batchSize int
+}
+
+// getMetadataUpdateBatchInterval returns the interval for which metadata
+// updates of a volume are coalesced before being submitted to CNS. If
+// environment variable METADATA_UPDATE_BATCH_INTERVAL_SECONDS is set and
+// valid, return the value read from environment variable. Otherwise, use 0,
+// which updates the metadata of volumes immediately.
+func getMetadataUpdateBatchInterval(ctx context.Context) time.Duration {
+	log := logger.GetLogger(ctx)
+	var interval time.Duration
+	if v := os.Getenv("METADATA_UPDATE_BATCH_INTERVAL_SECONDS"); v != "" {
+		if value, err := strconv.Atoi(v); err == nil && value >= 0 {
+			interval = time.Duration(value) * time.Second
+		} else {
+			log.Warnf("Interval set in env variable METADATA_UPDATE_BATCH_INTERVAL_SECONDS %s "+
+				"is invalid, will update volume metadata immediately", v)
+		}
+	}
+	return interval
+}
+
+// getMetadataUpdateBatchSize returns the maximum number of volumes whose
+// metadata is updated by a single CNS task. If environment variable
+// METADATA_UPDATE_BATCH_SIZE is set and valid, return the value read from
+// environment variable. Otherwise, use the default value.
+func getMetadataUpdateBatchSize(ctx context.Context) int {
+	log := logger.GetLogger(ctx)
+	batchSize := defaultMetadataUpdateBatchSize
+	if v := os.Getenv("METADATA_UPDATE_BATCH_SIZE"); v != "" {
+		if value, err := strconv.Atoi(v); err == nil && value > 0 {
+			batchSize = value
+		} else {
+			log.Warnf("Batch size set in env variable METADATA_UPDATE_BATCH_SIZE %s "+
+				"is invalid, will use the default value %d", v, defaultMetadataUpdateBatchSize)
+		}
+	}
+	return batchSize
+}
+
+// newMetadataUpdateQueue returns a metadataUpdateQueue with the given batch
+// interval and batch size.
+func newMetadataUpdateQueue(interval time.Duration, batchSize int) *metadataUpdateQueue {
+	return &metadataUpdateQueue{
+		lock:      &sync.Mutex{},
+		pending:   make(map[string]*pendingMetadataUpdate),
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// getEntityMetadataKey returns the key identifying the Kubernetes entity the
+// given entity metadata belongs to.
+func getEntityMetadataKey(entityMetadata cnstypes.BaseCnsEntityMetadata) string {
+	if k8sEntityMetadata, ok := entityMetadata.(*cnstypes.CnsKubernetesEntityMetadata); ok {
+		return k8sEntityMetadata.EntityType + "/" + k8sEntityMetadata.Namespace + "/" +
+			k8sEntityMetadata.EntityName
+	}
+	return entityMetadata.GetCnsEntityMetadata().EntityName
+}
+
+// mergeEntityMetadata merges the entity metadata in newer into older. The
+// metadata of an entity in newer replaces the metadata of the same entity in
+// older.
+func mergeEntityMetadata(older []cnstypes.BaseCnsEntityMetadata,
+	newer []cnstypes.BaseCnsEntityMetadata) []cnstypes.BaseCnsEntityMetadata {
+	merged := make([]cnstypes.BaseCnsEntityMetadata, 0, len(older)+len(newer))
+	index := make(map[string]int)
+	for _, entityMetadataList := range [][]cnstypes.BaseCnsEntityMetadata{older, newer} {
+		for _, entityMetadata := range entityMetadataList {
+			key := getEntityMetadataKey(entityMetadata)
+			if i, ok := index[key]; ok {
+				merged[i] = entityMetadata
+				continue
+			}
+			index[key] = len(merged)
+			merged = append(merged, entityMetadata)
+		}
+	}
+	return merged
+}
+
+// add queues the given metadata update of a volume. If an update is already
+// pending for the volume, the changes are merged into it.
+func (q *metadataUpdateQueue) add(volumeManager volumes.Manager, spec *cnstypes.CnsVolumeMetadataUpdateSpec,
+	object runtime.Object) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	now := time.Now()
+	volumeID := spec.VolumeId.Id
+	if update, ok := q.pending[volumeID]; ok {
+		entityMetadata := mergeEntityMetadata(update.spec.Metadata.EntityMetadata, spec.Metadata.EntityMetadata)
+		update.spec.Metadata = spec.Metadata
+		update.spec.Metadata.EntityMetadata = entityMetadata
+		update.volumeManager = volumeManager
+		update.object = object
+		update.lastQueuedTime = now
+		return
+	}
+	q.pending[volumeID] = &pendingMetadataUpdate{
+		volumeManager:  volumeManager,
+		spec:           *spec,
+		object:         object,
+		queuedTime:     now,
+		lastQueuedTime: now,
+	}
+	prometheus.MetadataUpdateQueueDepth.Set(float64(len(q.pending)))
+}
+
+// remove drops the pending metadata update of the given volume.
+func (q *metadataUpdateQueue) remove(volumeID string) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	delete(q.pending, volumeID)
+	prometheus.MetadataUpdateQueueDepth.Set(float64(len(q.pending)))
+}
+
+// removeEntity drops the pending metadata update of the given entity of the
+// given volume, so that a deleted entity is not added back to the volume.
+func (q *metadataUpdateQueue) removeEntity(volumeID string, entityType string, name string, namespace string) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	update, ok := q.pending[volumeID]
+	if !ok {
+		return
+	}
+	key := entityType + "/" + namespace + "/" + name
+	var entityMetadata []cnstypes.BaseCnsEntityMetadata
+	for _, metadata := range update.spec.Metadata.EntityMetadata {
+		if getEntityMetadataKey(metadata) != key {
+			entityMetadata = append(entityMetadata, metadata)
+		}
+	}
+	if len(entityMetadata) == 0 {
+		delete(q.pending, volumeID)
+	} else {
+		update.spec.Metadata.EntityMetadata = entityMetadata
+	}
+	prometheus.MetadataUpdateQueueDepth.Set(float64(len(q.pending)))
+}
+
+// popReady removes and returns the pending updates for which no changes have
+// been queued for the batch interval, or which have been postponed for too
+// long by successive changes.
+func (q *metadataUpdateQueue) popReady(now time.Time) []*pendingMetadataUpdate {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	var ready []*pendingMetadataUpdate
+	for volumeID, update := range q.pending {
+		if now.Sub(update.lastQueuedTime) >= q.interval ||
+			now.Sub(update.queuedTime) >= metadataUpdateMaxDelayFactor*q.interval {
+			ready = append(ready, update)
+			delete(q.pending, volumeID)
+		}
+	}
+	prometheus.MetadataUpdateQueueDepth.Set(float64(len(q.pending)))
+	return ready
+}
+
+// run submits the ready metadata updates to CNS until the given context is
+// done.
+func (q *metadataUpdateQueue) run(ctx context.Context, metadataSyncer *metadataSyncInformer) {
+	log := logger.GetLogger(ctx)
+	log.Infof("Starting metadata update queue with batch interval %v and batch size %d", q.interval, q.batchSize)
+	ticker := time.NewTicker(q.interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			q.flush(ctx, metadataSyncer, q.popReady(time.Now()))
+		}
+	}
+}
+
+// flush updates the metadata of the given volumes, batching the updates of
+// the volumes on the same vCenter. Failed updates are not retried; they are
+// reconciled by full sync.
+func (q *metadataUpdateQueue) flush(ctx context.Context, metadataSyncer *metadataSyncInformer,
+	updates []*pendingMetadataUpdate) {
+	log := logger.GetLogger(ctx)
+	updatesPerManager := make(map[volumes.Manager][]*pendingMetadataUpdate)
+	for _, update := range updates {
+		updatesPerManager[update.volumeManager] = append(updatesPerManager[update.volumeManager], update)
+	}
+	for volumeManager, managerUpdates := range updatesPerManager {
+		for start := 0; start < len(managerUpdates); start += q.batchSize {
+			end := start + q.batchSize
+			if end > len(managerUpdates) {
+				end = len(managerUpdates)
+			}
+			batch := managerUpdates[start:end]
+			specs := make([]cnstypes.CnsVolumeMetadataUpdateSpec, 0, len(batch))
+			for _, update := range batch {
+				specs = append(specs, update.spec)
+			}
+			log.Debugf("Calling BatchUpdateVolumeMetadata for %d volumes", len(specs))
+			volumeErrors, err := volumeManager.BatchUpdateVolumeMetadata(ctx, specs)
+			if err != nil {
+				log.Errorf("BatchUpdateVolumeMetadata failed for %d volumes with err %v", len(specs), err)
+			}
+			for _, update := range batch {
+				volumeID := update.spec.VolumeId.Id
+				updateErr := err
+				if updateErr == nil {
+					updateErr = volumeErrors[volumeID]
+				}
+				status := prometheus.PrometheusPassStatus
+				if updateErr != nil {
+					status = prometheus.PrometheusFailStatus
+					recordFaultEvent(ctx, metadataSyncer, update.object, common.UpdateVolumeMetadataFailedReason, "",
+						fmt.Errorf("failed to update metadata of volume %q in CNS. Error: %v", volumeID, updateErr))
+				}
+				prometheus.MetadataUpdateQueueLatencyHistVec.WithLabelValues(status).Observe(
+					time.Since(update.queuedTime).Seconds())
+			}
+		}
+	}
+}
@@ -0,0 +1,326 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	clientset "k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/util/retry"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+// netPermissionsUpdateDelay is the time the updates of the net permissions
+// sources are coalesced for before re-applying the net permissions on the
+// file volumes.
+const netPermissionsUpdateDelay = 10 * time.Second
+
+// netPermissionsSyncer re-applies the scoped net permissions on file volumes
+// when their sources are updated. Updates are coalesced for a delay, so that a
+// burst of updates re-applies the net permissions once.
+type netPermissionsSyncer struct {
+	k8sClient          clientset.Interface
+	metadataSyncer     *metadataSyncInformer
+	storageClassLister storagelisters.StorageClassLister
+	namespaceLister    corelisters.NamespaceLister
+	source             common.NetPermissionsSource
+	delay              time.Duration
+
+	// lock protects the pending updates and the timer.
+	lock sync.Mutex
+	// updatedSources maps the StorageClass params used to reference the
+	// updated ConfigMaps or Secrets to their names.
+	updatedSources map[string]map[string]bool
+	// updatedNamespaces are the Namespaces with updated annotations.
+	updatedNamespaces map[string]bool
+	timer             *time.Timer
+	// applyLock serializes re-applying the net permissions.
+	applyLock sync.Mutex
+}
+
+// initNetPermissionsListeners sets up the listeners which re-apply the scoped
+// net permissions on file volumes when the ConfigMaps or Secrets referenced by
+// StorageClasses, or the net permissions annotation on Namespaces, change.
+func initNetPermissionsListeners(ctx context.Context, k8sClient clientset.Interface,
+	metadataSyncer *metadataSyncInformer) {
+	log := logger.GetLogger(ctx)
+	csiNamespace := common.GetCSINamespace()
+	informerManager := metadataSyncer.k8sInformerManager
+	syncer := &netPermissionsSyncer{
+		k8sClient:          k8sClient,
+		metadataSyncer:     metadataSyncer,
+		storageClassLister: informerManager.GetStorageClassLister(),
+		delay:              netPermissionsUpdateDelay,
+		updatedSources:     make(map[string]map[string]bool),
+		updatedNamespaces:  make(map[string]bool),
+	}
+	informerManager.AddConfigMapListener(ctx, k8sClient, csiNamespace,
+		nil, // Add.
+		func(oldObj interface{}, newObj interface{}) { // Update.
+			oldConfigMap, okOld := oldObj.(*v1.ConfigMap)
+			newConfigMap, okNew := newObj.(*v1.ConfigMap)
+			if !okOld || !okNew || reflect.DeepEqual(oldConfigMap.Data, newConfigMap.Data) {
+				return
+			}
+			syncer.enqueue(common.AttributeNetPermissionsConfigMap, newConfigMap.Name, "")
+		},
+		nil) // Delete.
+	informerManager.AddSecretListener(ctx, k8sClient, csiNamespace,
+		nil, // Add.
+		func(oldObj interface{}, newObj interface{}) { // Update.
+			oldSecret, okOld := oldObj.(*v1.Secret)
+			newSecret, okNew := newObj.(*v1.Secret)
+			if !okOld || !okNew || reflect.DeepEqual(oldSecret.Data, newSecret.Data) {
+				return
+			}
+			syncer.enqueue(common.AttributeNetPermissionsSecret, newSecret.Name, "")
+		},
+		nil) // Delete.
+	informerManager.AddNamespaceListener(
+		nil, // Add.
+		func(oldObj interface{}, newObj interface{}) { // Update.
+			oldNamespace, okOld := oldObj.(*v1.Namespace)
+			newNamespace, okNew := newObj.(*v1.Namespace)
+			if !okOld || !okNew || oldNamespace.Annotations[common.NetPermissionsNamespaceAnnotationKey] ==
+				newNamespace.Annotations[common.NetPermissionsNamespaceAnnotationKey] {
+				return
+			}
+			syncer.enqueue("", "", newNamespace.Name)
+		},
+		nil) // Delete.
+	syncer.namespaceLister = informerManager.GetNamespaceLister()
+	syncer.source = common.NewListerNetPermissionsSource(informerManager.GetFilteredConfigMapLister(),
+		informerManager.GetFilteredSecretLister(), syncer.namespaceLister)
+	log.Infof("Initialized listeners for scoped net permissions of file volumes")
+}
+
+// enqueue records the update of the ConfigMap or Secret with the given name,
+// referenced using the StorageClass param scParam, or of the given Namespace,
+// and schedules re-applying the net permissions if not already scheduled.
+func (s *netPermissionsSyncer) enqueue(scParam string, name string, namespace string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if scParam != "" {
+		if s.updatedSources[scParam] == nil {
+			s.updatedSources[scParam] = make(map[string]bool)
+		}
+		s.updatedSources[scParam][name] = true
+	}
+	if namespace != "" {
+		s.updatedNamespaces[namespace] = true
+	}
+	if s.timer == nil {
+		s.timer = time.AfterFunc(s.delay, s.process)
+	}
+}
+
+// takePending returns the pending updates and resets them.
+func (s *netPermissionsSyncer) takePending() (map[string]map[string]bool, map[string]bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sources, namespaces := s.updatedSources, s.updatedNamespaces
+	s.updatedSources = make(map[string]map[string]bool)
+	s.updatedNamespaces = make(map[string]bool)
+	s.timer = nil
+	return sources, namespaces
+}
+
+// process re-applies the net permissions on the file volumes affected by the
+// pending updates.
+func (s *netPermissionsSyncer) process() {
+	ctx, log := logger.GetNewContextWithLogger()
+	s.applyLock.Lock()
+	defer s.applyLock.Unlock()
+	sources, namespaces := s.takePending()
+	scNames, namespaces, err := s.getUpdatedScopes(ctx, sources, namespaces)
+	if err != nil {
+		log.Errorf("failed to get the scopes of the updated net permissions. Error: %+v", err)
+		return
+	}
+	if len(scNames) == 0 && len(namespaces) == 0 {
+		log.Debugf("Updated net permissions sources %v are not referenced", sources)
+		return
+	}
+	log.Infof("Net permissions updated for StorageClasses %v and Namespaces %v. Re-applying on file volumes",
+		scNames, namespaces)
+	applyNetPermissionsToFileVolumes(ctx, s.k8sClient, s.metadataSyncer, s.storageClassLister, s.source,
+		scNames, namespaces)
+}
+
+// getUpdatedScopes returns the StorageClasses and Namespaces which reference
+// the updated ConfigMaps or Secrets in sources, along with the given updated
+// namespaces.
+func (s *netPermissionsSyncer) getUpdatedScopes(ctx context.Context, sources map[string]map[string]bool,
+	namespaces map[string]bool) (map[string]bool, map[string]bool, error) {
+	scNames := make(map[string]bool)
+	if len(sources) == 0 {
+		return scNames, namespaces, nil
+	}
+	storageClasses, err := s.storageClassLister.List(labels.Everything())
+	if err != nil {
+		return nil, nil, logger.LogNewErrorf(logger.GetLogger(ctx),
+			"failed to list StorageClasses. Error: %+v", err)
+	}
+	for _, sc := range storageClasses {
+		if sc.Provisioner != csitypes.Name {
+			continue
+		}
+		for param, value := range sc.Parameters {
+			if sources[strings.ToLower(param)][value] {
+				scNames[sc.Name] = true
+			}
+		}
+	}
+	if configMaps := sources[common.AttributeNetPermissionsConfigMap]; len(configMaps) > 0 {
+		nsList, err := s.namespaceLister.List(labels.Everything())
+		if err != nil {
+			return nil, nil, logger.LogNewErrorf(logger.GetLogger(ctx),
+				"failed to list Namespaces. Error: %+v", err)
+		}
+		for _, ns := range nsList {
+			if configMaps[ns.Annotations[common.NetPermissionsNamespaceAnnotationKey]] {
+				namespaces[ns.Name] = true
+			}
+		}
+	}
+	return scNames, namespaces, nil
+}
+
+// applyNetPermissionsToFileVolumes re-applies the scoped net permissions on
+// the bound file volumes which use one of the given StorageClasses or belong
+// to one of the given Namespaces.
+func applyNetPermissionsToFileVolumes(ctx context.Context, k8sClient clientset.Interface,
+	metadataSyncer *metadataSyncInformer, storageClassLister storagelisters.StorageClassLister,
+	source common.NetPermissionsSource, scNames map[string]bool, namespaces map[string]bool) {
+	log := logger.GetLogger(ctx)
+	pvs, err := metadataSyncer.pvLister.List(labels.Everything())
+	if err != nil {
+		log.Errorf("failed to list PersistentVolumes. Error: %+v", err)
+		return
+	}
+	for _, pv := range pvs {
+		if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != csitypes.Name ||
+			pv.Spec.CSI.VolumeAttributes[common.AttributeDiskType] != common.DiskTypeFileVolume ||
+			pv.Spec.ClaimRef == nil || pv.Status.Phase != v1.VolumeBound {
+			continue
+		}
+		if !scNames[pv.Spec.StorageClassName] && !namespaces[pv.Spec.ClaimRef.Namespace] {
+			continue
+		}
+		err = applyNetPermissionsToFileVolume(ctx, k8sClient, metadataSyncer, storageClassLister, source, pv)
+		if err != nil {
+			log.Errorf("failed to apply net permissions on PV %q. Error: %+v", pv.Name, err)
+		}
+	}
+}
+
+// applyNetPermissionsToFileVolume computes the scoped net permissions for the
+// given file volume, configures them on the file share using
+// ConfigureVolumeACLs and records the client IPs on the PV annotation.
+func applyNetPermissionsToFileVolume(ctx context.Context, k8sClient clientset.Interface,
+	metadataSyncer *metadataSyncInformer, storageClassLister storagelisters.StorageClassLister,
+	source common.NetPermissionsSource, pv *v1.PersistentVolume) error {
+	log := logger.GetLogger(ctx)
+	var netPermissionsConfigMap, netPermissionsSecret string
+	if pv.Spec.StorageClassName != "" {
+		sc, err := storageClassLister.Get(pv.Spec.StorageClassName)
+		if err != nil {
+			return logger.LogNewErrorf(log, "failed to get StorageClass %q. Error: %+v",
+				pv.Spec.StorageClassName, err)
+		}
+		for param, value := range sc.Parameters {
+			switch strings.ToLower(param) {
+			case common.AttributeNetPermissionsConfigMap:
+				netPermissionsConfigMap = value
+			case common.AttributeNetPermissionsSecret:
+				netPermissionsSecret = value
+			}
+		}
+	}
+	netPermissions, err := common.GetScopedNetPermissions(ctx, source,
+		metadataSyncer.configInfo.Cfg.NetPermissions, netPermissionsConfigMap, netPermissionsSecret,
+		pv.Spec.ClaimRef.Namespace)
+	if err != nil {
+		return err
+	}
+	// Client IPs the file share is currently exported to. Volumes created
+	// before the scoped net permissions were enabled use the global ones.
+	appliedIPsData, ok := pv.Annotations[common.AppliedNetPermissionIPsAnnotationKey]
+	if !ok {
+		appliedIPsData, ok = pv.Spec.CSI.VolumeAttributes[common.AttributeNetPermissionIPs]
+	}
+	if !ok {
+		appliedIPsData = common.GetNetPermissionIPs(
+			common.MergeNetPermissions(metadataSyncer.configInfo.Cfg.NetPermissions))
+	}
+	appliedIPs, err := common.ParseNetPermissionIPs(appliedIPsData)
+	if err != nil {
+		return logger.LogNewErrorf(log, "failed to parse applied net permission IPs %q. Error: %+v",
+			appliedIPsData, err)
+	}
+
+	volumeID := pv.Spec.CSI.VolumeHandle
+	_, volumeManager, err := getVcHostAndVolumeManagerForVolumeID(ctx, metadataSyncer, volumeID)
+	if err != nil {
+		return err
+	}
+	spec := common.GetNetPermissionsACLSpec(volumeID, netPermissions, appliedIPs)
+	log.Debugf("Configuring net permissions for volume %q with spec: %+v", volumeID, spec)
+	err = volumeManager.ConfigureVolumeACLs(ctx, spec)
+	if err != nil {
+		return logger.LogNewErrorf(log, "failed to configure ACLs for volume %q. Error: %+v", volumeID, err)
+	}
+
+	err = setAppliedNetPermissionIPs(ctx, k8sClient, pv.Name, common.GetNetPermissionIPs(netPermissions))
+	if err != nil {
+		return logger.LogNewErrorf(log, "failed to update PV %q with applied net permissions. Error: %+v",
+			pv.Name, err)
+	}
+	log.Infof("Successfully applied net permissions on volume %q for PV %q", volumeID, pv.Name)
+	return nil
+}
+
+// setAppliedNetPermissionIPs records the given client IPs on the annotation of
+// the PV with the given name. The PV is read again on conflicts, so that the
+// client IPs are recorded even if the PV is updated concurrently.
+func setAppliedNetPermissionIPs(ctx context.Context, k8sClient clientset.Interface, pvName string,
+	appliedIPsData string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		pv, err := k8sClient.CoreV1().PersistentVolumes().Get(ctx, pvName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if pv.Annotations == nil {
+			pv.Annotations = make(map[string]string)
+		}
+		pv.Annotations[common.AppliedNetPermissionIPsAnnotationKey] = appliedIPsData
+		_, err = k8sClient.CoreV1().PersistentVolumes().Update(ctx, pv, metav1.UpdateOptions{})
+		return err
+	})
+}
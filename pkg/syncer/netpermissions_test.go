@@ -0,0 +1,153 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+func TestNetPermissionsSyncerCoalescesUpdates(t *testing.T) {
+	syncer := &netPermissionsSyncer{
+		delay:             time.Hour,
+		updatedSources:    make(map[string]map[string]bool),
+		updatedNamespaces: make(map[string]bool),
+	}
+	syncer.enqueue(common.AttributeNetPermissionsConfigMap, "cm-1", "")
+	timer := syncer.timer
+	syncer.enqueue(common.AttributeNetPermissionsConfigMap, "cm-1", "")
+	syncer.enqueue(common.AttributeNetPermissionsSecret, "secret-1", "")
+	syncer.enqueue("", "", "ns-1")
+	if timer == nil || syncer.timer != timer {
+		t.Fatalf("expected the updates to be scheduled once")
+	}
+	timer.Stop()
+
+	sources, namespaces := syncer.takePending()
+	expectedSources := map[string]map[string]bool{
+		common.AttributeNetPermissionsConfigMap: {"cm-1": true},
+		common.AttributeNetPermissionsSecret:    {"secret-1": true},
+	}
+	if !reflect.DeepEqual(sources, expectedSources) {
+		t.Errorf("expected updated sources %v, got %v", expectedSources, sources)
+	}
+	if !reflect.DeepEqual(namespaces, map[string]bool{"ns-1": true}) {
+		t.Errorf("unexpected updated namespaces %v", namespaces)
+	}
+	if syncer.timer != nil || len(syncer.updatedSources) != 0 || len(syncer.updatedNamespaces) != 0 {
+		t.Errorf("expected the pending updates to be reset")
+	}
+}
+
+func TestNetPermissionsSyncerGetUpdatedScopes(t *testing.T) {
+	scIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, sc := range []*storagev1.StorageClass{
+		{ObjectMeta: metav1.ObjectMeta{Name: "sc-cm"}, Provisioner: csitypes.Name,
+			Parameters: map[string]string{"netPermissionsConfigMap": "cm-1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "sc-secret"}, Provisioner: csitypes.Name,
+			Parameters: map[string]string{common.AttributeNetPermissionsSecret: "secret-1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "sc-other"}, Provisioner: "other.csi.driver",
+			Parameters: map[string]string{common.AttributeNetPermissionsConfigMap: "cm-1"}},
+	} {
+		if err := scIndexer.Add(sc); err != nil {
+			t.Fatalf("failed to add StorageClass: %v", err)
+		}
+	}
+	nsIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, ns := range []*v1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "ns-cm",
+			Annotations: map[string]string{common.NetPermissionsNamespaceAnnotationKey: "cm-1"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "ns-none"}},
+	} {
+		if err := nsIndexer.Add(ns); err != nil {
+			t.Fatalf("failed to add Namespace: %v", err)
+		}
+	}
+	syncer := &netPermissionsSyncer{
+		storageClassLister: storagelisters.NewStorageClassLister(scIndexer),
+		namespaceLister:    corelisters.NewNamespaceLister(nsIndexer),
+	}
+
+	scNames, namespaces, err := syncer.getUpdatedScopes(context.Background(),
+		map[string]map[string]bool{common.AttributeNetPermissionsConfigMap: {"cm-1": true}},
+		map[string]bool{"ns-updated": true})
+	if err != nil {
+		t.Fatalf("failed to get updated scopes: %v", err)
+	}
+	if !reflect.DeepEqual(scNames, map[string]bool{"sc-cm": true}) {
+		t.Errorf("unexpected StorageClasses %v", scNames)
+	}
+	if !reflect.DeepEqual(namespaces, map[string]bool{"ns-cm": true, "ns-updated": true}) {
+		t.Errorf("unexpected Namespaces %v", namespaces)
+	}
+
+	scNames, namespaces, err = syncer.getUpdatedScopes(context.Background(),
+		map[string]map[string]bool{common.AttributeNetPermissionsSecret: {"secret-1": true}},
+		map[string]bool{})
+	if err != nil {
+		t.Fatalf("failed to get updated scopes: %v", err)
+	}
+	if !reflect.DeepEqual(scNames, map[string]bool{"sc-secret": true}) || len(namespaces) != 0 {
+		t.Errorf("unexpected scopes %v and %v for an updated Secret", scNames, namespaces)
+	}
+}
+
+func TestSetAppliedNetPermissionIPsRetriesOnConflict(t *testing.T) {
+	ctx := context.Background()
+	pv := &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv-1",
+		Annotations: map[string]string{"other": "value"}}}
+	k8sClient := testclient.NewSimpleClientset(pv)
+	conflicts := 0
+	k8sClient.PrependReactor("update", "persistentvolumes",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if conflicts > 0 {
+				return false, nil, nil
+			}
+			conflicts++
+			return true, nil, apierrors.NewConflict(v1.Resource("persistentvolumes"), pv.Name,
+				errors.New("the object has been modified"))
+		})
+
+	if err := setAppliedNetPermissionIPs(ctx, k8sClient, pv.Name, `["*"]`); err != nil {
+		t.Fatalf("failed to set applied net permission IPs: %v", err)
+	}
+	updatedPV, err := k8sClient.CoreV1().PersistentVolumes().Get(ctx, pv.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get PV: %v", err)
+	}
+	if conflicts != 1 || updatedPV.Annotations[common.AppliedNetPermissionIPsAnnotationKey] != `["*"]` ||
+		updatedPV.Annotations["other"] != "value" {
+		t.Errorf("unexpected annotations %v after %d conflicts", updatedPV.Annotations, conflicts)
+	}
+}
@@ -0,0 +1,530 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	snapshotinformers "github.com/kubernetes-csi/external-snapshotter/client/v4/informers/externalversions"
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	vim25types "github.com/vmware/govmomi/vim25/types"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/audit"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/utils"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
+)
+
+const (
+	// snapshotQueryVolumeBatchSize is the maximum number of volumes whose
+	// snapshots are queried by a single QuerySnapshots call.
+	snapshotQueryVolumeBatchSize = 50
+	// cnsMaxSnapshotsPerVolume is the maximum number of snapshots CNS supports
+	// on a block volume.
+	cnsMaxSnapshotsPerVolume = 32
+	// snapshotMetadataKeyPrefix is the prefix of the keys of the FCD metadata
+	// of a volume holding the VolumeSnapshot metadata of its snapshots. The
+	// key of a snapshot is the prefix followed by the CNS snapshot ID.
+	snapshotMetadataKeyPrefix = "cns.vmware.com/snapshot-"
+	// defaultSnapshotNamePrefix is the default prefix of the names generated
+	// by the csi-snapshotter for the snapshots it creates, which the driver
+	// sets as description of the CNS snapshots.
+	defaultSnapshotNamePrefix = "snapshot"
+)
+
+var (
+	// orphanSnapshotLock protects orphanSnapshotFirstSeen.
+	orphanSnapshotLock = &sync.Mutex{}
+	// orphanSnapshotFirstSeen maps the CSI snapshot ID of an orphan CNS
+	// snapshot to the time at which full sync first found it orphan.
+	orphanSnapshotFirstSeen = make(map[string]time.Time)
+
+	// snapshotMetadataLock protects pushedSnapshotMetadata.
+	snapshotMetadataLock = &sync.Mutex{}
+	// pushedSnapshotMetadata maps the CSI snapshot ID of a CNS snapshot to the
+	// VolumeSnapshot metadata last pushed to the FCD metadata of its volume.
+	pushedSnapshotMetadata = make(map[string]string)
+)
+
+// volumeSnapshotMetadata is the VolumeSnapshot metadata pushed to the FCD
+// metadata of the volume of its CNS snapshot.
+type volumeSnapshotMetadata struct {
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// getOrphanSnapshotGCGracePeriod returns the time for which a CNS snapshot
+// must be found orphan before it is deleted by full sync. If environment
+// variable ORPHAN_SNAPSHOT_GC_GRACE_PERIOD_MINUTES is set and valid, return
+// the value read from environment variable. Otherwise, use 0, which only
+// reports orphan snapshots and never deletes them.
+func getOrphanSnapshotGCGracePeriod(ctx context.Context) time.Duration {
+	log := logger.GetLogger(ctx)
+	var gracePeriod time.Duration
+	if v := os.Getenv("ORPHAN_SNAPSHOT_GC_GRACE_PERIOD_MINUTES"); v != "" {
+		if value, err := strconv.Atoi(v); err == nil && value >= 0 {
+			gracePeriod = time.Duration(value) * time.Minute
+		} else {
+			log.Warnf("Grace period set in env variable ORPHAN_SNAPSHOT_GC_GRACE_PERIOD_MINUTES %s "+
+				"is invalid, will not delete orphan snapshots", v)
+		}
+	}
+	return gracePeriod
+}
+
+// getSnapshotNamePrefix returns the prefix of the names generated by the
+// csi-snapshotter for the snapshots it creates. If environment variable
+// SNAPSHOT_NAME_PREFIX is set, return the value read from environment
+// variable. Otherwise, use the default prefix of the csi-snapshotter. It must
+// match the --snapshot-name-prefix argument of the csi-snapshotter.
+func getSnapshotNamePrefix() string {
+	if v := os.Getenv("SNAPSHOT_NAME_PREFIX"); v != "" {
+		return v
+	}
+	return defaultSnapshotNamePrefix
+}
+
+// isDriverCreatedSnapshot returns true if the given CNS snapshot description
+// is a name generated by the csi-snapshotter with the given prefix, i.e. the
+// snapshot was created by the driver. Snapshots created by other tools, e.g.
+// backup solutions, have other descriptions and must not be deleted.
+func isDriverCreatedSnapshot(description string, namePrefix string) bool {
+	if !strings.HasPrefix(description, namePrefix+"-") {
+		return false
+	}
+	_, err := uuid.Parse(strings.TrimPrefix(description, namePrefix+"-"))
+	return err == nil
+}
+
+// initVolumeSnapshotInformers starts the informers on the VolumeSnapshots and
+// VolumeSnapshotContents. The VolumeSnapshotContents of the driver are
+// reconciled with the CNS snapshots by full sync, and the name, namespace and
+// labels of the VolumeSnapshots are pushed to the FCD metadata of the volumes
+// of their CNS snapshots, as CNS does not support metadata on snapshots. The
+// informers are not started if the snapshot CRDs are not installed.
+func initVolumeSnapshotInformers(ctx context.Context, metadataSyncer *metadataSyncInformer) error {
+	log := logger.GetLogger(ctx)
+	snapshotterClient, err := k8s.NewSnapshotterClient(ctx)
+	if err != nil {
+		return logger.LogNewErrorf(log, "failed to create snapshotter client. Err: %v", err)
+	}
+	_, err = snapshotterClient.SnapshotV1().VolumeSnapshotContents().List(ctx, metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return logger.LogNewErrorf(log, "failed to list VolumeSnapshotContents. Err: %v", err)
+	}
+	metadataSyncer.snapshotterClient = snapshotterClient
+	informerFactory := snapshotinformers.NewSharedInformerFactory(snapshotterClient, 0)
+	contentInformer := informerFactory.Snapshot().V1().VolumeSnapshotContents()
+	contentInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			volumeSnapshotContentUpdated(nil, obj, metadataSyncer)
+		},
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			volumeSnapshotContentUpdated(oldObj, newObj, metadataSyncer)
+		},
+		DeleteFunc: func(obj interface{}) {
+			volumeSnapshotContentDeleted(obj, metadataSyncer)
+		},
+	})
+	snapshotInformer := informerFactory.Snapshot().V1().VolumeSnapshots()
+	snapshotInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			volumeSnapshotUpdated(obj, metadataSyncer)
+		},
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			volumeSnapshotUpdated(newObj, metadataSyncer)
+		},
+	})
+	metadataSyncer.volumeSnapshotContentLister = contentInformer.Lister()
+	metadataSyncer.volumeSnapshotLister = snapshotInformer.Lister()
+	informerFactory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), contentInformer.Informer().HasSynced,
+		snapshotInformer.Informer().HasSynced) {
+		return logger.LogNewError(log, "failed to sync VolumeSnapshot and VolumeSnapshotContent informer caches")
+	}
+	log.Infof("Initialized VolumeSnapshot and VolumeSnapshotContent informers")
+	return nil
+}
+
+// volumeSnapshotContentUpdated pushes the metadata of the VolumeSnapshot
+// bound to the given VolumeSnapshotContent of the driver once its CNS
+// snapshot is created.
+func volumeSnapshotContentUpdated(oldObj interface{}, newObj interface{}, metadataSyncer *metadataSyncInformer) {
+	ctx, log := logger.GetNewContextWithLogger()
+	newContent, ok := newObj.(*snapshotv1.VolumeSnapshotContent)
+	if !ok || newContent == nil || newContent.Spec.Driver != csitypes.Name {
+		return
+	}
+	snapshotHandle := getVolumeSnapshotContentHandle(newContent)
+	if snapshotHandle == "" {
+		return
+	}
+	if oldContent, ok := oldObj.(*snapshotv1.VolumeSnapshotContent); ok && oldContent != nil &&
+		getVolumeSnapshotContentHandle(oldContent) == snapshotHandle {
+		return
+	}
+	snapshotRef := newContent.Spec.VolumeSnapshotRef
+	snapshot, err := metadataSyncer.volumeSnapshotLister.VolumeSnapshots(snapshotRef.Namespace).Get(snapshotRef.Name)
+	if err != nil {
+		log.Debugf("VolumeSnapshot %s/%s of VolumeSnapshotContent %s is not found. Err: %v",
+			snapshotRef.Namespace, snapshotRef.Name, newContent.Name, err)
+		return
+	}
+	if err = syncVolumeSnapshotMetadata(ctx, metadataSyncer, snapshot); err != nil {
+		log.Errorf("failed to push the metadata of VolumeSnapshot %s/%s. Err: %v",
+			snapshot.Namespace, snapshot.Name, err)
+	}
+}
+
+// volumeSnapshotContentDeleted deletes the VolumeSnapshot metadata of the CNS
+// snapshot of the given VolumeSnapshotContent of the driver from the FCD
+// metadata of its volume.
+func volumeSnapshotContentDeleted(obj interface{}, metadataSyncer *metadataSyncInformer) {
+	ctx, log := logger.GetNewContextWithLogger()
+	content, ok := obj.(*snapshotv1.VolumeSnapshotContent)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			return
+		}
+		if content, ok = tombstone.Obj.(*snapshotv1.VolumeSnapshotContent); !ok {
+			return
+		}
+	}
+	if content == nil || content.Spec.Driver != csitypes.Name {
+		return
+	}
+	snapshotHandle := getVolumeSnapshotContentHandle(content)
+	volumeID, snapshotID, err := common.ParseCSISnapshotID(snapshotHandle)
+	if err != nil {
+		return
+	}
+	snapshotMetadataLock.Lock()
+	delete(pushedSnapshotMetadata, snapshotHandle)
+	snapshotMetadataLock.Unlock()
+	// The volume may already be deleted along with its snapshots.
+	err = metadataSyncer.volumeManager.UpdateVStorageObjectMetadata(ctx, volumeID, nil,
+		[]string{getSnapshotMetadataKey(snapshotID)})
+	if err != nil {
+		log.Debugf("failed to delete the metadata of CNS snapshot %q of VolumeSnapshotContent %s. Err: %v",
+			snapshotHandle, content.Name, err)
+	}
+}
+
+// volumeSnapshotUpdated pushes the metadata of the given VolumeSnapshot to
+// the FCD metadata of the volume of its CNS snapshot.
+func volumeSnapshotUpdated(obj interface{}, metadataSyncer *metadataSyncInformer) {
+	ctx, log := logger.GetNewContextWithLogger()
+	snapshot, ok := obj.(*snapshotv1.VolumeSnapshot)
+	if !ok || snapshot == nil {
+		return
+	}
+	if err := syncVolumeSnapshotMetadata(ctx, metadataSyncer, snapshot); err != nil {
+		log.Errorf("failed to push the metadata of VolumeSnapshot %s/%s. Err: %v",
+			snapshot.Namespace, snapshot.Name, err)
+	}
+}
+
+// getSnapshotMetadataKey returns the key of the FCD metadata of a volume
+// holding the VolumeSnapshot metadata of the CNS snapshot with the given ID.
+func getSnapshotMetadataKey(snapshotID string) string {
+	return snapshotMetadataKeyPrefix + snapshotID
+}
+
+// getSnapshotMetadataValue returns the JSON encoded metadata of the given
+// VolumeSnapshot pushed to the FCD metadata of its volume.
+func getSnapshotMetadataValue(snapshot *snapshotv1.VolumeSnapshot) (string, error) {
+	value, err := json.Marshal(volumeSnapshotMetadata{
+		Name:      snapshot.Name,
+		Namespace: snapshot.Namespace,
+		Labels:    snapshot.Labels,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// isSnapshotMetadataPushed returns true if the given metadata was last pushed
+// for the CNS snapshot with the given CSI snapshot ID.
+func isSnapshotMetadataPushed(csiSnapshotID string, value string) bool {
+	snapshotMetadataLock.Lock()
+	defer snapshotMetadataLock.Unlock()
+	pushedValue, ok := pushedSnapshotMetadata[csiSnapshotID]
+	return ok && pushedValue == value
+}
+
+// syncVolumeSnapshotMetadata pushes the name, namespace and labels of the
+// given VolumeSnapshot to the FCD metadata of the volume of its CNS snapshot,
+// unless they were already pushed. VolumeSnapshots which are not bound to a
+// VolumeSnapshotContent of the driver backed by a CNS snapshot are ignored.
+func syncVolumeSnapshotMetadata(ctx context.Context, metadataSyncer *metadataSyncInformer,
+	snapshot *snapshotv1.VolumeSnapshot) error {
+	log := logger.GetLogger(ctx)
+	if snapshot.DeletionTimestamp != nil || snapshot.Status == nil ||
+		snapshot.Status.BoundVolumeSnapshotContentName == nil {
+		return nil
+	}
+	content, err := metadataSyncer.volumeSnapshotContentLister.Get(*snapshot.Status.BoundVolumeSnapshotContentName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if content.Spec.Driver != csitypes.Name {
+		return nil
+	}
+	snapshotHandle := getVolumeSnapshotContentHandle(content)
+	volumeID, snapshotID, err := common.ParseCSISnapshotID(snapshotHandle)
+	if err != nil {
+		return nil
+	}
+	value, err := getSnapshotMetadataValue(snapshot)
+	if err != nil {
+		return err
+	}
+	if isSnapshotMetadataPushed(snapshotHandle, value) {
+		return nil
+	}
+	err = metadataSyncer.volumeManager.UpdateVStorageObjectMetadata(ctx, volumeID,
+		[]vim25types.KeyValue{{Key: getSnapshotMetadataKey(snapshotID), Value: value}}, nil)
+	if err != nil {
+		return err
+	}
+	snapshotMetadataLock.Lock()
+	pushedSnapshotMetadata[snapshotHandle] = value
+	snapshotMetadataLock.Unlock()
+	log.Infof("Pushed the metadata of VolumeSnapshot %s/%s to CNS snapshot %q on volume %q",
+		snapshot.Namespace, snapshot.Name, snapshotID, volumeID)
+	return nil
+}
+
+// fullSyncVolumeSnapshotMetadata pushes the metadata of the VolumeSnapshots
+// which was not pushed yet, e.g. because the push failed or because the
+// syncer restarted.
+func fullSyncVolumeSnapshotMetadata(ctx context.Context, metadataSyncer *metadataSyncInformer) {
+	log := logger.GetLogger(ctx)
+	snapshots, err := metadataSyncer.volumeSnapshotLister.List(labels.Everything())
+	if err != nil {
+		log.Errorf("FullSync: failed to list VolumeSnapshots. Err: %v", err)
+		return
+	}
+	for _, snapshot := range snapshots {
+		if err = syncVolumeSnapshotMetadata(ctx, metadataSyncer, snapshot); err != nil {
+			log.Errorf("FullSync: failed to push the metadata of VolumeSnapshot %s/%s. Err: %v",
+				snapshot.Namespace, snapshot.Name, err)
+		}
+	}
+}
+
+// getVolumeSnapshotContentHandle returns the CSI snapshot ID of the given
+// VolumeSnapshotContent, or an empty string if it is not known yet.
+func getVolumeSnapshotContentHandle(content *snapshotv1.VolumeSnapshotContent) string {
+	if content.Status != nil && content.Status.SnapshotHandle != nil {
+		return *content.Status.SnapshotHandle
+	}
+	if content.Spec.Source.SnapshotHandle != nil {
+		return *content.Spec.Source.SnapshotHandle
+	}
+	return ""
+}
+
+// getVolumeSnapshotContentMap returns a map of the CSI snapshot ID to the name
+// of the VolumeSnapshotContent of the driver backed by the snapshot.
+func getVolumeSnapshotContentMap(contents []*snapshotv1.VolumeSnapshotContent) map[string]string {
+	contentMap := make(map[string]string)
+	for _, content := range contents {
+		if content.Spec.Driver != csitypes.Name {
+			continue
+		}
+		if snapshotHandle := getVolumeSnapshotContentHandle(content); snapshotHandle != "" {
+			contentMap[snapshotHandle] = content.Name
+		}
+	}
+	return contentMap
+}
+
+// fullSyncReconcileSnapshots compares the CNS snapshots of the block volumes
+// among the given CNS volumes with the VolumeSnapshotContents of the driver.
+// It reports and returns the CNS snapshots which are not backing any
+// VolumeSnapshotContent, and reports the VolumeSnapshotContents whose CNS
+// snapshot no longer exists.
+func fullSyncReconcileSnapshots(ctx context.Context, metadataSyncer *metadataSyncInformer,
+	cnsVolumes []cnstypes.CnsVolume) ([]cnstypes.CnsSnapshot, error) {
+	log := logger.GetLogger(ctx)
+	contents, err := metadataSyncer.volumeSnapshotContentLister.List(labels.Everything())
+	if err != nil {
+		return nil, logger.LogNewErrorf(log, "FullSync: failed to list VolumeSnapshotContents. Err: %v", err)
+	}
+	contentMap := getVolumeSnapshotContentMap(contents)
+
+	var orphanSnapshots []cnstypes.CnsSnapshot
+	// queriedVolumes holds the volumes whose snapshots were queried.
+	queriedVolumes := make(map[string]bool)
+	// cnsSnapshots holds the CSI snapshot IDs of the CNS snapshots found.
+	cnsSnapshots := make(map[string]bool)
+	var blockVolumeIDs []string
+	for _, volume := range cnsVolumes {
+		if volume.VolumeType == string(cnstypes.CnsVolumeTypeBlock) {
+			blockVolumeIDs = append(blockVolumeIDs, volume.VolumeId.Id)
+		}
+	}
+	for start := 0; start < len(blockVolumeIDs); start += snapshotQueryVolumeBatchSize {
+		end := start + snapshotQueryVolumeBatchSize
+		if end > len(blockVolumeIDs) {
+			end = len(blockVolumeIDs)
+		}
+		snapshotQueryFilter := cnstypes.CnsSnapshotQueryFilter{
+			Cursor: &cnstypes.CnsCursor{
+				Offset: 0,
+				Limit:  utils.DefaultQuerySnapshotLimit,
+			},
+		}
+		for _, volumeID := range blockVolumeIDs[start:end] {
+			snapshotQueryFilter.SnapshotQuerySpecs = append(snapshotQueryFilter.SnapshotQuerySpecs,
+				cnstypes.CnsSnapshotQuerySpec{VolumeId: cnstypes.CnsVolumeId{Id: volumeID}})
+		}
+		entries, _, err := utils.QuerySnapshotsUtil(ctx, metadataSyncer.volumeManager, snapshotQueryFilter,
+			int64(end-start)*cnsMaxSnapshotsPerVolume)
+		if err != nil {
+			log.Errorf("FullSync: failed to query snapshots of %d volumes. Err: %v", end-start, err)
+			continue
+		}
+		for _, volumeID := range blockVolumeIDs[start:end] {
+			queriedVolumes[volumeID] = true
+		}
+		for _, entry := range entries {
+			if entry.Error != nil || entry.Snapshot.SnapshotId.Id == "" {
+				continue
+			}
+			csiSnapshotID := entry.Snapshot.VolumeId.Id + common.VSphereCSISnapshotIdDelimiter +
+				entry.Snapshot.SnapshotId.Id
+			cnsSnapshots[csiSnapshotID] = true
+			if _, ok := contentMap[csiSnapshotID]; !ok {
+				log.Warnf("FullSync: CNS snapshot %q on volume %q with description %q created at %v "+
+					"is not backing any VolumeSnapshotContent", entry.Snapshot.SnapshotId.Id,
+					entry.Snapshot.VolumeId.Id, entry.Snapshot.Description, entry.Snapshot.CreateTime)
+				orphanSnapshots = append(orphanSnapshots, entry.Snapshot)
+			}
+		}
+	}
+	for csiSnapshotID, contentName := range contentMap {
+		volumeID, _, err := common.ParseCSISnapshotID(csiSnapshotID)
+		if err != nil || !queriedVolumes[volumeID] || cnsSnapshots[csiSnapshotID] {
+			continue
+		}
+		log.Warnf("FullSync: CNS snapshot %q of VolumeSnapshotContent %s is not found in CNS",
+			csiSnapshotID, contentName)
+	}
+	log.Infof("FullSync: found %d CNS snapshots on %d volumes, %d of them not backing any VolumeSnapshotContent",
+		len(cnsSnapshots), len(queriedVolumes), len(orphanSnapshots))
+	return orphanSnapshots, nil
+}
+
+// getOrphanSnapshotsToDelete records the time at which each of the given
+// orphan snapshots was first found orphan, forgets the snapshots which are no
+// longer orphan, and returns the snapshots created by the driver, i.e. whose
+// description is a name generated by the csi-snapshotter with the given
+// prefix, which have been orphan and exist for longer than the grace period.
+// No snapshots are returned if the grace period is 0.
+func getOrphanSnapshotsToDelete(orphanSnapshots []cnstypes.CnsSnapshot, now time.Time,
+	gracePeriod time.Duration, snapshotNamePrefix string) []cnstypes.CnsSnapshot {
+	orphanSnapshotLock.Lock()
+	defer orphanSnapshotLock.Unlock()
+	var snapshotsToDelete []cnstypes.CnsSnapshot
+	firstSeen := make(map[string]time.Time)
+	for _, snapshot := range orphanSnapshots {
+		csiSnapshotID := snapshot.VolumeId.Id + common.VSphereCSISnapshotIdDelimiter + snapshot.SnapshotId.Id
+		seen, ok := orphanSnapshotFirstSeen[csiSnapshotID]
+		if !ok {
+			seen = now
+		}
+		firstSeen[csiSnapshotID] = seen
+		if gracePeriod > 0 && now.Sub(seen) >= gracePeriod && now.Sub(snapshot.CreateTime) >= gracePeriod &&
+			isDriverCreatedSnapshot(snapshot.Description, snapshotNamePrefix) {
+			snapshotsToDelete = append(snapshotsToDelete, snapshot)
+		}
+	}
+	orphanSnapshotFirstSeen = firstSeen
+	return snapshotsToDelete
+}
+
+// fullSyncCollectOrphanSnapshots reports the number of orphan CNS snapshots
+// and deletes the ones which have been orphan for longer than the grace
+// period set in ORPHAN_SNAPSHOT_GC_GRACE_PERIOD_MINUTES, if they were created
+// by the driver. Before deleting a snapshot, it checks again with the API
+// server, not the informer cache, that no VolumeSnapshotContent was created
+// for it in the meantime.
+func fullSyncCollectOrphanSnapshots(ctx context.Context, metadataSyncer *metadataSyncInformer,
+	orphanSnapshots []cnstypes.CnsSnapshot) {
+	log := logger.GetLogger(ctx)
+	prometheus.OrphanSnapshotsGauge.Set(float64(len(orphanSnapshots)))
+	snapshotsToDelete := getOrphanSnapshotsToDelete(orphanSnapshots, time.Now(),
+		getOrphanSnapshotGCGracePeriod(ctx), getSnapshotNamePrefix())
+	if len(snapshotsToDelete) == 0 {
+		return
+	}
+	contentList, err := metadataSyncer.snapshotterClient.SnapshotV1().VolumeSnapshotContents().List(ctx,
+		metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("FullSync: failed to list VolumeSnapshotContents, will not delete orphan snapshots. Err: %v", err)
+		return
+	}
+	contents := make([]*snapshotv1.VolumeSnapshotContent, 0, len(contentList.Items))
+	for i := range contentList.Items {
+		contents = append(contents, &contentList.Items[i])
+	}
+	contentMap := getVolumeSnapshotContentMap(contents)
+	for _, snapshot := range snapshotsToDelete {
+		csiSnapshotID := snapshot.VolumeId.Id + common.VSphereCSISnapshotIdDelimiter + snapshot.SnapshotId.Id
+		if contentName, ok := contentMap[csiSnapshotID]; ok {
+			log.Infof("FullSync: CNS snapshot %q is now backing VolumeSnapshotContent %s, will not delete it",
+				csiSnapshotID, contentName)
+			continue
+		}
+		log.Infof("FullSync: deleting orphan CNS snapshot %q on volume %q with description %q created at %v",
+			snapshot.SnapshotId.Id, snapshot.VolumeId.Id, snapshot.Description, snapshot.CreateTime)
+		auditCtx := audit.NewContext(ctx)
+		err := metadataSyncer.volumeManager.DeleteSnapshot(auditCtx, snapshot.VolumeId.Id, snapshot.SnapshotId.Id)
+		audit.Log(auditCtx, &audit.Event{
+			Operation:  audit.OperationFullSyncDeleteSnapshot,
+			Component:  audit.ComponentSyncer,
+			VolumeID:   snapshot.VolumeId.Id,
+			SnapshotID: csiSnapshotID,
+		}, err)
+		if err != nil {
+			log.Errorf("FullSync: failed to delete orphan CNS snapshot %q. Err: %v", csiSnapshotID, err)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"testing"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	snapshotlisters "github.com/kubernetes-csi/external-snapshotter/client/v4/listers/volumesnapshot/v1"
+	vim25types "github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	volumes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+// fakeSnapshotMetadataVolumeManager records the FCD metadata updates.
+type fakeSnapshotMetadataVolumeManager struct {
+	volumes.Manager
+	metadata map[string]map[string]string
+	updates  int
+}
+
+func (m *fakeSnapshotMetadataVolumeManager) UpdateVStorageObjectMetadata(ctx context.Context, volumeID string,
+	metadata []vim25types.KeyValue, deleteKeys []string) error {
+	m.updates++
+	if m.metadata[volumeID] == nil {
+		m.metadata[volumeID] = make(map[string]string)
+	}
+	for _, kv := range metadata {
+		m.metadata[volumeID][kv.Key] = kv.Value
+	}
+	for _, key := range deleteKeys {
+		delete(m.metadata[volumeID], key)
+	}
+	return nil
+}
+
+func TestSyncVolumeSnapshotMetadata(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	const (
+		volumeID   = "e5b9ab5b-3f4c-4d3a-a2e5-e3c1c4fa9a3e"
+		snapshotID = "4a0b5e8a-64f7-4b53-9bc3-0c5d2e8f3f6d"
+	)
+	contentName := "snapcontent-1"
+	snapshotHandle := volumeID + "+" + snapshotID
+	contentIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	err := contentIndexer.Add(&snapshotv1.VolumeSnapshotContent{
+		ObjectMeta: metav1.ObjectMeta{Name: contentName},
+		Spec: snapshotv1.VolumeSnapshotContentSpec{
+			Driver:            csitypes.Name,
+			VolumeSnapshotRef: v1.ObjectReference{Namespace: "ns1", Name: "snap1"},
+		},
+		Status: &snapshotv1.VolumeSnapshotContentStatus{SnapshotHandle: &snapshotHandle},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	volumeManager := &fakeSnapshotMetadataVolumeManager{metadata: make(map[string]map[string]string)}
+	metadataSyncer := &metadataSyncInformer{
+		volumeManager:               volumeManager,
+		volumeSnapshotContentLister: snapshotlisters.NewVolumeSnapshotContentLister(contentIndexer),
+	}
+	snapshot := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: "snap1", Namespace: "ns1", Labels: map[string]string{"app": "db"}},
+		Status:     &snapshotv1.VolumeSnapshotStatus{BoundVolumeSnapshotContentName: &contentName},
+	}
+
+	if err = syncVolumeSnapshotMetadata(ctx, metadataSyncer, snapshot); err != nil {
+		t.Fatal(err)
+	}
+	key := getSnapshotMetadataKey(snapshotID)
+	expected := `{"name":"snap1","namespace":"ns1","labels":{"app":"db"}}`
+	if value := volumeManager.metadata[volumeID][key]; value != expected {
+		t.Errorf("expected metadata %q, got %q", expected, value)
+	}
+
+	// Unchanged metadata is not pushed again.
+	if err = syncVolumeSnapshotMetadata(ctx, metadataSyncer, snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if volumeManager.updates != 1 {
+		t.Errorf("expected 1 metadata update, got %d", volumeManager.updates)
+	}
+
+	// Changed labels are pushed.
+	snapshot.Labels = map[string]string{"app": "web"}
+	if err = syncVolumeSnapshotMetadata(ctx, metadataSyncer, snapshot); err != nil {
+		t.Fatal(err)
+	}
+	expected = `{"name":"snap1","namespace":"ns1","labels":{"app":"web"}}`
+	if value := volumeManager.metadata[volumeID][key]; value != expected {
+		t.Errorf("expected metadata %q, got %q", expected, value)
+	}
+
+	// The metadata is deleted with the VolumeSnapshotContent.
+	content, err := metadataSyncer.volumeSnapshotContentLister.Get(contentName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	volumeSnapshotContentDeleted(content, metadataSyncer)
+	if _, ok := volumeManager.metadata[volumeID][key]; ok {
+		t.Errorf("expected metadata %q to be deleted", key)
+	}
+	if isSnapshotMetadataPushed(snapshotHandle, expected) {
+		t.Errorf("expected pushed metadata of %q to be forgotten", snapshotHandle)
+	}
+
+	// Unbound VolumeSnapshots are ignored.
+	unbound := &snapshotv1.VolumeSnapshot{ObjectMeta: metav1.ObjectMeta{Name: "snap2", Namespace: "ns1"}}
+	updates := volumeManager.updates
+	if err = syncVolumeSnapshotMetadata(ctx, metadataSyncer, unbound); err != nil {
+		t.Fatal(err)
+	}
+	if volumeManager.updates != updates {
+		t.Errorf("expected no metadata update for unbound VolumeSnapshot")
+	}
+}
@@ -0,0 +1,391 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	pbmtypes "github.com/vmware/govmomi/pbm/types"
+	vimtypes "github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	volumes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+const (
+	// storagePolicyComplianceBatchSize is the maximum number of volumes whose
+	// compliance is checked by a single PBM call.
+	storagePolicyComplianceBatchSize = 100
+
+	// storagePolicyNonCompliantReason is the reason of the event recorded on
+	// a PV when its volume no longer complies with its storage policy.
+	storagePolicyNonCompliantReason = "StoragePolicyNonCompliant"
+	// storagePolicyCompliantReason is the reason of the event recorded on a
+	// PV when its volume complies with its storage policy again.
+	storagePolicyCompliantReason = "StoragePolicyCompliant"
+	// storagePolicyReappliedReason is the reason of the event recorded on a
+	// PV when the storage policy of its volume is reapplied.
+	storagePolicyReappliedReason = "StoragePolicyReapplied"
+	// storagePolicyReapplyFailedReason is the reason of the event recorded on
+	// a PV when reapplying the storage policy of its volume fails.
+	storagePolicyReapplyFailedReason = "StoragePolicyReapplyFailed"
+
+	// storagePolicyRemediationBackoffStart is the time to wait before
+	// reapplying the storage policy of a volume again, doubled after each
+	// attempt.
+	storagePolicyRemediationBackoffStart = 1 * time.Hour
+	// storagePolicyRemediationBackoffMax is the maximum time to wait before
+	// reapplying the storage policy of a volume again.
+	storagePolicyRemediationBackoffMax = 24 * time.Hour
+)
+
+// getStoragePolicyComplianceIntervalInMin returns the interval at which the
+// storage policy compliance of volumes is checked. If environment variable
+// STORAGE_POLICY_COMPLIANCE_INTERVAL_MINUTES is set and valid, return the
+// value read from environment variable. Otherwise, use the default value.
+func getStoragePolicyComplianceIntervalInMin(ctx context.Context) int {
+	log := logger.GetLogger(ctx)
+	interval := defaultStoragePolicyComplianceIntervalInMin
+	if v := os.Getenv("STORAGE_POLICY_COMPLIANCE_INTERVAL_MINUTES"); v != "" {
+		if value, err := strconv.Atoi(v); err == nil && value > 0 {
+			interval = value
+		} else {
+			log.Warnf("StoragePolicyCompliance: interval set in env variable STORAGE_POLICY_COMPLIANCE_INTERVAL_MINUTES "+
+				"%s is invalid, will use the default interval", v)
+		}
+	}
+	return interval
+}
+
+// isStoragePolicyComplianceRemediationEnabled returns true if the storage
+// policy of non-compliant volumes should be reapplied, i.e. if environment
+// variable STORAGE_POLICY_COMPLIANCE_REMEDIATION is set to true.
+func isStoragePolicyComplianceRemediationEnabled(ctx context.Context) bool {
+	log := logger.GetLogger(ctx)
+	if v := os.Getenv("STORAGE_POLICY_COMPLIANCE_REMEDIATION"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err == nil {
+			return enabled
+		}
+		log.Warnf("StoragePolicyCompliance: value set in env variable STORAGE_POLICY_COMPLIANCE_REMEDIATION "+
+			"%s is invalid, will not reapply storage policies", v)
+	}
+	return false
+}
+
+// storagePolicyRemediation tracks the attempts to reapply the storage policy
+// of non-compliant volumes, so that the attempts for a volume which stays
+// non-compliant are backed off exponentially.
+type storagePolicyRemediation struct {
+	attempts map[string]*storagePolicyRemediationAttempt
+}
+
+// storagePolicyRemediationAttempt holds the number of attempts to reapply the
+// storage policy of a volume and the time after which it can be retried.
+type storagePolicyRemediationAttempt struct {
+	count       int
+	nextAttempt time.Time
+}
+
+// newStoragePolicyRemediation returns a storagePolicyRemediation with no
+// attempts.
+func newStoragePolicyRemediation() *storagePolicyRemediation {
+	return &storagePolicyRemediation{
+		attempts: make(map[string]*storagePolicyRemediationAttempt),
+	}
+}
+
+// shouldAttempt returns true if the storage policy of the given volume can be
+// reapplied at the given time.
+func (r *storagePolicyRemediation) shouldAttempt(volumeID string, now time.Time) bool {
+	attempt, ok := r.attempts[volumeID]
+	return !ok || !now.Before(attempt.nextAttempt)
+}
+
+// recordAttempt records an attempt to reapply the storage policy of the given
+// volume at the given time and backs off the next one.
+func (r *storagePolicyRemediation) recordAttempt(volumeID string, now time.Time) {
+	attempt, ok := r.attempts[volumeID]
+	if !ok {
+		attempt = &storagePolicyRemediationAttempt{}
+		r.attempts[volumeID] = attempt
+	}
+	backoff := storagePolicyRemediationBackoffStart
+	for i := 0; i < attempt.count && backoff < storagePolicyRemediationBackoffMax; i++ {
+		backoff *= 2
+	}
+	if backoff > storagePolicyRemediationBackoffMax {
+		backoff = storagePolicyRemediationBackoffMax
+	}
+	attempt.count++
+	attempt.nextAttempt = now.Add(backoff)
+}
+
+// reset forgets the attempts for the volumes which are not in the given set,
+// i.e. which are compliant again or no longer checked.
+func (r *storagePolicyRemediation) reset(nonCompliantVolumes map[string]bool) {
+	for volumeID := range r.attempts {
+		if !nonCompliantVolumes[volumeID] {
+			delete(r.attempts, volumeID)
+		}
+	}
+}
+
+// getVolumeManagersByVCenter returns the volume managers of the syncer, keyed
+// by vCenter host.
+func getVolumeManagersByVCenter(metadataSyncer *metadataSyncInformer) map[string]volumes.Manager {
+	if !isMultiVCenterFssEnabled {
+		return map[string]volumes.Manager{metadataSyncer.host: metadataSyncer.volumeManager}
+	}
+	return metadataSyncer.volumeManagers
+}
+
+// csiCheckStoragePolicyCompliance checks the compliance of the block volumes
+// of the bound PVs with their storage policies on all the vCenters, records
+// the result in the storage policy compliance annotation on the PVs and
+// records an event on a PV when the compliance of its volume changes. If
+// remediation is enabled, the storage policy of non-compliant volumes is
+// reapplied when their datastore is still compatible with it, backing off the
+// attempts for volumes which stay non-compliant.
+func csiCheckStoragePolicyCompliance(ctx context.Context, k8sclient clientset.Interface,
+	metadataSyncer *metadataSyncInformer, remediation *storagePolicyRemediation) {
+	log := logger.GetLogger(ctx)
+	log.Debug("csiCheckStoragePolicyCompliance: start")
+	k8sPVs, err := getBoundPVs(ctx, metadataSyncer)
+	if err != nil {
+		log.Errorf("csiCheckStoragePolicyCompliance: Failed to get PVs from kubernetes. Err: %+v", err)
+		return
+	}
+	pvMap := make(map[string]*v1.PersistentVolume, len(k8sPVs))
+	for _, pv := range k8sPVs {
+		pvMap[pv.Spec.CSI.VolumeHandle] = pv
+	}
+	nonCompliantVolumes := make(map[string]bool)
+	for vCenterHost, volumeManager := range getVolumeManagersByVCenter(metadataSyncer) {
+		checkStoragePolicyComplianceOnVCenter(ctx, k8sclient, metadataSyncer, remediation, vCenterHost,
+			volumeManager, pvMap, nonCompliantVolumes)
+	}
+	remediation.reset(nonCompliantVolumes)
+	log.Debug("csiCheckStoragePolicyCompliance: end")
+}
+
+// checkStoragePolicyComplianceOnVCenter checks the compliance of the block
+// volumes of the given vCenter whose PVs are in pvMap, and adds the volumes
+// found non-compliant to nonCompliantVolumes.
+func checkStoragePolicyComplianceOnVCenter(ctx context.Context, k8sclient clientset.Interface,
+	metadataSyncer *metadataSyncInformer, remediation *storagePolicyRemediation, vCenterHost string,
+	volumeManager volumes.Manager, pvMap map[string]*v1.PersistentVolume, nonCompliantVolumes map[string]bool) {
+	log := logger.GetLogger(ctx)
+	queryFilter := cnstypes.CnsQueryFilter{
+		ContainerClusterIds: []string{
+			clusterIDforVolumeMetadata,
+		},
+	}
+	querySelection := cnstypes.CnsQuerySelection{
+		Names: []string{
+			string(cnstypes.QuerySelectionNameTypeVolumeType),
+			string(cnstypes.QuerySelectionNameTypePolicyId),
+			string(cnstypes.QuerySelectionNameTypeDataStoreUrl),
+		},
+	}
+	queryAllResult, err := volumeManager.QueryAllVolume(ctx, queryFilter, querySelection)
+	if err != nil {
+		log.Errorf("csiCheckStoragePolicyCompliance: failed to QueryAllVolume on vCenter %q with err=%+v",
+			vCenterHost, err.Error())
+		return
+	}
+
+	// Only block volumes with a storage policy and a bound PV are checked.
+	volumeMap := make(map[string]*cnstypes.CnsVolume)
+	var volumeIDs []string
+	for i, vol := range queryAllResult.Volumes {
+		if vol.VolumeType != string(cnstypes.CnsVolumeTypeBlock) || vol.StoragePolicyId == "" {
+			continue
+		}
+		if _, ok := pvMap[vol.VolumeId.Id]; !ok {
+			continue
+		}
+		volumeMap[vol.VolumeId.Id] = &queryAllResult.Volumes[i]
+		volumeIDs = append(volumeIDs, vol.VolumeId.Id)
+	}
+	if len(volumeIDs) == 0 {
+		log.Debugf("csiCheckStoragePolicyCompliance: no volumes to check on vCenter %q", vCenterHost)
+		return
+	}
+
+	vc, err := common.GetVCenterFromVCHost(ctx, cnsvsphere.GetVirtualCenterManager(ctx), vCenterHost)
+	if err != nil {
+		log.Errorf("csiCheckStoragePolicyCompliance: failed to get vCenter instance for %q. err=%v",
+			vCenterHost, err)
+		return
+	}
+	remediate := isStoragePolicyComplianceRemediationEnabled(ctx)
+	datastoreRefs := make(map[string]*vimtypes.ManagedObjectReference)
+	for start := 0; start < len(volumeIDs); start += storagePolicyComplianceBatchSize {
+		end := start + storagePolicyComplianceBatchSize
+		if end > len(volumeIDs) {
+			end = len(volumeIDs)
+		}
+		results, err := vc.PbmCheckVolumeCompliance(ctx, volumeIDs[start:end])
+		if err != nil {
+			log.Errorf("csiCheckStoragePolicyCompliance: failed to check compliance of %d volumes on vCenter %q. "+
+				"err=%v", end-start, vCenterHost, err)
+			continue
+		}
+		for _, volumeID := range volumeIDs[start:end] {
+			result, ok := results[volumeID]
+			if !ok {
+				log.Debugf("csiCheckStoragePolicyCompliance: no compliance result for volume %q", volumeID)
+				continue
+			}
+			pv := pvMap[volumeID]
+			prevStatus := pv.Annotations[annStoragePolicyCompliance]
+			changed := updateStoragePolicyComplianceStatus(ctx, k8sclient, pv, result.ComplianceStatus)
+			switch result.ComplianceStatus {
+			case string(pbmtypes.PbmComplianceStatusNonCompliant):
+				nonCompliantVolumes[volumeID] = true
+				now := time.Now()
+				reapply := remediate && remediation.shouldAttempt(volumeID, now)
+				if remediate && !reapply {
+					log.Debugf("csiCheckStoragePolicyCompliance: backing off reapplying storage policy to volume %q",
+						volumeID)
+				}
+				if !changed && !reapply {
+					continue
+				}
+				vol := volumeMap[volumeID]
+				datastoreRef := getDatastoreRefByURL(ctx, vc, vol.DatastoreUrl, datastoreRefs)
+				compatible := isDatastoreCompatibleWithPolicy(ctx, vc, datastoreRef, vol.StoragePolicyId)
+				if changed {
+					message := "Volume " + volumeID + " does not comply with storage policy " + vol.StoragePolicyId
+					if !compatible {
+						message += "; datastore " + vol.DatastoreUrl + " is not compatible with the policy"
+					}
+					metadataSyncer.eventRecorder.Event(pv, v1.EventTypeWarning, storagePolicyNonCompliantReason,
+						message)
+				}
+				if reapply && compatible {
+					remediation.recordAttempt(volumeID, now)
+					reapplyStoragePolicy(ctx, metadataSyncer, vc, pv, vol, *datastoreRef)
+				}
+			case string(pbmtypes.PbmComplianceStatusCompliant):
+				if changed && prevStatus == string(pbmtypes.PbmComplianceStatusNonCompliant) {
+					metadataSyncer.eventRecorder.Eventf(pv, v1.EventTypeNormal, storagePolicyCompliantReason,
+						"Volume %s complies with its storage policy again", volumeID)
+				}
+			}
+		}
+	}
+}
+
+// updateStoragePolicyComplianceStatus sets the storage policy compliance
+// annotation on the given PV to the given compliance status. It returns true
+// if the compliance status has changed and the PV was updated.
+func updateStoragePolicyComplianceStatus(ctx context.Context, k8sclient clientset.Interface,
+	pv *v1.PersistentVolume, complianceStatus string) bool {
+	log := logger.GetLogger(ctx)
+	val, found := pv.Annotations[annStoragePolicyCompliance]
+	if found && val == complianceStatus {
+		return false
+	}
+	pvClone := pv.DeepCopy()
+	metav1.SetMetaDataAnnotation(&pvClone.ObjectMeta, annStoragePolicyCompliance, complianceStatus)
+	timeNow := time.Now().Format(time.UnixDate)
+	metav1.SetMetaDataAnnotation(&pvClone.ObjectMeta, annStoragePolicyComplianceTS, timeNow)
+	_, err := k8sclient.CoreV1().PersistentVolumes().Update(ctx, pvClone, metav1.UpdateOptions{})
+	if err != nil {
+		log.Errorf("updateStoragePolicyComplianceStatus: Failed to update pv %s with err:%+v", pv.Name, err)
+		return false
+	}
+	log.Infof("updateStoragePolicyComplianceStatus: set storage policy compliance annotation for pv %s from old "+
+		"value %q to new value %q and timestamp annotation to %s", pv.Name, val, complianceStatus, timeNow)
+	return true
+}
+
+// getDatastoreRefByURL returns the reference of the datastore with the given
+// URL, or nil if it is not found. Datastores found are cached in the given
+// map.
+func getDatastoreRefByURL(ctx context.Context, vc *cnsvsphere.VirtualCenter, datastoreURL string,
+	datastoreRefs map[string]*vimtypes.ManagedObjectReference) *vimtypes.ManagedObjectReference {
+	log := logger.GetLogger(ctx)
+	if datastoreRef, ok := datastoreRefs[datastoreURL]; ok {
+		return datastoreRef
+	}
+	datacenters, err := vc.GetDatacenters(ctx)
+	if err != nil {
+		log.Errorf("failed to get datacenters from vCenter %q. err=%v", vc.Config.Host, err)
+		return nil
+	}
+	var datastoreRef *vimtypes.ManagedObjectReference
+	for _, dc := range datacenters {
+		dsInfo, err := dc.GetDatastoreInfoByURL(ctx, datastoreURL)
+		if err == nil {
+			ref := dsInfo.Datastore.Reference()
+			datastoreRef = &ref
+			break
+		}
+	}
+	if datastoreRef == nil {
+		log.Warnf("datastore with URL %q not found in vCenter %q", datastoreURL, vc.Config.Host)
+	}
+	datastoreRefs[datastoreURL] = datastoreRef
+	return datastoreRef
+}
+
+// isDatastoreCompatibleWithPolicy returns true if the given datastore is
+// compatible with the given storage policy.
+func isDatastoreCompatibleWithPolicy(ctx context.Context, vc *cnsvsphere.VirtualCenter,
+	datastoreRef *vimtypes.ManagedObjectReference, policyID string) bool {
+	log := logger.GetLogger(ctx)
+	if datastoreRef == nil {
+		return false
+	}
+	compat, err := vc.PbmCheckCompatibility(ctx, []vimtypes.ManagedObjectReference{*datastoreRef}, policyID)
+	if err != nil {
+		log.Errorf("failed to check compatibility of datastore %q with storage policy %q. err=%v",
+			datastoreRef.Value, policyID, err)
+		return false
+	}
+	return len(compat.CompatibleDatastores()) > 0
+}
+
+// reapplyStoragePolicy reapplies the storage policy of the volume of the given
+// PV and records the outcome as an event on the PV.
+func reapplyStoragePolicy(ctx context.Context, metadataSyncer *metadataSyncInformer, vc *cnsvsphere.VirtualCenter,
+	pv *v1.PersistentVolume, vol *cnstypes.CnsVolume, datastoreRef vimtypes.ManagedObjectReference) {
+	log := logger.GetLogger(ctx)
+	log.Infof("reapplyStoragePolicy: reapplying storage policy %q to volume %q", vol.StoragePolicyId, vol.VolumeId.Id)
+	err := vc.UpdateVolumePolicy(ctx, vol.VolumeId.Id, datastoreRef, vol.StoragePolicyId)
+	if err != nil {
+		log.Errorf("reapplyStoragePolicy: failed to reapply storage policy %q to volume %q. err=%v",
+			vol.StoragePolicyId, vol.VolumeId.Id, err)
+		metadataSyncer.eventRecorder.Eventf(pv, v1.EventTypeWarning, storagePolicyReapplyFailedReason,
+			"Failed to reapply storage policy %s to volume %s: %v", vol.StoragePolicyId, vol.VolumeId.Id, err)
+		return
+	}
+	metadataSyncer.eventRecorder.Eventf(pv, v1.EventTypeNormal, storagePolicyReappliedReason,
+		"Reapplied storage policy %s to volume %s", vol.StoragePolicyId, vol.VolumeId.Id)
+}
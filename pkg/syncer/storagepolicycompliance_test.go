@@ -0,0 +1,116 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pbmtypes "github.com/vmware/govmomi/pbm/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+
+	volumes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
+)
+
+func TestStoragePolicyRemediationBackoff(t *testing.T) {
+	remediation := newStoragePolicyRemediation()
+	now := time.Now()
+	if !remediation.shouldAttempt("volume-1", now) {
+		t.Fatalf("expected the first attempt to be allowed")
+	}
+
+	// Each attempt doubles the backoff, up to the maximum.
+	expectedBackoffs := []time.Duration{
+		storagePolicyRemediationBackoffStart,
+		2 * storagePolicyRemediationBackoffStart,
+		4 * storagePolicyRemediationBackoffStart,
+		8 * storagePolicyRemediationBackoffStart,
+		16 * storagePolicyRemediationBackoffStart,
+		storagePolicyRemediationBackoffMax,
+		storagePolicyRemediationBackoffMax,
+	}
+	for i, backoff := range expectedBackoffs {
+		remediation.recordAttempt("volume-1", now)
+		if remediation.shouldAttempt("volume-1", now.Add(backoff-time.Second)) {
+			t.Errorf("attempt %d: expected to back off for %v", i+1, backoff)
+		}
+		if !remediation.shouldAttempt("volume-1", now.Add(backoff)) {
+			t.Errorf("attempt %d: expected to retry after %v", i+1, backoff)
+		}
+		now = now.Add(backoff)
+	}
+	if !remediation.shouldAttempt("volume-2", now) {
+		t.Errorf("expected the attempts of other volumes not to be backed off")
+	}
+
+	remediation.recordAttempt("volume-2", now)
+	remediation.reset(map[string]bool{"volume-2": true})
+	if !remediation.shouldAttempt("volume-1", now) {
+		t.Errorf("expected the attempts of a compliant volume to be reset")
+	}
+	if remediation.shouldAttempt("volume-2", now) {
+		t.Errorf("expected the attempts of a non-compliant volume to be kept")
+	}
+}
+
+func TestUpdateStoragePolicyComplianceStatus(t *testing.T) {
+	ctx := context.Background()
+	pv := &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv-1"}}
+	k8sclient := testclient.NewSimpleClientset(pv)
+
+	nonCompliant := string(pbmtypes.PbmComplianceStatusNonCompliant)
+	if !updateStoragePolicyComplianceStatus(ctx, k8sclient, pv, nonCompliant) {
+		t.Fatalf("expected the compliance status to be updated")
+	}
+	updatedPV, err := k8sclient.CoreV1().PersistentVolumes().Get(ctx, pv.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get PV: %v", err)
+	}
+	if updatedPV.Annotations[annStoragePolicyCompliance] != nonCompliant ||
+		updatedPV.Annotations[annStoragePolicyComplianceTS] == "" {
+		t.Errorf("unexpected annotations %v", updatedPV.Annotations)
+	}
+	if updateStoragePolicyComplianceStatus(ctx, k8sclient, updatedPV, nonCompliant) {
+		t.Errorf("expected the PV not to be updated when the compliance status is unchanged")
+	}
+}
+
+func TestGetVolumeManagersByVCenter(t *testing.T) {
+	savedMultiVCenterFssEnabled := isMultiVCenterFssEnabled
+	defer func() {
+		isMultiVCenterFssEnabled = savedMultiVCenterFssEnabled
+	}()
+	metadataSyncer := &metadataSyncInformer{
+		host: "vc-1",
+		volumeManagers: map[string]volumes.Manager{
+			"vc-1": nil,
+			"vc-2": nil,
+		},
+	}
+
+	isMultiVCenterFssEnabled = false
+	if volumeManagers := getVolumeManagersByVCenter(metadataSyncer); len(volumeManagers) != 1 {
+		t.Errorf("expected the volume manager of vc-1 only, got %v", volumeManagers)
+	}
+	isMultiVCenterFssEnabled = true
+	if volumeManagers := getVolumeManagersByVCenter(metadataSyncer); len(volumeManagers) != 2 {
+		t.Errorf("expected the volume managers of all the vCenters, got %v", volumeManagers)
+	}
+}
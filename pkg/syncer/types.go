@@ -20,10 +20,13 @@ import (
 	"sync"
 	"time"
 
+	snapshotterClientSet "github.com/kubernetes-csi/external-snapshotter/client/v4/clientset/versioned"
+	snapshotlisters "github.com/kubernetes-csi/external-snapshotter/client/v4/listers/volumesnapshot/v1"
 	cnstypes "github.com/vmware/govmomi/cns/types"
 	v1 "k8s.io/api/core/v1"
 	clientset "k8s.io/client-go/kubernetes"
 	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	volumes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
@@ -67,6 +70,15 @@ const (
 
 	// default interval for pv to backingdiskobjectid mapping
 	defaultPVtoBackingDiskObjectIdIntervalInMin = 10
+
+	// key for storage policy compliance annotation on PV
+	annStoragePolicyCompliance = "cns.vmware.com/storage-policy-compliance"
+
+	// key for expressing timestamp for storage policy compliance annotation
+	annStoragePolicyComplianceTS = "cns.vmware.com/storage-policy-compliance-timestamp"
+
+	// default interval for storage policy compliance check
+	defaultStoragePolicyComplianceIntervalInMin = 60
 )
 
 var (
@@ -114,6 +126,24 @@ type metadataSyncInformer struct {
 	pvcLister          corelisters.PersistentVolumeClaimLister
 	podLister          corelisters.PodLister
 	coCommonInterface  commonco.COCommonInterface
+	// eventRecorder records events on PVs and PVCs when CNS operations on
+	// their volumes fail.
+	eventRecorder record.EventRecorder
+	// metadataUpdateQueue batches the metadata updates of PVs and PVCs. It is
+	// nil if metadata updates are not batched.
+	metadataUpdateQueue *metadataUpdateQueue
+	// volumeSnapshotContentLister lists the VolumeSnapshotContents reconciled
+	// with the CNS snapshots by full sync. It is nil if snapshots are not
+	// reconciled.
+	volumeSnapshotContentLister snapshotlisters.VolumeSnapshotContentLister
+	// snapshotterClient reads the VolumeSnapshotContents from the API server
+	// before orphan CNS snapshots are deleted. It is nil if snapshots are not
+	// reconciled.
+	snapshotterClient snapshotterClientSet.Interface
+	// volumeSnapshotLister lists the VolumeSnapshots whose metadata is pushed
+	// to the FCD metadata of the volumes of their CNS snapshots. It is nil if
+	// snapshots are not reconciled.
+	volumeSnapshotLister snapshotlisters.VolumeSnapshotLister
 	// topologyVCMap maintains a cache of topology tags to the vCenter IP/FQDN which holds the tag.
 	// Example - {region1: {VC1: struct{}{}, VC2: struct{}{}},
 	//            zone1: {VC1: struct{}{}},
@@ -428,3 +428,33 @@ func getVcHostAndVolumeManagerForVolumeID(ctx context.Context,
 	return "", nil, logger.LogNewErrorf(log,
 		"failed to get VC host and volume manager. VolumeInfoService is not initialized.")
 }
+
+// getKubernetesObjectReferences returns the references, as Kind/Name or
+// Kind/Namespace/Name, of the Kubernetes objects in the given CNS volume
+// metadata.
+func getKubernetesObjectReferences(entityMetadata []cnstypes.BaseCnsEntityMetadata) []string {
+	var refs []string
+	for _, baseMetadata := range entityMetadata {
+		metadata, ok := baseMetadata.(*cnstypes.CnsKubernetesEntityMetadata)
+		if !ok {
+			continue
+		}
+		var kind string
+		switch cnstypes.CnsKubernetesEntityType(metadata.EntityType) {
+		case cnstypes.CnsKubernetesEntityTypePV:
+			kind = "PersistentVolume"
+		case cnstypes.CnsKubernetesEntityTypePVC:
+			kind = "PersistentVolumeClaim"
+		case cnstypes.CnsKubernetesEntityTypePOD:
+			kind = "Pod"
+		default:
+			kind = metadata.EntityType
+		}
+		if metadata.Namespace == "" {
+			refs = append(refs, kind+"/"+metadata.EntityName)
+		} else {
+			refs = append(refs, kind+"/"+metadata.Namespace+"/"+metadata.EntityName)
+		}
+	}
+	return refs
+}
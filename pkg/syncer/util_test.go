@@ -5,11 +5,14 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	cnstypes "github.com/vmware/govmomi/cns/types"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/syncer/k8scloudoperator"
 )
 
@@ -193,3 +196,203 @@ func TestGetSCNameFromPVC(t *testing.T) {
 	}
 	t.Log("testGetSCNameFromPVC: end")
 }
+
+func TestFullSyncShardAndSkipPVs(t *testing.T) {
+	ctx := context.Background()
+	var pvs []*corev1.PersistentVolume
+	for i := 0; i < 20; i++ {
+		pvs = append(pvs, &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pv-%d", i), ResourceVersion: "1"},
+			Spec: corev1.PersistentVolumeSpec{
+				PersistentVolumeSource: corev1.PersistentVolumeSource{
+					CSI: &corev1.CSIPersistentVolumeSource{VolumeHandle: fmt.Sprintf("volume-%d", i)},
+				},
+				ClaimRef: &corev1.ObjectReference{Namespace: fmt.Sprintf("ns-%d", i%2)},
+			},
+		})
+	}
+	for _, shardBy := range []string{fullSyncShardByVolumeID, fullSyncShardByNamespace} {
+		total := 0
+		namespaceShards := make(map[string]int)
+		for shard := 0; shard < 3; shard++ {
+			shardPVs := getFullSyncShardPVs(pvs, shard, 3, shardBy)
+			for _, pv := range shardPVs {
+				namespace := pv.Spec.ClaimRef.Namespace
+				if s, ok := namespaceShards[namespace]; ok && s != shard && shardBy == fullSyncShardByNamespace {
+					t.Errorf("PVs in namespace %s are in different shards", namespace)
+				}
+				namespaceShards[namespace] = shard
+			}
+			total += len(shardPVs)
+		}
+		if total != len(pvs) {
+			t.Errorf("expected %d PVs across shards by %s, got %d", len(pvs), shardBy, total)
+		}
+	}
+
+	// PVs found in sync are skipped until they change.
+	markFullSyncPVsInSync(pvs, pvs, pvcMap{}, podMap{}, []cnstypes.CnsVolumeCreateSpec{{Name: "pv-0"}},
+		[]cnstypes.CnsVolumeMetadataUpdateSpec{{VolumeId: cnstypes.CnsVolumeId{Id: "volume-1"}}})
+	pvs[2] = pvs[2].DeepCopy()
+	pvs[2].ResourceVersion = "2"
+	changedPVs := getFullSyncChangedPVs(ctx, pvs, pvcMap{}, podMap{}, time.Hour)
+	if len(changedPVs) != 3 || changedPVs[0].Name != "pv-0" || changedPVs[1].Name != "pv-1" ||
+		changedPVs[2].Name != "pv-2" {
+		t.Errorf("unexpected changed PVs %+v", changedPVs)
+	}
+	if changedPVs = getFullSyncChangedPVs(ctx, pvs, pvcMap{}, podMap{}, 0); len(changedPVs) != len(pvs) {
+		t.Errorf("expected all PVs when skipping is disabled, got %d", len(changedPVs))
+	}
+
+	// PVs whose PVC is used by a new pod are no longer skipped.
+	pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "pvc-3", Namespace: "ns-1",
+		ResourceVersion: "1"}}
+	pvToPVCMap := pvcMap{pvs[3].Name: pvc}
+	markFullSyncPVsInSync(pvs, pvs[3:4], pvToPVCMap, podMap{}, nil, nil)
+	if changedPVs = getFullSyncChangedPVs(ctx, pvs[3:4], pvToPVCMap, podMap{}, time.Hour); len(changedPVs) != 0 {
+		t.Errorf("expected the unchanged PV to be skipped, got %+v", changedPVs)
+	}
+	pvcToPodMap := podMap{"ns-1/pvc-3": {&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-1",
+		Namespace: "ns-1", ResourceVersion: "1"}}}}
+	if changedPVs = getFullSyncChangedPVs(ctx, pvs[3:4], pvToPVCMap, pvcToPodMap, time.Hour); len(changedPVs) != 1 {
+		t.Errorf("expected the PV whose PVC is used by a new pod not to be skipped, got %+v", changedPVs)
+	}
+}
+
+func TestMetadataUpdateQueue(t *testing.T) {
+	queue := newMetadataUpdateQueue(time.Minute, defaultMetadataUpdateBatchSize)
+	getUpdateSpec := func(entityType cnstypes.CnsKubernetesEntityType, name string, namespace string,
+		labels map[string]string) *cnstypes.CnsVolumeMetadataUpdateSpec {
+		return &cnstypes.CnsVolumeMetadataUpdateSpec{
+			VolumeId: cnstypes.CnsVolumeId{Id: "volume-1"},
+			Metadata: cnstypes.CnsVolumeMetadata{
+				EntityMetadata: []cnstypes.BaseCnsEntityMetadata{
+					cnsvsphere.GetCnsKubernetesEntityMetaData(name, labels, false, string(entityType),
+						namespace, "cluster-1", nil),
+				},
+			},
+		}
+	}
+	queue.add(nil, getUpdateSpec(cnstypes.CnsKubernetesEntityTypePVC, "pvc-1", "ns-1",
+		map[string]string{"app": "a"}), nil)
+	queue.add(nil, getUpdateSpec(cnstypes.CnsKubernetesEntityTypePV, "pv-1", "",
+		map[string]string{"tier": "gold"}), nil)
+	queue.add(nil, getUpdateSpec(cnstypes.CnsKubernetesEntityTypePVC, "pvc-1", "ns-1",
+		map[string]string{"app": "b"}), nil)
+
+	if ready := queue.popReady(time.Now()); len(ready) != 0 {
+		t.Fatalf("expected no updates to be ready before the batch interval, got %d", len(ready))
+	}
+	ready := queue.popReady(time.Now().Add(time.Minute))
+	if len(ready) != 1 {
+		t.Fatalf("expected 1 update to be ready after the batch interval, got %d", len(ready))
+	}
+	entityMetadata := ready[0].spec.Metadata.EntityMetadata
+	if len(entityMetadata) != 2 {
+		t.Fatalf("expected the updates of the PV and PVC to be merged, got %+v", entityMetadata)
+	}
+	pvcMetadata := entityMetadata[0].(*cnstypes.CnsKubernetesEntityMetadata)
+	if pvcMetadata.EntityName != "pvc-1" || len(pvcMetadata.Labels) != 1 || pvcMetadata.Labels[0].Value != "b" {
+		t.Errorf("expected the latest labels of the PVC, got %+v", pvcMetadata)
+	}
+
+	queue.add(nil, getUpdateSpec(cnstypes.CnsKubernetesEntityTypePVC, "pvc-1", "ns-1", nil), nil)
+	queue.removeEntity("volume-1", string(cnstypes.CnsKubernetesEntityTypePVC), "pvc-1", "ns-1")
+	if ready := queue.popReady(time.Now().Add(time.Hour)); len(ready) != 0 {
+		t.Errorf("expected the update of the deleted PVC to be dropped, got %d updates", len(ready))
+	}
+}
+
+func TestGetOrphanSnapshotsToDelete(t *testing.T) {
+	now := time.Now()
+	getSnapshot := func(snapshotID string, description string, createTime time.Time) cnstypes.CnsSnapshot {
+		return cnstypes.CnsSnapshot{
+			SnapshotId:  cnstypes.CnsSnapshotId{Id: snapshotID},
+			VolumeId:    cnstypes.CnsVolumeId{Id: "volume-1"},
+			Description: description,
+			CreateTime:  createTime,
+		}
+	}
+	orphanSnapshots := []cnstypes.CnsSnapshot{
+		getSnapshot("snapshot-1", "snapshot-"+uuid.New().String(), now.Add(-2*time.Hour)),
+		getSnapshot("snapshot-2", "snapshot-"+uuid.New().String(), now),
+		// Not created by the driver.
+		getSnapshot("snapshot-3", "nightly backup", now.Add(-2*time.Hour)),
+	}
+	if snapshots := getOrphanSnapshotsToDelete(orphanSnapshots, now, time.Hour, "snapshot"); len(snapshots) != 0 {
+		t.Fatalf("expected no snapshots to be deleted when first found orphan, got %+v", snapshots)
+	}
+	snapshots := getOrphanSnapshotsToDelete(orphanSnapshots, now.Add(time.Hour), time.Hour, "snapshot")
+	if len(snapshots) != 2 {
+		t.Fatalf("expected 2 snapshots to be deleted after the grace period, got %+v", snapshots)
+	}
+	// snapshot-2 is no longer orphan and snapshot-1 is found orphan again.
+	getOrphanSnapshotsToDelete(orphanSnapshots[1:], now.Add(time.Hour), time.Hour, "snapshot")
+	snapshots = getOrphanSnapshotsToDelete(orphanSnapshots[:1], now.Add(time.Hour), time.Hour, "snapshot")
+	if len(snapshots) != 0 {
+		t.Errorf("expected no snapshots to be deleted once found orphan again, got %+v", snapshots)
+	}
+	if snapshots := getOrphanSnapshotsToDelete(orphanSnapshots, now.Add(2*time.Hour), 0, "snapshot"); len(snapshots) != 0 {
+		t.Errorf("expected no snapshots to be deleted when the grace period is 0, got %+v", snapshots)
+	}
+}
+
+func TestIsDriverCreatedSnapshot(t *testing.T) {
+	snapshotName := "snapshot-" + uuid.New().String()
+	tests := []struct {
+		description string
+		namePrefix  string
+		expected    bool
+	}{
+		{snapshotName, "snapshot", true},
+		{"backup-" + uuid.New().String(), "backup", true},
+		{snapshotName, "backup", false},
+		{"snapshot-nightly", "snapshot", false},
+		{"", "snapshot", false},
+	}
+	for _, test := range tests {
+		if isDriverCreatedSnapshot(test.description, test.namePrefix) != test.expected {
+			t.Errorf("expected isDriverCreatedSnapshot(%q, %q) to be %v", test.description, test.namePrefix,
+				test.expected)
+		}
+	}
+}
+
+func TestGetKubernetesObjectReferences(t *testing.T) {
+	entityMetadata := []cnstypes.BaseCnsEntityMetadata{
+		&cnstypes.CnsKubernetesEntityMetadata{
+			CnsEntityMetadata: cnstypes.CnsEntityMetadata{EntityName: "pv-1"},
+			EntityType:        string(cnstypes.CnsKubernetesEntityTypePV),
+		},
+		&cnstypes.CnsKubernetesEntityMetadata{
+			CnsEntityMetadata: cnstypes.CnsEntityMetadata{EntityName: "pvc-1"},
+			EntityType:        string(cnstypes.CnsKubernetesEntityTypePVC),
+			Namespace:         "ns-1",
+		},
+	}
+	expected := []string{"PersistentVolume/pv-1", "PersistentVolumeClaim/ns-1/pvc-1"}
+	if refs := getKubernetesObjectReferences(entityMetadata); !reflect.DeepEqual(refs, expected) {
+		t.Errorf("expected references %v, got %v", expected, refs)
+	}
+}
+
+func TestFilterRelocatingPVs(t *testing.T) {
+	getPV := func(name string, volumeHandle string) *corev1.PersistentVolume {
+		return &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: corev1.PersistentVolumeSpec{
+				PersistentVolumeSource: corev1.PersistentVolumeSource{
+					CSI: &corev1.CSIPersistentVolumeSource{VolumeHandle: volumeHandle},
+				},
+			},
+		}
+	}
+	pvs := []*corev1.PersistentVolume{getPV("pv-1", "volume-1"), getPV("pv-2", "volume-2")}
+	if filtered := filterRelocatingPVs(context.TODO(), pvs, nil); len(filtered) != 2 {
+		t.Fatalf("expected all the PVs without relocations, got %d PVs", len(filtered))
+	}
+	filtered := filterRelocatingPVs(context.TODO(), pvs, map[string]bool{"volume-1": true})
+	if len(filtered) != 1 || filtered[0].Name != "pv-2" {
+		t.Fatalf("expected the PV whose volume is being relocated to be skipped, got %+v", filtered)
+	}
+}
@@ -18,15 +18,20 @@ package syncer
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	cnstypes "github.com/vmware/govmomi/cns/types"
 	pbmtypes "github.com/vmware/govmomi/pbm/types"
+	vimtypes "github.com/vmware/govmomi/vim25/types"
+	vsantypes "github.com/vmware/govmomi/vsan/types"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	clientset "k8s.io/client-go/kubernetes"
 
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
@@ -48,6 +53,12 @@ func csiGetVolumeHealthStatus(ctx context.Context, k8sclient clientset.Interface
 			string(cnstypes.QuerySelectionNameTypeHealthStatus),
 		},
 	}
+	richVolumeHealth := metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.RichVolumeHealth)
+	if richVolumeHealth {
+		querySelection.Names = append(querySelection.Names,
+			string(cnstypes.QuerySelectionNameTypeComplianceStatus),
+			string(cnstypes.QuerySelectionNameTypeBackingObjectDetails))
+	}
 	queryAllResult, err := metadataSyncer.volumeManager.QueryAllVolume(ctx, queryFilter, querySelection)
 	if err != nil {
 		log.Errorf("csiGetVolumeHealthStatus: failed to QueryAllVolume with err=%+v", err.Error())
@@ -82,20 +93,32 @@ func csiGetVolumeHealthStatus(ctx context.Context, k8sclient clientset.Interface
 
 	// volumeIdToHealthStatusMap maps vol.VolumeId.Id to vol.HealthStatus.
 	volumeIdToHealthStatusMap := make(volumeIdHealthStatusMap, len(queryAllResult.Volumes))
+	// volumeIdToVolumeMap maps vol.VolumeId.Id to the CNS volume.
+	volumeIdToVolumeMap := make(map[string]*cnstypes.CnsVolume, len(queryAllResult.Volumes))
 
-	for _, vol := range queryAllResult.Volumes {
+	for i, vol := range queryAllResult.Volumes {
 		volumeIdToHealthStatusMap[vol.VolumeId.Id] = vol.HealthStatus
+		volumeIdToVolumeMap[vol.VolumeId.Id] = &queryAllResult.Volumes[i]
+	}
+	var resyncingVsanObjects map[string]bool
+	if richVolumeHealth {
+		resyncingVsanObjects = getResyncingVsanObjects(ctx, metadataSyncer, queryAllResult.Volumes)
 	}
 
-	accessibleVolumeCount := 0
-	inaccessibleVolumeCount := 0
+	volumeHealthCounts := make(map[string]int)
 	for volID, pvc := range volumeHandleToPvcMap {
 		var volHealthStatusAnn string
 		if volHealthStatus, ok := volumeIdToHealthStatusMap[volID]; ok {
 			// Only update PVC health annotation if the HealthStatus of volume is
 			// not "unknown".
 			if volHealthStatus != string(pbmtypes.PbmHealthStatusForEntityUnknown) {
-				volHealthStatusAnn, err = common.ConvertVolumeHealthStatus(ctx, volID, volHealthStatus)
+				if richVolumeHealth {
+					vol := volumeIdToVolumeMap[volID]
+					volHealthStatusAnn, err = common.ConvertDetailedVolumeHealthStatus(ctx, volID, volHealthStatus,
+						vol.ComplianceStatus, resyncingVsanObjects[getBackingDiskObjectID(vol)])
+				} else {
+					volHealthStatusAnn, err = common.ConvertVolumeHealthStatus(ctx, volID, volHealthStatus)
+				}
 				if err != nil {
 					log.Errorf("csiGetVolumeHealthStatus: invalid health status %q for volume %q", volHealthStatus, volID)
 				}
@@ -117,17 +140,24 @@ func csiGetVolumeHealthStatus(ctx context.Context, k8sclient clientset.Interface
 			volHealthStatusAnn = common.VolHealthStatusInaccessible
 			updateVolumeHealthStatus(ctx, k8sclient, pvc, volHealthStatusAnn)
 		}
-		switch volHealthStatusAnn {
-		case common.VolHealthStatusAccessible:
-			accessibleVolumeCount += 1
-		case common.VolHealthStatusInaccessible:
-			inaccessibleVolumeCount += 1
+		if richVolumeHealth && volHealthStatusAnn != "" &&
+			volHealthStatusAnn != string(pbmtypes.PbmHealthStatusForEntityUnknown) {
+			updateVolumeHealthCondition(ctx, k8sclient, pvc, getVolumeHealthCondition(volHealthStatusAnn))
 		}
+		volumeHealthCounts[volHealthStatusAnn] += 1
 	}
 	prometheus.VolumeHealthGaugeVec.WithLabelValues(
-		prometheus.PrometheusAccessibleVolumes).Set(float64(accessibleVolumeCount))
+		prometheus.PrometheusAccessibleVolumes).Set(float64(volumeHealthCounts[common.VolHealthStatusAccessible]))
 	prometheus.VolumeHealthGaugeVec.WithLabelValues(
-		prometheus.PrometheusInaccessibleVolumes).Set(float64(inaccessibleVolumeCount))
+		prometheus.PrometheusInaccessibleVolumes).Set(float64(volumeHealthCounts[common.VolHealthStatusInaccessible]))
+	if richVolumeHealth {
+		prometheus.VolumeHealthGaugeVec.WithLabelValues(prometheus.PrometheusReducedAvailabilityVolumes).Set(
+			float64(volumeHealthCounts[common.VolHealthStatusReducedAvailability]))
+		prometheus.VolumeHealthGaugeVec.WithLabelValues(prometheus.PrometheusNonCompliantVolumes).Set(
+			float64(volumeHealthCounts[common.VolHealthStatusNonCompliant]))
+		prometheus.VolumeHealthGaugeVec.WithLabelValues(prometheus.PrometheusResyncingVolumes).Set(
+			float64(volumeHealthCounts[common.VolHealthStatusResyncing]))
+	}
 
 	log.Infof("GetVolumeHealthStatus: end")
 }
@@ -180,3 +210,145 @@ func updateVolumeHealthStatus(ctx context.Context, k8sclient clientset.Interface
 		}
 	}
 }
+
+// getBackingDiskObjectID returns the vSAN object ID of the disk backing the
+// given block volume, or an empty string if it is not known.
+func getBackingDiskObjectID(vol *cnstypes.CnsVolume) string {
+	if vol == nil || vol.BackingObjectDetails == nil {
+		return ""
+	}
+	if blockBackingDetails, ok := vol.BackingObjectDetails.(*cnstypes.CnsBlockBackingDetails); ok {
+		return blockBackingDetails.BackingDiskObjectId
+	}
+	return ""
+}
+
+// getResyncingVsanObjects returns the IDs of the vSAN objects backing the
+// given volumes which are being resynchronized. vSAN is only queried if some
+// volume has reduced availability, as only those can be resyncing. Failures
+// are logged and treated as no object being resynchronized.
+func getResyncingVsanObjects(ctx context.Context, metadataSyncer *metadataSyncInformer,
+	volumes []cnstypes.CnsVolume) map[string]bool {
+	log := logger.GetLogger(ctx)
+	resyncingObjects := make(map[string]bool)
+	degradedObjects := make(map[string]bool)
+	for i := range volumes {
+		if volumes[i].HealthStatus != string(pbmtypes.PbmHealthStatusForEntityYellow) {
+			continue
+		}
+		if objectID := getBackingDiskObjectID(&volumes[i]); objectID != "" {
+			degradedObjects[objectID] = true
+		}
+	}
+	if len(degradedObjects) == 0 {
+		return resyncingObjects
+	}
+	vc, err := cnsvsphere.GetVirtualCenterInstance(ctx, metadataSyncer.configInfo, false)
+	if err != nil {
+		log.Errorf("csiGetVolumeHealthStatus: failed to get vCenter instance. err=%v", err)
+		return resyncingObjects
+	}
+	if err = vc.ConnectVsan(ctx); err != nil {
+		log.Errorf("csiGetVolumeHealthStatus: failed to connect to vSAN. err=%v", err)
+		return resyncingObjects
+	}
+	clusterRef := vimtypes.ManagedObjectReference{
+		Type:  "ClusterComputeResource",
+		Value: metadataSyncer.configInfo.Cfg.Global.ClusterID,
+	}
+	objectIdentities, err := vc.VsanClient.VsanQueryObjectIdentities(ctx, clusterRef)
+	if err != nil {
+		log.Errorf("csiGetVolumeHealthStatus: failed to query vSAN object health for cluster %q. err=%v",
+			clusterRef.Value, err)
+		return resyncingObjects
+	}
+	if objectIdentities == nil || objectIdentities.Health == nil {
+		return resyncingObjects
+	}
+	for _, objectHealth := range objectIdentities.Health.ObjectHealthDetail {
+		if objectHealth.Health != string(vsantypes.VsanObjectHealthStatereducedavailabilitywithactiverebuild) {
+			continue
+		}
+		for _, objectID := range objectHealth.ObjUuids {
+			if degradedObjects[objectID] {
+				resyncingObjects[objectID] = true
+			}
+		}
+	}
+	log.Debugf("csiGetVolumeHealthStatus: %d of %d vSAN objects with reduced availability are resyncing",
+		len(resyncingObjects), len(degradedObjects))
+	return resyncingObjects
+}
+
+// getVolumeHealthCondition returns the volume health condition on a PVC for
+// the given volume health status. The condition is true only if the volume is
+// accessible and fully healthy.
+func getVolumeHealthCondition(volHealthStatus string) v1.PersistentVolumeClaimCondition {
+	condition := v1.PersistentVolumeClaimCondition{
+		Type:               common.VolumeHealthConditionType,
+		Status:             v1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+	}
+	switch volHealthStatus {
+	case common.VolHealthStatusAccessible:
+		condition.Status = v1.ConditionTrue
+		condition.Reason = "Accessible"
+		condition.Message = "Volume is accessible"
+	case common.VolHealthStatusReducedAvailability:
+		condition.Reason = "ReducedAvailability"
+		condition.Message = "Volume is accessible but has lost some of its redundancy"
+	case common.VolHealthStatusNonCompliant:
+		condition.Reason = "NonCompliant"
+		condition.Message = "Volume is accessible but does not comply with its storage policy"
+	case common.VolHealthStatusResyncing:
+		condition.Reason = "Resyncing"
+		condition.Message = "Volume is accessible and its data is being resynchronized"
+	default:
+		condition.Reason = "Inaccessible"
+		condition.Message = "Volume is inaccessible"
+	}
+	return condition
+}
+
+// getPVCCondition returns the condition of the given type on the given PVC,
+// or nil if it is not set.
+func getPVCCondition(pvc *v1.PersistentVolumeClaim,
+	conditionType v1.PersistentVolumeClaimConditionType) *v1.PersistentVolumeClaimCondition {
+	for i := range pvc.Status.Conditions {
+		if pvc.Status.Conditions[i].Type == conditionType {
+			return &pvc.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// updateVolumeHealthCondition sets the given volume health condition on the
+// given PVC, if its status or reason has changed. The condition is patched
+// into the PVC status, leaving the other conditions untouched.
+func updateVolumeHealthCondition(ctx context.Context, k8sclient clientset.Interface,
+	pvc *v1.PersistentVolumeClaim, condition v1.PersistentVolumeClaimCondition) {
+	log := logger.GetLogger(ctx)
+	existing := getPVCCondition(pvc, condition.Type)
+	if existing != nil && existing.Status == condition.Status && existing.Reason == condition.Reason {
+		return
+	}
+	patchBytes, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []v1.PersistentVolumeClaimCondition{condition},
+		},
+	})
+	if err != nil {
+		log.Errorf("updateVolumeHealthCondition: failed to marshal condition for pvc %s/%s. err=%v",
+			pvc.Namespace, pvc.Name, err)
+		return
+	}
+	_, err = k8sclient.CoreV1().PersistentVolumeClaims(pvc.Namespace).Patch(ctx, pvc.Name,
+		types.StrategicMergePatchType, patchBytes, metav1.PatchOptions{}, "status")
+	if err != nil {
+		log.Errorf("updateVolumeHealthCondition: failed to set %s condition on pvc %s/%s. err=%v",
+			condition.Type, pvc.Namespace, pvc.Name, err)
+		return
+	}
+	log.Infof("updateVolumeHealthCondition: set %s condition on pvc %s/%s to %s with reason %s",
+		condition.Type, pvc.Namespace, pvc.Name, condition.Status, condition.Reason)
+}
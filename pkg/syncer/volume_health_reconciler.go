@@ -263,6 +263,12 @@ func (rc *volumeHealthReconciler) svcUpdatePVC(oldObj, newObj interface{}) {
 	}
 	log.Debugf("updatePVC: new PVC %+v", newPVC)
 
+	if isVolumeHealthConditionChanged(oldPVC, newPVC) {
+		// volume health condition changed.
+		log.Infof("updatePVC: Detected volume health condition change. Add PVC %s/%s", newPVC.Namespace, newPVC.Name)
+		rc.svcAddPVC(newObj)
+		return
+	}
 	newPVCAnnValue, newPVCFound := newPVC.ObjectMeta.Annotations[annVolumeHealth]
 	oldPVCAnnValue, oldPVCFound := oldPVC.ObjectMeta.Annotations[annVolumeHealth]
 	if !newPVCFound && !oldPVCFound {
@@ -277,6 +283,17 @@ func (rc *volumeHealthReconciler) svcUpdatePVC(oldObj, newObj interface{}) {
 	}
 }
 
+// isVolumeHealthConditionChanged returns true if the status or reason of the
+// volume health condition differs between the given PVCs.
+func isVolumeHealthConditionChanged(oldPVC *v1.PersistentVolumeClaim, newPVC *v1.PersistentVolumeClaim) bool {
+	oldCondition := getPVCCondition(oldPVC, common.VolumeHealthConditionType)
+	newCondition := getPVCCondition(newPVC, common.VolumeHealthConditionType)
+	if oldCondition == nil || newCondition == nil {
+		return oldCondition != newCondition
+	}
+	return oldCondition.Status != newCondition.Status || oldCondition.Reason != newCondition.Reason
+}
+
 // Run starts the reconciler.
 func (rc *volumeHealthReconciler) Run(
 	ctx context.Context, workers int) {
@@ -419,6 +436,18 @@ func (rc *volumeHealthReconciler) updateTKGPVC(ctx context.Context,
 	}
 	log.Debugf("updateTKGPVC: Found Tanzu Kubernetes Grid PVC %s/%s", tkgPVCObj.Namespace, tkgPVCObj.Name)
 
+	// Copy the volume health condition, which is only set on the PVC in
+	// Supervisor Cluster when the RichVolumeHealth FSS is enabled there.
+	if svcPVC != nil {
+		svcCondition := getPVCCondition(svcPVC, common.VolumeHealthConditionType)
+		if svcCondition != nil {
+			updateVolumeHealthCondition(ctx, rc.tkgKubeClient, tkgPVCObj, *svcCondition)
+		}
+	} else if getPVCCondition(tkgPVCObj, common.VolumeHealthConditionType) != nil {
+		updateVolumeHealthCondition(ctx, rc.tkgKubeClient, tkgPVCObj,
+			getVolumeHealthCondition(common.VolHealthStatusInaccessible))
+	}
+
 	// Check if annotation is the same on PVC in Tanzu Kubernetes Grid and
 	// Supervisor Cluster and copy from Supervisor Cluster if different.
 	var tkgAnnValue, svcAnnValue string